@@ -17,10 +17,31 @@ export interface Viewport {
  * Options for browser.newPage()
  */
 export interface NewPageOptions {
+  /**
+   * Name of a device preset from devices, whose viewport, deviceScaleFactor and userAgent
+   * are used unless given. Touch support and mobile viewport behavior aren't emulated.
+   */
+  device?: string;
+
+  /**
+   * User agent reported by navigator.userAgent. safaridriver can't change the User-Agent
+   * header, so it's only overridden in the page, after it loaded.
+   */
+  userAgent?: string;
+
   /**
    * Viewport dimensions (default: { width: 1280, height: 720 })
    */
   viewport?: Viewport;
+
+  /**
+   * Device pixel ratio to capture the page at (default: 1), e.g. 2 for retina screenshots.
+   * It's requested with the safari:devicePixelRatio capability. Safari may ignore it and
+   * capture at the screen's real ratio, which screenshots then record instead.
+   * Screenshots record this value, and comparisons reject images captured
+   * at different ratios.
+   */
+  deviceScaleFactor?: number;
 }
 
 /**
@@ -39,6 +60,100 @@ export interface BrowserContext {
    * @returns Promise<Cookie[]>
    */
   cookies(): Promise<Cookie[]>;
+
+  /**
+   * Add cookies to this browser context, e.g. to seed auth cookies before navigating.
+   * WebDriver only accepts cookies for the current page's domain, so navigate to the site first.
+   * @example
+   * await page.goto('https://example.com');
+   * await context.addCookies([{ name: 'session', value: 'abc123', path: '/' }]);
+   */
+  addCookies(cookies: Cookie[]): Promise<void>;
+
+  /**
+   * Delete all cookies in this browser context
+   * @example
+   * await context.clearCookies();
+   */
+  clearCookies(): Promise<void>;
+
+  /**
+   * Delete a single cookie by name
+   * @param name Cookie name
+   * @example
+   * await context.deleteCookie('session');
+   */
+  deleteCookie(name: string): Promise<void>;
+
+  /**
+   * Add a script that runs on every page of this context when the page is created
+   * and after each navigation, after the built-in injection script. WebDriver has no
+   * hook to run scripts before the page's own, so init scripts run after them. An
+   * init script that throws rejects the navigation
+   * @param script JavaScript source
+   * @example
+   * context.addInitScript('Math.random = () => 0.5;');
+   * const page = await context.newPage();
+   */
+  addInitScript(script: string): void;
+
+  /**
+   * Make navigator.geolocation report a fixed position on every page of this context
+   * @param latitude Latitude between -90 and 90
+   * @param longitude Longitude between -180 and 180
+   * @param accuracy Accuracy in meters (default: 0)
+   * @example
+   * await context.setGeolocation(59.9139, 10.7522, 50);
+   */
+  setGeolocation(latitude: number, longitude: number, accuracy?: number): Promise<void>;
+
+  /**
+   * Answer the fetch and XMLHttpRequest requests of this context's pages whose URL
+   * matches a glob or /regex/ with a canned response, or fail them. Only requests
+   * made by page scripts can be intercepted, not subresource loads or navigations.
+   * The route added last wins
+   * @param pattern URL glob or /regex/
+   * @param handler Response to answer with, or { abort: true }
+   * @example
+   * await context.route('https://example.com/api/cart', { json: { items: [] } });
+   * await context.route('/analytics/', { abort: true });
+   */
+  route(pattern: string, handler: RouteHandler): Promise<void>;
+
+  /**
+   * Get the open pages of this context, including windows opened by its pages
+   */
+  pages(): Promise<Page[]>;
+
+  /**
+   * Wait for a page of this context to open a new window
+   * @example
+   * const [popup] = await Promise.all([
+   *   context.waitForPage(),
+   *   page.locator('a[target="_blank"]').click(),
+   * ]);
+   */
+  waitForPage(options?: { timeout?: number }): Promise<Page>;
+}
+
+/**
+ * Canned answer to the requests matching a route
+ */
+export interface RouteHandler {
+  /** Fail the request like a network error */
+  abort?: boolean;
+  /** Response status between 200 and 599 (default: 200) */
+  status?: number;
+  /** Response status text (default: the standard text for the status) */
+  statusText?: string;
+  /** Value sent as a JSON body, with content type application/json */
+  json?: any;
+  /** Body sent as is, with content type text/plain */
+  body?: string;
+  /** Overrides the content type */
+  contentType?: string;
+  /** Additional response headers */
+  headers?: Record<string, string>;
 }
 
 /**
@@ -49,6 +164,9 @@ export interface Cookie {
   value: string;
   domain?: string;
   path?: string;
+  /**
+   * Expiry as a Unix timestamp in seconds (`expiry` is accepted as an alias)
+   */
   expires?: number;
   httpOnly?: boolean;
   secure?: boolean;
@@ -81,6 +199,79 @@ export interface Browser {
    * Close the browser and all its pages
    */
   close(): Promise<void>;
+
+  /**
+   * compareScreenshots() run in the background, so that comparing large
+   * screenshots doesn't block the VU's event loop
+   * @example
+   * const similarity = await browser.compareScreenshotsAsync(screenshot1, screenshot2);
+   */
+  compareScreenshotsAsync(img1: ArrayBuffer, img2: ArrayBuffer, options?: CompareOptions): Promise<number>;
+
+  /**
+   * createDiffImage() run in the background
+   */
+  createDiffImageAsync(img1: ArrayBuffer, img2: ArrayBuffer, filePath: string, options?: CompareOptions): Promise<ArrayBuffer>;
+
+  /**
+   * createDiffImageWithStats() run in the background
+   * @example
+   * const [diff, stats] = await browser.createDiffImageWithStatsAsync(baseline, screenshot, 'diff.png');
+   */
+  createDiffImageWithStatsAsync(img1: ArrayBuffer, img2: ArrayBuffer, filePath: string, options?: CompareOptions): Promise<[ArrayBuffer, DiffStats]>;
+
+  /**
+   * compareAgainstBaseline() run in the background
+   * @example
+   * const result = await browser.compareAgainstBaselineAsync('home', await page.screenshot(), 'baselines');
+   */
+  compareAgainstBaselineAsync(name: string, actual: ArrayBuffer, baselineDir: string, options?: BaselineOptions): Promise<BaselineResult>;
+}
+
+/**
+ * Response of the document loaded by page.goto(), or of a request passed to
+ * page.onResponse() handlers
+ */
+export interface Response {
+  /**
+   * HTTP status, approximated from JavaScript as WebDriver doesn't expose it.
+   * 0 for requests that failed without a response
+   */
+  status: number;
+  url: string;
+  /**
+   * Whether the status is in the 2xx range
+   */
+  ok(): boolean;
+
+  /**
+   * HTTP status text, "" for page.goto() responses
+   */
+  statusText: string;
+
+  /**
+   * The request, null for page.goto() responses
+   */
+  request: Request | null;
+
+  /**
+   * Time from the request's start to its response in milliseconds, 0 for
+   * page.goto() responses
+   */
+  duration: number;
+}
+
+/**
+ * A fetch or XMLHttpRequest request made by the page, passed to page.onRequest() handlers
+ */
+export interface Request {
+  url: string;
+  method: string;
+  resourceType: 'fetch' | 'xhr';
+  /**
+   * When the request started, in milliseconds since the Unix epoch
+   */
+  timestamp: number;
 }
 
 /**
@@ -91,9 +282,15 @@ export interface GotoOptions {
    * When to consider navigation succeeded.
    * - 'load': Wait for the load event (default)
    * - 'domcontentloaded': Wait for DOMContentLoaded event
-   * - 'networkidle': Wait for document.readyState === 'complete' + 500ms
+   * - 'networkidle': Wait until no fetch/XMLHttpRequest has been in flight for networkIdleTime
+   * - 'commit': Return as soon as the new document replaced the current one, without waiting for it to load
+   */
+  waitUntil?: 'load' | 'domcontentloaded' | 'networkidle' | 'commit';
+
+  /**
+   * Quiet window in milliseconds for 'networkidle' (default: 500)
    */
-  waitUntil?: 'load' | 'domcontentloaded' | 'networkidle';
+  networkIdleTime?: number;
 }
 
 /**
@@ -106,28 +303,128 @@ export interface WaitForOptions {
    * - 'detached': Wait for element to not be present in DOM
    * - 'visible': Wait for element to be visible (default)
    * - 'hidden': Wait for element to be hidden
+   * - 'stable': Wait for element to stop moving and resizing, i.e. the same
+   *   bounding box in two consecutive checks 100ms apart
+   */
+  state?: 'attached' | 'detached' | 'visible' | 'hidden' | 'stable';
+
+  /**
+   * Wait until the number of matching elements satisfies a condition instead of a state.
+   * Either an exact count, or a comparison such as '>= 5', '== 0' or '< 10'
+   * (operators: ==, !=, >, >=, <, <=).
+   */
+  count?: number | string;
+
+  /**
+   * Maximum time to wait in milliseconds (default: 30000, see page.setDefaultTimeout)
+   */
+  timeout?: number;
+}
+
+/**
+ * Options for waitForFunction
+ */
+export interface WaitForFunctionOptions {
+  /**
+   * Maximum time to wait in milliseconds (default: 30000, see page.setDefaultTimeout)
    */
-  state?: 'attached' | 'detached' | 'visible' | 'hidden';
+  timeout?: number;
+
+  /**
+   * Interval between evaluations in milliseconds (default: 100)
+   */
+  polling?: number;
 }
 
 /**
  * Locator represents a way to find element(s) on the page at any moment
  */
+/**
+ * Options for locator.filter()
+ */
+export interface FilterOptions {
+  /**
+   * Keep elements whose text content contains the string (case-insensitive),
+   * or matches a regex written as '/pattern/'
+   */
+  hasText?: string;
+}
+
 export interface Locator {
+  /**
+   * Create a locator for the elements matching a CSS selector within this locator's element
+   * @param selector CSS selector, matched within the parent element
+   * @example
+   * await page.locator('#product-42').locator('button.save').click();
+   */
+  locator(selector: string): Locator;
+
+  /**
+   * Create a locator for the element at index among the matches of this locator
+   * @param index Zero-based index, negative indexes count from the end
+   * @example
+   * await page.locator('tr.order').nth(2).click();
+   */
+  nth(index: number): Locator;
+
+  /**
+   * Create a locator for the first match of this locator
+   * @example
+   * await page.locator('tr.order').first().click();
+   */
+  first(): Locator;
+
+  /**
+   * Create a locator for the last match of this locator
+   * @example
+   * await page.locator('tr.order').last().click();
+   */
+  last(): Locator;
+
+  /**
+   * Create a locator that narrows down the matches of this locator
+   * @param options Filter options
+   * @example
+   * const row = page.locator('tr').filter({ hasText: 'Order #1234' });
+   * await row.locator('button.cancel').click();
+   */
+  filter(options: FilterOptions): Locator;
+
   /**
    * Click on the element matched by the locator
    */
   click(): Promise<void>;
+
+  /**
+   * Click on the element and wait for the navigation it triggers, e.g. submitting a form
+   * @param options waitUntil (default: 'load') and networkIdleTime like goto(), timeout for the navigation to start
+   * @returns Promise that rejects if the click doesn't navigate
+   * @example
+   * await page.locator('form button[type="submit"]').clickAndWaitForNavigation({ waitUntil: 'domcontentloaded' });
+   */
+  clickAndWaitForNavigation(options?: { waitUntil?: 'load' | 'domcontentloaded' | 'networkidle' | 'commit'; networkIdleTime?: number; timeout?: number }): Promise<void>;
   
   /**
-   * Get the number of elements matching the locator
+   * Get the number of elements matching the locator. With options, first wait
+   * until at least min elements match (default: 1), up to the timeout
+   * @example
+   * const results = await page.locator('li.result').count({ min: 5, timeout: 5000 });
    */
-  count(): Promise<number>;
+  count(options?: { min?: number; timeout?: number }): Promise<number>;
   
   /**
    * Get all elements matching the locator as an array of Locators
    */
   all(): Promise<Locator[]>;
+
+  /**
+   * Find the element once and get a Locator bound to it, whose methods don't find it
+   * again. Calls fail once the element is removed from the document
+   * @example
+   * const row = await page.locator('tr.order').first().elementHandle();
+   * await row.click();
+   */
+  elementHandle(): Promise<Locator>;
   
   /**
    * Wait for the element to reach a specific state
@@ -135,6 +432,7 @@ export interface Locator {
    * @example
    * await page.locator('button').waitFor({ state: 'visible' });
    * await page.locator('div.loading').waitFor({ state: 'hidden', timeout: 5000 });
+   * await page.locator('li.result').waitFor({ count: '>= 1' });
    */
   waitFor(options?: WaitForOptions): Promise<void>;
 
@@ -147,10 +445,28 @@ export interface Locator {
    */
   textContent(): Promise<string>;
 
+  /**
+   * Get the text content of every element matching the locator in a single call
+   * @returns Promise that resolves to the text contents, in document order
+   * @example
+   * const prices = await page.locator('td.price').allTextContents();
+   */
+  allTextContents(): Promise<string[]>;
+
+  /**
+   * Run a JavaScript function with all elements matching the locator in a single call
+   * @param script Function expression receiving the elements array and args, or a function body reading arguments[0]
+   * @param args Extra arguments passed to the function
+   * @returns Promise that resolves to the function's return value
+   * @example
+   * const ids = await page.locator('tr.order').evaluateAll('(rows) => rows.map(r => r.dataset.id)');
+   */
+  evaluateAll(script: string, ...args: any[]): Promise<any>;
+
   /**
    * Type text into the element character by character
    * @param text Text to type
-   * @param options Typing options
+   * @param options Typing options; delay is the time in milliseconds between keystrokes
    * @example
    * // Type text into an input field
    * await page.locator('input[name="email"]').type('user@example.com');
@@ -159,18 +475,117 @@ export interface Locator {
    * await page.locator('input[name="search"]').type('search query', { delay: 100 });
    */
   type(text: string, options?: { delay?: number }): Promise<void>;
+
+  /**
+   * Focus the element and press a key or a "+" joined key combination; Meta is Command
+   * @param key Key or combination, e.g. "Enter", "Meta+A", "Shift+Tab"
+   * @example
+   * await page.locator('#editor').press('Meta+A');
+   */
+  press(key: string): Promise<void>;
+
+  /**
+   * Focus the element and press the key of each character of text
+   * @param text Characters to press
+   * @param options delay is the pause in milliseconds between keystrokes
+   * @example
+   * await page.locator('#autocomplete').pressSequentially('saf', { delay: 100 });
+   */
+  pressSequentially(text: string, options?: { delay?: number }): Promise<void>;
+
+  /**
+   * Empty an input, textarea or contenteditable element, firing input and change events
+   * @example
+   * await page.locator('input[name="search"]').clear();
+   */
+  clear(): Promise<void>;
+
+  /**
+   * Tap the center of the element with a touch pointer, falling back to dispatching
+   * touchstart and touchend events if the driver doesn't support touch input
+   * @example
+   * await page.locator('#menu-toggle').tap();
+   */
+  tap(): Promise<void>;
+
+  /**
+   * Dispatch a synthetic DOM event on the element, created with the constructor matching its type
+   * @param type Event type, e.g. 'dragstart' or a custom event
+   * @param eventInit Properties of the event; events bubble and are cancelable by default
+   * @example
+   * await page.locator('#card').dispatchEvent('card:select', { detail: { id: 7 } });
+   */
+  dispatchEvent(type: string, eventInit?: Record<string, any>): Promise<void>;
+
+  /**
+   * Outline the matching elements in red and cover them with a fading box, to debug which elements a selector picks
+   * @param options.duration How long the highlight lasts in milliseconds (default: 2000)
+   * @param options.persist Keep the highlight until the next click, key press or input (default: false)
+   * @example
+   * await page.locator('nav a').highlight({ persist: true });
+   */
+  highlight(options?: { duration?: number; persist?: boolean }): Promise<void>;
+
+  /**
+   * Set the files of an <input type="file">, or clear it with an empty array
+   * @param paths File paths, resolved against the working directory
+   * @example
+   * await page.locator('input[type="file"]').setInputFiles(['./fixtures/avatar.png']);
+   */
+  setInputFiles(paths: string[]): Promise<void>;
+
+  /**
+   * Check whether the element's bounding box intersects the current viewport
+   * @param options.ratio Fraction (0-1] of the element that must be inside the viewport (default: any part)
+   * @example
+   * const onScreen = await page.locator('header.sticky').isInViewport();
+   * const fullyVisible = await page.locator('img.hero').isInViewport({ ratio: 1 });
+   */
+  isInViewport(options?: { ratio?: number }): Promise<boolean>;
+
+  /**
+   * Wait until the element and its descendants have no running CSS animations or transitions
+   * @param options.timeout Maximum time to wait in milliseconds (default: 30000, see page.setDefaultTimeout)
+   * @example
+   * await page.locator('button.expand').click();
+   * await page.locator('.panel').waitForAnimationEnd();
+   */
+  waitForAnimationEnd(options?: { timeout?: number }): Promise<void>;
+
+  /**
+   * Screenshot the element and compare it against a baseline image, creating the baseline on first run
+   * @param baselinePath Path of the baseline PNG
+   * @param options Comparison and baseline options
+   * @returns Promise that resolves to the comparison result, or rejects when the screenshot doesn't match
+   * @example
+   * const result = await page.locator('button.primary').expectScreenshot('baselines/primary-button.png', {
+   *   minSimilarity: 0.995,
+   *   diffDir: 'diffs',
+   * });
+   */
+  expectScreenshot(baselinePath: string, options?: BaselineOptions): Promise<BaselineResult>;
 }
 
 /**
  * Browser page instance
  */
 export interface Page {
+  /**
+   * Get the browser context of the page
+   */
+  context(): BrowserContext;
+
   /**
    * Navigate to a URL
-   * @param url The URL to navigate to
+   * @param url The URL to navigate to: http:, https:, file: (absolute path), data: or about:
    * @param options Navigation options
+   * @returns Promise that resolves to the response of the loaded document, or null if it wasn't loaded over HTTP.
+   * WebDriver doesn't expose HTTP statuses, so the status is approximated from JavaScript.
+   * @example
+   * const response = await page.goto('https://example.com/missing');
+   * check(response, { 'status is 404': (r) => r.status === 404 });
    */
-  goto(url: string, options?: GotoOptions): Promise<void>;
+  goto(url: string, options?: GotoOptions): Promise<Response | null>;
   
   /**
    * Get the current page URL
@@ -188,28 +603,163 @@ export interface Page {
    * const allItems = await page.locator('div.item').all();
    */
   locator(selector: string): Locator;
+
+  /**
+   * List the frames of the top-level document
+   */
+  frames(): Promise<Frame[]>;
+
+  /**
+   * Get the frame matching a name or URL (exact, glob or /regex/)
+   * @example
+   * const payment = page.frame({ name: 'payment' });
+   * await payment.fill('#card', '4242 4242 4242 4242');
+   */
+  frame(options: FrameOptions): Frame;
+
+  /**
+   * Get the frame matching a frame selector: a CSS selector for the frame
+   * element, "name=..." or "url=..."
+   * @example
+   * await page.frameLocator('iframe#checkout').locator('button.pay').click();
+   */
+  frameLocator(selector: string): Frame;
   
+  /**
+   * Get a Locator bound to the focused element (document.activeElement), or null
+   * if nothing has focus
+   * @example
+   * await page.locator('#password').press('Tab');
+   * const focused = await page.getActiveElement();
+   * console.log(await focused.textContent());
+   */
+  getActiveElement(): Promise<Locator | null>;
+
   /**
    * Get the current page title
    */
   title(): Promise<string>;
+
+  /**
+   * Get the current size of the viewport, or null if it can't be measured
+   */
+  viewportSize(): Viewport | null;
+
+  /**
+   * Resize the window so that the viewport has the given size
+   * @example
+   * await page.setViewportSize(375, 667);
+   */
+  setViewportSize(width: number, height: number): Promise<void>;
   
+  /**
+   * Get the full serialized HTML of the page, including the doctype
+   * @example
+   * const html = await page.content();
+   */
+  content(): Promise<string>;
+
+  /**
+   * Replace the page's document with the given HTML, e.g. to test isolated components without a server
+   * @param html The HTML to load
+   * @param options Optional wait conditions
+   * @example
+   * await page.setContent('<button id="buy">Buy</button>');
+   * await page.click('#buy');
+   */
+  setContent(html: string, options?: GotoOptions): Promise<void>;
+
   /**
    * Execute JavaScript in the page context
    * @param script The JavaScript code to execute
    */
   evaluate(script: string): Promise<any>;
+
+  /**
+   * Execute JavaScript in the page context with arguments, avoiding string interpolation
+   * @param script A function expression called with args, or a function body reading `arguments`
+   * @param args JSON-serializable arguments
+   * @example
+   * const sum = await page.evaluateWithArgs('(a, b) => a + b', [1, 2]);
+   * const text = await page.evaluateWithArgs('sel => document.querySelector(sel).textContent', ['h1']);
+   */
+  evaluateWithArgs(script: string, args: any[]): Promise<any>;
+
+  /**
+   * Send a WebDriver command to an endpoint of the page's session and return the
+   * value of the response. An escape hatch that isn't stable across safaridriver versions
+   * @param method HTTP method, e.g. 'GET' or 'POST'
+   * @param path Path relative to the session, e.g. '/source'
+   * @param body JSON payload; POST requests without one send {}
+   * @example
+   * const source = await page.rawWebDriver('GET', '/source');
+   */
+  rawWebDriver(method: string, path: string, body?: any): Promise<any>;
+
+  /**
+   * Poll a function or expression in the page until it returns a truthy value
+   * @param script A (possibly async) function expression, or a plain expression
+   * @param options Polling options
+   * @example
+   * await page.waitForFunction('() => window.appReady === true');
+   * await page.waitForFunction("document.querySelectorAll('li').length > 3", { timeout: 5000, polling: 250 });
+   */
+  waitForFunction(script: string, options?: WaitForFunctionOptions): Promise<void>;
+
+  /**
+   * Wait for an element matching the selector to reach a state
+   * @param selector Selector for the element
+   * @param options Wait options
+   * @example
+   * await page.waitForSelector('#app', { state: 'attached', timeout: 60000 });
+   */
+  waitForSelector(selector: string, options?: WaitForOptions): Promise<void>;
+
+  /**
+   * Wait until the page's URL matches a glob ("**" and "*") or a /regex/
+   * @param pattern Glob matching the whole URL, or regex matching anywhere in it
+   * @param options timeout in milliseconds
+   * @returns Promise that resolves to the matching URL
+   * @example
+   * await page.locator('a[href="/orders"]').click();
+   * await page.waitForURL('/\\/orders$/');
+   */
+  waitForURL(pattern: string, options?: { timeout?: number }): Promise<string>;
+
+  /**
+   * Wait until the current document reaches a load state, e.g. after a click started a navigation.
+   * Resolves right away if the document is already in that state.
+   * @param state 'load' (default), 'domcontentloaded' or 'networkidle'
+   * @param options networkIdleTime in milliseconds for 'networkidle' (default: 500)
+   * @example
+   * await page.locator('form button[type="submit"]').click();
+   * await page.waitForLoadState('domcontentloaded');
+   */
+  waitForLoadState(state?: 'load' | 'domcontentloaded' | 'networkidle', options?: { networkIdleTime?: number }): Promise<void>;
+
+  /**
+   * Set the timeout used by waits that aren't given one, and by actions waiting for
+   * their element to be attached, visible and enabled, for all pages
+   * @param milliseconds Timeout in milliseconds; 0 restores the 30 second default
+   * @example
+   * page.setDefaultTimeout(60000); // Slow CI machine
+   */
+  setDefaultTimeout(milliseconds: number): void;
   
   /**
    * Click an element
    * @param selector Selector for the element. Supports multiple strategies:
-   *   - CSS: "button.submit" (default)
+   *   - CSS: "button.submit" (default), with 'button:has-text("Save")' and "li:visible" on the last compound selector
    *   - XPath: "xpath=//button[@type='submit']" or "//button"
-   *   - Text: "text=Submit Form" (exact text match)
+   *   - Text: "text=Submit Form" (exact text match) or "text=/Order #\\d+/" (regex)
+   *   - Text, case-insensitive: "text-i=submit form"
    *   - Visible Text: "visible-text=Submit" (visible elements only)
    *   - Data TestID: "data-testid=submit-button"
    *   - ARIA Label: "aria-label=Close dialog"
-   *   - ARIA Role: "role=button"
+   *   - Placeholder: "placeholder=Email address" (inputs and textareas)
+   *   - Title: "title=Delete item"
+   *   - ARIA Role: "role=button" or 'role=button[name="Sign in"]' (implicit roles and accessible name)
+   *   - Shadow-piercing CSS: "pierce=button.save" or ">>> button.save" (open shadow roots)
    *   - ID: "id=submitBtn"
    *   - Class: "class=submit-button"
    *   - Tag: "tag=button"
@@ -239,14 +789,38 @@ export interface Page {
    * const buffer = await page.screenshot();
    */
   screenshot(options?: { path?: string }): Promise<ArrayBuffer>;
-  
+
   /**
-   * Wait for a specified amount of time
-   * @param milliseconds Number of milliseconds to wait
+   * Screenshot the viewport and compare it against the baseline image `screenshots/name.png`, creating the baseline on first run
+   * @param name Baseline name, the .png extension is optional
+   * @param options Comparison and baseline options
+   * @returns Promise that resolves to the comparison result, or rejects when the screenshot doesn't match
    * @example
-   * await page.waitForTimeout(1000); // Wait for 1 second
+   * await page.expectScreenshot('home', { minSimilarity: 0.98, diffDir: 'diffs' });
    */
-  waitForTimeout(milliseconds: number): Promise<void>;
+  expectScreenshot(name: string, options?: ExpectScreenshotOptions): Promise<BaselineResult>;
+
+  /**
+   * Render the page as a PDF using its print stylesheet
+   * @example
+   * await page.pdf({ path: 'invoice.pdf', format: 'A4', margin: { top: '2cm', bottom: '2cm' } });
+   */
+  pdf(options?: PdfOptions): Promise<ArrayBuffer>;
+
+  /**
+   * Get the load timings of the page's current document
+   * @example
+   * const { ttfb, load } = await page.metrics();
+   */
+  metrics(): Promise<PageMetrics>;
+  
+  /**
+   * Wait for a specified amount of time
+   * @param milliseconds Number of milliseconds to wait
+   * @example
+   * await page.waitForTimeout(1000); // Wait for 1 second
+   */
+  waitForTimeout(milliseconds: number): Promise<void>;
   
   /**
    * Compare two screenshots and return a similarity score
@@ -277,11 +851,343 @@ export interface Page {
   countPixelDifference(img1: ArrayBuffer, img2: ArrayBuffer, threshold: number): number;
   
   /**
-   * Close the page
+   * Get the page's localStorage for the current origin
+   * @example
+   * const storage = await page.localStorage();
+   * console.log(storage['featureFlags']);
+   */
+  localStorage(): Promise<Record<string, string>>;
+
+  /**
+   * Get the page's sessionStorage for the current origin
+   */
+  sessionStorage(): Promise<Record<string, string>>;
+
+  /**
+   * Set a localStorage item for the current origin
+   * @example
+   * await page.goto('https://example.com');
+   * await page.setLocalStorage('authToken', 'abc123');
+   * await page.goto('https://example.com/app');
+   */
+  setLocalStorage(key: string, value: string): Promise<void>;
+
+  /**
+   * Remove all localStorage items for the current origin
+   */
+  clearLocalStorage(): Promise<void>;
+
+  /**
+   * Reset the page to a clean slate between iterations without recreating the session.
+   * Clears localStorage, sessionStorage and cookies, then navigates to about:blank.
+   * @example
+   * await page.reset();
+   */
+  reset(): Promise<void>;
+
+  /**
+   * Start recording the high-level actions (goto, click, fill, type) performed on the page
+   * @example
+   * page.startTracing();
+   * await page.goto('https://example.com/login');
+   * await page.fill('#username', 'admin');
+   * await page.click('button[type="submit"]');
+   * const trace = page.stopTracing({ path: 'login-trace.json' });
+   */
+  startTracing(): void;
+
+  /**
+   * Stop recording and return the trace serialized as JSON
+   */
+  stopTracing(options?: TraceOptions): string;
+
+  /**
+   * Replay the actions of a trace returned by stopTracing, in order
+   * @example
+   * const trace = open('./login-trace.json');
+   * await page.replay(trace);
+   */
+  replay(trace: string): Promise<void>;
+
+  /**
+   * Set the handler called with every native dialog (alert, confirm, prompt)
+   * the page opens, or remove it with null. Dialogs the handler doesn't accept
+   * are dismissed, as are all dialogs while no handler is set
+   * @example
+   * page.onDialog((dialog) => dialog.accept());
+   */
+  onDialog(handler: ((dialog: Dialog) => void) | null): void;
+
+  /**
+   * Set the handler called with every message the page logs to the console,
+   * or remove it with null. Messages are collected at the end of each page
+   * operation, so messages logged while a document loads aren't captured
+   * @example
+   * page.onConsole((msg) => console.log(`${msg.type()}: ${msg.text()}`));
+   */
+  onConsole(handler: ((message: ConsoleMessage) => void) | null): void;
+
+  /**
+   * Set the handler called with every uncaught exception and unhandled promise
+   * rejection in the page, or remove it with null. Errors are collected at the
+   * end of each page operation, which rejects if the handler throws
+   * @example
+   * page.onPageError((error) => { throw new Error(error.message()); });
+   */
+  onPageError(handler: ((error: PageError) => void) | null): void;
+
+  /**
+   * Set the handler called with every fetch and XMLHttpRequest request the
+   * page makes, or remove it with null. Requests are collected at the end of
+   * each page operation
+   * @example
+   * page.onRequest((request) => console.log(`${request.method} ${request.url}`));
+   */
+  onRequest(handler: ((request: Request) => void) | null): void;
+
+  /**
+   * Set the handler called with the response to every fetch and
+   * XMLHttpRequest request the page makes, or remove it with null. Requests
+   * that failed without a response have status 0
+   * @example
+   * page.onResponse((response) => console.log(`${response.status} ${response.url}`));
+   */
+  onResponse(handler: ((response: Response) => void) | null): void;
+
+  /**
+   * Close the page's window; windows it opened stay open, and its session is
+   * deleted with its last window
    */
   close(): Promise<void>;
 }
 
+/**
+ * Native dialog opened by a page
+ */
+export interface Dialog {
+  /**
+   * Get the message shown by the dialog
+   */
+  message(): string;
+
+  /**
+   * Accept the dialog, typing promptText into a prompt() dialog first
+   */
+  accept(promptText?: string): void;
+
+  /**
+   * Dismiss the dialog
+   */
+  dismiss(): void;
+}
+
+/**
+ * A message logged by the page to the console, passed to page.onConsole() handlers
+ */
+export interface ConsoleMessage {
+  /**
+   * Console method the message was logged with
+   */
+  type(): 'log' | 'debug' | 'info' | 'warning' | 'error';
+
+  /**
+   * The arguments joined with spaces, objects serialized as JSON
+   */
+  text(): string;
+
+  /**
+   * URL of the document that logged the message
+   */
+  url(): string;
+}
+
+/**
+ * An uncaught exception or unhandled promise rejection in the page, passed to
+ * page.onPageError() handlers
+ */
+export interface PageError {
+  /**
+   * The error's message
+   */
+  message(): string;
+
+  /**
+   * The error's stack trace, or "" if the page threw a value that isn't an Error
+   */
+  stack(): string;
+
+  /**
+   * Where the error was thrown. Line and column are 0 when unknown, e.g. for
+   * unhandled rejections
+   */
+  location(): { url: string; lineNumber: number; columnNumber: number };
+}
+
+/**
+ * Options for page.frame()
+ */
+export interface FrameOptions {
+  /** Frame name attribute */
+  name?: string;
+  /** Frame URL, matched exactly, as a glob, or as a /regex/ */
+  url?: string;
+}
+
+/**
+ * Frame of a page. Every operation switches the session into the frame and
+ * back to the top-level document
+ */
+export interface Frame {
+  /** Frame name, set for frames returned by page.frames() */
+  name: string;
+  /** Frame URL, set for frames returned by page.frames() */
+  url: string;
+
+  /**
+   * Create a locator for elements inside the frame
+   */
+  locator(selector: string): Locator;
+
+  /**
+   * Get a frame nested in this frame
+   */
+  frameLocator(selector: string): Frame;
+
+  /**
+   * Click an element inside the frame
+   */
+  click(selector: string): Promise<void>;
+
+  /**
+   * Fill an input field inside the frame
+   */
+  fill(selector: string, text: string): Promise<void>;
+
+  /**
+   * Execute JavaScript in the frame's document
+   */
+  evaluate(script: string): Promise<any>;
+
+  /**
+   * Get the HTML content of the frame's document
+   */
+  content(): Promise<string>;
+}
+
+/**
+ * Load timings of a document, in milliseconds since the navigation started.
+ * Timings of events that didn't happen yet are 0
+ */
+export interface PageMetrics {
+  /** Time to the first byte of the response */
+  ttfb: number;
+  /** Time until the DOMContentLoaded event finished */
+  domContentLoaded: number;
+  /** Time until the load event finished */
+  load: number;
+  /** Time until the first paint */
+  firstPaint: number;
+  /** Time until the first text or image was painted */
+  firstContentfulPaint: number;
+}
+
+/**
+ * Options for page.pdf(). Sizes are numbers in pixels or strings with a px, in, cm or mm unit
+ */
+export interface PdfOptions {
+  /** Where to save the PDF */
+  path?: string;
+  /** Paper format, e.g. "Letter" (default) or "A4" */
+  format?: string;
+  /** Page width, overriding format */
+  width?: number | string;
+  /** Page height, overriding format */
+  height?: number | string;
+  /** Page margins (default: 1cm) */
+  margin?: { top?: number | string; right?: number | string; bottom?: number | string; left?: number | string };
+  /** Landscape orientation (default: false) */
+  landscape?: boolean;
+  /** Print background colors and images (default: false) */
+  printBackground?: boolean;
+  /** Scale of the rendering, between 0.1 and 2 (default: 1) */
+  scale?: number;
+  /** Pages to print, e.g. "1-3, 5" (default: all) */
+  pageRanges?: string;
+}
+
+/**
+ * Options for stopping a trace
+ */
+export interface TraceOptions {
+  /**
+   * File to also write the serialized trace to
+   */
+  path?: string;
+}
+
+/**
+ * A single recorded page action
+ */
+export interface TraceAction {
+  type: 'goto' | 'click' | 'fill' | 'type';
+  /** Unix milliseconds */
+  timestamp: number;
+  url?: string;
+  waitUntil?: string;
+  selector?: string;
+  text?: string;
+}
+
+/**
+ * A rectangle in image pixel space (already DPR-scaled)
+ */
+export interface Rectangle {
+  min: { x: number; y: number };
+  max: { x: number; y: number };
+}
+
+/**
+ * Options for compareScreenshots() and createDiffImage()
+ */
+export interface CompareOptions {
+  /**
+   * Regions skipped during comparison and painted a neutral color in the diff.
+   * Useful for masking dynamic content such as timestamps and ads.
+   */
+  ignoreRegions?: Rectangle[];
+
+  /**
+   * Per-channel difference (0-255) above which a pixel is highlighted in the diff (default: 10).
+   * Only used by createDiffImage().
+   */
+  threshold?: number;
+
+  /**
+   * Color used to highlight different pixels in the diff (default: red).
+   * Only used by createDiffImage().
+   */
+  highlightColor?: { r: number; g: number; b: number; a: number };
+
+  /**
+   * Radius in pixels searched for a matching pixel in the other image before a
+   * difference is highlighted, so antialiasing noise doesn't swamp the diff (default: 0).
+   * Only used by createDiffImage().
+   */
+  antiAliasTolerance?: number;
+
+  /**
+   * When the screenshots differ in size, the larger one is scaled down with bilinear
+   * interpolation. Set this to use faster nearest-neighbor scaling instead (default: false).
+   */
+  nearestNeighbor?: boolean;
+
+  /**
+   * Throw an error carrying both sizes when the screenshots differ in size,
+   * instead of scaling the larger one down (default: false)
+   */
+  strict?: boolean;
+}
+
 /**
  * The global browser instance
  */
@@ -289,9 +1195,12 @@ export declare const browser: Browser;
 
 /**
  * Compare two screenshots and return a similarity score
- * @param img1 First screenshot buffer
- * @param img2 Second screenshot buffer
+ * @param img1 First screenshot buffer, PNG or JPEG
+ * @param img2 Second screenshot buffer, PNG or JPEG
+ * @param options Optional comparison options
  * @returns Similarity score between 0.0 (completely different) and 1.0 (identical)
+ * @throws If both screenshots record a device pixel ratio and the ratios differ
+ * @throws If `strict` is set and the screenshots differ in size
  * @example
  * import { compareScreenshots } from "k6/x/browser_safari";
  * 
@@ -300,15 +1209,21 @@ export declare const browser: Browser;
  * const screenshot2 = await page.screenshot();
  * const similarity = compareScreenshots(screenshot1, screenshot2);
  * console.log(`Images are ${(similarity * 100).toFixed(2)}% similar`);
+ *
+ * // Ignore a banner with a live clock
+ * const masked = compareScreenshots(screenshot1, screenshot2, {
+ *   ignoreRegions: [{ min: { x: 0, y: 0 }, max: { x: 1280, y: 40 } }],
+ * });
  */
-export declare function compareScreenshots(img1: ArrayBuffer, img2: ArrayBuffer): number;
+export declare function compareScreenshots(img1: ArrayBuffer, img2: ArrayBuffer, options?: CompareOptions): number;
 
 /**
  * Create a visual diff image highlighting differences between two screenshots
- * Identical pixels are shown in grayscale, different pixels are highlighted in red
- * @param img1 First screenshot buffer
- * @param img2 Second screenshot buffer
+ * Identical pixels are shown in grayscale, different pixels are highlighted in red (configurable)
+ * @param img1 First screenshot buffer, PNG or JPEG
+ * @param img2 Second screenshot buffer, PNG or JPEG
  * @param filePath Optional path to save the diff image (e.g., "diff.png")
+ * @param options Optional comparison options
  * @returns The diff image as an ArrayBuffer
  * @example
  * import { createDiffImage } from "k6/x/browser_safari";
@@ -322,5 +1237,224 @@ export declare function compareScreenshots(img1: ArrayBuffer, img2: ArrayBuffer)
  * 
  * // Or just get the buffer without saving
  * const diffImage = createDiffImage(screenshot1, screenshot2, "");
+ *
+ * // Magenta highlights, ignoring small antialiasing shifts
+ * createDiffImage(screenshot1, screenshot2, "diff.png", {
+ *   threshold: 20,
+ *   highlightColor: { r: 255, g: 0, b: 255, a: 255 },
+ *   antiAliasTolerance: 1,
+ * });
+ */
+export declare function createDiffImage(img1: ArrayBuffer, img2: ArrayBuffer, filePath: string, options?: CompareOptions): ArrayBuffer;
+
+/**
+ * Compare a screenshot against a baseline image file, like compareScreenshots()
+ * with the baseline as the first image. The file is read by the extension, so
+ * the script doesn't pass its bytes around.
+ * @param img Screenshot buffer
+ * @param baselinePath Path of the PNG or JPEG baseline
+ * @returns Similarity score between 0.0 (completely different) and 1.0 (identical)
+ * @example
+ * import { compareScreenshotToFile } from "k6/x/browser_safari";
+ *
+ * const similarity = compareScreenshotToFile(await page.screenshot(), "baselines/home.png");
+ */
+export declare function compareScreenshotToFile(img: ArrayBuffer, baselinePath: string, options?: CompareOptions): number;
+
+/**
+ * Create the diff image of a screenshot against a baseline image file, like
+ * createDiffImage() with the baseline as the first image
+ * @param img Screenshot buffer
+ * @param baselinePath Path of the PNG or JPEG baseline
+ * @param filePath Path to save the diff image to, or "" to only return it
+ * @example
+ * createDiffImageWithBaselineFile(await page.screenshot(), "baselines/home.png", "diffs/home-diff.png");
+ */
+export declare function createDiffImageWithBaselineFile(img: ArrayBuffer, baselinePath: string, filePath: string, options?: CompareOptions): ArrayBuffer;
+
+/**
+ * Statistics of the pixels highlighted in a diff image
+ */
+export interface DiffStats {
+  /**
+   * Number of highlighted pixels
+   */
+  diffPixelCount: number;
+  /**
+   * Number of pixels compared, excluding ignored regions
+   */
+  totalPixels: number;
+  /**
+   * diffPixelCount as a percentage (0-100) of totalPixels
+   */
+  diffPercentage: number;
+}
+
+/**
+ * Create the same diff image as createDiffImage() and count the highlighted pixels,
+ * without decoding the images a second time
+ * @returns The diff image and its statistics
+ * @example
+ * import { createDiffImageWithStats } from "k6/x/browser_safari";
+ *
+ * const [diff, stats] = createDiffImageWithStats(baseline, await page.screenshot(), "diff.png");
+ * check(stats, { "less than 1% of pixels changed": (s) => s.diffPercentage < 1 });
+ */
+export declare function createDiffImageWithStats(img1: ArrayBuffer, img2: ArrayBuffer, filePath: string, options?: CompareOptions): [ArrayBuffer, DiffStats];
+
+/**
+ * A device preset
+ */
+export interface Device {
+  /**
+   * Screen size in CSS pixels, in portrait orientation
+   */
+  viewport: Viewport;
+  deviceScaleFactor: number;
+  /**
+   * User agent of the device's Safari, reported by navigator.userAgent
+   */
+  userAgent: string;
+  isMobile: boolean;
+  hasTouch: boolean;
+}
+
+/**
+ * Set the lowest level of the messages the extension logs through the k6 logger
+ * (default: 'warn', or XK6_SAFARI_LOG_LEVEL). k6's own level still applies, so
+ * debug messages also need --verbose
+ * @param level 'debug', 'info', 'warn', 'error' or 'none'
+ * @example
+ * setLogLevel('error');
+ */
+export declare function setLogLevel(level: 'debug' | 'info' | 'warn' | 'error' | 'none'): void;
+
+/**
+ * Device presets by name, e.g. "iPhone 14" or "iPad Air", for the device option of newPage() and newContext()
+ * @example
+ * const page = await browser.newPage({ device: 'iPhone 14' });
+ */
+export declare const devices: Record<string, Device>;
+
+/**
+ * Options of expect() and of each assertion
+ */
+export interface ExpectOptions {
+  /**
+   * Time in milliseconds to retry the assertion for (default: 30000, see page.setDefaultTimeout())
+   */
+  timeout?: number;
+}
+
+/**
+ * Assertions on the elements matched by a locator, retried until they pass or time out
+ */
+export interface LocatorAssertions {
+  /**
+   * The first matching element is visible
+   */
+  toBeVisible(options?: ExpectOptions): Promise<void>;
+
+  /**
+   * The first matching element isn't disabled
+   */
+  toBeEnabled(options?: ExpectOptions): Promise<void>;
+
+  /**
+   * The text content of the first matching element is text, ignoring whitespace differences, or matches a /regex/
+   */
+  toHaveText(text: string, options?: ExpectOptions): Promise<void>;
+
+  /**
+   * The value of the first matching input, textarea or select is value, or matches a /regex/
+   */
+  toHaveValue(value: string, options?: ExpectOptions): Promise<void>;
+
+  /**
+   * Exactly count elements match the locator
+   */
+  toHaveCount(count: number, options?: ExpectOptions): Promise<void>;
+}
+
+/**
+ * Assertions on a locator that are retried until they pass, rejecting with what was last received after the timeout
+ * @example
+ * import { expect } from "k6/x/browser_safari";
+ *
+ * await page.locator('button.checkout').click();
+ * await expect(page.locator('#status')).toHaveText('Order placed');
+ */
+export declare function expect(locator: Locator, options?: ExpectOptions): LocatorAssertions;
+
+/**
+ * Options for compareAgainstBaseline()
+ */
+export interface BaselineOptions extends CompareOptions {
+  /**
+   * Similarity (0-1) below which the comparison fails (default: 0.99)
+   */
+  minSimilarity?: number;
+
+  /**
+   * Directory the diff and actual images are written to on failure (default: the baseline directory)
+   */
+  diffDir?: string;
+
+  /**
+   * Overwrite a mismatching baseline with the actual image and pass (default: false)
+   */
+  update?: boolean;
+}
+
+/**
+ * Options for page.expectScreenshot()
+ */
+export interface ExpectScreenshotOptions extends BaselineOptions {
+  /**
+   * Directory holding the baseline images (default: screenshots)
+   */
+  baselineDir?: string;
+}
+
+/**
+ * Result of compareAgainstBaseline()
+ */
+export interface BaselineResult {
+  name: string;
+  passed: boolean;
+  similarity: number;
+  /**
+   * True when no baseline existed and the actual image was stored as the new baseline
+   */
+  baselineCreated: boolean;
+  /**
+   * True when update or XK6_SAFARI_UPDATE_SNAPSHOTS is set and a mismatching baseline was overwritten
+   */
+  baselineUpdated: boolean;
+  baselinePath: string;
+  /**
+   * Path of the diff image, only set on failure
+   */
+  diffPath: string;
+  /**
+   * Path of the actual image, only set on failure
+   */
+  actualPath: string;
+}
+
+/**
+ * Compare a screenshot against the baseline image `baselineDir/name.png`.
+ * If the baseline doesn't exist yet, the screenshot is stored as the new baseline and the comparison passes.
+ * On failure, `name-diff.png` and `name-actual.png` are written to `diffDir`.
+ * Set `XK6_SAFARI_UPDATE_SNAPSHOTS=1` to overwrite mismatching baselines and pass instead.
+ * @param name Baseline name, without the .png extension
+ * @param actual Screenshot buffer
+ * @param baselineDir Directory holding the baseline images
+ * @param options Comparison options
+ * @example
+ * import { compareAgainstBaseline } from "k6/x/browser_safari";
+ *
+ * const result = compareAgainstBaseline("home", await page.screenshot(), "baselines", { diffDir: "diffs" });
+ * check(result, { "home matches baseline": (r) => r.passed });
  */
-export declare function createDiffImage(img1: ArrayBuffer, img2: ArrayBuffer, filePath: string): ArrayBuffer;
\ No newline at end of file
+export declare function compareAgainstBaseline(name: string, actual: ArrayBuffer, baselineDir: string, options?: BaselineOptions): BaselineResult;
@@ -0,0 +1,86 @@
+package browser
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// actionabilityScript returns why the element in arguments[0] can't be acted
+// on, or null once it's attached, visible and enabled
+const actionabilityScript = isVisibleScript + `
+	var el = arguments[0];
+	if (!el.isConnected) return 'detached';
+	if (!isVisible(el)) return 'not visible';
+	if ((el.matches && el.matches(':disabled')) || el.getAttribute('aria-disabled') === 'true') {
+		return 'disabled';
+	}
+	return null;
+`
+
+// actionableElementID resolves the element matched by the locator, waiting up to
+// the default timeout for it to be attached, visible and enabled, like a user
+// would before clicking or typing into it
+func (l *Locator) actionableElementID(ctx context.Context) (string, error) {
+	timeout := DefaultTimeout()
+	deadline := time.Now().Add(timeout)
+
+	for {
+		reason := "not attached"
+		elementID, err := l.resolveElementID(ctx)
+		switch {
+		case err == nil:
+			result, err := l.page.client.ExecuteScript(ctx, actionabilityScript, elementReferences([]string{elementID}))
+			if err != nil {
+				return "", fmt.Errorf("failed to check element actionability: %w", err)
+			}
+			if reason, _ = result.(string); reason == "" {
+				return elementID, nil
+			}
+		case !errors.Is(err, ErrElementNotFound) || l.elementID != "":
+			// Elements found earlier won't come back
+			return "", err
+		}
+
+		if time.Now().After(deadline) {
+			return "", fmt.Errorf("%w waiting for selector '%s' to be actionable after %s: element is %s",
+				ErrTimeout, l.selector, timeout, reason)
+		}
+
+		select {
+		case <-ctx.Done():
+			return "", ctx.Err()
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+}
+
+// isStaleElement returns whether err is the driver reporting that an element
+// reference no longer points to an element in the document
+func isStaleElement(err error) bool {
+	var wdErr *WebDriverError
+	return errors.As(err, &wdErr) && wdErr.Code == "stale element reference"
+}
+
+// withActionableElement calls fn with the element matched by the locator once
+// it's actionable. If the page re-renders the element between finding it and
+// fn acting on it, the element is found again from the selector and fn is
+// retried once. Locators bound to an element aren't retried, as their selector
+// may match other elements
+func (l *Locator) withActionableElement(ctx context.Context, fn func(elementID string) error) error {
+	elementID, err := l.actionableElementID(ctx)
+	if err != nil {
+		return err
+	}
+
+	err = fn(elementID)
+	if !isStaleElement(err) || l.elementID != "" {
+		return err
+	}
+
+	if elementID, err = l.actionableElementID(ctx); err != nil {
+		return err
+	}
+	return fn(elementID)
+}
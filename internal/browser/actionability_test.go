@@ -0,0 +1,125 @@
+package browser
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestLocatorActionableElementID(t *testing.T) {
+	t.Cleanup(func() { SetDefaultTimeout(0) })
+
+	var mu sync.Mutex
+	var finds int
+	var states []interface{} // Reported by the actionability script, in order
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		if strings.HasSuffix(r.URL.Path, "/element") {
+			// The element is rendered after the first lookup
+			finds++
+			if finds == 1 {
+				w.WriteHeader(http.StatusNotFound)
+				_, _ = w.Write([]byte(`{"value":{"error":"no such element","message":""}}`))
+				return
+			}
+			_, _ = w.Write([]byte(`{"value":{"element-6066-11e4-a52e-4f735466cecf":"submit"}}`))
+			return
+		}
+
+		var state interface{}
+		if len(states) > 0 {
+			state, states = states[0], states[1:]
+		}
+		data, _ := json.Marshal(map[string]interface{}{"value": state})
+		_, _ = w.Write(data)
+	}))
+	defer server.Close()
+
+	page := &Page{client: NewWebDriverClient(server.URL).forSession("session-1")}
+	locator := page.Locator("button.submit")
+	ctx := context.Background()
+
+	states = []interface{}{"not visible", "disabled", nil}
+	elementID, err := locator.actionableElementID(ctx)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if elementID != "submit" || finds != 4 || len(states) != 0 {
+		t.Errorf("Expected the element to be found once actionable, got %q after %d lookups", elementID, finds)
+	}
+
+	SetDefaultTimeout(250 * time.Millisecond)
+	states = []interface{}{"disabled", "disabled", "disabled", "disabled", "disabled"}
+	_, err = locator.actionableElementID(ctx)
+	if !errors.Is(err, ErrTimeout) || !strings.Contains(err.Error(), "element is disabled") {
+		t.Errorf("Expected a timeout reporting the element's state, got %v", err)
+	}
+}
+
+func TestLocatorWithActionableElementStale(t *testing.T) {
+	var mu sync.Mutex
+	var finds int
+	var clicked []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		if strings.HasSuffix(r.URL.Path, "/element") {
+			// The framework re-renders the button after it was first found
+			finds++
+			id := "old"
+			if finds > 1 {
+				id = "new"
+			}
+			_, _ = w.Write([]byte(`{"value":{"element-6066-11e4-a52e-4f735466cecf":"` + id + `"}}`))
+			return
+		}
+
+		var payload struct {
+			Script string              `json:"script"`
+			Args   []map[string]string `json:"args"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&payload)
+		elementID := payload.Args[0]["element-6066-11e4-a52e-4f735466cecf"]
+		if elementID == "old" && !strings.Contains(payload.Script, "isConnected") {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"value":{"error":"stale element reference","message":"gone"}}`))
+			return
+		}
+		if strings.Contains(payload.Script, "element.click()") {
+			clicked = append(clicked, elementID)
+		}
+		_, _ = w.Write([]byte(`{"value":null}`))
+	}))
+	defer server.Close()
+
+	page := &Page{client: NewWebDriverClient(server.URL).forSession("session-1")}
+	ctx := context.Background()
+	click := func(l *Locator) error {
+		return l.withActionableElement(ctx, func(elementID string) error {
+			return page.client.ClickElement(ctx, elementID)
+		})
+	}
+
+	if err := click(page.Locator("button.save")); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if finds != 2 || strings.Join(clicked, ",") != "new" {
+		t.Errorf("Expected the re-rendered element to be found again and clicked, got %d lookups and clicks %v", finds, clicked)
+	}
+
+	// A locator bound to the stale element can't find it again
+	if err := click(page.Locator("button.save").pinned("old")); !isStaleElement(err) {
+		t.Errorf("Expected the stale element error, got %v", err)
+	}
+}
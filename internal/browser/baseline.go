@@ -0,0 +1,206 @@
+package browser
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/grafana/sobek"
+)
+
+const (
+	// defaultMinSimilarity is the similarity below which a baseline comparison fails
+	defaultMinSimilarity = 0.99
+
+	// defaultBaselineDir is the directory page.expectScreenshot stores baselines in
+	defaultBaselineDir = "screenshots"
+
+	// updateSnapshotsEnv enables overwriting baselines with the actual images when set to a true value
+	updateSnapshotsEnv = "XK6_SAFARI_UPDATE_SNAPSHOTS"
+)
+
+// BaselineOptions contains options for CompareAgainstBaseline
+type BaselineOptions struct {
+	CompareOptions
+
+	// MinSimilarity is the similarity score (0-1) below which the comparison
+	// fails. Defaults to defaultMinSimilarity when zero.
+	MinSimilarity float64 `js:"minSimilarity"`
+
+	// DiffDir is the directory the diff and actual images are written to on
+	// failure. Defaults to the baseline directory.
+	DiffDir string `js:"diffDir"`
+
+	// Update overwrites a mismatching baseline with the actual image, like
+	// XK6_SAFARI_UPDATE_SNAPSHOTS does for every comparison
+	Update bool `js:"update"`
+}
+
+// ExpectScreenshotOptions contains options for Page.ExpectScreenshot
+type ExpectScreenshotOptions struct {
+	BaselineOptions
+
+	// BaselineDir is the directory holding the baseline images. Defaults to
+	// defaultBaselineDir.
+	BaselineDir string `js:"baselineDir"`
+}
+
+// BaselineResult describes the outcome of a baseline comparison
+type BaselineResult struct {
+	Name            string  `js:"name"`
+	Passed          bool    `js:"passed"`
+	Similarity      float64 `js:"similarity"`
+	BaselineCreated bool    `js:"baselineCreated"`
+	BaselineUpdated bool    `js:"baselineUpdated"`
+	BaselinePath    string  `js:"baselinePath"`
+	DiffPath        string  `js:"diffPath"`   // Only set on failure
+	ActualPath      string  `js:"actualPath"` // Only set on failure
+}
+
+// CompareAgainstBaseline compares actual against the baseline image baselineDir/name.png
+// If the baseline doesn't exist yet, actual is written as the new baseline and the
+// comparison passes. On failure, a diff image and the actual image are written to
+// options.DiffDir as name-diff.png and name-actual.png.
+// When options.Update or XK6_SAFARI_UPDATE_SNAPSHOTS is set, mismatching baselines are overwritten
+// with actual and the comparison passes, to accept intentional UI changes in bulk. This includes
+// baselines captured at another device pixel ratio, or of another size in strict mode.
+func CompareAgainstBaseline(name string, actual []byte, baselineDir string, opts ...BaselineOptions) (*BaselineResult, error) {
+	var options BaselineOptions
+	if len(opts) > 0 {
+		options = opts[0]
+	}
+	minSimilarity := options.MinSimilarity
+	if minSimilarity == 0 {
+		minSimilarity = defaultMinSimilarity
+	}
+	diffDir := options.DiffDir
+	if diffDir == "" {
+		diffDir = baselineDir
+	}
+
+	result := &BaselineResult{
+		Name:         name,
+		BaselinePath: filepath.Join(baselineDir, name+".png"),
+	}
+
+	baseline, err := os.ReadFile(result.BaselinePath)
+	if errors.Is(err, fs.ErrNotExist) {
+		if err := writeImageFile(result.BaselinePath, actual); err != nil {
+			return nil, fmt.Errorf("failed to create baseline: %w", err)
+		}
+		result.Passed = true
+		result.Similarity = 1.0
+		result.BaselineCreated = true
+		return result, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read baseline %s: %w", result.BaselinePath, err)
+	}
+
+	update := options.Update || updateSnapshots()
+	result.Similarity, err = CompareImages(baseline, actual, options.CompareOptions)
+	// A baseline captured at another device pixel ratio, or of another size in
+	// strict mode, can't be compared, but can still be replaced
+	mismatched := errors.Is(err, ErrDPRMismatch) || errors.Is(err, ErrDimensionMismatch)
+	if err != nil && !(update && mismatched) {
+		return nil, fmt.Errorf("failed to compare against baseline %s: %w", result.BaselinePath, err)
+	}
+
+	result.Passed = err == nil && result.Similarity >= minSimilarity
+	if result.Passed {
+		return result, nil
+	}
+
+	if update {
+		if err := writeImageFile(result.BaselinePath, actual); err != nil {
+			return nil, fmt.Errorf("failed to update baseline: %w", err)
+		}
+		result.Passed = true
+		result.BaselineUpdated = true
+		return result, nil
+	}
+
+	result.DiffPath = filepath.Join(diffDir, name+"-diff.png")
+	result.ActualPath = filepath.Join(diffDir, name+"-actual.png")
+
+	if err := os.MkdirAll(filepath.Dir(result.DiffPath), 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create diff directory: %w", err)
+	}
+	if _, err := CreateDiffImage(baseline, actual, result.DiffPath, options.CompareOptions); err != nil {
+		return nil, err
+	}
+	if err := writeImageFile(result.ActualPath, actual); err != nil {
+		return nil, fmt.Errorf("failed to write actual image: %w", err)
+	}
+
+	return result, nil
+}
+
+// CompareScreenshotToFile compares a screenshot against the baseline image at
+// baselinePath, like CompareImages. The baseline is read in Go so that scripts
+// don't have to load it and pass its bytes back in
+func CompareScreenshotToFile(imgBytes []byte, baselinePath string, opts ...CompareOptions) (float64, error) {
+	baseline, err := os.ReadFile(baselinePath)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read baseline %s: %w", baselinePath, err)
+	}
+
+	return CompareImages(baseline, imgBytes, opts...)
+}
+
+// CreateDiffImageWithBaselineFile creates the diff image of a screenshot against
+// the baseline image at baselinePath, like CreateDiffImage, reading the baseline in Go
+func CreateDiffImageWithBaselineFile(imgBytes []byte, baselinePath, filePath string, opts ...CompareOptions) ([]byte, error) {
+	baseline, err := os.ReadFile(baselinePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read baseline %s: %w", baselinePath, err)
+	}
+
+	return CreateDiffImage(baseline, imgBytes, filePath, opts...)
+}
+
+// ScreenshotMismatchError is returned when a screenshot doesn't match its baseline
+type ScreenshotMismatchError struct {
+	Result *BaselineResult
+}
+
+func (e *ScreenshotMismatchError) Error() string {
+	return fmt.Sprintf("screenshot %q does not match baseline %s: similarity %.4f, diff written to %s",
+		e.Result.Name, e.Result.BaselinePath, e.Result.Similarity, e.Result.DiffPath)
+}
+
+// splitBaselinePath splits a baseline file path into the baseline directory and
+// the name CompareAgainstBaseline expects, stripping any .png extension
+func splitBaselinePath(baselinePath string) (dir, name string) {
+	dir, file := filepath.Split(baselinePath)
+	if dir == "" {
+		dir = "."
+	}
+	return filepath.Clean(dir), strings.TrimSuffix(file, ".png")
+}
+
+// writeImageFile writes an image to path, creating parent directories as needed
+func writeImageFile(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+// updateSnapshots reports whether baselines should be overwritten with the actual images
+func updateSnapshots() bool {
+	update, err := strconv.ParseBool(os.Getenv(updateSnapshotsEnv))
+	return err == nil && update
+}
+
+// CompareAgainstBaselineAsync is CompareAgainstBaseline run off the event loop.
+// It resolves to the result
+func (b *Browser) CompareAgainstBaselineAsync(name string, actual []byte, baselineDir string, opts ...BaselineOptions) *sobek.Promise {
+	return Promise(b.VU, func() (any, error) {
+		return CompareAgainstBaseline(name, actual, baselineDir, opts...)
+	})
+}
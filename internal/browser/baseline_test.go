@@ -0,0 +1,261 @@
+package browser
+
+import (
+	"encoding/base64"
+	"image"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.k6.io/k6/js/modulestest"
+)
+
+func TestCompareAgainstBaseline(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	diffDir := filepath.Join(dir, "diffs")
+	baseline := solidPNG(t, 10, 10, white, white)
+	changed := solidPNG(t, 10, 10, black, black)
+
+	// First run creates the baseline
+	result, err := CompareAgainstBaseline("home", baseline, dir)
+	require.NoError(t, err)
+	require.True(t, result.Passed)
+	require.True(t, result.BaselineCreated)
+	require.FileExists(t, filepath.Join(dir, "home.png"))
+
+	// Matching screenshots pass without writing a diff
+	result, err = CompareAgainstBaseline("home", baseline, dir, BaselineOptions{DiffDir: diffDir})
+	require.NoError(t, err)
+	require.True(t, result.Passed)
+	require.False(t, result.BaselineCreated)
+	require.Equal(t, 1.0, result.Similarity)
+	require.Empty(t, result.DiffPath)
+	require.NoDirExists(t, diffDir)
+
+	// Mismatches fail and write the diff and actual images
+	result, err = CompareAgainstBaseline("home", changed, dir, BaselineOptions{DiffDir: diffDir})
+	require.NoError(t, err)
+	require.False(t, result.Passed)
+	require.Less(t, result.Similarity, defaultMinSimilarity)
+	require.Equal(t, filepath.Join(diffDir, "home-diff.png"), result.DiffPath)
+	require.FileExists(t, result.DiffPath)
+	actual, err := os.ReadFile(result.ActualPath)
+	require.NoError(t, err)
+	require.Equal(t, changed, actual)
+
+	// The baseline is left untouched
+	stored, err := os.ReadFile(result.BaselinePath)
+	require.NoError(t, err)
+	require.Equal(t, baseline, stored)
+}
+
+func TestCompareAgainstBaselineUpdateSnapshots(t *testing.T) {
+	t.Setenv(updateSnapshotsEnv, "1")
+
+	dir := t.TempDir()
+	baseline := solidPNG(t, 10, 10, white, white)
+	changed := solidPNG(t, 10, 10, black, black)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "home.png"), baseline, 0o644))
+
+	result, err := CompareAgainstBaseline("home", changed, dir)
+	require.NoError(t, err)
+	require.True(t, result.Passed)
+	require.True(t, result.BaselineUpdated)
+	require.Less(t, result.Similarity, defaultMinSimilarity)
+	require.Empty(t, result.DiffPath)
+
+	stored, err := os.ReadFile(result.BaselinePath)
+	require.NoError(t, err)
+	require.Equal(t, changed, stored)
+}
+
+func TestCompareAgainstBaselineUpdateIncomparable(t *testing.T) {
+	t.Parallel()
+
+	img := solidPNG(t, 10, 10, white, white)
+	at1, err := setPNGDevicePixelRatio(img, 1)
+	require.NoError(t, err)
+	at2, err := setPNGDevicePixelRatio(img, 2)
+	require.NoError(t, err)
+	larger := solidPNG(t, 20, 10, white, white)
+	strict := BaselineOptions{CompareOptions: CompareOptions{Strict: true}}
+
+	tests := []struct {
+		name     string
+		baseline []byte
+		actual   []byte
+		options  BaselineOptions
+		wantErr  error
+	}{
+		{"device pixel ratio", at1, at2, BaselineOptions{}, ErrDPRMismatch},
+		{"size in strict mode", img, larger, strict, ErrDimensionMismatch},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			dir := t.TempDir()
+			path := filepath.Join(dir, "home.png")
+			require.NoError(t, os.WriteFile(path, tt.baseline, 0o644))
+
+			// Without update mode the images can't be compared
+			_, err := CompareAgainstBaseline("home", tt.actual, dir, tt.options)
+			require.ErrorIs(t, err, tt.wantErr)
+
+			tt.options.Update = true
+			result, err := CompareAgainstBaseline("home", tt.actual, dir, tt.options)
+			require.NoError(t, err)
+			require.True(t, result.Passed)
+			require.True(t, result.BaselineUpdated)
+			require.Empty(t, result.DiffPath)
+
+			stored, err := os.ReadFile(path)
+			require.NoError(t, err)
+			require.Equal(t, tt.actual, stored)
+		})
+	}
+}
+
+func TestCompareScreenshotToFile(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	baselinePath := filepath.Join(dir, "home.png")
+	baseline := solidPNG(t, 10, 10, white, white)
+	changed := solidPNG(t, 10, 10, white, black, image.Rect(0, 0, 5, 10))
+	require.NoError(t, os.WriteFile(baselinePath, baseline, 0o644))
+
+	// The results are the same as comparing the baseline's bytes
+	similarity, err := CompareScreenshotToFile(changed, baselinePath)
+	require.NoError(t, err)
+	want, err := CompareImages(baseline, changed)
+	require.NoError(t, err)
+	require.Equal(t, want, similarity)
+
+	diffPath := filepath.Join(dir, "home-diff.png")
+	diff, err := CreateDiffImageWithBaselineFile(changed, baselinePath, diffPath, CompareOptions{AntiAliasTolerance: 1})
+	require.NoError(t, err)
+	wantDiff, err := CreateDiffImage(baseline, changed, "", CompareOptions{AntiAliasTolerance: 1})
+	require.NoError(t, err)
+	require.Equal(t, wantDiff, diff)
+	require.FileExists(t, diffPath)
+
+	// A missing baseline is reported with its path
+	missing := filepath.Join(dir, "missing.png")
+	_, err = CompareScreenshotToFile(changed, missing)
+	require.ErrorIs(t, err, fs.ErrNotExist)
+	require.ErrorContains(t, err, missing)
+	_, err = CreateDiffImageWithBaselineFile(changed, missing, "")
+	require.ErrorIs(t, err, fs.ErrNotExist)
+}
+
+func TestSplitBaselinePath(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		path, dir, name string
+	}{
+		{"baselines/button.png", "baselines", "button"},
+		{"button.png", ".", "button"},
+		{"/tmp/snapshots/header", "/tmp/snapshots", "header"},
+	}
+
+	for _, tt := range tests {
+		dir, name := splitBaselinePath(tt.path)
+		require.Equal(t, tt.dir, dir, tt.path)
+		require.Equal(t, tt.name, name, tt.path)
+	}
+}
+
+func TestScreenshotMismatchError(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	baseline := solidPNG(t, 10, 10, white, white)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "button.png"), baseline, 0o644))
+
+	result, err := CompareAgainstBaseline("button", solidPNG(t, 10, 10, black, black), dir)
+	require.NoError(t, err)
+	require.False(t, result.Passed)
+
+	err = &ScreenshotMismatchError{Result: result}
+	require.Contains(t, err.Error(), `screenshot "button" does not match baseline`)
+	require.Contains(t, err.Error(), result.DiffPath)
+}
+
+func TestPageExpectScreenshot(t *testing.T) {
+	dir := t.TempDir()
+	white10 := solidPNG(t, 10, 10, white, white)
+	black10 := solidPNG(t, 10, 10, black, black)
+
+	screenshot := white10
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if !strings.HasSuffix(r.URL.Path, "/screenshot") {
+			// Without the viewport size the whole window is captured
+			w.WriteHeader(http.StatusInternalServerError)
+			_, _ = w.Write([]byte(`{"value":{"error":"javascript error","message":""}}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"value":"` + base64.StdEncoding.EncodeToString(screenshot) + `"}`))
+	}))
+	defer server.Close()
+
+	runtime := modulestest.NewRuntime(t)
+	page := &Page{vu: runtime.VU, client: NewWebDriverClient(server.URL).forSession("session-1")}
+	require.NoError(t, runtime.VU.Runtime().Set("page", page))
+	require.NoError(t, runtime.VU.Runtime().Set("dir", dir))
+
+	expect := func(options string) (string, string) {
+		t.Helper()
+		_, err := runtime.RunOnEventLoop(`
+			var result = null, failure = "";
+			page.expectScreenshot("home.png", ` + options + `)
+				.then(function(r) { result = r; })
+				.catch(function(e) { failure = String(e); });
+		`)
+		require.NoError(t, err)
+		result := runtime.VU.Runtime().Get("result").Export()
+		if result == nil {
+			return "", runtime.VU.Runtime().Get("failure").String()
+		}
+		r, ok := result.(*BaselineResult)
+		require.True(t, ok)
+		switch {
+		case r.BaselineCreated:
+			return "created", ""
+		case r.BaselineUpdated:
+			return "updated", ""
+		}
+		return "passed", ""
+	}
+
+	// The first run stores the baseline
+	got, failure := expect(`{ baselineDir: dir }`)
+	require.Equal(t, "created", got, failure)
+	require.FileExists(t, filepath.Join(dir, "home.png"))
+
+	got, failure = expect(`{ baselineDir: dir }`)
+	require.Equal(t, "passed", got, failure)
+
+	// Mismatches reject and write the diff
+	screenshot = black10
+	_, failure = expect(`{ baselineDir: dir, diffDir: dir + "/diffs" }`)
+	require.Contains(t, failure, `screenshot "home" does not match baseline`)
+	require.FileExists(t, filepath.Join(dir, "diffs", "home-diff.png"))
+
+	// Unless the baseline is being updated
+	got, failure = expect(`{ baselineDir: dir, update: true }`)
+	require.Equal(t, "updated", got, failure)
+	stored, err := os.ReadFile(filepath.Join(dir, "home.png"))
+	require.NoError(t, err)
+	require.Equal(t, black10, stored)
+}
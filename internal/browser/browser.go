@@ -3,14 +3,20 @@ package browser
 import (
 	"context"
 	_ "embed"
+	"errors"
 	"fmt"
 	"net"
 	"os"
 	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
 	"sync"
+	"syscall"
 	"time"
 
 	"github.com/grafana/sobek"
+	"github.com/sirupsen/logrus"
 	"go.k6.io/k6/js/modules"
 	"go.k6.io/k6/js/promises"
 )
@@ -18,64 +24,163 @@ import (
 //go:embed injection_script.js
 var injectionScript string
 
-var (
-	safariDriverCmd  *exec.Cmd
-	safariDriverMu   sync.Mutex
-	safariDriverRefs int
+// DefaultDriverURL is the WebDriver endpoint used when no safaridriver could be
+// configured, e.g. when it isn't installed, assuming one was started externally
+const DefaultDriverURL = "http://localhost:4444"
+
+// Environment variables configuring safaridriver
+const (
+	safariDriverPathEnv = "XK6_SAFARIDRIVER_PATH"
+	safariDriverPortEnv = "XK6_SAFARIDRIVER_PORT"
 )
 
-// StartSafariDriver starts safaridriver if it's not already running
-func StartSafariDriver() error {
-	safariDriverMu.Lock()
-	defer safariDriverMu.Unlock()
+// driverShutdownGracePeriod is how long safaridriver gets to exit after SIGTERM before it's killed
+var driverShutdownGracePeriod = 3 * time.Second
+
+// SafariDriver is a safaridriver process started by the extension
+// Each VU starts its own driver on a free port so VUs don't share a driver
+type SafariDriver struct {
+	Path string // Executable path
+	Port int
+
+	mu       sync.Mutex
+	cmd      *exec.Cmd // nil when the process isn't running
+	pinned   bool      // The port was set with XK6_SAFARIDRIVER_PORT
+	external bool      // The port was already in use by a driver we don't manage
+}
+
+// NewSafariDriver configures safaridriver on a free port chosen by the OS,
+// without starting it. XK6_SAFARIDRIVER_PATH overrides the executable (default:
+// safaridriver on the PATH) and XK6_SAFARIDRIVER_PORT pins the port. If the
+// pinned port is already in use, the driver listening on it is assumed to be
+// safaridriver and is used as is.
+func NewSafariDriver() (*SafariDriver, error) {
+	path := os.Getenv(safariDriverPathEnv)
+	if path == "" {
+		path = "safaridriver"
+	}
+
+	if v := os.Getenv(safariDriverPortEnv); v != "" {
+		port, err := strconv.Atoi(v)
+		if err != nil || port <= 0 || port > 65535 {
+			return nil, fmt.Errorf("invalid %s: %q", safariDriverPortEnv, v)
+		}
+
+		if isPortInUse(port) {
+			return &SafariDriver{Path: path, Port: port, pinned: true, external: true}, nil
+		}
+		if _, err := exec.LookPath(path); err != nil {
+			return nil, fmt.Errorf("failed to find safaridriver %s: %w", path, err)
+		}
+		return &SafariDriver{Path: path, Port: port, pinned: true}, nil
+	}
+
+	if _, err := exec.LookPath(path); err != nil {
+		return nil, fmt.Errorf("failed to find safaridriver %s: %w", path, err)
+	}
+	port, err := freePort()
+	if err != nil {
+		return nil, fmt.Errorf("failed to allocate a port for safaridriver: %w", err)
+	}
+
+	return &SafariDriver{Path: path, Port: port}, nil
+}
+
+// StartSafariDriver configures safaridriver like NewSafariDriver and starts it
+func StartSafariDriver() (*SafariDriver, error) {
+	driver, err := NewSafariDriver()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := driver.ensureRunning(); err != nil {
+		return nil, err
+	}
+
+	return driver, nil
+}
+
+// DriverURL returns the base URL of the driver's WebDriver endpoint
+func (d *SafariDriver) DriverURL() string {
+	return fmt.Sprintf("http://localhost:%d", d.Port)
+}
+
+// ensureRunning starts the driver process on its port if it isn't running
+func (d *SafariDriver) ensureRunning() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
 
-	// If already running, just increment reference count
-	if safariDriverCmd != nil && safariDriverCmd.Process != nil {
-		safariDriverRefs++
+	if d.external || d.cmd != nil {
 		return nil
 	}
 
-	// Check if port 4444 is already in use
-	if isPortInUse(4444) {
-		// Assume safaridriver is already running externally
-		safariDriverRefs++
+	// Another VU may have started the driver on the pinned port meanwhile
+	if d.pinned && isPortInUse(d.Port) {
+		d.external = true
 		return nil
 	}
 
-	// Start safaridriver
-	cmd := exec.Command("safaridriver", "--port", "4444")
+	cmd := exec.Command(d.Path, "--port", strconv.Itoa(d.Port))
 	if err := cmd.Start(); err != nil {
-		return fmt.Errorf("failed to start safaridriver: %w", err)
+		return fmt.Errorf("failed to start safaridriver %s: %w", d.Path, err)
 	}
 
-	safariDriverCmd = cmd
-	safariDriverRefs = 1
-
-	// Wait for safaridriver to be ready
-	if err := waitForPort(4444, 10*time.Second); err != nil {
+	// Wait until safaridriver can accept sessions, not just until its port is open
+	client := NewWebDriverClient(d.DriverURL())
+	if err := client.WaitForReady(context.Background(), 10*time.Second); err != nil {
 		cmd.Process.Kill()
-		safariDriverCmd = nil
+		cmd.Wait()
 		return fmt.Errorf("safaridriver did not become ready: %w", err)
 	}
 
+	d.cmd = cmd
 	return nil
 }
 
-// stopSafariDriver decrements the reference count and stops safaridriver if no more references
-func stopSafariDriver() {
-	safariDriverMu.Lock()
-	defer safariDriverMu.Unlock()
+// Stop stops the driver process
+// The driver is restarted on the same port by the next ensureRunning
+func (d *SafariDriver) Stop() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.cmd == nil {
+		return
+	}
+
+	// Ask safaridriver to shut down so it can close its Safari windows,
+	// and only kill it if it doesn't exit within the grace period
+	done := make(chan struct{})
+	go func() {
+		d.cmd.Wait()
+		close(done)
+	}()
 
-	if safariDriverRefs > 0 {
-		safariDriverRefs--
+	if err := d.cmd.Process.Signal(syscall.SIGTERM); err != nil {
+		d.cmd.Process.Kill()
 	}
 
-	// Only stop if we started it and there are no more references
-	if safariDriverRefs == 0 && safariDriverCmd != nil && safariDriverCmd.Process != nil {
-		safariDriverCmd.Process.Kill()
-		safariDriverCmd.Wait()
-		safariDriverCmd = nil
+	select {
+	case <-done:
+	case <-time.After(driverShutdownGracePeriod):
+		logf(nil, logrus.WarnLevel, "safaridriver did not exit within %v, killing it", driverShutdownGracePeriod)
+		d.cmd.Process.Kill()
+		<-done
 	}
+
+	d.cmd = nil
+}
+
+// freePort asks the OS for a free TCP port
+// The port is released before safaridriver binds it, so another process could
+// take it in between; the readiness wait then fails and the error is reported
+func freePort() (int, error) {
+	l, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		return 0, err
+	}
+	defer l.Close()
+
+	return l.Addr().(*net.TCPAddr).Port, nil
 }
 
 // isPortInUse checks if a TCP port is in use
@@ -88,28 +193,24 @@ func isPortInUse(port int) bool {
 	return true
 }
 
-// waitForPort waits for a TCP port to become available
-func waitForPort(port int, timeout time.Duration) error {
-	deadline := time.Now().Add(timeout)
-	for time.Now().Before(deadline) {
-		if isPortInUse(port) {
-			return nil
-		}
-		time.Sleep(100 * time.Millisecond)
-	}
-	return fmt.Errorf("port %d did not become available within %v", port, timeout)
-}
-
 // Viewport represents the browser viewport dimensions
 type Viewport struct {
-	Width  int
-	Height int
+	Width  int `js:"width"`
+	Height int `js:"height"`
 }
 
 // Browser represents a Safari browser instance
 type Browser struct {
-	VU     modules.VU
-	Client *WebDriverClient
+	VU      modules.VU
+	Client  *WebDriverClient
+	Driver  *SafariDriver // nil when using an externally started safaridriver
+	Metrics *K6Metrics    // nil to not emit k6 metrics
+
+	pagesMu sync.Mutex
+	pages   []*Page // Open pages, whose sessions are deleted on Close
+
+	driverMu  sync.Mutex
+	driverCtx context.Context // VU context that stops the driver once done
 }
 
 // NewContext creates a new browser context with optional configuration
@@ -119,62 +220,102 @@ func (b *Browser) NewContext(options ...map[string]interface{}) *BrowserContext
 		opts = options[0]
 	}
 
-	return &BrowserContext{
+	return b.newContext(opts)
+}
+
+// newContext creates a browser context with a client of its own, so that its
+// pages' sessions are isolated from those of other contexts
+func (b *Browser) newContext(options map[string]interface{}) *BrowserContext {
+	bc := &BrowserContext{
 		browser: b,
 		vu:      b.VU,
-		options: opts,
+		options: options,
+		client:  b.Client.forSession(""),
 	}
+	bc.client.replaceSession = bc.reconnectPage
+	return bc
 }
 
 // NewPage creates a new page in the browser
 func (b *Browser) NewPage(options ...map[string]interface{}) (*sobek.Promise, error) {
+	var opts map[string]interface{}
+	if len(options) > 0 {
+		opts = options[0]
+	}
+	return b.newPage(opts, nil)
+}
+
+// newPage creates a new page, belonging to browserContext, or to a context of
+// its own if it's nil
+func (b *Browser) newPage(options map[string]interface{}, browserContext *BrowserContext) (*sobek.Promise, error) {
+	pageOpts, err := pageOptionsFrom(options)
+	if err != nil {
+		return nil, err
+	}
+
+	vuCtx := b.VU.Context()
 	return Promise(b.VU, func() (any, error) {
 		ctx := context.Background()
 
-		// Parse viewport options
-		viewport := &Viewport{Width: 1280, Height: 720} // Default viewport
-		if len(options) > 0 && options[0] != nil {
-			if viewportOpt, ok := options[0]["viewport"].(map[string]interface{}); ok {
-				if width, ok := viewportOpt["width"].(float64); ok {
-					viewport.Width = int(width)
-				}
-				if height, ok := viewportOpt["height"].(float64); ok {
-					viewport.Height = int(height)
-				}
+		// Start the driver on the first page, or restart it if a previous
+		// Close stopped it
+		if b.Driver != nil {
+			if err := b.startDriver(vuCtx); err != nil {
+				return nil, err
 			}
 		}
 
 		// Create a new WebDriver session with viewport
 		capabilities := map[string]interface{}{
 			"browserName":             "Safari",
-			"safari:devicePixelRatio": 1.0, // Force DPR to 1 for consistent screenshots
+			"safari:devicePixelRatio": pageOpts.deviceScaleFactor,
+			// Leave dialogs open so the page's dialog handler can close them
+			"unhandledPromptBehavior": "ignore",
+		}
+
+		if browserContext == nil {
+			browserContext = b.newContext(nil)
 		}
 
-		session, err := b.Client.CreateSession(ctx, capabilities)
+		session, err := browserContext.client.CreateSession(ctx, capabilities)
 		if err != nil {
 			return nil, fmt.Errorf("failed to create session: %w", err)
 		}
 
+		// Bind the page to its own session so pages don't share injected state
 		page := &Page{
-			vu:      b.VU,
-			client:  b.Client,
-			session: session,
+			vu:        b.VU,
+			client:    browserContext.client.forSession(session.SessionID),
+			session:   session,
+			browser:   b,
+			context:   browserContext,
+			userAgent: pageOpts.userAgent,
 		}
-
-		// Set the window size to match viewport
-		// Add extra height to account for Safari's browser chrome (address bar, tabs, etc.)
-		// Safari's chrome is typically around 52-60 pixels
-		windowHeight := viewport.Height + 52
-		if err := b.Client.SetWindowSize(ctx, viewport.Width, windowHeight); err != nil {
-			fmt.Printf("WARN: failed to set window size: %v\n", err)
+		page.client.dialogHandler = page.handleDialog
+		page.client.onReconnect = browserContext.rebindClient
+		page.client.metrics, page.client.vu = b.Metrics, b.VU
+		page.client.deviceScaleFactor = pageOpts.deviceScaleFactor
+		page.client.capabilities = capabilities
+		b.trackPage(page)
+		browserContext.addPage(page)
+
+		// Remember the page's window to tell it apart from windows it opens
+		if handle, err := page.client.GetWindowHandle(ctx); err != nil {
+			logf(b.VU, logrus.WarnLevel, "failed to get window handle: %v", err)
+		} else {
+			page.windowHandle = handle
+			page.windows = &sessionWindows{current: handle}
 		}
 
-		// Inject the initialization script
-		if err := page.injectScript(ctx); err != nil {
-			// Log warning but don't fail page creation
-			fmt.Printf("WARN: failed to inject initialization script: %v\n", err)
+		// Size the window so that the viewport, without Safari's toolbars, matches
+		if err := page.client.setViewportSize(ctx, pageOpts.viewport); err != nil {
+			logf(b.VU, logrus.WarnLevel, "failed to set viewport size: %v", err)
 		}
 
+		// Inject the initialization script. Log warnings but don't fail page
+		// creation, a failing init script fails the page's navigations instead
+		logInjectScriptError(b.VU, "in the new page", page.injectScript(ctx))
+
 		return page, nil
 	}), nil
 }
@@ -183,53 +324,209 @@ func (b *Browser) NewPage(options ...map[string]interface{}) (*sobek.Promise, er
 func (b *Browser) Close() (*sobek.Promise, error) {
 	return Promise(b.VU, func() (any, error) {
 		ctx := context.Background()
-		err := b.Client.DeleteSession(ctx)
 
-		// Decrement safaridriver reference count
-		stopSafariDriver()
+		// Always end the sessions before tearing down the driver, so that
+		// safaridriver closes their Safari windows
+		b.deleteSessions(ctx)
 
-		return nil, err
+		if b.Driver != nil {
+			b.Driver.Stop()
+		}
+
+		return nil, nil
 	}), nil
 }
 
+// startDriver starts the browser's driver if it isn't running. Once vuCtx, the
+// context of the VU, is done, the pages' sessions are deleted and the driver is
+// stopped like on Close, so that it doesn't outlive the VU if the script
+// doesn't close the browser
+func (b *Browser) startDriver(vuCtx context.Context) error {
+	if err := b.Driver.ensureRunning(); err != nil {
+		return err
+	}
+
+	b.driverMu.Lock()
+	defer b.driverMu.Unlock()
+
+	if vuCtx == nil || vuCtx.Done() == nil || vuCtx == b.driverCtx {
+		return nil
+	}
+	b.driverCtx = vuCtx
+	go func() {
+		<-vuCtx.Done()
+		b.deleteSessions(context.Background())
+		b.Driver.Stop()
+	}()
+	return nil
+}
+
+// trackPage records an open page so Close can delete its session
+func (b *Browser) trackPage(page *Page) {
+	b.pagesMu.Lock()
+	defer b.pagesMu.Unlock()
+
+	b.pages = append(b.pages, page)
+}
+
+// untrackPage forgets a page whose session was deleted
+func (b *Browser) untrackPage(page *Page) {
+	b.pagesMu.Lock()
+	defer b.pagesMu.Unlock()
+
+	for i, p := range b.pages {
+		if p == page {
+			b.pages = append(b.pages[:i], b.pages[i+1:]...)
+			return
+		}
+	}
+}
+
+// deleteSessions deletes the sessions of all open pages and the browser client's session
+func (b *Browser) deleteSessions(ctx context.Context) {
+	b.pagesMu.Lock()
+	pages := b.pages
+	b.pages = nil
+	b.pagesMu.Unlock()
+
+	deleted := make(map[string]bool)
+	for _, page := range pages {
+		sessionID := page.client.session()
+		if sessionID == "" {
+			continue
+		}
+		page.client.DeleteSession(ctx)
+		deleted[sessionID] = true
+	}
+
+	// The browser client is bound to the latest session, which may already be gone
+	if sessionID := b.Client.session(); deleted[sessionID] {
+		b.Client.clearSession(sessionID)
+	} else if sessionID != "" {
+		b.Client.DeleteSession(ctx)
+	}
+}
+
 // Page represents a browser page
 type Page struct {
 	vu      modules.VU
 	client  *WebDriverClient
 	session *WebDriverSession
+	browser *Browser        // nil for pages not created by a Browser
+	context *BrowserContext // Pages created with Browser.NewPage get a context of their own
+
+	windows      *sessionWindows // Windows of the page's session, nil if the handle is unknown
+	windowHandle string          // The page's window
+
+	userAgent string // Reported by navigator.userAgent if set
+
+	traceMu sync.Mutex
+	trace   *actionTrace // nil unless tracing is active
+
+	sessionMu sync.Mutex // Held by each page operation if the page's windows are unknown
+
+	handlerMu        sync.Mutex
+	dialogHandler    sobek.Callable // nil dismisses dialogs
+	consoleHandler   sobek.Callable // nil leaves console messages in the page
+	pageErrorHandler sobek.Callable // nil leaves page errors in the page
+	requestHandler   sobek.Callable // nil leaves requests in the page
+	responseHandler  sobek.Callable // nil leaves responses in the page
+	handlerCallbacks []*handlerCallback
 }
 
-// injectScript injects the initialization script into the page
+// injectScript injects the initialization script into the page, then the user
+// agent and geolocation overrides and the routes, followed by the init scripts
+// added to the page's browser context
 func (p *Page) injectScript(ctx context.Context) error {
 	if p.client == nil {
 		return fmt.Errorf("browser session not initialized")
 	}
 
 	// Execute the embedded injection script
-	_, err := p.client.ExecuteScript(ctx, injectionScript, nil)
-	return err
+	if _, err := p.client.ExecuteScript(ctx, injectionScript, nil); err != nil {
+		return err
+	}
+
+	if p.userAgent != "" {
+		if _, err := p.client.ExecuteScript(ctx, userAgentScript, []interface{}{p.userAgent}); err != nil {
+			return fmt.Errorf("failed to override the user agent: %w", err)
+		}
+	}
+
+	if p.context == nil {
+		return nil
+	}
+	if geo := p.context.currentGeolocation(); geo != nil {
+		if err := p.applyGeolocation(ctx, geo); err != nil {
+			return fmt.Errorf("failed to override the geolocation: %w", err)
+		}
+	}
+	if routes := p.context.currentRoutes(); len(routes) > 0 {
+		if err := p.applyRoutes(ctx, routes); err != nil {
+			return fmt.Errorf("failed to apply the routes: %w", err)
+		}
+	}
+	for i, script := range p.context.currentInitScripts() {
+		if _, err := p.client.ExecuteScript(ctx, script, nil); err != nil {
+			return &initScriptError{index: i, err: err}
+		}
+	}
+
+	return nil
+}
+
+// initScriptError is the failure of an init script added to the page's context
+type initScriptError struct {
+	index int
+	err   error
+}
+
+func (e *initScriptError) Error() string {
+	return fmt.Sprintf("init script %d failed: %v", e.index, e.err)
+}
+
+func (e *initScriptError) Unwrap() error {
+	return e.err
+}
+
+// reinjectScript injects the scripts again once the document was replaced, e.g.
+// after a navigation. The page works without the built-in scripts, so failing
+// to inject them is only logged, but a failing init script is returned, as the
+// page then lacks the stubs or flags the test relies on
+func (p *Page) reinjectScript(ctx context.Context, when string) error {
+	err := p.injectScript(ctx)
+	var initErr *initScriptError
+	if errors.As(err, &initErr) {
+		return err
+	}
+	logInjectScriptError(p.vu, when, err)
+	return nil
 }
 
-// Goto navigates to a URL with optional wait conditions
+// logInjectScriptError logs a failure to inject the scripts, if any, telling a
+// failing init script apart from the built-in scripts
+func logInjectScriptError(vu modules.VU, when string, err error) {
+	var initErr *initScriptError
+	switch {
+	case err == nil:
+	case errors.As(err, &initErr):
+		logf(vu, logrus.WarnLevel, "init script %d failed %s: %v", initErr.index, when, initErr.err)
+	default:
+		logf(vu, logrus.WarnLevel, "failed to inject script %s: %v", when, err)
+	}
+}
+
+// Goto navigates to a URL with optional wait conditions, resolving to the
+// response of the loaded document, or null if it has none, e.g. about:blank
 func (p *Page) Goto(url string, options map[string]interface{}) (*sobek.Promise, error) {
 	if p.client == nil {
 		return nil, fmt.Errorf("browser session not initialized")
 	}
 
-	return Promise(p.vu, func() (any, error) {
+	return p.promise(func() (any, error) {
 		ctx := context.Background()
 
-		// Parse options
-		var navOptions *NavigateOptions
-		if options != nil {
-			navOptions = &NavigateOptions{
-				WaitUntil: "load",
-			}
-
-			if waitUntil, ok := options["waitUntil"].(string); ok {
-				navOptions.WaitUntil = waitUntil
-			}
-		}
+		navOptions := navigateOptionsFrom(options)
 
 		err := p.client.Navigate(ctx, url, navOptions)
 		if err != nil {
@@ -237,12 +534,77 @@ func (p *Page) Goto(url string, options map[string]interface{}) (*sobek.Promise,
 		}
 
 		// Re-inject the script after navigation
-		if err := p.injectScript(ctx); err != nil {
+		if err := p.reinjectScript(ctx, "after navigation"); err != nil {
+			return nil, err
+		}
+
+		if err := p.emitLoadMetrics(ctx); err != nil {
+			logf(p.vu, logrus.WarnLevel, "failed to emit page load metrics: %v", err)
+		}
+
+		action := TraceAction{Type: TraceActionGoto, URL: url}
+		if navOptions != nil {
+			action.WaitUntil = navOptions.WaitUntil
+		}
+		p.recordAction(action)
+
+		response, err := p.client.navigationResponse(ctx)
+		if err != nil {
 			// Log warning but don't fail navigation
-			fmt.Printf("WARN: failed to inject script after navigation: %v\n", err)
+			logf(p.vu, logrus.WarnLevel, "%v", err)
+		}
+		if response == nil {
+			return nil, nil
 		}
 
-		return nil, nil
+		return response, nil
+	}), nil
+}
+
+// clickAndWaitForNavigation calls click, then waits up to timeout for the
+// navigation it triggers and for the new document to reach navOptions.WaitUntil,
+// re-injecting the script into it
+func (p *Page) clickAndWaitForNavigation(ctx context.Context, click func() error, navOptions *NavigateOptions, timeout time.Duration) error {
+	token, err := p.client.markDocument(ctx)
+	if err != nil {
+		return err
+	}
+
+	if err := click(); err != nil {
+		return fmt.Errorf("failed to click element: %w", err)
+	}
+
+	if err := p.client.waitForNewDocument(ctx, token, timeout); err != nil {
+		return err
+	}
+	if navOptions.WaitUntil != "commit" {
+		if err := p.client.WaitForLoadState(ctx, navOptions.WaitUntil, navOptions.NetworkIdleTime); err != nil {
+			return err
+		}
+	}
+
+	// Re-inject the script after navigation
+	return p.reinjectScript(ctx, "after navigation")
+}
+
+// SetContent replaces the page's document with the given HTML
+func (p *Page) SetContent(html string, options map[string]interface{}) (*sobek.Promise, error) {
+	if p.client == nil {
+		return nil, fmt.Errorf("browser session not initialized")
+	}
+
+	return p.promise(func() (any, error) {
+		ctx := context.Background()
+
+		navOptions := navigateOptionsFrom(options)
+
+		err := p.client.SetContent(ctx, html, navOptions)
+		if err != nil {
+			return nil, err
+		}
+
+		// Re-inject the script since the document was replaced
+		return nil, p.reinjectScript(ctx, "after setting content")
 	}), nil
 }
 
@@ -253,7 +615,12 @@ func (p *Page) URL() string {
 	}
 
 	ctx := context.Background()
-	url, err := p.client.GetCurrentURL(ctx)
+	var url string
+	err := p.inWindow(ctx, func() error {
+		var err error
+		url, err = p.client.GetCurrentURL(ctx)
+		return err
+	})
 	if err != nil {
 		return ""
 	}
@@ -269,13 +636,43 @@ func (p *Page) Locator(selector string) *Locator {
 	}
 }
 
+// activeElementScript returns the focused element in an array, or an empty
+// array if nothing has focus and the body is the active element
+const activeElementScript = `
+	var element = document.activeElement;
+	return element && element !== document.body ? [element] : [];
+`
+
+// GetActiveElement resolves to a locator bound to the focused element, i.e.
+// document.activeElement, e.g. to check where a series of Tab presses moved the
+// focus, or to null if nothing has focus
+func (p *Page) GetActiveElement() (*sobek.Promise, error) {
+	if p.client == nil {
+		return nil, fmt.Errorf("browser session not initialized")
+	}
+
+	return p.promise(func() (any, error) {
+		ctx := context.Background()
+		result, err := p.client.ExecuteScript(ctx, activeElementScript, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get the active element: %w", err)
+		}
+
+		elementIDs := elementIDsFromResult(result)
+		if len(elementIDs) == 0 {
+			return nil, nil
+		}
+		return &Locator{page: p, selector: ":focus", elementID: elementIDs[0], vu: p.vu}, nil
+	}), nil
+}
+
 // Title returns the current page title
 func (p *Page) Title() (*sobek.Promise, error) {
 	if p.client == nil {
 		return nil, fmt.Errorf("browser session not initialized")
 	}
 
-	return Promise(p.vu, func() (any, error) {
+	return p.promise(func() (any, error) {
 		ctx := context.Background()
 		title, err := p.client.GetTitle(ctx)
 		if err != nil {
@@ -285,13 +682,35 @@ func (p *Page) Title() (*sobek.Promise, error) {
 	}), nil
 }
 
+// Content returns the full serialized HTML of the page
+func (p *Page) Content() (*sobek.Promise, error) {
+	if p.client == nil {
+		return nil, fmt.Errorf("browser session not initialized")
+	}
+
+	return p.promise(func() (any, error) {
+		ctx := context.Background()
+		script := `
+			var doctype = document.doctype ? new XMLSerializer().serializeToString(document.doctype) : '';
+			return doctype + document.documentElement.outerHTML;
+		`
+		result, err := p.client.ExecuteScript(ctx, script, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get content: %w", err)
+		}
+
+		content, _ := result.(string)
+		return content, nil
+	}), nil
+}
+
 // Evaluate executes JavaScript and returns the result
 func (p *Page) Evaluate(script string) (*sobek.Promise, error) {
 	if p.client == nil {
 		return nil, fmt.Errorf("browser session not initialized")
 	}
 
-	return Promise(p.vu, func() (any, error) {
+	return p.promise(func() (any, error) {
 		ctx := context.Background()
 		result, err := p.client.ExecuteScript(ctx, script, nil)
 		if err != nil {
@@ -301,46 +720,224 @@ func (p *Page) Evaluate(script string) (*sobek.Promise, error) {
 	}), nil
 }
 
-// Click clicks an element by CSS selector
-func (p *Page) Click(selector string) (*sobek.Promise, error) {
+// EvaluateWithArgs executes JavaScript with arguments and returns the result
+// The script can be a function expression, which is called with args, or a
+// function body that reads them from the arguments object
+func (p *Page) EvaluateWithArgs(script string, args []interface{}) (*sobek.Promise, error) {
 	if p.client == nil {
 		return nil, fmt.Errorf("browser session not initialized")
 	}
+	if args == nil {
+		args = []interface{}{}
+	}
 
-	return Promise(p.vu, func() (any, error) {
+	return p.promise(func() (any, error) {
 		ctx := context.Background()
-		elementID, err := p.client.FindElement(ctx, selector)
+		result, err := p.client.ExecuteScript(ctx, functionCallScript(script), args)
 		if err != nil {
-			return nil, fmt.Errorf("failed to find element: %w", err)
+			return nil, fmt.Errorf("failed to execute script: %w", err)
 		}
+		return result, nil
+	}), nil
+}
 
-		err = p.client.ClickElement(ctx, elementID)
+// RawWebDriver sends a WebDriver command to an endpoint of the page's session
+// and returns the value of the response. It's an escape hatch for commands the
+// module doesn't implement, e.g. rawWebDriver('GET', '/source'), whose
+// behavior may change across safaridriver versions
+func (p *Page) RawWebDriver(method, path string, body ...interface{}) (*sobek.Promise, error) {
+	if p.client == nil {
+		return nil, fmt.Errorf("browser session not initialized")
+	}
+
+	var payload interface{}
+	if len(body) > 0 {
+		payload = body[0]
+	}
+
+	return p.promise(func() (any, error) {
+		ctx := context.Background()
+		result, err := p.client.ExecuteCommand(ctx, method, path, payload)
 		if err != nil {
-			return nil, fmt.Errorf("failed to click element: %w", err)
+			return nil, fmt.Errorf("failed to execute WebDriver command: %w", err)
+		}
+		return result, nil
+	}), nil
+}
+
+// functionExpressionRegex matches scripts that start with a function expression
+var functionExpressionRegex = regexp.MustCompile(`^(async\s+)?(function\b|\([^)]*\)\s*=>|[A-Za-z_$][\w$]*\s*=>)`)
+
+// functionCallScript wraps a function expression so WebDriver calls it with the
+// script arguments; function bodies are returned unchanged
+func functionCallScript(script string) string {
+	trimmed := strings.TrimSpace(script)
+	if !functionExpressionRegex.MatchString(trimmed) {
+		return script
+	}
+	return "return (" + trimmed + ").apply(null, arguments);"
+}
+
+// WaitForFunction polls a JavaScript function or expression until it returns a truthy value
+// Options: timeout (ms, default DefaultTimeout) and polling interval (ms, default 100)
+func (p *Page) WaitForFunction(script string, options map[string]interface{}) (*sobek.Promise, error) {
+	if p.client == nil {
+		return nil, fmt.Errorf("browser session not initialized")
+	}
+
+	interval := 100 * time.Millisecond
+	if options != nil {
+		if ms, ok := toFloat64(options["polling"]); ok && ms > 0 {
+			interval = time.Duration(ms) * time.Millisecond
 		}
+	}
+	timeout := timeoutFromOptions(options)
+	if timeout <= 0 {
+		timeout = DefaultTimeout()
+	}
 
+	return p.promise(func() (any, error) {
+		ctx := context.Background()
+		err := p.client.pollForConditionWithOptions(ctx, truthyConditionScript(script), interval, timeout)
+		if err != nil {
+			return nil, fmt.Errorf("failed waiting for function: %w", err)
+		}
 		return nil, nil
 	}), nil
 }
 
-// Fill fills an input field with text
-func (p *Page) Fill(selector, text string) (*sobek.Promise, error) {
+// truthyConditionScript wraps a function expression or a plain expression in a
+// condition script returning whether its result is truthy
+// Functions may be async, as WebDriver waits for returned promises
+func truthyConditionScript(script string) string {
+	trimmed := strings.TrimSpace(script)
+	if functionExpressionRegex.MatchString(trimmed) {
+		return "return Promise.resolve((" + trimmed + ")()).then(function(v) { return !!v; });"
+	}
+	return "return !!(" + trimmed + ");"
+}
+
+// WaitForSelector waits for an element matching the selector to reach a state
+// Options: state ("visible" by default) and timeout (ms, default DefaultTimeout)
+func (p *Page) WaitForSelector(selector string, options map[string]interface{}) (*sobek.Promise, error) {
 	if p.client == nil {
 		return nil, fmt.Errorf("browser session not initialized")
 	}
 
-	return Promise(p.vu, func() (any, error) {
+	state := "visible"
+	if options != nil {
+		if stateVal, ok := options["state"].(string); ok {
+			state = stateVal
+		}
+	}
+	timeout := timeoutFromOptions(options)
+
+	return p.promise(func() (any, error) {
+		ctx := context.Background()
+		err := p.client.WaitForSelector(ctx, selector, state, timeout)
+		if err != nil {
+			return nil, fmt.Errorf("waitForSelector failed for selector '%s': %w", selector, err)
+		}
+		return nil, nil
+	}), nil
+}
+
+// WaitForURL waits until the page's URL matches pattern, a glob ("**/orders/*")
+// or a /regex/, e.g. after a click triggering a client-side route change
+// Options: timeout (ms, default DefaultTimeout). Resolves to the matching URL
+func (p *Page) WaitForURL(pattern string, options map[string]interface{}) (*sobek.Promise, error) {
+	if p.client == nil {
+		return nil, fmt.Errorf("browser session not initialized")
+	}
+
+	re, err := urlPatternRegex(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid URL pattern '%s': %w", pattern, err)
+	}
+	timeout := timeoutFromOptions(options)
+	if timeout <= 0 {
+		timeout = DefaultTimeout()
+	}
+
+	return p.promise(func() (any, error) {
+		url, err := p.client.waitForURL(context.Background(), re, 100*time.Millisecond, timeout)
+		if err != nil {
+			return nil, fmt.Errorf("waitForURL failed for pattern '%s': %w", pattern, err)
+		}
+		return url, nil
+	}), nil
+}
+
+// WaitForLoadState waits until the page reaches state: "load" (default),
+// "domcontentloaded" or "networkidle", e.g. after a click triggering a
+// navigation. Options: networkIdleTime (ms) for "networkidle"
+func (p *Page) WaitForLoadState(state string, options map[string]interface{}) (*sobek.Promise, error) {
+	if p.client == nil {
+		return nil, fmt.Errorf("browser session not initialized")
+	}
+
+	switch state {
+	case "", "load", "domcontentloaded", "networkidle":
+	default:
+		return nil, fmt.Errorf("invalid load state '%s', expected load, domcontentloaded or networkidle", state)
+	}
+	var networkIdleTime time.Duration
+	if navOptions := navigateOptionsFrom(options); navOptions != nil {
+		networkIdleTime = navOptions.NetworkIdleTime
+	}
+
+	return p.promise(func() (any, error) {
+		if err := p.client.WaitForLoadState(context.Background(), state, networkIdleTime); err != nil {
+			return nil, fmt.Errorf("waitForLoadState failed for state '%s': %w", state, err)
+		}
+		return nil, nil
+	}), nil
+}
+
+// SetDefaultTimeout sets the timeout in milliseconds used by waits that aren't
+// given one. The default applies to all pages.
+func (p *Page) SetDefaultTimeout(ms int) {
+	SetDefaultTimeout(time.Duration(ms) * time.Millisecond)
+}
+
+// Click clicks an element by CSS selector
+func (p *Page) Click(selector string) (*sobek.Promise, error) {
+	if p.client == nil {
+		return nil, fmt.Errorf("browser session not initialized")
+	}
+
+	return p.promise(func() (any, error) {
 		ctx := context.Background()
-		elementID, err := p.client.FindElement(ctx, selector)
+		err := p.Locator(selector).withActionableElement(ctx, func(elementID string) error {
+			return p.client.ClickElement(ctx, elementID)
+		})
 		if err != nil {
-			return nil, fmt.Errorf("failed to find element: %w", err)
+			return nil, fmt.Errorf("failed to click element: %w", err)
 		}
 
-		err = p.client.SendKeys(ctx, elementID, text)
+		p.recordAction(TraceAction{Type: TraceActionClick, Selector: selector})
+
+		return nil, nil
+	}), nil
+}
+
+// Fill fills an input field with text
+func (p *Page) Fill(selector, text string) (*sobek.Promise, error) {
+	if p.client == nil {
+		return nil, fmt.Errorf("browser session not initialized")
+	}
+
+	return p.promise(func() (any, error) {
+		ctx := context.Background()
+		err := p.Locator(selector).withActionableElement(ctx, func(elementID string) error {
+			return p.client.SendKeys(ctx, elementID, text)
+		})
 		if err != nil {
 			return nil, fmt.Errorf("failed to send keys: %w", err)
 		}
 
+		p.recordAction(TraceAction{Type: TraceActionFill, Selector: selector, Text: text})
+
 		return nil, nil
 	}), nil
 }
@@ -351,7 +948,7 @@ func (p *Page) Screenshot(options map[string]interface{}) (*sobek.Promise, error
 		return nil, fmt.Errorf("browser session not initialized")
 	}
 
-	return Promise(p.vu, func() (any, error) {
+	return p.promise(func() (any, error) {
 		ctx := context.Background()
 		screenshotData, err := p.client.TakeScreenshot(ctx)
 		if err != nil {
@@ -373,27 +970,200 @@ func (p *Page) Screenshot(options map[string]interface{}) (*sobek.Promise, error
 	}), nil
 }
 
+// ExpectScreenshot screenshots the viewport and compares it against the baseline
+// image name.png in options.BaselineDir, creating the baseline on first run. The
+// promise resolves with the comparison result, or rejects with a
+// ScreenshotMismatchError when the screenshot doesn't match.
+func (p *Page) ExpectScreenshot(name string, opts ...ExpectScreenshotOptions) (*sobek.Promise, error) {
+	if p.client == nil {
+		return nil, fmt.Errorf("browser session not initialized")
+	}
+
+	var options ExpectScreenshotOptions
+	if len(opts) > 0 {
+		options = opts[0]
+	}
+	baselineDir := options.BaselineDir
+	if baselineDir == "" {
+		baselineDir = defaultBaselineDir
+	}
+	name = strings.TrimSuffix(name, ".png")
+
+	return p.promise(func() (any, error) {
+		screenshot, err := p.client.TakeScreenshot(context.Background())
+		if err != nil {
+			return nil, fmt.Errorf("failed to take screenshot: %w", err)
+		}
+
+		result, err := CompareAgainstBaseline(name, screenshot, baselineDir, options.BaselineOptions)
+		if err != nil {
+			return nil, err
+		}
+		if !result.Passed {
+			return nil, &ScreenshotMismatchError{Result: result}
+		}
+
+		return result, nil
+	}), nil
+}
+
+// Reset gives the page a clean slate without recreating the session: it clears
+// localStorage, sessionStorage and cookies, then navigates to about:blank
+func (p *Page) Reset() (*sobek.Promise, error) {
+	if p.client == nil {
+		return nil, fmt.Errorf("browser session not initialized")
+	}
+
+	return p.promise(func() (any, error) {
+		ctx := context.Background()
+
+		// Storage is per origin, so clear it before navigating away
+		script := `
+			try { window.localStorage.clear(); } catch (e) {}
+			try { window.sessionStorage.clear(); } catch (e) {}
+		`
+		if _, err := p.client.ExecuteScript(ctx, script, nil); err != nil {
+			return nil, fmt.Errorf("failed to clear storage: %w", err)
+		}
+
+		if err := p.client.DeleteAllCookies(ctx); err != nil {
+			return nil, fmt.Errorf("failed to clear cookies: %w", err)
+		}
+
+		if err := p.client.Navigate(ctx, "about:blank", nil); err != nil {
+			return nil, fmt.Errorf("failed to navigate to about:blank: %w", err)
+		}
+
+		// Re-inject the script after navigation
+		return nil, p.reinjectScript(ctx, "after reset")
+	}), nil
+}
+
+// LocalStorage returns the page's localStorage as a map of keys to values
+func (p *Page) LocalStorage() (*sobek.Promise, error) {
+	return p.readStorage("localStorage")
+}
+
+// SessionStorage returns the page's sessionStorage as a map of keys to values
+func (p *Page) SessionStorage() (*sobek.Promise, error) {
+	return p.readStorage("sessionStorage")
+}
+
+// readStorage returns the contents of window.localStorage or window.sessionStorage
+func (p *Page) readStorage(storage string) (*sobek.Promise, error) {
+	if p.client == nil {
+		return nil, fmt.Errorf("browser session not initialized")
+	}
+
+	return p.promise(func() (any, error) {
+		ctx := context.Background()
+
+		script := `
+			var storage = window[arguments[0]];
+			var items = {};
+			for (var i = 0; i < storage.length; i++) {
+				var key = storage.key(i);
+				items[key] = storage.getItem(key);
+			}
+			return items;
+		`
+		result, err := p.client.ExecuteScript(ctx, script, []interface{}{storage})
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", storage, err)
+		}
+
+		items := make(map[string]string)
+		if resultMap, ok := result.(map[string]interface{}); ok {
+			for key, value := range resultMap {
+				if str, ok := value.(string); ok {
+					items[key] = str
+				}
+			}
+		}
+
+		return items, nil
+	}), nil
+}
+
+// SetLocalStorage sets a localStorage item for the current page's origin
+func (p *Page) SetLocalStorage(key, value string) (*sobek.Promise, error) {
+	if p.client == nil {
+		return nil, fmt.Errorf("browser session not initialized")
+	}
+
+	return p.promise(func() (any, error) {
+		ctx := context.Background()
+
+		script := `window.localStorage.setItem(arguments[0], arguments[1]);`
+		if _, err := p.client.ExecuteScript(ctx, script, []interface{}{key, value}); err != nil {
+			return nil, fmt.Errorf("failed to set localStorage item '%s': %w", key, err)
+		}
+
+		return nil, nil
+	}), nil
+}
+
+// ClearLocalStorage removes all localStorage items for the current page's origin
+func (p *Page) ClearLocalStorage() (*sobek.Promise, error) {
+	if p.client == nil {
+		return nil, fmt.Errorf("browser session not initialized")
+	}
+
+	return p.promise(func() (any, error) {
+		ctx := context.Background()
+
+		if _, err := p.client.ExecuteScript(ctx, `window.localStorage.clear();`, nil); err != nil {
+			return nil, fmt.Errorf("failed to clear localStorage: %w", err)
+		}
+
+		return nil, nil
+	}), nil
+}
+
 // WaitForTimeout waits for the specified number of milliseconds
 func (p *Page) WaitForTimeout(milliseconds int) (*sobek.Promise, error) {
-	return Promise(p.vu, func() (interface{}, error) {
+	return p.promise(func() (interface{}, error) {
 		duration := time.Duration(milliseconds) * time.Millisecond
 		time.Sleep(duration)
 		return nil, nil
 	}), nil
 }
 
-// Close closes the page
+// Close closes the page's window. The page's session is deleted with its last
+// window, while windows the page opened, like target="_blank" links, stay open
 func (p *Page) Close() (*sobek.Promise, error) {
 	if p.client == nil {
 		return nil, fmt.Errorf("browser session not initialized")
 	}
 
+	// Not p.promise: the session's windows only turn out to be shared once the
+	// windows the page opened were looked up, so the page's window is switched
+	// to afterwards
+	release := p.reserveHandlerCallback()
 	return Promise(p.vu, func() (any, error) {
+		defer release()
 		ctx := context.Background()
-		err := p.client.DeleteSession(ctx)
 
-		// Decrement safaridriver reference count
-		stopSafariDriver()
+		// Windows the page opened get pages of their own first, through which
+		// they can be used and closed, and their session deleted with the browser
+		// Failing to list them leaves only the page's window to close
+		if p.context != nil && p.windows != nil {
+			_, _ = p.context.discoverPages(ctx)
+		}
+
+		var err error
+		if p.windows != nil && p.windows.shared.Load() {
+			err = p.inWindow(ctx, func() error { return p.closeWindow(ctx) })
+		} else {
+			err = p.client.DeleteSession(ctx)
+		}
+
+		if p.browser != nil {
+			p.browser.untrackPage(p)
+		}
+		if p.context != nil {
+			p.context.removePage(p)
+		}
 
 		return nil, err
 	}), nil
@@ -420,12 +1190,58 @@ func Promise(vu modules.VU, fn PromisifiedFunc) *sobek.Promise {
 	return p
 }
 
+// navigateOptionsFrom parses the waitUntil and networkIdleTime (ms) options of
+// Goto and SetContent, returning nil when no options are given
+func navigateOptionsFrom(options map[string]interface{}) *NavigateOptions {
+	if options == nil {
+		return nil
+	}
+
+	navOptions := &NavigateOptions{
+		WaitUntil: "load",
+	}
+	if waitUntil, ok := options["waitUntil"].(string); ok {
+		navOptions.WaitUntil = waitUntil
+	}
+	if ms, ok := toFloat64(options["networkIdleTime"]); ok && ms > 0 {
+		navOptions.NetworkIdleTime = time.Duration(ms) * time.Millisecond
+	}
+
+	return navOptions
+}
+
+// timeoutFromOptions returns the timeout option in milliseconds as a duration,
+// or zero if it isn't set
+func timeoutFromOptions(options map[string]interface{}) time.Duration {
+	if options == nil {
+		return 0
+	}
+	if ms, ok := toFloat64(options["timeout"]); ok && ms > 0 {
+		return time.Duration(ms * float64(time.Millisecond))
+	}
+	return 0
+}
+
+// toFloat64 converts a number exported from the JS runtime to float64
+// Integral JS numbers are exported as int64, others as float64
+func toFloat64(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case int64:
+		return float64(n), true
+	case int:
+		return float64(n), true
+	default:
+		return 0, false
+	}
+}
+
 type ctxKey int
 
 const (
 	ctxKeyVU ctxKey = iota
 	ctxKeyPid
-	ctxKeyCustomK6Metrics
 )
 
 // GetVU returns the attached k6 VU instance from ctx, which can be used to
@@ -1,8 +1,24 @@
 package browser
 
 import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
 	"testing"
 	"time"
+
+	"github.com/grafana/sobek"
+	"go.k6.io/k6/js/modulestest"
 )
 
 func TestBrowserCreation(t *testing.T) {
@@ -62,3 +78,414 @@ func TestPageWaitForTimeout(t *testing.T) {
 		t.Errorf("Test initialization took too long: %v", elapsed)
 	}
 }
+
+func TestPageStorageWithoutSession(t *testing.T) {
+	// Storage helpers should fail fast when the page has no client
+	page := &Page{}
+
+	if _, err := page.LocalStorage(); err == nil {
+		t.Error("Expected error when reading localStorage without session")
+	}
+	if _, err := page.SessionStorage(); err == nil {
+		t.Error("Expected error when reading sessionStorage without session")
+	}
+	if _, err := page.SetLocalStorage("key", "value"); err == nil {
+		t.Error("Expected error when setting localStorage without session")
+	}
+	if _, err := page.ClearLocalStorage(); err == nil {
+		t.Error("Expected error when clearing localStorage without session")
+	}
+}
+
+func TestPageContentWithoutSession(t *testing.T) {
+	page := &Page{}
+
+	if _, err := page.Content(); err == nil {
+		t.Error("Expected error when getting content without session")
+	}
+	if _, err := page.SetContent("<p>hello</p>", nil); err == nil {
+		t.Error("Expected error when setting content without session")
+	}
+}
+
+func TestPageEvaluateWithArgsWithoutSession(t *testing.T) {
+	page := &Page{}
+
+	if _, err := page.EvaluateWithArgs("(a, b) => a + b", []interface{}{1, 2}); err == nil {
+		t.Error("Expected error when evaluating without session")
+	}
+}
+
+func TestFunctionCallScript(t *testing.T) {
+	tests := []struct {
+		script   string
+		expected string
+	}{
+		{"(a, b) => a + b", "return ((a, b) => a + b).apply(null, arguments);"},
+		{"x => x * 2", "return (x => x * 2).apply(null, arguments);"},
+		{"  function (sel) { return document.querySelector(sel); }", "return (function (sel) { return document.querySelector(sel); }).apply(null, arguments);"},
+		{"async () => window.appReady", "return (async () => window.appReady).apply(null, arguments);"},
+		{"return arguments[0] + arguments[1];", "return arguments[0] + arguments[1];"},
+		{"return document.title", "return document.title"},
+	}
+
+	for _, tt := range tests {
+		if got := functionCallScript(tt.script); got != tt.expected {
+			t.Errorf("functionCallScript(%q) = %q, expected %q", tt.script, got, tt.expected)
+		}
+	}
+}
+
+func TestPageWaitForFunctionWithoutSession(t *testing.T) {
+	page := &Page{}
+
+	if _, err := page.WaitForFunction("() => window.appReady === true", nil); err == nil {
+		t.Error("Expected error when waiting for function without session")
+	}
+}
+
+func TestTruthyConditionScript(t *testing.T) {
+	tests := []struct {
+		script   string
+		expected string
+	}{
+		{"() => window.appReady === true", "return Promise.resolve((() => window.appReady === true)()).then(function(v) { return !!v; });"},
+		{"async function() { return fetchDone; }", "return Promise.resolve((async function() { return fetchDone; })()).then(function(v) { return !!v; });"},
+		{"document.querySelectorAll('li').length > 3", "return !!(document.querySelectorAll('li').length > 3);"},
+	}
+
+	for _, tt := range tests {
+		if got := truthyConditionScript(tt.script); got != tt.expected {
+			t.Errorf("truthyConditionScript(%q) = %q, expected %q", tt.script, got, tt.expected)
+		}
+	}
+}
+
+func TestPageWaitForSelectorWithoutSession(t *testing.T) {
+	page := &Page{}
+
+	if _, err := page.WaitForSelector("#app", map[string]interface{}{"timeout": int64(500)}); err == nil {
+		t.Error("Expected error when waiting for selector without session")
+	}
+}
+
+func TestPageSetDefaultTimeout(t *testing.T) {
+	t.Cleanup(func() { SetDefaultTimeout(0) })
+
+	page := &Page{}
+	page.SetDefaultTimeout(60000)
+
+	if got := DefaultTimeout(); got != time.Minute {
+		t.Errorf("Expected default timeout of 1m, got %v", got)
+	}
+}
+
+func TestTimeoutFromOptions(t *testing.T) {
+	tests := []struct {
+		name     string
+		options  map[string]interface{}
+		expected time.Duration
+	}{
+		{"nil options", nil, 0},
+		{"no timeout", map[string]interface{}{"state": "visible"}, 0},
+		{"integer milliseconds", map[string]interface{}{"timeout": int64(5000)}, 5 * time.Second},
+		{"fractional milliseconds", map[string]interface{}{"timeout": 1.5}, 1500 * time.Microsecond},
+		{"zero", map[string]interface{}{"timeout": int64(0)}, 0},
+		{"invalid", map[string]interface{}{"timeout": "soon"}, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := timeoutFromOptions(tt.options); got != tt.expected {
+				t.Errorf("timeoutFromOptions(%v) = %v, expected %v", tt.options, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestNavigateOptionsFrom(t *testing.T) {
+	if got := navigateOptionsFrom(nil); got != nil {
+		t.Errorf("Expected nil options, got %+v", got)
+	}
+
+	got := navigateOptionsFrom(map[string]interface{}{})
+	if got.WaitUntil != "load" || got.NetworkIdleTime != 0 {
+		t.Errorf("Expected load with default idle time, got %+v", got)
+	}
+
+	got = navigateOptionsFrom(map[string]interface{}{"waitUntil": "networkidle", "networkIdleTime": int64(1000)})
+	if got.WaitUntil != "networkidle" {
+		t.Errorf("Expected networkidle, got %q", got.WaitUntil)
+	}
+	if got.NetworkIdleTime != time.Second {
+		t.Errorf("Expected idle time of 1s, got %v", got.NetworkIdleTime)
+	}
+}
+
+func TestFreePort(t *testing.T) {
+	port, err := freePort()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if port <= 0 || port > 65535 {
+		t.Errorf("Expected a valid port, got %d", port)
+	}
+
+	// The port is released, so a driver can bind it
+	l, err := net.Listen("tcp", fmt.Sprintf("localhost:%d", port))
+	if err != nil {
+		t.Fatalf("Expected port %d to be free: %v", port, err)
+	}
+	l.Close()
+}
+
+func TestSafariDriver(t *testing.T) {
+	driver := &SafariDriver{Port: 45123}
+
+	if got := driver.DriverURL(); got != "http://localhost:45123" {
+		t.Errorf("Expected driver URL 'http://localhost:45123', got '%s'", got)
+	}
+
+	// Stopping a driver that isn't running is a no-op
+	driver.Stop()
+
+	t.Setenv(safariDriverPathEnv, filepath.Join(t.TempDir(), "missing-safaridriver"))
+	_, err := StartSafariDriver()
+	if err == nil || !strings.Contains(err.Error(), "missing-safaridriver") {
+		t.Errorf("Expected error naming the configured executable, got %v", err)
+	}
+
+	// Configuring the driver doesn't start it, that's left to the first page
+	sleep, err := exec.LookPath("sleep")
+	if err != nil {
+		t.Skip("sleep is not on the PATH")
+	}
+	t.Setenv(safariDriverPathEnv, sleep)
+	driver, err = NewSafariDriver()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if driver.cmd != nil || driver.Port == 0 {
+		t.Errorf("Expected a driver on a free port that isn't running, got port %d and %v", driver.Port, driver.cmd)
+	}
+}
+
+func TestStartSafariDriverPinnedPort(t *testing.T) {
+	// A driver already listening on the pinned port is used as is
+	l, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer l.Close()
+	port := l.Addr().(*net.TCPAddr).Port
+
+	t.Setenv(safariDriverPathEnv, filepath.Join(t.TempDir(), "missing-safaridriver"))
+	t.Setenv(safariDriverPortEnv, strconv.Itoa(port))
+
+	driver, err := StartSafariDriver()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if driver.Port != port {
+		t.Errorf("Expected port %d, got %d", port, driver.Port)
+	}
+	if err := driver.ensureRunning(); err != nil {
+		t.Errorf("Expected external driver to be left alone, got %v", err)
+	}
+	driver.Stop()
+
+	t.Setenv(safariDriverPortEnv, "not-a-port")
+	if _, err := StartSafariDriver(); err == nil {
+		t.Error("Expected error for an invalid port")
+	}
+}
+
+func TestBrowserStartDriverStopsWithVU(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("SIGTERM is not supported on Windows")
+	}
+
+	// A running process stands in for safaridriver
+	cmd := exec.Command("sleep", "30")
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	driver := &SafariDriver{cmd: cmd}
+	b := &Browser{Client: NewWebDriverClient("http://localhost:4444"), Driver: driver}
+
+	vuCtx, cancel := context.WithCancel(context.Background())
+	if err := b.startDriver(vuCtx); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := b.startDriver(vuCtx); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// The driver is stopped once the VU is done, without the script closing the browser
+	cancel()
+	deadline := time.Now().Add(driverShutdownGracePeriod)
+	for {
+		driver.mu.Lock()
+		stopped := driver.cmd == nil
+		driver.mu.Unlock()
+		if stopped {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Expected the driver to be stopped once the VU context is done")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if cmd.ProcessState == nil {
+		t.Error("Expected the driver process to have exited")
+	}
+}
+
+func TestSafariDriverStop(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("SIGTERM is not supported on Windows")
+	}
+
+	// Processes that exit on SIGTERM are not killed
+	cmd := exec.Command("sleep", "30")
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	driver := &SafariDriver{cmd: cmd}
+
+	start := time.Now()
+	driver.Stop()
+	if elapsed := time.Since(start); elapsed >= driverShutdownGracePeriod {
+		t.Errorf("Expected graceful shutdown to be quick, took %v", elapsed)
+	}
+	if status, ok := cmd.ProcessState.Sys().(syscall.WaitStatus); !ok || status.Signal() != syscall.SIGTERM {
+		t.Errorf("Expected process to exit on SIGTERM, got %v", cmd.ProcessState)
+	}
+
+	// Processes ignoring SIGTERM are killed after the grace period
+	grace := driverShutdownGracePeriod
+	driverShutdownGracePeriod = 200 * time.Millisecond
+	t.Cleanup(func() { driverShutdownGracePeriod = grace })
+
+	cmd = exec.Command("sh", "-c", "trap '' TERM; while true; do sleep 0.1; done")
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	time.Sleep(100 * time.Millisecond) // Let the shell install the trap
+	driver = &SafariDriver{cmd: cmd}
+
+	driver.Stop()
+	if status, ok := cmd.ProcessState.Sys().(syscall.WaitStatus); !ok || status.Signal() != syscall.SIGKILL {
+		t.Errorf("Expected process to be killed, got %v", cmd.ProcessState)
+	}
+	if driver.cmd != nil {
+		t.Error("Expected driver to be marked as stopped")
+	}
+}
+
+func TestBrowserDeleteSessions(t *testing.T) {
+	var mu sync.Mutex
+	var deleted []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodDelete {
+			mu.Lock()
+			deleted = append(deleted, r.URL.Path)
+			mu.Unlock()
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"value":null}`))
+	}))
+	defer server.Close()
+
+	client := NewWebDriverClient(server.URL)
+	client.setSession("session-2") // The latest session created
+	browser := &Browser{Client: client}
+
+	page1 := &Page{client: client.forSession("session-1"), browser: browser}
+	page2 := &Page{client: client.forSession("session-2"), browser: browser}
+	closed := &Page{client: client.forSession("session-0"), browser: browser}
+	browser.trackPage(closed)
+	browser.trackPage(page1)
+	browser.trackPage(page2)
+	browser.untrackPage(closed)
+
+	browser.deleteSessions(context.Background())
+
+	expected := []string{"/session/session-1", "/session/session-2"}
+	if len(deleted) != len(expected) || deleted[0] != expected[0] || deleted[1] != expected[1] {
+		t.Errorf("Expected each open session to be deleted once %v, got %v", expected, deleted)
+	}
+	if client.session() != "" {
+		t.Errorf("Expected browser client session to be cleared, got '%s'", client.session())
+	}
+	if len(browser.pages) != 0 {
+		t.Errorf("Expected no tracked pages, got %d", len(browser.pages))
+	}
+}
+
+func TestPageGetActiveElement(t *testing.T) {
+	var mu sync.Mutex
+	focused := `[{"element-6066-11e4-a52e-4f735466cecf":"search-input"}]`
+	var usedElement bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		switch {
+		case strings.Contains(string(body), "document.activeElement"):
+			_, _ = w.Write([]byte(`{"value":` + focused + `}`))
+		case strings.Contains(r.URL.Path+string(body), "search-input"):
+			usedElement = true
+			_, _ = w.Write([]byte(`{"value":"Search"}`))
+		default:
+			_, _ = w.Write([]byte(`{"value":null}`))
+		}
+	}))
+	defer server.Close()
+
+	runtime := modulestest.NewRuntime(t)
+	page := &Page{vu: runtime.VU, client: NewWebDriverClient(server.URL).forSession("session-1")}
+	rt := runtime.VU.Runtime()
+	if err := rt.Set("page", page); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := runtime.RunOnEventLoop(`
+		var text, failure = "";
+		page.getActiveElement()
+			.then(function(element) { return element.textContent(); })
+			.then(function(t) { text = t; })
+			.catch(function(e) { failure = String(e); });
+	`)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if failure := rt.Get("failure").String(); failure != "" {
+		t.Fatalf("Unexpected failure: %s", failure)
+	}
+	if text := rt.Get("text").String(); text != "Search" || !usedElement {
+		t.Errorf("Expected the locator to act on the focused element, got %q", text)
+	}
+
+	// Nothing has focus
+	mu.Lock()
+	focused = `[]`
+	mu.Unlock()
+	_, err = runtime.RunOnEventLoop(`
+		var active = "unset";
+		page.getActiveElement().then(function(element) { active = element; });
+	`)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if active := rt.Get("active"); !sobek.IsNull(active) {
+		t.Errorf("Expected null when nothing has focus, got %v", active)
+	}
+
+	if _, err := (&Page{}).GetActiveElement(); err == nil {
+		t.Error("Expected error when getting the active element without session")
+	}
+}
@@ -0,0 +1,127 @@
+package browser
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/grafana/sobek"
+	"github.com/sirupsen/logrus"
+)
+
+// ConsoleMessage is a message logged by a page with console.log, debug, info,
+// warn or error, passed to the handler set with Page.OnConsole
+type ConsoleMessage struct {
+	kind string
+	text string
+	url  string
+}
+
+// Type returns the console method the message was logged with: "log", "debug",
+// "info", "warning" or "error"
+func (m *ConsoleMessage) Type() string {
+	return m.kind
+}
+
+// Text returns the message's arguments joined with spaces, with objects
+// serialized as JSON
+func (m *ConsoleMessage) Text() string {
+	return m.text
+}
+
+// URL returns the URL of the document that logged the message
+func (m *ConsoleMessage) URL() string {
+	return m.url
+}
+
+// takeConsoleMessagesScript returns and clears the console messages buffered by
+// the injection script, with the number of messages dropped once it was full
+const takeConsoleMessagesScript = `
+	var buffer = window.__webdriverConsole;
+	if (!buffer) return { messages: [], dropped: 0 };
+	var result = { messages: buffer.messages, dropped: buffer.dropped };
+	buffer.messages = [];
+	buffer.dropped = 0;
+	return result;
+`
+
+// OnConsole sets the handler called with every message the page logs to the
+// console, or clears it if handler is null. Since WebDriver has no events, the
+// messages are buffered by the injection script and passed to the handler at
+// the end of each page operation
+func (p *Page) OnConsole(handler sobek.Value) error {
+	callable, err := handlerFrom("console", handler)
+	if err != nil {
+		return err
+	}
+
+	p.handlerMu.Lock()
+	defer p.handlerMu.Unlock()
+
+	p.consoleHandler = callable
+	return nil
+}
+
+// dispatchConsoleMessages passes the console messages buffered by the page to
+// the console handler, if one is set. It must be called in the page's window
+func (p *Page) dispatchConsoleMessages(ctx context.Context) {
+	p.handlerMu.Lock()
+	handler := p.consoleHandler
+	p.handlerMu.Unlock()
+	if handler == nil || p.client == nil {
+		return
+	}
+
+	messages, dropped, err := p.client.takeConsoleMessages(ctx)
+	if err != nil {
+		logf(p.vu, logrus.DebugLevel, "failed to collect console messages: %v", err)
+		return
+	}
+	if dropped > 0 {
+		logf(p.vu, logrus.WarnLevel, "dropped %d console messages logged while the page's buffer was full", dropped)
+	}
+
+	for _, msg := range messages {
+		err := p.callHandler(func(rt *sobek.Runtime) error {
+			_, err := handler(sobek.Undefined(), rt.ToValue(msg))
+			return err
+		})
+		if errors.Is(err, errNoCallback) {
+			return
+		}
+		if err != nil {
+			logf(p.vu, logrus.WarnLevel, "console handler failed: %v", err)
+		}
+	}
+}
+
+// takeConsoleMessages returns and clears the console messages buffered by the
+// injection script, and the number of messages it dropped
+func (c *WebDriverClient) takeConsoleMessages(ctx context.Context) ([]*ConsoleMessage, int, error) {
+	result, err := c.ExecuteScript(ctx, takeConsoleMessagesScript, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	buffer, ok := result.(map[string]interface{})
+	if !ok {
+		return nil, 0, fmt.Errorf("unexpected console messages result: %v", result)
+	}
+
+	var messages []*ConsoleMessage
+	items, _ := buffer["messages"].([]interface{})
+	for _, item := range items {
+		fields, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		msg := &ConsoleMessage{}
+		msg.kind, _ = fields["type"].(string)
+		msg.text, _ = fields["text"].(string)
+		msg.url, _ = fields["url"].(string)
+		messages = append(messages, msg)
+	}
+
+	dropped, _ := toFloat64(buffer["dropped"])
+	return messages, int(dropped), nil
+}
@@ -0,0 +1,109 @@
+package browser
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"go.k6.io/k6/js/modulestest"
+)
+
+// consoleServer answers the console collection script with the buffered
+// messages, once, and every other script with 1
+type consoleServer struct {
+	mu       sync.Mutex
+	buffered string // JSON array of the messages buffered by the page
+	collects int
+}
+
+func (cs *consoleServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	w.Header().Set("Content-Type", "application/json")
+
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+
+	if !strings.Contains(string(body), "__webdriverConsole") {
+		_, _ = w.Write([]byte(`{"value":1}`))
+		return
+	}
+	cs.collects++
+	messages := cs.buffered
+	cs.buffered = "[]"
+	_, _ = w.Write([]byte(`{"value":{"messages":` + messages + `,"dropped":0}}`))
+}
+
+func TestPageOnConsole(t *testing.T) {
+	runtime := modulestest.NewRuntime(t)
+	cs := &consoleServer{buffered: `[
+		{"type":"log","text":"hello {\"a\":1}","url":"https://example.com/"},
+		{"type":"warning","text":"careful","url":"https://example.com/"}
+	]`}
+	server := httptest.NewServer(cs)
+	defer server.Close()
+
+	page := &Page{vu: runtime.VU, client: NewWebDriverClient(server.URL).forSession("session-1")}
+	if err := runtime.VU.Runtime().Set("page", page); err != nil {
+		t.Fatal(err)
+	}
+
+	// Messages are passed to the handler before the operation resolves
+	_, err := runtime.RunOnEventLoop(`
+		var events = [];
+		page.onConsole(function(msg) {
+			events.push(msg.type() + ": " + msg.text() + " @ " + msg.url());
+		});
+		page.evaluate("return 1;").then(function() { events.push("resolved"); });
+	`)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := `log: hello {"a":1} @ https://example.com/,warning: careful @ https://example.com/,resolved`
+	if got := runtime.VU.Runtime().Get("events").String(); got != expected {
+		t.Errorf("Expected events %s, got %s", expected, got)
+	}
+
+	// A failing handler doesn't fail the operation
+	cs.mu.Lock()
+	cs.buffered = `[{"type":"error","text":"boom","url":""}]`
+	cs.mu.Unlock()
+	_, err = runtime.RunOnEventLoop(`
+		var failure = "", calls = 0;
+		page.onConsole(function() { calls++; throw new Error("handler failed"); });
+		page.evaluate("return 1;").catch(function(e) { failure = String(e); });
+	`)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	rt := runtime.VU.Runtime()
+	if calls := rt.Get("calls").ToInteger(); calls != 1 {
+		t.Errorf("Expected the handler to be called once, got %d", calls)
+	}
+	if failure := rt.Get("failure").String(); failure != "" {
+		t.Errorf("Expected the operation to succeed, got %s", failure)
+	}
+
+	// Without a handler, messages aren't collected
+	cs.mu.Lock()
+	cs.collects = 0
+	cs.mu.Unlock()
+	if _, err := runtime.RunOnEventLoop(`
+		page.onConsole(null);
+		page.evaluate("return 1;");
+	`); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	cs.mu.Lock()
+	if cs.collects != 0 {
+		t.Errorf("Expected no console collection without a handler, got %d", cs.collects)
+	}
+	cs.mu.Unlock()
+
+	if err := page.OnConsole(runtime.VU.Runtime().ToValue("not a function")); err == nil {
+		t.Error("Expected error for a handler that isn't a function")
+	}
+}
@@ -3,6 +3,7 @@ package browser
 import (
 	"context"
 	"fmt"
+	"sync"
 
 	"github.com/grafana/sobek"
 	"go.k6.io/k6/js/modules"
@@ -13,16 +14,121 @@ type BrowserContext struct {
 	browser *Browser
 	vu      modules.VU
 	options map[string]interface{} // Store context options (e.g., viewport)
-	pages   []*Page                // Track pages created in this context
+
+	// client creates the sessions of the context's pages and is bound to the
+	// latest open one, which cookie commands target. It shares the browser
+	// client's connection pool, but no session with other contexts
+	client *WebDriverClient
+
+	pagesMu    sync.Mutex
+	pages      []*Page    // Open pages of the context, including windows they opened
+	discoverMu sync.Mutex // Held while looking for new windows, so each gets one page
+
+	initScriptsMu sync.Mutex
+	initScripts   []string // Run after the injection script on every page of the context
+
+	geolocationMu sync.Mutex
+	geolocation   *geolocation // Reported by navigator.geolocation if set
+
+	routesMu sync.Mutex
+	routes   []route // Canned answers to the fetch and XHR requests of the pages
 }
 
 // NewPage creates a new page in this browser context
 func (bc *BrowserContext) NewPage() (*sobek.Promise, error) {
-	// Delegate to browser's NewPage implementation with stored options
-	if bc.options != nil {
-		return bc.browser.NewPage(bc.options)
+	// Delegate to browser's page creation with stored options
+	return bc.browser.newPage(bc.options, bc)
+}
+
+// addPage records an open page of the context
+func (bc *BrowserContext) addPage(page *Page) {
+	bc.pagesMu.Lock()
+	defer bc.pagesMu.Unlock()
+
+	bc.pages = append(bc.pages, page)
+}
+
+// removePage forgets a closed page, and binds the context's client to the
+// session of the latest page left open
+func (bc *BrowserContext) removePage(page *Page) {
+	bc.pagesMu.Lock()
+	defer bc.pagesMu.Unlock()
+
+	for i, p := range bc.pages {
+		if p == page {
+			bc.pages = append(bc.pages[:i], bc.pages[i+1:]...)
+			break
+		}
+	}
+
+	bc.bindClient()
+}
+
+// bindClient binds the context's client to the session of the latest page left
+// open. It must be called with pagesMu held
+func (bc *BrowserContext) bindClient() {
+	if bc.client == nil {
+		return
+	}
+	sessionID := ""
+	for i := len(bc.pages) - 1; i >= 0 && sessionID == ""; i-- {
+		sessionID = bc.pages[i].client.session()
+	}
+	bc.client.setSession(sessionID)
+}
+
+// rebindClient binds the context's client to the session of the latest page,
+// e.g. once a page replaced its invalidated session
+func (bc *BrowserContext) rebindClient() {
+	bc.pagesMu.Lock()
+	defer bc.pagesMu.Unlock()
+
+	bc.bindClient()
+}
+
+// reconnectPage replaces the invalidated session the context's client is bound
+// to through the page it belongs to, so that the context's commands keep going
+// to the page's session rather than to a new session of their own
+func (bc *BrowserContext) reconnectPage(ctx context.Context, invalidID string) (string, error) {
+	for _, page := range bc.currentPages() {
+		if page.client.session() == invalidID {
+			return page.client.reconnect(ctx, invalidID)
+		}
 	}
-	return bc.browser.NewPage()
+
+	// The page replaced its session already
+	bc.rebindClient()
+	if sessionID := bc.client.session(); sessionID != "" && sessionID != invalidID {
+		return sessionID, nil
+	}
+	return "", ErrNoSession
+}
+
+// currentPages returns the open pages of the context, in the order they were opened
+func (bc *BrowserContext) currentPages() []*Page {
+	bc.pagesMu.Lock()
+	defer bc.pagesMu.Unlock()
+
+	return append([]*Page(nil), bc.pages...)
+}
+
+// AddInitScript adds a script that runs on every page of the context when the page is
+// created and after each navigation, after the built-in injection script. WebDriver has
+// no hook to run scripts before the document's own, so init scripts run after them. A
+// failing init script fails the navigation and is logged when creating the page
+func (bc *BrowserContext) AddInitScript(script string) {
+	bc.initScriptsMu.Lock()
+	defer bc.initScriptsMu.Unlock()
+
+	bc.initScripts = append(bc.initScripts, script)
+}
+
+// currentInitScripts returns the scripts added with AddInitScript, in order
+func (bc *BrowserContext) currentInitScripts() []string {
+	bc.initScriptsMu.Lock()
+	defer bc.initScriptsMu.Unlock()
+
+	return append([]string(nil), bc.initScripts...)
 }
 
 // Cookies returns all cookies for the current context
@@ -32,7 +138,7 @@ func (bc *BrowserContext) Cookies() (*sobek.Promise, error) {
 
 		// Get cookies from the WebDriver session
 		// If there's no active session, this will return an error
-		cookies, err := bc.browser.Client.GetAllCookies(ctx)
+		cookies, err := bc.client.GetAllCookies(ctx)
 		if err != nil {
 			return nil, fmt.Errorf("failed to get cookies: %w", err)
 		}
@@ -40,3 +146,89 @@ func (bc *BrowserContext) Cookies() (*sobek.Promise, error) {
 		return cookies, nil
 	}), nil
 }
+
+// AddCookies adds cookies to the current context, e.g. to seed auth cookies before navigating
+// Supported fields are name, value, domain, path, secure, httpOnly, expiry (or expires) and sameSite
+func (bc *BrowserContext) AddCookies(cookies []map[string]interface{}) (*sobek.Promise, error) {
+	return Promise(bc.vu, func() (interface{}, error) {
+		ctx := context.Background()
+
+		for _, cookie := range cookies {
+			wdCookie, err := toWebDriverCookie(cookie)
+			if err != nil {
+				return nil, err
+			}
+
+			if err := bc.client.AddCookie(ctx, wdCookie); err != nil {
+				return nil, fmt.Errorf("failed to add cookie '%s': %w", wdCookie["name"], err)
+			}
+		}
+
+		return nil, nil
+	}), nil
+}
+
+// ClearCookies deletes all cookies in the current context
+func (bc *BrowserContext) ClearCookies() (*sobek.Promise, error) {
+	return Promise(bc.vu, func() (interface{}, error) {
+		ctx := context.Background()
+
+		if err := bc.client.DeleteAllCookies(ctx); err != nil {
+			return nil, fmt.Errorf("failed to clear cookies: %w", err)
+		}
+
+		return nil, nil
+	}), nil
+}
+
+// DeleteCookie deletes the cookie with the given name from the current context
+func (bc *BrowserContext) DeleteCookie(name string) (*sobek.Promise, error) {
+	return Promise(bc.vu, func() (interface{}, error) {
+		ctx := context.Background()
+
+		if err := bc.client.DeleteCookie(ctx, name); err != nil {
+			return nil, fmt.Errorf("failed to delete cookie '%s': %w", name, err)
+		}
+
+		return nil, nil
+	}), nil
+}
+
+// toWebDriverCookie converts a cookie from the script into a WebDriver cookie object,
+// keeping only the fields WebDriver supports
+func toWebDriverCookie(cookie map[string]interface{}) (map[string]interface{}, error) {
+	name, ok := cookie["name"].(string)
+	if !ok || name == "" {
+		return nil, fmt.Errorf("cookie is missing a name")
+	}
+	value, ok := cookie["value"].(string)
+	if !ok {
+		return nil, fmt.Errorf("cookie '%s' is missing a value", name)
+	}
+
+	wdCookie := map[string]interface{}{
+		"name":  name,
+		"value": value,
+	}
+
+	for _, field := range []string{"domain", "path", "sameSite"} {
+		if v, ok := cookie[field].(string); ok && v != "" {
+			wdCookie[field] = v
+		}
+	}
+	for _, field := range []string{"secure", "httpOnly"} {
+		if v, ok := cookie[field].(bool); ok {
+			wdCookie[field] = v
+		}
+	}
+
+	// WebDriver calls it expiry, Playwright calls it expires
+	for _, field := range []string{"expiry", "expires"} {
+		if v, ok := toFloat64(cookie[field]); ok && v > 0 {
+			wdCookie["expiry"] = int64(v)
+			break
+		}
+	}
+
+	return wdCookie, nil
+}
@@ -1,6 +1,12 @@
 package browser
 
 import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -72,3 +78,295 @@ func TestBrowserContextCookies(t *testing.T) {
 	require.NoError(t, err)
 	require.NotNil(t, promise)
 }
+
+func TestBrowserContextAddCookies(t *testing.T) {
+	t.Parallel()
+
+	runtime := modulestest.NewRuntime(t)
+
+	browser := &Browser{
+		VU:     runtime.VU,
+		Client: NewWebDriverClient("http://localhost:4444"),
+	}
+
+	context := browser.NewContext()
+
+	// AddCookies should return a promise
+	promise, err := context.AddCookies([]map[string]interface{}{
+		{"name": "session", "value": "abc"},
+	})
+	require.NoError(t, err)
+	require.NotNil(t, promise)
+}
+
+func TestBrowserContextCookiesAfterReconnect(t *testing.T) {
+	t.Parallel()
+
+	var mu sync.Mutex
+	sessions := 0
+	valid := ""
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.URL.Path == "/session":
+			sessions++
+			valid = fmt.Sprintf("session-%d", sessions)
+			_, _ = fmt.Fprintf(w, `{"value":{"sessionId":%q,"capabilities":{}}}`, valid)
+		case !strings.HasPrefix(r.URL.Path, "/session/"+valid+"/"):
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"value":{"error":"invalid session id","message":"expired"}}`))
+		case strings.HasSuffix(r.URL.Path, "/cookie"):
+			_, _ = fmt.Fprintf(w, `{"value":[{"name":"session","value":%q}]}`, valid)
+		case strings.HasSuffix(r.URL.Path, "/title"):
+			_, _ = w.Write([]byte(`{"value":"Home"}`))
+		default:
+			_, _ = w.Write([]byte(`{"value":null}`))
+		}
+	}))
+	defer server.Close()
+
+	runtime := modulestest.NewRuntime(t)
+	opts := DefaultClientOptions()
+	opts.AutoReconnect = true
+	browser := &Browser{VU: runtime.VU, Client: NewWebDriverClientWithOptions(server.URL, opts)}
+	require.NoError(t, runtime.VU.Runtime().Set("context", browser.NewContext()))
+
+	_, err := runtime.RunOnEventLoop(`
+		var page, failure = "";
+		context.newPage().then(function(p) { page = p; }).catch(function(e) { failure = String(e); });
+	`)
+	require.NoError(t, err)
+	require.Empty(t, runtime.VU.Runtime().Get("failure").String())
+
+	// safaridriver invalidates the session while it's idle. The page replaces
+	// it, and the context's cookies are read from the page's new session
+	mu.Lock()
+	valid = ""
+	mu.Unlock()
+	_, err = runtime.RunOnEventLoop(`
+		var cookies;
+		page.title()
+			.then(function() { return context.cookies(); })
+			.then(function(c) { cookies = c[0].value; })
+			.catch(function(e) { failure = String(e); });
+	`)
+	require.NoError(t, err)
+	require.Empty(t, runtime.VU.Runtime().Get("failure").String())
+	require.Equal(t, "session-2", runtime.VU.Runtime().Get("cookies").String())
+
+	// The context's commands reconnect the page's session too, rather than
+	// creating a session of their own
+	mu.Lock()
+	valid = ""
+	mu.Unlock()
+	_, err = runtime.RunOnEventLoop(`
+		context.clearCookies()
+			.then(function() { return context.cookies(); })
+			.then(function(c) { cookies = c[0].value; })
+			.catch(function(e) { failure = String(e); });
+	`)
+	require.NoError(t, err)
+	require.Empty(t, runtime.VU.Runtime().Get("failure").String())
+	require.Equal(t, "session-3", runtime.VU.Runtime().Get("cookies").String())
+	require.Equal(t, 3, sessions)
+
+	page := runtime.VU.Runtime().Get("page").Export().(*Page)
+	require.Equal(t, "session-3", page.client.session())
+}
+
+func TestToWebDriverCookie(t *testing.T) {
+	t.Parallel()
+
+	cookie, err := toWebDriverCookie(map[string]interface{}{
+		"name":     "session",
+		"value":    "abc",
+		"domain":   "example.com",
+		"path":     "/",
+		"secure":   true,
+		"httpOnly": false,
+		"expires":  int64(1700000000),
+		"sameSite": "Lax",
+		"unknown":  "dropped",
+	})
+	require.NoError(t, err)
+	require.Equal(t, map[string]interface{}{
+		"name":     "session",
+		"value":    "abc",
+		"domain":   "example.com",
+		"path":     "/",
+		"secure":   true,
+		"httpOnly": false,
+		"expiry":   int64(1700000000),
+		"sameSite": "Lax",
+	}, cookie)
+
+	_, err = toWebDriverCookie(map[string]interface{}{"value": "abc"})
+	require.Error(t, err)
+
+	_, err = toWebDriverCookie(map[string]interface{}{"name": "session"})
+	require.Error(t, err)
+}
+
+func TestBrowserContextDeleteCookies(t *testing.T) {
+	t.Parallel()
+
+	runtime := modulestest.NewRuntime(t)
+
+	browser := &Browser{
+		VU:     runtime.VU,
+		Client: NewWebDriverClient("http://localhost:4444"),
+	}
+
+	context := browser.NewContext()
+
+	// ClearCookies and DeleteCookie should return promises
+	promise, err := context.ClearCookies()
+	require.NoError(t, err)
+	require.NotNil(t, promise)
+
+	promise, err = context.DeleteCookie("session")
+	require.NoError(t, err)
+	require.NotNil(t, promise)
+}
+
+func TestBrowserContextAddInitScript(t *testing.T) {
+	t.Parallel()
+
+	runtime := modulestest.NewRuntime(t)
+
+	var mu sync.Mutex
+	var executed []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload struct {
+			Script string `json:"script"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&payload); err == nil {
+			mu.Lock()
+			executed = append(executed, payload.Script)
+			mu.Unlock()
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"value":null}`))
+	}))
+	defer server.Close()
+
+	client := NewWebDriverClient(server.URL)
+	client.setSession("session-1")
+	browser := &Browser{VU: runtime.VU, Client: client}
+
+	context := browser.NewContext()
+	context.AddInitScript("Math.random = function() { return 0.5; };")
+	context.AddInitScript("window.featureFlags = { newCheckout: true };")
+	require.Equal(t, []string{
+		"Math.random = function() { return 0.5; };",
+		"window.featureFlags = { newCheckout: true };",
+	}, context.currentInitScripts())
+
+	// Init scripts run after the built-in injection script
+	page := &Page{vu: runtime.VU, client: client, context: context}
+	require.NoError(t, page.injectScript(t.Context()))
+	require.Equal(t, []string{
+		injectionScript,
+		"Math.random = function() { return 0.5; };",
+		"window.featureFlags = { newCheckout: true };",
+	}, executed)
+
+	// Pages outside a context only run the injection script
+	executed = nil
+	page = &Page{vu: runtime.VU, client: client}
+	require.NoError(t, page.injectScript(t.Context()))
+	require.Equal(t, []string{injectionScript}, executed)
+}
+
+func TestPageReinjectScriptInitScriptFailure(t *testing.T) {
+	t.Parallel()
+
+	runtime := modulestest.NewRuntime(t)
+
+	var failing string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload struct {
+			Script string `json:"script"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&payload)
+		w.Header().Set("Content-Type", "application/json")
+		if payload.Script == failing {
+			w.WriteHeader(http.StatusInternalServerError)
+			_, _ = w.Write([]byte(`{"value":{"error":"javascript error","message":"boom"}}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"value":null}`))
+	}))
+	defer server.Close()
+
+	client := NewWebDriverClient(server.URL)
+	client.setSession("session-1")
+	context := (&Browser{VU: runtime.VU, Client: client}).NewContext()
+	context.AddInitScript("window.ready = true;")
+	context.AddInitScript("throw new Error('boom');")
+	page := &Page{vu: runtime.VU, client: client, context: context}
+
+	// A failing init script fails the navigation
+	failing = "throw new Error('boom');"
+	err := page.reinjectScript(t.Context(), "after navigation")
+	var initErr *initScriptError
+	require.ErrorAs(t, err, &initErr)
+	require.Equal(t, 1, initErr.index)
+	require.ErrorContains(t, err, "init script 1 failed")
+
+	// A failing built-in injection script is only logged
+	failing = injectionScript
+	require.NoError(t, page.reinjectScript(t.Context(), "after navigation"))
+}
+
+func TestBrowserContextIsolatedSessions(t *testing.T) {
+	t.Parallel()
+
+	var mu sync.Mutex
+	var created int
+	var cookieRequests []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/session":
+			created++
+			_, _ = fmt.Fprintf(w, `{"value":{"sessionId":"session-%d","capabilities":{}}}`, created)
+		case strings.HasSuffix(r.URL.Path, "/cookie"):
+			cookieRequests = append(cookieRequests, r.URL.Path)
+			_, _ = w.Write([]byte(`{"value":[]}`))
+		default:
+			_, _ = w.Write([]byte(`{"value":null}`))
+		}
+	}))
+	defer server.Close()
+
+	runtime := modulestest.NewRuntime(t)
+	browser := &Browser{VU: runtime.VU, Client: NewWebDriverClient(server.URL)}
+	require.NoError(t, runtime.VU.Runtime().Set("browser", browser))
+
+	_, err := runtime.RunOnEventLoop(`
+		var failure = "";
+		var first = browser.newContext(), second = browser.newContext();
+		var firstPage;
+		first.newPage()
+			.then(function(page) { firstPage = page; return second.newPage(); })
+			.then(function() { return first.cookies(); })
+			.then(function() { return second.cookies(); })
+			.then(function() { return firstPage.close(); })
+			.then(function() { return first.cookies(); })
+			.catch(function(e) { failure = String(e); });
+	`)
+	require.NoError(t, err)
+
+	// Each context reads the cookies of its own page's session, rather than
+	// the latest session created
+	require.Equal(t, []string{"/session/session-1/cookie", "/session/session-2/cookie"}, cookieRequests)
+	require.Contains(t, runtime.VU.Runtime().Get("failure").String(), "no active session")
+	require.Empty(t, browser.Client.session())
+}
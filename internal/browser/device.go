@@ -0,0 +1,138 @@
+package browser
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Device is a preset emulating a device in a desktop Safari window
+// safaridriver can only size the window and set the device pixel ratio; the
+// user agent is overridden in the page, and touch events and mobile viewport
+// behavior can't be emulated
+type Device struct {
+	Viewport          Viewport `js:"viewport"`
+	DeviceScaleFactor float64  `js:"deviceScaleFactor"`
+	UserAgent         string   `js:"userAgent"` // The user agent of the device's Safari
+	IsMobile          bool     `js:"isMobile"`
+	HasTouch          bool     `js:"hasTouch"`
+}
+
+// Mobile Safari user agents of the presets
+const (
+	iPhoneUserAgent = "Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X) AppleWebKit/605.1.15 " +
+		"(KHTML, like Gecko) Version/17.0 Mobile/15E148 Safari/604.1"
+	iPadUserAgent = "Mozilla/5.0 (iPad; CPU OS 17_0 like Mac OS X) AppleWebKit/605.1.15 " +
+		"(KHTML, like Gecko) Version/17.0 Mobile/15E148 Safari/604.1"
+	desktopUserAgent = "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/605.1.15 " +
+		"(KHTML, like Gecko) Version/17.0 Safari/605.1.15"
+)
+
+// Devices are the device presets, by name, usable with the device option of
+// newPage and newContext. Viewports are in CSS pixels, in portrait orientation
+var Devices = map[string]Device{
+	"iPhone SE": {
+		Viewport: Viewport{Width: 375, Height: 667}, DeviceScaleFactor: 2,
+		UserAgent: iPhoneUserAgent, IsMobile: true, HasTouch: true,
+	},
+	"iPhone 13": {
+		Viewport: Viewport{Width: 390, Height: 844}, DeviceScaleFactor: 3,
+		UserAgent: iPhoneUserAgent, IsMobile: true, HasTouch: true,
+	},
+	"iPhone 14": {
+		Viewport: Viewport{Width: 390, Height: 844}, DeviceScaleFactor: 3,
+		UserAgent: iPhoneUserAgent, IsMobile: true, HasTouch: true,
+	},
+	"iPhone 14 Pro Max": {
+		Viewport: Viewport{Width: 430, Height: 932}, DeviceScaleFactor: 3,
+		UserAgent: iPhoneUserAgent, IsMobile: true, HasTouch: true,
+	},
+	"iPhone 15": {
+		Viewport: Viewport{Width: 393, Height: 852}, DeviceScaleFactor: 3,
+		UserAgent: iPhoneUserAgent, IsMobile: true, HasTouch: true,
+	},
+	"iPad Mini": {
+		Viewport: Viewport{Width: 768, Height: 1024}, DeviceScaleFactor: 2,
+		UserAgent: iPadUserAgent, IsMobile: true, HasTouch: true,
+	},
+	"iPad Air": {
+		Viewport: Viewport{Width: 820, Height: 1180}, DeviceScaleFactor: 2,
+		UserAgent: iPadUserAgent, IsMobile: true, HasTouch: true,
+	},
+	"iPad Pro 11": {
+		Viewport: Viewport{Width: 834, Height: 1194}, DeviceScaleFactor: 2,
+		UserAgent: iPadUserAgent, IsMobile: true, HasTouch: true,
+	},
+	"Desktop Safari": {
+		Viewport: Viewport{Width: 1280, Height: 720}, DeviceScaleFactor: 1,
+		UserAgent: desktopUserAgent,
+	},
+}
+
+// pageOptions are the options of a new page
+type pageOptions struct {
+	viewport          Viewport
+	deviceScaleFactor float64
+	userAgent         string // Empty to keep Safari's
+}
+
+// pageOptionsFrom parses the options of newPage and newContext. A device preset
+// is applied first, so the viewport, deviceScaleFactor and userAgent options
+// override it
+func pageOptionsFrom(options map[string]interface{}) (pageOptions, error) {
+	// Pin DPR to 1 by default for consistent screenshots
+	po := pageOptions{viewport: Viewport{Width: 1280, Height: 720}, deviceScaleFactor: 1}
+	if options == nil {
+		return po, nil
+	}
+
+	if name, ok := options["device"].(string); ok && name != "" {
+		device, ok := Devices[name]
+		if !ok {
+			return po, fmt.Errorf("unknown device '%s', known devices are %v", name, deviceNames())
+		}
+		po.viewport = device.Viewport
+		po.deviceScaleFactor = device.DeviceScaleFactor
+		po.userAgent = device.UserAgent
+	}
+
+	if viewport, ok := options["viewport"].(map[string]interface{}); ok {
+		if width, ok := toFloat64(viewport["width"]); ok && width > 0 {
+			po.viewport.Width = int(width)
+		}
+		if height, ok := toFloat64(viewport["height"]); ok && height > 0 {
+			po.viewport.Height = int(height)
+		}
+	}
+	if dpr, ok := toFloat64(options["deviceScaleFactor"]); ok && dpr > 0 {
+		po.deviceScaleFactor = dpr
+	}
+	if userAgent, ok := options["userAgent"].(string); ok && userAgent != "" {
+		po.userAgent = userAgent
+	}
+
+	return po, nil
+}
+
+// deviceNames returns the names of the device presets, sorted
+func deviceNames() []string {
+	names := make([]string, 0, len(Devices))
+	for name := range Devices {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// userAgentScript overrides the user agent reported to the page's scripts
+// safaridriver has no capability for the user agent, so requests keep sending
+// Safari's, and scripts that ran before the override was injected saw it too
+const userAgentScript = `
+	var userAgent = arguments[0];
+	var appVersion = userAgent.replace(/^Mozilla\//, '');
+	Object.defineProperty(Navigator.prototype, 'userAgent', {
+		get: function() { return userAgent; }, configurable: true
+	});
+	Object.defineProperty(Navigator.prototype, 'appVersion', {
+		get: function() { return appVersion; }, configurable: true
+	});
+`
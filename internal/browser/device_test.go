@@ -0,0 +1,84 @@
+package browser
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestPageOptionsFrom(t *testing.T) {
+	got, err := pageOptionsFrom(nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got.viewport != (Viewport{Width: 1280, Height: 720}) || got.deviceScaleFactor != 1 {
+		t.Errorf("Expected the default viewport at DPR 1, got %+v", got)
+	}
+
+	got, err = pageOptionsFrom(map[string]interface{}{"device": "iPhone 14"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got.viewport != (Viewport{Width: 390, Height: 844}) || got.deviceScaleFactor != 3 || !strings.Contains(got.userAgent, "iPhone") {
+		t.Errorf("Expected the iPhone 14 preset, got %+v", got)
+	}
+
+	// Explicit options override the preset; integral JS numbers arrive as int64
+	got, err = pageOptionsFrom(map[string]interface{}{
+		"device":            "iPad Air",
+		"viewport":          map[string]interface{}{"width": int64(1180), "height": 820.0},
+		"deviceScaleFactor": int64(1),
+		"userAgent":         "k6-load-test",
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got.viewport != (Viewport{Width: 1180, Height: 820}) || got.deviceScaleFactor != 1 || got.userAgent != "k6-load-test" {
+		t.Errorf("Expected the options to override the preset, got %+v", got)
+	}
+
+	_, err = pageOptionsFrom(map[string]interface{}{"device": "Nokia 3310"})
+	if err == nil || !strings.Contains(err.Error(), "unknown device 'Nokia 3310'") || !strings.Contains(err.Error(), "iPhone 14") {
+		t.Errorf("Expected an unknown device error listing the presets, got %v", err)
+	}
+}
+
+func TestPageInjectScriptUserAgent(t *testing.T) {
+	var overrides []interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload struct {
+			Script string        `json:"script"`
+			Args   []interface{} `json:"args"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&payload)
+		if strings.Contains(payload.Script, "Navigator.prototype") {
+			overrides = append(overrides, payload.Args...)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"value":null}`))
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	client := NewWebDriverClient(server.URL).forSession("session-1")
+
+	page := &Page{client: client}
+	if err := page.injectScript(ctx); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(overrides) != 0 {
+		t.Errorf("Expected Safari's user agent to be kept, got overrides %v", overrides)
+	}
+
+	page = &Page{client: client, userAgent: "k6-load-test"}
+	if err := page.injectScript(ctx); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(overrides) != 1 || overrides[0] != "k6-load-test" {
+		t.Errorf("Expected the user agent to be overridden, got %v", overrides)
+	}
+}
@@ -0,0 +1,230 @@
+package browser
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/grafana/sobek"
+	"github.com/sirupsen/logrus"
+)
+
+// errNoCallback is returned by callHandler when no page operation reserved an
+// event loop callback to call a handler with
+var errNoCallback = errors.New("no event loop callback reserved")
+
+// Dialog is a native JavaScript dialog (alert, confirm or prompt) opened by a page
+// Handlers set with Page.OnDialog decide whether it is accepted or dismissed
+type Dialog struct {
+	message    string
+	accepted   bool
+	promptText *string // Typed into a prompt() dialog before it is accepted
+}
+
+// Message returns the message shown by the dialog
+func (d *Dialog) Message() string {
+	return d.message
+}
+
+// Accept accepts the dialog, typing promptText into it if it's a prompt() dialog
+func (d *Dialog) Accept(promptText ...string) {
+	d.accepted = true
+	if len(promptText) > 0 {
+		d.promptText = &promptText[0]
+	}
+}
+
+// Dismiss dismisses the dialog, which is the default if the handler does neither
+func (d *Dialog) Dismiss() {
+	d.accepted = false
+	d.promptText = nil
+}
+
+// handlerCallback keeps the event loop alive while a page operation runs, so that
+// the page's handlers can be called on it, e.g. the dialog handler if the
+// operation is blocked by a dialog
+type handlerCallback struct {
+	enqueue  func(func() error)
+	busy     bool // A handler is being called with it
+	released bool // The operation it was reserved for finished
+}
+
+// OnDialog sets the handler called with every dialog opened by the page, or
+// clears it if handler is null. The handler is called with a Dialog and must
+// accept or dismiss it synchronously. Without a handler, dialogs are dismissed
+// so they don't block the page
+func (p *Page) OnDialog(handler sobek.Value) error {
+	callable, err := handlerFrom("dialog", handler)
+	if err != nil {
+		return err
+	}
+
+	p.handlerMu.Lock()
+	defer p.handlerMu.Unlock()
+
+	p.dialogHandler = callable
+	return nil
+}
+
+// handlerFrom returns the function handler, or nil if it's null or undefined.
+// name is the kind of handler, for the error message
+func handlerFrom(name string, handler sobek.Value) (sobek.Callable, error) {
+	if handler == nil || sobek.IsUndefined(handler) || sobek.IsNull(handler) {
+		return nil, nil
+	}
+	callable, ok := sobek.AssertFunction(handler)
+	if !ok {
+		return nil, fmt.Errorf("%s handler must be a function", name)
+	}
+	return callable, nil
+}
+
+// promise runs fn asynchronously like Promise, in the page's window, keeping a
+// callback reserved so that dialogs blocking fn can be passed to the page's
+// dialog handler, and the console messages, errors and network events recorded
+// meanwhile to its other handlers. fn fails if the page error handler throws
+func (p *Page) promise(fn PromisifiedFunc) *sobek.Promise {
+	release := p.reserveHandlerCallback()
+	return Promise(p.vu, func() (any, error) {
+		defer release()
+
+		ctx := context.Background()
+		var result any
+		err := p.inWindow(ctx, func() error {
+			var err error
+			result, err = fn()
+			p.dispatchConsoleMessages(ctx)
+			p.dispatchNetworkEvents(ctx)
+			if handlerErr := p.dispatchPageErrors(ctx); err == nil {
+				err = handlerErr
+			}
+			return err
+		})
+		return result, err
+	})
+}
+
+// hasHandler reports whether any of the page's handlers is set. It must be
+// called with handlerMu held
+func (p *Page) hasHandler() bool {
+	return p.dialogHandler != nil || p.consoleHandler != nil || p.pageErrorHandler != nil ||
+		p.requestHandler != nil || p.responseHandler != nil
+}
+
+// reserveHandlerCallback registers an event loop callback for the page's
+// handlers if any is set, returning the func that releases it. It must be
+// called on the event loop
+func (p *Page) reserveHandlerCallback() func() {
+	p.handlerMu.Lock()
+	defer p.handlerMu.Unlock()
+
+	if !p.hasHandler() || p.vu == nil {
+		return func() {}
+	}
+
+	cb := &handlerCallback{enqueue: p.vu.RegisterCallback()}
+	p.handlerCallbacks = append(p.handlerCallbacks, cb)
+
+	return func() {
+		p.handlerMu.Lock()
+		defer p.handlerMu.Unlock()
+
+		cb.released = true
+		for i, c := range p.handlerCallbacks {
+			if c == cb {
+				p.handlerCallbacks = append(p.handlerCallbacks[:i], p.handlerCallbacks[i+1:]...)
+				break
+			}
+		}
+		// A busy callback is being used by the handler, which won't re-register it
+		if !cb.busy {
+			cb.enqueue(func() error { return nil })
+		}
+	}
+}
+
+// handleDialog closes the dialog blocking the page's session, as decided by the
+// dialog handler, or dismisses it if there is no handler
+func (p *Page) handleDialog(ctx context.Context) error {
+	message, err := p.client.GetAlertText(ctx)
+	if err != nil {
+		return err
+	}
+
+	dialog := &Dialog{message: message}
+	p.callDialogHandler(dialog)
+
+	if !dialog.accepted {
+		return p.client.DismissAlert(ctx)
+	}
+	if dialog.promptText != nil {
+		if err := p.client.SendAlertText(ctx, *dialog.promptText); err != nil {
+			return err
+		}
+	}
+	return p.client.AcceptAlert(ctx)
+}
+
+// callDialogHandler calls the dialog handler with dialog on the event loop and
+// waits for it to return. The dialog is left dismissed if the handler can't be
+// called, because it isn't set or no callback was reserved, or if it throws
+func (p *Page) callDialogHandler(dialog *Dialog) {
+	p.handlerMu.Lock()
+	handler := p.dialogHandler
+	p.handlerMu.Unlock()
+	if handler == nil {
+		return
+	}
+
+	err := p.callHandler(func(rt *sobek.Runtime) error {
+		_, err := handler(sobek.Undefined(), rt.ToValue(dialog))
+		return err
+	})
+	if errors.Is(err, errNoCallback) {
+		return
+	}
+	if err != nil {
+		logf(p.vu, logrus.WarnLevel, "dialog handler failed, dismissing the dialog: %v", err)
+		dialog.Dismiss()
+	}
+}
+
+// callHandler runs call on the event loop with a callback reserved by the page
+// operation in progress, and waits for it to return. It returns errNoCallback
+// without running call if no callback is available
+func (p *Page) callHandler(call func(rt *sobek.Runtime) error) error {
+	p.handlerMu.Lock()
+	var cb *handlerCallback
+	for _, c := range p.handlerCallbacks {
+		if !c.busy {
+			cb = c
+			break
+		}
+	}
+	if cb == nil {
+		p.handlerMu.Unlock()
+		return errNoCallback
+	}
+	cb.busy = true
+	enqueue := cb.enqueue
+	p.handlerMu.Unlock()
+
+	done := make(chan error, 1)
+	enqueue(func() error {
+		err := call(p.vu.Runtime())
+
+		// Enqueuing used up the callback; register it again unless its
+		// operation finished in the meantime
+		p.handlerMu.Lock()
+		cb.busy = false
+		if !cb.released {
+			cb.enqueue = p.vu.RegisterCallback()
+		}
+		p.handlerMu.Unlock()
+
+		done <- err
+		return nil
+	})
+
+	return <-done
+}
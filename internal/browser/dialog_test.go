@@ -0,0 +1,153 @@
+package browser
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"go.k6.io/k6/js/modulestest"
+)
+
+// dialogServer fakes a WebDriver session with a dialog open until it's closed
+type dialogServer struct {
+	mu       sync.Mutex
+	open     bool
+	requests []string
+}
+
+func (ds *dialogServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	switch {
+	case strings.Contains(r.URL.Path, "/alert/"):
+		ds.requests = append(ds.requests, r.Method+" "+r.URL.Path[strings.Index(r.URL.Path, "/alert/"):]+" "+string(body))
+		if r.Method == "GET" {
+			_, _ = w.Write([]byte(`{"value":"What's your name?"}`))
+			return
+		}
+		if !strings.HasSuffix(r.URL.Path, "/text") {
+			ds.open = false
+		}
+		_, _ = w.Write([]byte(`{"value":null}`))
+
+	case ds.open:
+		w.WriteHeader(http.StatusInternalServerError)
+		_, _ = w.Write([]byte(`{"value":{"error":"unexpected alert open","message":"A dialog is open"}}`))
+
+	default:
+		_, _ = w.Write([]byte(`{"value":"after dialog"}`))
+	}
+}
+
+func (ds *dialogServer) recorded() string {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	return strings.Join(ds.requests, ",")
+}
+
+func TestPageDialogDismissedByDefault(t *testing.T) {
+	runtime := modulestest.NewRuntime(t)
+	ds := &dialogServer{open: true}
+	server := httptest.NewServer(ds)
+	defer server.Close()
+
+	page := &Page{vu: runtime.VU, client: NewWebDriverClient(server.URL).forSession("session-1")}
+	page.client.dialogHandler = page.handleDialog
+	if err := runtime.VU.Runtime().Set("page", page); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := runtime.RunOnEventLoop(`
+		var result;
+		page.evaluate("return 1;").then(function(value) { result = value; });
+	`)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if got := runtime.VU.Runtime().Get("result").String(); got != "after dialog" {
+		t.Errorf("Expected the script to run after the dialog closed, got %s", got)
+	}
+	expected := "GET /alert/text ,POST /alert/dismiss {}"
+	if got := ds.recorded(); got != expected {
+		t.Errorf("Expected requests %s, got %s", expected, got)
+	}
+}
+
+func TestPageOnDialog(t *testing.T) {
+	runtime := modulestest.NewRuntime(t)
+	ds := &dialogServer{open: true}
+	server := httptest.NewServer(ds)
+	defer server.Close()
+
+	page := &Page{vu: runtime.VU, client: NewWebDriverClient(server.URL).forSession("session-1")}
+	page.client.dialogHandler = page.handleDialog
+	if err := runtime.VU.Runtime().Set("page", page); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := runtime.RunOnEventLoop(`
+		var message, result;
+		page.onDialog(function(dialog) {
+			message = dialog.message();
+			dialog.accept("Ada");
+		});
+		page.evaluate("return 1;").then(function(value) { result = value; });
+	`)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	rt := runtime.VU.Runtime()
+	if got := rt.Get("message").String(); got != "What's your name?" {
+		t.Errorf("Expected the handler to get the dialog message, got %s", got)
+	}
+	if got := rt.Get("result").String(); got != "after dialog" {
+		t.Errorf("Expected the script to run after the dialog closed, got %s", got)
+	}
+	expected := `GET /alert/text ,POST /alert/text {"text":"Ada"},POST /alert/accept {}`
+	if got := ds.recorded(); got != expected {
+		t.Errorf("Expected requests %s, got %s", expected, got)
+	}
+}
+
+func TestPageOnDialogThrows(t *testing.T) {
+	runtime := modulestest.NewRuntime(t)
+	ds := &dialogServer{open: true}
+	server := httptest.NewServer(ds)
+	defer server.Close()
+
+	page := &Page{vu: runtime.VU, client: NewWebDriverClient(server.URL).forSession("session-1")}
+	page.client.dialogHandler = page.handleDialog
+	if err := runtime.VU.Runtime().Set("page", page); err != nil {
+		t.Fatal(err)
+	}
+
+	// A failing handler leaves the dialog dismissed rather than blocking the page
+	_, err := runtime.RunOnEventLoop(`
+		page.onDialog(function(dialog) {
+			dialog.accept();
+			throw new Error("boom");
+		});
+		page.evaluate("return 1;");
+	`)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := "GET /alert/text ,POST /alert/dismiss {}"
+	if got := ds.recorded(); got != expected {
+		t.Errorf("Expected requests %s, got %s", expected, got)
+	}
+
+	if err := page.OnDialog(runtime.VU.Runtime().ToValue("not a function")); err == nil {
+		t.Error("Expected error for a handler that isn't a function")
+	}
+}
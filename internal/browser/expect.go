@@ -0,0 +1,221 @@
+package browser
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/grafana/sobek"
+)
+
+// expectPollInterval is how often a failing assertion is checked again
+const expectPollInterval = 100 * time.Millisecond
+
+// LocatorAssertions are assertions on the elements matched by a locator
+// Unlike a one-off check, each assertion is retried until it passes or its
+// timeout elapses, so it can be made right after the action it verifies
+type LocatorAssertions struct {
+	locator *Locator
+	timeout time.Duration
+}
+
+// Expect returns the assertions on the locator
+// Options: timeout (ms, default DefaultTimeout), which each assertion can override
+func Expect(locator *Locator, options map[string]interface{}) (*LocatorAssertions, error) {
+	if locator == nil {
+		return nil, fmt.Errorf("expect requires a locator")
+	}
+
+	return &LocatorAssertions{locator: locator, timeout: timeoutFromOptions(options)}, nil
+}
+
+// elementAssertionStateScript reads the state of the element assertions check
+const elementAssertionStateScript = isVisibleScript + `
+	var el = arguments[0];
+	return {
+		visible: isVisible(el),
+		enabled: !(el.matches && el.matches(':disabled')) && el.getAttribute('aria-disabled') !== 'true',
+		text: el.textContent,
+		value: el.value === undefined || el.value === null ? null : String(el.value)
+	};
+`
+
+// elementAssertionState is the state of the first element matched by a locator
+type elementAssertionState struct {
+	visible bool
+	enabled bool
+	text    string
+	value   *string // Only form controls have a value
+}
+
+// elementState reads the state of the first element matched by the locator
+func (a *LocatorAssertions) elementState(ctx context.Context) (*elementAssertionState, error) {
+	elementID, err := a.locator.resolveElementID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := a.locator.page.client.ExecuteScript(ctx, elementAssertionStateScript, elementReferences([]string{elementID}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read element state: %w", err)
+	}
+
+	values, _ := result.(map[string]interface{})
+	state := &elementAssertionState{}
+	state.visible, _ = values["visible"].(bool)
+	state.enabled, _ = values["enabled"].(bool)
+	state.text, _ = values["text"].(string)
+	if value, ok := values["value"].(string); ok {
+		state.value = &value
+	}
+	return state, nil
+}
+
+// assert runs check until it passes or the timeout elapses, then rejects with
+// the assertion and what was last received. check returns whether the assertion
+// passes and a description of the value it checked
+func (a *LocatorAssertions) assert(assertion string, options []map[string]interface{},
+	check func(ctx context.Context) (bool, string, error),
+) *sobek.Promise {
+	timeout := a.timeout
+	if len(options) > 0 {
+		if t := timeoutFromOptions(options[0]); t > 0 {
+			timeout = t
+		}
+	}
+	if timeout <= 0 {
+		timeout = DefaultTimeout()
+	}
+
+	l := a.locator
+	return l.promise(func() (interface{}, error) {
+		if l.page.client == nil {
+			return nil, fmt.Errorf("browser session not initialized")
+		}
+
+		ctx := context.Background()
+		deadline := time.Now().Add(timeout)
+
+		var received string
+		for {
+			passed, value, err := check(ctx)
+			if err == nil && passed {
+				return nil, nil
+			}
+			// Elements that are missing for now are retried like failed checks
+			if err != nil {
+				received = err.Error()
+			} else {
+				received = value
+			}
+
+			if !time.Now().Add(expectPollInterval).Before(deadline) {
+				break
+			}
+			time.Sleep(expectPollInterval)
+		}
+
+		return nil, fmt.Errorf("expect(locator('%s')).%s failed after %s: received %s",
+			l.selector, assertion, timeout, received)
+	})
+}
+
+// textMatcher matches text exactly, ignoring differences in whitespace, or
+// against a /regex/
+func textMatcher(expected string) (func(string) bool, error) {
+	if IsRegex(expected) {
+		re, err := ParseRegex(expected)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regex '%s': %w", expected, err)
+		}
+		return re.MatchString, nil
+	}
+
+	normalized := normalizeWhitespace(expected)
+	return func(text string) bool {
+		return normalizeWhitespace(text) == normalized
+	}, nil
+}
+
+// ToBeVisible asserts that the first matching element is visible
+func (a *LocatorAssertions) ToBeVisible(options ...map[string]interface{}) (*sobek.Promise, error) {
+	return a.assert("toBeVisible()", options, func(ctx context.Context) (bool, string, error) {
+		state, err := a.elementState(ctx)
+		if err != nil {
+			return false, "", err
+		}
+		return state.visible, "hidden", nil
+	}), nil
+}
+
+// ToBeEnabled asserts that the first matching element is not disabled
+func (a *LocatorAssertions) ToBeEnabled(options ...map[string]interface{}) (*sobek.Promise, error) {
+	return a.assert("toBeEnabled()", options, func(ctx context.Context) (bool, string, error) {
+		state, err := a.elementState(ctx)
+		if err != nil {
+			return false, "", err
+		}
+		return state.enabled, "disabled", nil
+	}), nil
+}
+
+// ToHaveText asserts the text content of the first matching element, exactly
+// but ignoring differences in whitespace, or against a /regex/
+func (a *LocatorAssertions) ToHaveText(expected string, options ...map[string]interface{}) (*sobek.Promise, error) {
+	matches, err := textMatcher(expected)
+	if err != nil {
+		return nil, err
+	}
+
+	return a.assert(fmt.Sprintf("toHaveText(%s)", jsStringLiteral(expected)), options,
+		func(ctx context.Context) (bool, string, error) {
+			state, err := a.elementState(ctx)
+			if err != nil {
+				return false, "", err
+			}
+			return matches(state.text), jsStringLiteral(state.text), nil
+		}), nil
+}
+
+// ToHaveValue asserts the value of the first matching input, textarea or
+// select, exactly or against a /regex/
+func (a *LocatorAssertions) ToHaveValue(expected string, options ...map[string]interface{}) (*sobek.Promise, error) {
+	var matches func(string) bool
+	if IsRegex(expected) {
+		re, err := ParseRegex(expected)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regex '%s': %w", expected, err)
+		}
+		matches = re.MatchString
+	} else {
+		matches = func(value string) bool { return value == expected }
+	}
+
+	return a.assert(fmt.Sprintf("toHaveValue(%s)", jsStringLiteral(expected)), options,
+		func(ctx context.Context) (bool, string, error) {
+			state, err := a.elementState(ctx)
+			if err != nil {
+				return false, "", err
+			}
+			if state.value == nil {
+				return false, "an element without a value", nil
+			}
+			return matches(*state.value), jsStringLiteral(*state.value), nil
+		}), nil
+}
+
+// ToHaveCount asserts the number of elements matching the locator
+func (a *LocatorAssertions) ToHaveCount(expected int, options ...map[string]interface{}) (*sobek.Promise, error) {
+	if expected < 0 {
+		return nil, fmt.Errorf("expected count must not be negative, got %d", expected)
+	}
+
+	return a.assert(fmt.Sprintf("toHaveCount(%d)", expected), options,
+		func(ctx context.Context) (bool, string, error) {
+			count, err := a.locator.count(ctx)
+			if err != nil {
+				return false, "", err
+			}
+			return count == expected, fmt.Sprintf("%d", count), nil
+		}), nil
+}
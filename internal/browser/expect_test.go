@@ -0,0 +1,125 @@
+package browser
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"go.k6.io/k6/js/modulestest"
+)
+
+// expectServer fakes a page whose status element finishes loading after a few checks
+type expectServer struct {
+	mu     sync.Mutex
+	checks int // State checks left until the status is done
+}
+
+func (es *expectServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var payload map[string]interface{}
+	_ = json.NewDecoder(r.Body).Decode(&payload)
+	script, _ := payload["script"].(string)
+
+	es.mu.Lock()
+	defer es.mu.Unlock()
+
+	var value interface{}
+	switch {
+	case strings.HasSuffix(r.URL.Path, "/element"):
+		value = map[string]string{"element-6066-11e4-a52e-4f735466cecf": "status"}
+	case strings.Contains(script, "querySelectorAll"):
+		value = 2
+	case strings.Contains(script, "getComputedStyle"):
+		state := map[string]interface{}{"visible": false, "enabled": false, "text": "\n  Loading… ", "value": "pending"}
+		if es.checks--; es.checks <= 0 {
+			state = map[string]interface{}{"visible": true, "enabled": true, "text": "\n  Order   placed ", "value": "done"}
+		}
+		value = state
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	data, _ := json.Marshal(map[string]interface{}{"value": value})
+	_, _ = w.Write(data)
+}
+
+func TestExpectRetriesUntilPassing(t *testing.T) {
+	es := &expectServer{checks: 3}
+	server := httptest.NewServer(es)
+	defer server.Close()
+
+	runtime := modulestest.NewRuntime(t)
+	page := &Page{vu: runtime.VU, client: NewWebDriverClient(server.URL).forSession("session-1")}
+	if err := runtime.VU.Runtime().Set("page", page); err != nil {
+		t.Fatal(err)
+	}
+	if err := runtime.VU.Runtime().Set("expect", Expect); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := runtime.RunOnEventLoop(`
+		var failure = "";
+		var status = page.locator("#status");
+		expect(status).toHaveText("Order placed")
+			.then(function() { return expect(status).toBeVisible(); })
+			.then(function() { return expect(status).toBeEnabled(); })
+			.then(function() { return expect(status).toHaveValue("/^do/"); })
+			.then(function() { return expect(page.locator("li")).toHaveCount(2); })
+			.catch(function(e) { failure = String(e); });
+	`)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if failure := runtime.VU.Runtime().Get("failure").String(); failure != "" {
+		t.Fatalf("Unexpected failure: %s", failure)
+	}
+	if es.checks > 0 {
+		t.Errorf("Expected the text to be checked until it matched, %d checks left", es.checks)
+	}
+}
+
+func TestExpectFailsAfterTimeout(t *testing.T) {
+	es := &expectServer{checks: 1000}
+	server := httptest.NewServer(es)
+	defer server.Close()
+
+	runtime := modulestest.NewRuntime(t)
+	page := &Page{vu: runtime.VU, client: NewWebDriverClient(server.URL).forSession("session-1")}
+	if err := runtime.VU.Runtime().Set("page", page); err != nil {
+		t.Fatal(err)
+	}
+	if err := runtime.VU.Runtime().Set("expect", Expect); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := runtime.RunOnEventLoop(`
+		var failures = [];
+		var record = function(e) { failures.push(String(e)); };
+		expect(page.locator("#status"), { timeout: 250 }).toHaveText("Order placed").catch(record);
+		expect(page.locator("li")).toHaveCount(3, { timeout: 250 }).catch(record);
+	`)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var failures []string
+	if err := runtime.VU.Runtime().ExportTo(runtime.VU.Runtime().Get("failures"), &failures); err != nil {
+		t.Fatal(err)
+	}
+	// The assertions run concurrently
+	sort.Strings(failures)
+	expected := []string{
+		`expect(locator('#status')).toHaveText("Order placed") failed after 250ms: received "\n  Loading… "`,
+		`expect(locator('li')).toHaveCount(3) failed after 250ms: received 2`,
+	}
+	if len(failures) != len(expected) {
+		t.Fatalf("Expected %d failures, got %q", len(expected), failures)
+	}
+	for i := range expected {
+		if !strings.Contains(failures[i], expected[i]) {
+			t.Errorf("Expected failure %q, got %q", expected[i], failures[i])
+		}
+	}
+}
@@ -0,0 +1,242 @@
+package browser
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/grafana/sobek"
+	"github.com/sirupsen/logrus"
+	"go.k6.io/k6/js/modules"
+)
+
+// Frame is an iframe of a page
+// WebDriver commands run in one browsing context at a time, so every operation
+// on a frame switches the session into it and back to the top-level document
+type Frame struct {
+	Name string `js:"name"` // Set for frames returned by Page.Frames
+	URL  string `js:"url"`  // Set for frames returned by Page.Frames
+
+	page      *Page
+	parent    *Frame        // nil for frames of the top-level document
+	selector  FrameSelector // Finds the frame element in its parent's document
+	elementID string        // If set, the frame element found by Page.Frames
+	vu        modules.VU
+}
+
+// frameListScript returns the frame elements of the document with their name and URL
+const frameListScript = `
+	return Array.from(document.querySelectorAll('iframe, frame')).map(function(frame) {
+		return { element: frame, name: frame.name || '', url: frame.src || '' };
+	});
+`
+
+// Frames returns the frames of the top-level document
+func (p *Page) Frames() (*sobek.Promise, error) {
+	if p.client == nil {
+		return nil, fmt.Errorf("browser session not initialized")
+	}
+
+	return p.promise(func() (any, error) {
+		ctx := context.Background()
+		result, err := p.client.ExecuteScript(ctx, frameListScript, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list frames: %w", err)
+		}
+
+		entries, _ := result.([]interface{})
+		frames := make([]*Frame, 0, len(entries))
+		for _, entry := range entries {
+			info, ok := entry.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			elementIDs := elementIDsFromResult([]interface{}{info["element"]})
+			if len(elementIDs) == 0 {
+				continue
+			}
+
+			frame := &Frame{page: p, elementID: elementIDs[0], vu: p.vu}
+			frame.Name, _ = info["name"].(string)
+			frame.URL, _ = info["url"].(string)
+
+			// The selector only describes the frame in errors, it is found by element
+			frame.selector = FrameSelector{Name: frame.Name}
+			if frame.Name == "" {
+				frame.selector = FrameSelector{URL: frame.URL}
+			}
+			frames = append(frames, frame)
+		}
+
+		return frames, nil
+	}), nil
+}
+
+// Frame returns the frame matching options.name or options.url
+// The URL is matched exactly, as a glob, or as a /regex/
+func (p *Page) Frame(options map[string]interface{}) (*Frame, error) {
+	var selector FrameSelector
+	if name, ok := options["name"].(string); ok && name != "" {
+		selector.Name = name
+	} else if url, ok := options["url"].(string); ok && url != "" {
+		selector.URL = url
+	} else {
+		return nil, fmt.Errorf("frame requires a name or url option")
+	}
+
+	return &Frame{page: p, selector: selector, vu: p.vu}, nil
+}
+
+// FrameLocator returns the frame matching the frame selector, which is a CSS
+// selector for the frame element, "name=..." or "url=..."
+func (p *Page) FrameLocator(selector string) *Frame {
+	return &Frame{page: p, selector: ParseFrameSelector(selector), vu: p.vu}
+}
+
+// FrameLocator returns the frame nested in this frame matching the frame selector
+func (f *Frame) FrameLocator(selector string) *Frame {
+	return &Frame{page: f.page, parent: f, selector: ParseFrameSelector(selector), vu: f.vu}
+}
+
+// Locator creates a locator for elements inside the frame
+func (f *Frame) Locator(selector string) *Locator {
+	return &Locator{
+		page:     f.page,
+		selector: selector,
+		frame:    f,
+		vu:       f.vu,
+	}
+}
+
+// Click clicks an element inside the frame
+func (f *Frame) Click(selector string) (*sobek.Promise, error) {
+	return f.promise(func(ctx context.Context) (any, error) {
+		err := f.Locator(selector).withActionableElement(ctx, func(elementID string) error {
+			return f.page.client.ClickElement(ctx, elementID)
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to click element: %w", err)
+		}
+		return nil, nil
+	})
+}
+
+// Fill fills an input field inside the frame with text
+func (f *Frame) Fill(selector, text string) (*sobek.Promise, error) {
+	return f.promise(func(ctx context.Context) (any, error) {
+		err := f.Locator(selector).withActionableElement(ctx, func(elementID string) error {
+			return f.page.client.SendKeys(ctx, elementID, text)
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to send keys: %w", err)
+		}
+		return nil, nil
+	})
+}
+
+// Evaluate executes JavaScript in the frame's document and returns the result
+func (f *Frame) Evaluate(script string) (*sobek.Promise, error) {
+	return f.promise(func(ctx context.Context) (any, error) {
+		result, err := f.page.client.ExecuteScript(ctx, script, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to execute script: %w", err)
+		}
+		return result, nil
+	})
+}
+
+// Content returns the full serialized HTML of the frame's document
+func (f *Frame) Content() (*sobek.Promise, error) {
+	return f.promise(func(ctx context.Context) (any, error) {
+		script := `
+			var doctype = document.doctype ? new XMLSerializer().serializeToString(document.doctype) : '';
+			return doctype + document.documentElement.outerHTML;
+		`
+		result, err := f.page.client.ExecuteScript(ctx, script, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get content: %w", err)
+		}
+
+		content, _ := result.(string)
+		return content, nil
+	})
+}
+
+// promise runs fn asynchronously with the session switched into the frame
+func (f *Frame) promise(fn func(ctx context.Context) (any, error)) (*sobek.Promise, error) {
+	if f.page.client == nil {
+		return nil, fmt.Errorf("browser session not initialized")
+	}
+
+	return f.page.promise(func() (any, error) {
+		ctx := context.Background()
+
+		var result any
+		err := f.within(ctx, func() error {
+			var err error
+			result, err = fn(ctx)
+			return err
+		})
+		return result, err
+	}), nil
+}
+
+// within runs fn with the session switched into the frame, then switches back
+// to the top-level document. It must be called through the page's inWindow, so
+// that no other operation of the session runs meanwhile. Failing to switch back
+// fails the operation, as the page's next operations would run in the frame
+func (f *Frame) within(ctx context.Context, fn func() error) (err error) {
+	if f.page.client == nil {
+		return fmt.Errorf("browser session not initialized")
+	}
+
+	defer func() {
+		if switchErr := f.page.client.SwitchToFrame(ctx, ""); switchErr != nil {
+			if err == nil {
+				err = fmt.Errorf("failed to switch back to the top-level document: %w", switchErr)
+				return
+			}
+			logf(f.page.vu, logrus.WarnLevel, "failed to switch back to the top-level document: %v", switchErr)
+		}
+	}()
+
+	if err := f.enter(ctx); err != nil {
+		return err
+	}
+	return fn()
+}
+
+// enter switches the session from the top-level document into the frame,
+// through its parent frames, and makes sure the injection script ran in it
+func (f *Frame) enter(ctx context.Context) error {
+	if f.parent != nil {
+		if err := f.parent.enter(ctx); err != nil {
+			return err
+		}
+	} else if err := f.page.client.SwitchToFrame(ctx, ""); err != nil {
+		return err
+	}
+
+	elementID := f.elementID
+	if elementID == "" {
+		result, err := f.page.client.ExecuteScript(ctx, generateFrameSelectorScript(f.selector), nil)
+		if err != nil {
+			return fmt.Errorf("failed to find frame %s: %w", f.selector, err)
+		}
+
+		elementIDs := elementIDsFromResult([]interface{}{result})
+		if len(elementIDs) == 0 {
+			return fmt.Errorf("frame %s not found: %w", f.selector, ErrElementNotFound)
+		}
+		elementID = elementIDs[0]
+	}
+
+	if err := f.page.client.SwitchToFrame(ctx, elementID); err != nil {
+		return fmt.Errorf("failed to switch to frame %s: %w", f.selector, err)
+	}
+
+	if _, err := f.page.client.ExecuteScript(ctx, injectionScript, nil); err != nil {
+		return fmt.Errorf("failed to inject script into frame %s: %w", f.selector, err)
+	}
+
+	return nil
+}
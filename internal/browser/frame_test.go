@@ -0,0 +1,260 @@
+package browser
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// frameServer fakes a WebDriver session and records frame switches and scripts
+type frameServer struct {
+	mu    sync.Mutex
+	calls []string
+}
+
+func (fs *frameServer) handler(frameFound bool) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload map[string]interface{}
+		_ = json.NewDecoder(r.Body).Decode(&payload)
+
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/frame"):
+			target := "top"
+			if ref, ok := payload["id"].(map[string]interface{}); ok {
+				target, _ = ref["element-6066-11e4-a52e-4f735466cecf"].(string)
+			}
+			fs.record("switch:" + target)
+			_, _ = w.Write([]byte(`{"value":null}`))
+
+		case strings.HasSuffix(r.URL.Path, "/execute/sync"):
+			script, _ := payload["script"].(string)
+			findFrame := strings.Contains(script, "iframe, frame") || strings.Contains(script, "document.querySelector(")
+			switch {
+			case script == injectionScript:
+				fs.record("inject")
+				_, _ = w.Write([]byte(`{"value":null}`))
+			case findFrame && frameFound:
+				fs.record("find-frame")
+				_, _ = w.Write([]byte(`{"value":{"element-6066-11e4-a52e-4f735466cecf":"frame-1"}}`))
+			case findFrame:
+				fs.record("find-frame")
+				_, _ = w.Write([]byte(`{"value":null}`))
+			default:
+				fs.record("script")
+				_, _ = w.Write([]byte(`{"value":"ok"}`))
+			}
+		}
+	})
+}
+
+func (fs *frameServer) record(call string) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	fs.calls = append(fs.calls, call)
+}
+
+func (fs *frameServer) recorded() string {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	return strings.Join(fs.calls, ",")
+}
+
+func TestFrameWithin(t *testing.T) {
+	fs := &frameServer{}
+	server := httptest.NewServer(fs.handler(true))
+	defer server.Close()
+
+	page := &Page{client: NewWebDriverClient(server.URL).forSession("session-1")}
+	frame := page.FrameLocator("name=payment")
+
+	err := frame.within(context.Background(), func() error {
+		_, err := page.client.ExecuteScript(context.Background(), "return 1;", nil)
+		return err
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// The frame is found from the top-level document, injected after switching,
+	// and the session is switched back once done
+	expected := "switch:top,find-frame,switch:frame-1,inject,script,switch:top"
+	if got := fs.recorded(); got != expected {
+		t.Errorf("Expected calls %s, got %s", expected, got)
+	}
+}
+
+func TestFrameWithinNested(t *testing.T) {
+	fs := &frameServer{}
+	server := httptest.NewServer(fs.handler(true))
+	defer server.Close()
+
+	page := &Page{client: NewWebDriverClient(server.URL).forSession("session-1")}
+	frame := page.FrameLocator("#outer").FrameLocator("#inner")
+
+	if err := frame.within(context.Background(), func() error { return nil }); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := "switch:top,find-frame,switch:frame-1,inject,find-frame,switch:frame-1,inject,switch:top"
+	if got := fs.recorded(); got != expected {
+		t.Errorf("Expected calls %s, got %s", expected, got)
+	}
+}
+
+func TestFrameWithinSerializesPageOperations(t *testing.T) {
+	fs := &frameServer{}
+	server := httptest.NewServer(fs.handler(true))
+	defer server.Close()
+
+	page := &Page{client: NewWebDriverClient(server.URL).forSession("session-1")}
+	frame := page.FrameLocator("iframe")
+	ctx := context.Background()
+
+	// A page operation started while a frame operation is switched into the
+	// frame waits for the session to be switched back to the top
+	entered := make(chan struct{})
+	done := make(chan error, 1)
+	go func() {
+		done <- page.inWindow(ctx, func() error {
+			return frame.within(ctx, func() error {
+				close(entered)
+				time.Sleep(50 * time.Millisecond)
+				_, err := page.client.ExecuteScript(ctx, "return 1;", nil)
+				return err
+			})
+		})
+	}()
+
+	<-entered
+	err := page.inWindow(ctx, func() error {
+		_, err := page.client.ExecuteScript(ctx, "return 2;", nil)
+		return err
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := <-done; err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := "switch:top,find-frame,switch:frame-1,inject,script,switch:top,script"
+	if got := fs.recorded(); got != expected {
+		t.Errorf("Expected calls %s, got %s", expected, got)
+	}
+}
+
+func TestFrameWithinDialog(t *testing.T) {
+	var mu sync.Mutex
+	dialogOpen := true
+	var switches []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		if strings.HasSuffix(r.URL.Path, "/frame") {
+			// A dialog opened by the page blocks the first switch
+			if dialogOpen {
+				w.WriteHeader(http.StatusInternalServerError)
+				_, _ = w.Write([]byte(`{"value":{"error":"unexpected alert open","message":"alert"}}`))
+				return
+			}
+			switches = append(switches, "switch")
+		}
+		_, _ = w.Write([]byte(`{"value":{"element-6066-11e4-a52e-4f735466cecf":"frame-1"}}`))
+	}))
+	defer server.Close()
+
+	page := &Page{client: NewWebDriverClient(server.URL).forSession("session-1")}
+	dialogs := 0
+	page.client.dialogHandler = func(context.Context) error {
+		mu.Lock()
+		defer mu.Unlock()
+		dialogs++
+		dialogOpen = false
+		return nil
+	}
+
+	if err := page.FrameLocator("iframe").within(context.Background(), func() error { return nil }); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// The dialog is closed with the page's handler and the switches sent again
+	if dialogs != 1 || len(switches) != 3 {
+		t.Errorf("Expected 1 dialog handled and 3 switches, got %d and %d", dialogs, len(switches))
+	}
+}
+
+func TestFrameWithinNotFound(t *testing.T) {
+	fs := &frameServer{}
+	server := httptest.NewServer(fs.handler(false))
+	defer server.Close()
+
+	page := &Page{client: NewWebDriverClient(server.URL).forSession("session-1")}
+	frame := page.FrameLocator("url=https://pay.example.com/**")
+
+	called := false
+	err := frame.within(context.Background(), func() error {
+		called = true
+		return nil
+	})
+	if !errors.Is(err, ErrElementNotFound) {
+		t.Errorf("Expected ErrElementNotFound, got %v", err)
+	}
+	if err != nil && !strings.Contains(err.Error(), "url=https://pay.example.com/**") {
+		t.Errorf("Expected error to name the frame, got %v", err)
+	}
+	if called {
+		t.Error("Expected fn not to run when the frame is missing")
+	}
+
+	// The session is still switched back to the top-level document
+	if got := fs.recorded(); !strings.HasSuffix(got, "switch:top") {
+		t.Errorf("Expected a final switch to top, got %s", got)
+	}
+}
+
+func TestPageFrame(t *testing.T) {
+	page := &Page{}
+
+	frame, err := page.Frame(map[string]interface{}{"name": "payment"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if frame.selector != (FrameSelector{Name: "payment"}) {
+		t.Errorf("Unexpected selector: %+v", frame.selector)
+	}
+
+	frame, err = page.Frame(map[string]interface{}{"url": "/checkout/"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if frame.selector != (FrameSelector{URL: "/checkout/"}) {
+		t.Errorf("Unexpected selector: %+v", frame.selector)
+	}
+
+	if _, err := page.Frame(map[string]interface{}{}); err == nil {
+		t.Error("Expected error without a name or url")
+	}
+
+	// Locators created from a frame, and locators derived from them, run in the frame
+	locator := page.FrameLocator("iframe#widget").Locator(".item").Locator("button").First()
+	if locator.frame == nil || locator.frame.selector.CSS != "iframe#widget" {
+		t.Errorf("Expected derived locators to keep the frame, got %+v", locator.frame)
+	}
+}
+
+func TestFrameSelectorString(t *testing.T) {
+	for _, selector := range []string{"name=payment", "url=https://pay.example.com/**", "iframe#checkout"} {
+		if got := ParseFrameSelector(selector).String(); got != selector {
+			t.Errorf("Expected %q, got %q", selector, got)
+		}
+	}
+}
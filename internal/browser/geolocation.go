@@ -0,0 +1,111 @@
+package browser
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/grafana/sobek"
+)
+
+// geolocation is a position reported to the pages of a context
+type geolocation struct {
+	latitude  float64
+	longitude float64
+	accuracy  float64 // In meters
+}
+
+// geolocationScript replaces navigator.geolocation with a shim reporting the
+// position in arguments[0]. WebDriver has no way to override the position, and
+// Safari would prompt for permission anyway. Running it again updates the
+// position, which is reported to the watchers too
+const geolocationScript = `
+	var coords = arguments[0];
+	var state = window.__webdriverGeolocation;
+	if (!state) {
+		state = window.__webdriverGeolocation = { watchers: {}, nextID: 1 };
+		var position = function() {
+			return {
+				coords: {
+					latitude: state.coords.latitude, longitude: state.coords.longitude,
+					accuracy: state.coords.accuracy, altitude: null, altitudeAccuracy: null,
+					heading: null, speed: null
+				},
+				timestamp: Date.now()
+			};
+		};
+		state.notify = function(success) {
+			setTimeout(function() { success(position()); }, 0);
+		};
+		var shim = {
+			getCurrentPosition: function(success) { state.notify(success); },
+			watchPosition: function(success) {
+				var id = state.nextID++;
+				state.watchers[id] = success;
+				state.notify(success);
+				return id;
+			},
+			clearWatch: function(id) { delete state.watchers[id]; }
+		};
+		Object.defineProperty(Navigator.prototype, 'geolocation', {
+			get: function() { return shim; }, configurable: true
+		});
+	}
+	var changed = !!state.coords;
+	state.coords = coords;
+	if (changed) {
+		Object.keys(state.watchers).forEach(function(id) { state.notify(state.watchers[id]); });
+	}
+`
+
+// SetGeolocation sets the position reported by navigator.geolocation to the pages
+// of the context, including pages that are already open, e.g. to test
+// location-aware features deterministically. The accuracy is in meters (default 0)
+func (bc *BrowserContext) SetGeolocation(latitude, longitude float64, accuracy ...float64) (*sobek.Promise, error) {
+	if latitude < -90 || latitude > 90 {
+		return nil, fmt.Errorf("latitude must be between -90 and 90, got %v", latitude)
+	}
+	if longitude < -180 || longitude > 180 {
+		return nil, fmt.Errorf("longitude must be between -180 and 180, got %v", longitude)
+	}
+	geo := &geolocation{latitude: latitude, longitude: longitude}
+	if len(accuracy) > 0 {
+		if accuracy[0] < 0 {
+			return nil, fmt.Errorf("accuracy must not be negative, got %v", accuracy[0])
+		}
+		geo.accuracy = accuracy[0]
+	}
+
+	bc.geolocationMu.Lock()
+	bc.geolocation = geo
+	bc.geolocationMu.Unlock()
+
+	return Promise(bc.vu, func() (interface{}, error) {
+		ctx := context.Background()
+		for _, page := range bc.currentPages() {
+			err := page.inWindow(ctx, func() error { return page.applyGeolocation(ctx, geo) })
+			if err != nil {
+				return nil, fmt.Errorf("failed to set geolocation: %w", err)
+			}
+		}
+		return nil, nil
+	}), nil
+}
+
+// currentGeolocation returns the position set with SetGeolocation, or nil
+func (bc *BrowserContext) currentGeolocation() *geolocation {
+	bc.geolocationMu.Lock()
+	defer bc.geolocationMu.Unlock()
+
+	return bc.geolocation
+}
+
+// applyGeolocation makes navigator.geolocation report the position in the page
+func (p *Page) applyGeolocation(ctx context.Context, geo *geolocation) error {
+	coords := map[string]interface{}{
+		"latitude":  geo.latitude,
+		"longitude": geo.longitude,
+		"accuracy":  geo.accuracy,
+	}
+	_, err := p.client.ExecuteScript(ctx, geolocationScript, []interface{}{coords})
+	return err
+}
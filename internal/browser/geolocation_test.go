@@ -0,0 +1,72 @@
+package browser
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"go.k6.io/k6/js/modulestest"
+)
+
+func TestBrowserContextSetGeolocation(t *testing.T) {
+	var mu sync.Mutex
+	var positions []map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload struct {
+			Script string                   `json:"script"`
+			Args   []map[string]interface{} `json:"args"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&payload)
+		if strings.Contains(payload.Script, "__webdriverGeolocation") {
+			mu.Lock()
+			positions = append(positions, payload.Args[0])
+			mu.Unlock()
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"value":null}`))
+	}))
+	defer server.Close()
+
+	runtime := modulestest.NewRuntime(t)
+	browserContext := &BrowserContext{vu: runtime.VU}
+	page := &Page{vu: runtime.VU, client: NewWebDriverClient(server.URL).forSession("session-1"), context: browserContext}
+	browserContext.addPage(page)
+	if err := runtime.VU.Runtime().Set("context", browserContext); err != nil {
+		t.Fatal(err)
+	}
+
+	// Open pages are updated right away
+	_, err := runtime.RunOnEventLoop(`
+		var failure = "";
+		context.setGeolocation(59.95, 10.75, 25).catch(function(e) { failure = String(e); });
+	`)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if failure := runtime.VU.Runtime().Get("failure").String(); failure != "" {
+		t.Fatalf("Unexpected failure: %s", failure)
+	}
+	if len(positions) != 1 || positions[0]["latitude"] != 59.95 || positions[0]["longitude"] != 10.75 || positions[0]["accuracy"] != 25.0 {
+		t.Fatalf("Expected the position to be set in the open page, got %v", positions)
+	}
+
+	// Pages get it again after each navigation
+	if err := page.injectScript(context.Background()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(positions) != 2 || positions[1]["latitude"] != 59.95 {
+		t.Errorf("Expected the position to be injected again, got %v", positions)
+	}
+
+	if _, err := browserContext.SetGeolocation(91, 0); err == nil {
+		t.Error("Expected an out of range latitude to be rejected")
+	}
+	if _, err := browserContext.SetGeolocation(0, 0, -1); err == nil {
+		t.Error("Expected a negative accuracy to be rejected")
+	}
+}
@@ -2,69 +2,226 @@ package browser
 
 import (
 	"bytes"
+	"encoding/binary"
+	"errors"
 	"fmt"
+	"hash/crc32"
 	"image"
 	"image/color"
+	_ "image/jpeg" // Registers the JPEG decoder with image.Decode
 	"image/png"
 	"math"
 	"os"
+	"runtime"
+	"strconv"
+	"sync"
+
+	"github.com/grafana/sobek"
 )
 
-// CompareImages compares two image byte arrays and returns a similarity score
-// Returns a value between 0.0 (completely different) and 1.0 (identical)
-func CompareImages(img1Bytes, img2Bytes []byte) (float64, error) {
+var (
+	// ErrDPRMismatch is returned when two images were captured at different device pixel ratios
+	ErrDPRMismatch = errors.New("device pixel ratio mismatch")
+
+	// ErrDimensionMismatch is returned in strict mode when two images differ in size
+	ErrDimensionMismatch = errors.New("image dimension mismatch")
+)
+
+// DimensionMismatchError carries the sizes of two images that differ in strict mode
+// It wraps ErrDimensionMismatch
+type DimensionMismatchError struct {
+	First  image.Point
+	Second image.Point
+}
+
+func (e *DimensionMismatchError) Error() string {
+	return fmt.Sprintf("%s: first image is %dx%d, second is %dx%d",
+		ErrDimensionMismatch, e.First.X, e.First.Y, e.Second.X, e.Second.Y)
+}
+
+func (e *DimensionMismatchError) Unwrap() error {
+	return ErrDimensionMismatch
+}
+
+// pngSignature is the 8-byte header every PNG file starts with
+var pngSignature = []byte("\x89PNG\r\n\x1a\n")
+
+// dprTextKeyword is the PNG tEXt keyword used to record the device pixel ratio of a screenshot
+const dprTextKeyword = "xk6-browser-safari:devicePixelRatio"
+
+// CompareOptions contains options for image comparison
+type CompareOptions struct {
+	// IgnoreRegions are rectangles skipped during comparison and painted a neutral
+	// color in the diff. Coordinates are in image pixel space (already DPR-scaled).
+	IgnoreRegions []image.Rectangle `js:"ignoreRegions"`
+
+	// Threshold is the per-channel difference (0-255) above which a pixel is
+	// highlighted in the diff. Defaults to defaultDiffThreshold when nil.
+	Threshold *int `js:"threshold"`
+
+	// HighlightColor is used to paint different pixels in the diff. Defaults to red when nil.
+	HighlightColor *color.RGBA `js:"highlightColor"`
+
+	// AntiAliasTolerance is the radius in pixels searched for a matching pixel in
+	// the other image before a difference is highlighted. This keeps sub-pixel
+	// antialiasing shifts from swamping the diff. Zero disables the search.
+	AntiAliasTolerance int `js:"antiAliasTolerance"`
+
+	// NearestNeighbor scales mismatched-size images with nearest-neighbor
+	// sampling instead of bilinear interpolation. It is faster, but aliasing
+	// artifacts inflate pixel differences.
+	NearestNeighbor bool `js:"nearestNeighbor"`
+
+	// Strict returns a *DimensionMismatchError when the images differ in size
+	// instead of scaling the larger one down
+	Strict bool `js:"strict"`
+}
+
+// defaultDiffThreshold is the per-channel threshold used by CreateDiffImage when none is set
+const defaultDiffThreshold = 10
+
+var (
+	// ignoredRegionColor is used to paint ignored regions in the diff image
+	ignoredRegionColor = color.RGBA{R: 128, G: 128, B: 128, A: 255}
+
+	// defaultHighlightColor is used to paint different pixels in the diff image
+	defaultHighlightColor = color.RGBA{R: 255, G: 0, B: 0, A: 255}
+)
+
+// compareOptionsFrom returns the first options value, or the zero value if none given
+func compareOptionsFrom(opts []CompareOptions) CompareOptions {
+	if len(opts) > 0 {
+		return opts[0]
+	}
+	return CompareOptions{}
+}
+
+// diffThreshold returns the configured diff threshold or the default
+func (o CompareOptions) diffThreshold() int {
+	if o.Threshold != nil {
+		return *o.Threshold
+	}
+	return defaultDiffThreshold
+}
+
+// highlightColor returns the configured highlight color or the default
+func (o CompareOptions) highlightColor() color.RGBA {
+	if o.HighlightColor != nil {
+		return *o.HighlightColor
+	}
+	return defaultHighlightColor
+}
+
+// isIgnored reports whether the pixel at (x, y), relative to the image origin,
+// falls within one of the ignore regions
+func (o CompareOptions) isIgnored(x, y int) bool {
+	p := image.Pt(x, y)
+	for _, r := range o.IgnoreRegions {
+		if p.In(r) {
+			return true
+		}
+	}
+	return false
+}
+
+// decodeImagePair decodes two PNG or JPEG images for comparison, verifying that
+// their device pixel ratio metadata matches. If dimensions don't match, the larger
+// image is scaled down to match the smaller one, unless options.Strict is set.
+func decodeImagePair(img1Bytes, img2Bytes []byte, options CompareOptions) (image.Image, image.Image, error) {
+	if err := verifyDevicePixelRatio(img1Bytes, img2Bytes); err != nil {
+		return nil, nil, err
+	}
+
 	// Decode first image
-	img1, err := png.Decode(bytes.NewReader(img1Bytes))
+	img1, _, err := image.Decode(bytes.NewReader(img1Bytes))
 	if err != nil {
-		return 0, fmt.Errorf("failed to decode first image: %w", err)
+		return nil, nil, fmt.Errorf("failed to decode first image: %w", err)
 	}
 
 	// Decode second image
-	img2, err := png.Decode(bytes.NewReader(img2Bytes))
+	img2, _, err := image.Decode(bytes.NewReader(img2Bytes))
 	if err != nil {
-		return 0, fmt.Errorf("failed to decode second image: %w", err)
+		return nil, nil, fmt.Errorf("failed to decode second image: %w", err)
 	}
 
-	// Check if dimensions match
 	bounds1 := img1.Bounds()
 	bounds2 := img2.Bounds()
 
+	scale := scaleImageBilinear
+	if options.NearestNeighbor {
+		scale = scaleImage
+	}
+
 	// If dimensions don't match, scale the larger image down to match the smaller one
 	if bounds1.Dx() != bounds2.Dx() || bounds1.Dy() != bounds2.Dy() {
-		// Determine which image is larger
+		if options.Strict {
+			return nil, nil, &DimensionMismatchError{First: bounds1.Size(), Second: bounds2.Size()}
+		}
 		if bounds1.Dx() > bounds2.Dx() || bounds1.Dy() > bounds2.Dy() {
-			// Scale img1 down to match img2
-			img1 = scaleImage(img1, bounds2.Dx(), bounds2.Dy())
-			bounds1 = img1.Bounds()
+			img1 = scale(img1, bounds2.Dx(), bounds2.Dy())
 		} else {
-			// Scale img2 down to match img1
-			img2 = scaleImage(img2, bounds1.Dx(), bounds1.Dy())
-			bounds2 = img2.Bounds()
+			img2 = scale(img2, bounds1.Dx(), bounds1.Dy())
 		}
 	}
 
+	return img1, img2, nil
+}
+
+// CompareImages compares two image byte arrays and returns a similarity score
+// Returns a value between 0.0 (completely different) and 1.0 (identical)
+func CompareImages(img1Bytes, img2Bytes []byte, opts ...CompareOptions) (float64, error) {
+	options := compareOptionsFrom(opts)
+
+	img1, img2, err := decodeImagePair(img1Bytes, img2Bytes, options)
+	if err != nil {
+		return 0, err
+	}
+	bounds1 := img1.Bounds()
+
 	// Calculate MSE (Mean Squared Error)
-	var totalError float64
-	pixelCount := bounds1.Dx() * bounds1.Dy()
-
-	for y := bounds1.Min.Y; y < bounds1.Max.Y; y++ {
-		for x := bounds1.Min.X; x < bounds1.Max.X; x++ {
-			r1, g1, b1, a1 := img1.At(x, y).RGBA()
-			r2, g2, b2, a2 := img2.At(x, y).RGBA()
-
-			// Convert from uint32 (0-65535) to float64 (0-255)
-			dr := float64(r1>>8) - float64(r2>>8)
-			dg := float64(g1>>8) - float64(g2>>8)
-			db := float64(b1>>8) - float64(b2>>8)
-			da := float64(a1>>8) - float64(a2>>8)
-
-			// Sum of squared differences for all channels
-			totalError += dr*dr + dg*dg + db*db + da*da
+	// The squared differences are integers, so summing them per band as
+	// integers gives the same total in any order
+	bands := splitRows(bounds1)
+	bandErrors := make([]uint64, len(bands))
+	bandPixels := make([]int, len(bands))
+
+	processBands(bands, func(i int, band imageBand) {
+		for y := band.minY; y < band.maxY; y++ {
+			for x := bounds1.Min.X; x < bounds1.Max.X; x++ {
+				if options.isIgnored(x-bounds1.Min.X, y-bounds1.Min.Y) {
+					continue
+				}
+				bandPixels[i]++
+
+				r1, g1, b1, a1 := img1.At(x, y).RGBA()
+				r2, g2, b2, a2 := img2.At(x, y).RGBA()
+
+				// Convert from uint32 (0-65535) to 0-255
+				dr := int64(r1>>8) - int64(r2>>8)
+				dg := int64(g1>>8) - int64(g2>>8)
+				db := int64(b1>>8) - int64(b2>>8)
+				da := int64(a1>>8) - int64(a2>>8)
+
+				// Sum of squared differences for all channels
+				bandErrors[i] += uint64(dr*dr + dg*dg + db*db + da*da)
+			}
 		}
+	})
+
+	var totalError uint64
+	pixelCount := 0
+	for i := range bands {
+		totalError += bandErrors[i]
+		pixelCount += bandPixels[i]
+	}
+
+	// Every pixel was ignored, so there is nothing left to differ
+	if pixelCount == 0 {
+		return 1.0, nil
 	}
 
 	// Calculate MSE
-	mse := totalError / float64(pixelCount*4) // 4 channels (RGBA)
+	mse := float64(totalError) / float64(pixelCount*4) // 4 channels (RGBA)
 
 	// Convert MSE to similarity score (0-1)
 	// MSE ranges from 0 (identical) to 255^2 (completely different)
@@ -76,58 +233,99 @@ func CompareImages(img1Bytes, img2Bytes []byte) (float64, error) {
 }
 
 // PixelDifferenceCount counts how many pixels are different between two images
-func PixelDifferenceCount(img1Bytes, img2Bytes []byte, threshold uint32) (int, error) {
-	// Decode images
-	img1, err := png.Decode(bytes.NewReader(img1Bytes))
-	if err != nil {
-		return 0, fmt.Errorf("failed to decode first image: %w", err)
-	}
+func PixelDifferenceCount(img1Bytes, img2Bytes []byte, threshold uint32, opts ...CompareOptions) (int, error) {
+	options := compareOptionsFrom(opts)
 
-	img2, err := png.Decode(bytes.NewReader(img2Bytes))
+	img1, img2, err := decodeImagePair(img1Bytes, img2Bytes, options)
 	if err != nil {
-		return 0, fmt.Errorf("failed to decode second image: %w", err)
+		return 0, err
 	}
-
-	// Check dimensions
 	bounds1 := img1.Bounds()
-	bounds2 := img2.Bounds()
 
-	// If dimensions don't match, scale the larger image down to match the smaller one
-	if bounds1.Dx() != bounds2.Dx() || bounds1.Dy() != bounds2.Dy() {
-		if bounds1.Dx() > bounds2.Dx() || bounds1.Dy() > bounds2.Dy() {
-			img1 = scaleImage(img1, bounds2.Dx(), bounds2.Dy())
-			bounds1 = img1.Bounds()
-		} else {
-			img2 = scaleImage(img2, bounds1.Dx(), bounds1.Dy())
-			bounds2 = img2.Bounds()
+	// Count different pixels
+	bands := splitRows(bounds1)
+	bandCounts := make([]int, len(bands))
+
+	processBands(bands, func(i int, band imageBand) {
+		for y := band.minY; y < band.maxY; y++ {
+			for x := bounds1.Min.X; x < bounds1.Max.X; x++ {
+				if options.isIgnored(x-bounds1.Min.X, y-bounds1.Min.Y) {
+					continue
+				}
+
+				r1, g1, b1, a1 := img1.At(x, y).RGBA()
+				r2, g2, b2, a2 := img2.At(x, y).RGBA()
+
+				// Calculate difference in each channel
+				dr := int32(r1) - int32(r2)
+				dg := int32(g1) - int32(g2)
+				db := int32(b1) - int32(b2)
+				da := int32(a1) - int32(a2)
+
+				// Check if any channel differs by more than threshold
+				if abs32(dr) > int32(threshold) ||
+					abs32(dg) > int32(threshold) ||
+					abs32(db) > int32(threshold) ||
+					abs32(da) > int32(threshold) {
+					bandCounts[i]++
+				}
+			}
 		}
-	}
+	})
 
-	// Count different pixels
 	differentPixels := 0
+	for _, n := range bandCounts {
+		differentPixels += n
+	}
 
-	for y := bounds1.Min.Y; y < bounds1.Max.Y; y++ {
-		for x := bounds1.Min.X; x < bounds1.Max.X; x++ {
-			r1, g1, b1, a1 := img1.At(x, y).RGBA()
-			r2, g2, b2, a2 := img2.At(x, y).RGBA()
-
-			// Calculate difference in each channel
-			dr := int32(r1) - int32(r2)
-			dg := int32(g1) - int32(g2)
-			db := int32(b1) - int32(b2)
-			da := int32(a1) - int32(a2)
-
-			// Check if any channel differs by more than threshold
-			if abs32(dr) > int32(threshold) ||
-				abs32(dg) > int32(threshold) ||
-				abs32(db) > int32(threshold) ||
-				abs32(da) > int32(threshold) {
-				differentPixels++
-			}
+	return differentPixels, nil
+}
+
+// minRowsPerBand keeps small images from being split into bands that cost more
+// to schedule than to compare
+const minRowsPerBand = 64
+
+// imageBand is the horizontal band of rows [minY, maxY) of an image
+type imageBand struct {
+	minY, maxY int
+}
+
+// splitRows splits the rows of bounds into horizontal bands of at least
+// minRowsPerBand rows, one per GOMAXPROCS at most, so the pixel loops can
+// compare them concurrently
+func splitRows(bounds image.Rectangle) []imageBand {
+	count := min(runtime.GOMAXPROCS(0), bounds.Dy()/minRowsPerBand)
+	if count < 1 {
+		count = 1
+	}
+
+	bands := make([]imageBand, count)
+	for i := range bands {
+		bands[i] = imageBand{
+			minY: bounds.Min.Y + bounds.Dy()*i/count,
+			maxY: bounds.Min.Y + bounds.Dy()*(i+1)/count,
 		}
 	}
+	return bands
+}
 
-	return differentPixels, nil
+// processBands calls fn for each band on a goroutine of its own and waits for
+// them all. fn must only write to its band's results
+func processBands(bands []imageBand, fn func(i int, band imageBand)) {
+	if len(bands) == 1 {
+		fn(0, bands[0])
+		return
+	}
+
+	var wg sync.WaitGroup
+	for i, band := range bands {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			fn(i, band)
+		}()
+	}
+	wg.Wait()
 }
 
 func abs32(n int32) int32 {
@@ -160,93 +358,145 @@ func scaleImage(src image.Image, targetWidth, targetHeight int) image.Image {
 	return dst
 }
 
+// scaleImageBilinear scales an image to the target width and height using bilinear interpolation
+func scaleImageBilinear(src image.Image, targetWidth, targetHeight int) image.Image {
+	srcBounds := src.Bounds()
+	srcWidth := srcBounds.Dx()
+	srcHeight := srcBounds.Dy()
+
+	dst := image.NewRGBA(image.Rect(0, 0, targetWidth, targetHeight))
+
+	xRatio := float64(srcWidth) / float64(targetWidth)
+	yRatio := float64(srcHeight) / float64(targetHeight)
+
+	for y := 0; y < targetHeight; y++ {
+		// Map the destination pixel center back into source space
+		srcY := math.Max((float64(y)+0.5)*yRatio-0.5, 0)
+		y0 := int(srcY)
+		y1 := min(y0+1, srcHeight-1)
+		fy := srcY - float64(y0)
+
+		for x := 0; x < targetWidth; x++ {
+			srcX := math.Max((float64(x)+0.5)*xRatio-0.5, 0)
+			x0 := int(srcX)
+			x1 := min(x0+1, srcWidth-1)
+			fx := srcX - float64(x0)
+
+			r00, g00, b00, a00 := src.At(srcBounds.Min.X+x0, srcBounds.Min.Y+y0).RGBA()
+			r10, g10, b10, a10 := src.At(srcBounds.Min.X+x1, srcBounds.Min.Y+y0).RGBA()
+			r01, g01, b01, a01 := src.At(srcBounds.Min.X+x0, srcBounds.Min.Y+y1).RGBA()
+			r11, g11, b11, a11 := src.At(srcBounds.Min.X+x1, srcBounds.Min.Y+y1).RGBA()
+
+			lerp := func(c00, c10, c01, c11 uint32) uint16 {
+				top := float64(c00)*(1-fx) + float64(c10)*fx
+				bottom := float64(c01)*(1-fx) + float64(c11)*fx
+				return uint16(math.Round(top*(1-fy) + bottom*fy))
+			}
+
+			dst.Set(x, y, color.RGBA64{
+				R: lerp(r00, r10, r01, r11),
+				G: lerp(g00, g10, g01, g11),
+				B: lerp(b00, b10, b01, b11),
+				A: lerp(a00, a10, a01, a11),
+			})
+		}
+	}
+
+	return dst
+}
+
+// DiffStats summarizes the pixels highlighted in a diff image
+type DiffStats struct {
+	// DiffPixelCount is the number of highlighted pixels
+	DiffPixelCount int `js:"diffPixelCount"`
+
+	// TotalPixels is the number of pixels compared, excluding ignored regions
+	TotalPixels int `js:"totalPixels"`
+
+	// DiffPercentage is DiffPixelCount as a percentage (0-100) of TotalPixels
+	DiffPercentage float64 `js:"diffPercentage"`
+}
+
 // CreateDiffImage creates a visual diff image highlighting differences between two images
-// Identical pixels are shown in grayscale, different pixels are highlighted in red
+// Identical pixels are shown in grayscale, different pixels are highlighted in
+// options.HighlightColor (red by default) when any channel differs by more than
+// options.Threshold (10 by default)
+// Pixels inside options.IgnoreRegions are skipped and painted a neutral color
 // Returns the diff image as PNG bytes, and optionally saves to filePath if provided
-func CreateDiffImage(img1Bytes, img2Bytes []byte, filePath string) ([]byte, error) {
-	// Decode first image
-	img1, err := png.Decode(bytes.NewReader(img1Bytes))
-	if err != nil {
-		return nil, fmt.Errorf("failed to decode first image: %w", err)
-	}
+func CreateDiffImage(img1Bytes, img2Bytes []byte, filePath string, opts ...CompareOptions) ([]byte, error) {
+	diffBytes, _, err := CreateDiffImageWithStats(img1Bytes, img2Bytes, filePath, opts...)
+	return diffBytes, err
+}
 
-	// Decode second image
-	img2, err := png.Decode(bytes.NewReader(img2Bytes))
-	if err != nil {
-		return nil, fmt.Errorf("failed to decode second image: %w", err)
-	}
+// CreateDiffImageWithStats creates the same diff image as CreateDiffImage and
+// also returns how many pixels it highlights, so callers can pass or fail on
+// the difference without decoding the images again with PixelDifferenceCount
+func CreateDiffImageWithStats(img1Bytes, img2Bytes []byte, filePath string, opts ...CompareOptions) ([]byte, DiffStats, error) {
+	options := compareOptionsFrom(opts)
+	threshold := options.diffThreshold()
+	highlight := options.highlightColor()
 
-	// Check if dimensions match
-	bounds1 := img1.Bounds()
-	bounds2 := img2.Bounds()
+	var stats DiffStats
 
-	// If dimensions don't match, scale the larger image down to match the smaller one
-	if bounds1.Dx() != bounds2.Dx() || bounds1.Dy() != bounds2.Dy() {
-		if bounds1.Dx() > bounds2.Dx() || bounds1.Dy() > bounds2.Dy() {
-			img1 = scaleImage(img1, bounds2.Dx(), bounds2.Dy())
-			bounds1 = img1.Bounds()
-		} else {
-			img2 = scaleImage(img2, bounds1.Dx(), bounds1.Dy())
-			bounds2 = img2.Bounds()
-		}
+	img1, img2, err := decodeImagePair(img1Bytes, img2Bytes, options)
+	if err != nil {
+		return nil, stats, err
 	}
+	bounds1 := img1.Bounds()
 
 	// Create diff image
 	width := bounds1.Dx()
 	height := bounds1.Dy()
 	diffImg := image.NewRGBA(image.Rect(0, 0, width, height))
 
-	// Threshold for considering pixels different (adjust as needed)
-	const threshold = 10
-
-	for y := bounds1.Min.Y; y < bounds1.Max.Y; y++ {
-		for x := bounds1.Min.X; x < bounds1.Max.X; x++ {
-			r1, g1, b1, a1 := img1.At(x, y).RGBA()
-			r2, g2, b2, a2 := img2.At(x, y).RGBA()
-
-			// Convert from uint32 (0-65535) to uint8 (0-255)
-			r1b := uint8(r1 >> 8)
-			g1b := uint8(g1 >> 8)
-			b1b := uint8(b1 >> 8)
-			a1b := uint8(a1 >> 8)
-
-			r2b := uint8(r2 >> 8)
-			g2b := uint8(g2 >> 8)
-			b2b := uint8(b2 >> 8)
-			a2b := uint8(a2 >> 8)
-
-			// Calculate difference
-			dr := abs(int(r1b) - int(r2b))
-			dg := abs(int(g1b) - int(g2b))
-			db := abs(int(b1b) - int(b2b))
-			da := abs(int(a1b) - int(a2b))
-
-			// Check if pixels are different
-			if dr > threshold || dg > threshold || db > threshold || da > threshold {
-				// Highlight difference in red
-				diffImg.SetRGBA(x-bounds1.Min.X, y-bounds1.Min.Y, color.RGBA{
-					R: 255,
-					G: 0,
-					B: 0,
-					A: 255,
-				})
-			} else {
-				// Show identical pixels in grayscale (average of RGB)
-				gray := uint8((int(r1b) + int(g1b) + int(b1b)) / 3)
-				diffImg.SetRGBA(x-bounds1.Min.X, y-bounds1.Min.Y, color.RGBA{
-					R: gray,
-					G: gray,
-					B: gray,
-					A: a1b,
-				})
+	// Each band writes its own rows of the diff image
+	bands := splitRows(bounds1)
+	bandStats := make([]DiffStats, len(bands))
+
+	processBands(bands, func(i int, band imageBand) {
+		for y := band.minY; y < band.maxY; y++ {
+			for x := bounds1.Min.X; x < bounds1.Max.X; x++ {
+				if options.isIgnored(x-bounds1.Min.X, y-bounds1.Min.Y) {
+					diffImg.SetRGBA(x-bounds1.Min.X, y-bounds1.Min.Y, ignoredRegionColor)
+					continue
+				}
+				bandStats[i].TotalPixels++
+
+				c1 := color.RGBAModel.Convert(img1.At(x, y)).(color.RGBA)
+				c2 := color.RGBAModel.Convert(img2.At(x, y)).(color.RGBA)
+
+				// Check if pixels are different, tolerating antialiasing shifts if configured
+				if pixelsDiffer(c1, c2, threshold) &&
+					!hasNearbyMatch(img1, img2, x, y, options.AntiAliasTolerance, threshold) {
+					diffImg.SetRGBA(x-bounds1.Min.X, y-bounds1.Min.Y, highlight)
+					bandStats[i].DiffPixelCount++
+				} else {
+					// Show identical pixels in grayscale (average of RGB)
+					gray := uint8((int(c1.R) + int(c1.G) + int(c1.B)) / 3)
+					diffImg.SetRGBA(x-bounds1.Min.X, y-bounds1.Min.Y, color.RGBA{
+						R: gray,
+						G: gray,
+						B: gray,
+						A: c1.A,
+					})
+				}
 			}
 		}
+	})
+
+	for _, bs := range bandStats {
+		stats.TotalPixels += bs.TotalPixels
+		stats.DiffPixelCount += bs.DiffPixelCount
+	}
+
+	if stats.TotalPixels > 0 {
+		stats.DiffPercentage = float64(stats.DiffPixelCount) / float64(stats.TotalPixels) * 100
 	}
 
 	// Encode diff image to PNG
 	var buf bytes.Buffer
 	if err := png.Encode(&buf, diffImg); err != nil {
-		return nil, fmt.Errorf("failed to encode diff image: %w", err)
+		return nil, stats, fmt.Errorf("failed to encode diff image: %w", err)
 	}
 
 	diffBytes := buf.Bytes()
@@ -254,11 +504,52 @@ func CreateDiffImage(img1Bytes, img2Bytes []byte, filePath string) ([]byte, erro
 	// Save to file if path provided
 	if filePath != "" {
 		if err := os.WriteFile(filePath, diffBytes, 0644); err != nil {
-			return nil, fmt.Errorf("failed to write diff image to %s: %w", filePath, err)
+			return nil, stats, fmt.Errorf("failed to write diff image to %s: %w", filePath, err)
+		}
+	}
+
+	return diffBytes, stats, nil
+}
+
+// pixelsDiffer reports whether any channel of c1 and c2 differs by more than threshold
+func pixelsDiffer(c1, c2 color.RGBA, threshold int) bool {
+	return abs(int(c1.R)-int(c2.R)) > threshold ||
+		abs(int(c1.G)-int(c2.G)) > threshold ||
+		abs(int(c1.B)-int(c2.B)) > threshold ||
+		abs(int(c1.A)-int(c2.A)) > threshold
+}
+
+// hasNearbyMatch reports whether the pixel at (x, y) in either image matches a
+// pixel within radius in the other image, which indicates an antialiasing
+// shift rather than a real change
+func hasNearbyMatch(img1, img2 image.Image, x, y, radius, threshold int) bool {
+	if radius <= 0 {
+		return false
+	}
+
+	bounds := img1.Bounds()
+	c1 := color.RGBAModel.Convert(img1.At(x, y)).(color.RGBA)
+	c2 := color.RGBAModel.Convert(img2.At(x, y)).(color.RGBA)
+	matched1, matched2 := false, false
+
+	for ny := y - radius; ny <= y+radius; ny++ {
+		for nx := x - radius; nx <= x+radius; nx++ {
+			if !image.Pt(nx, ny).In(bounds) {
+				continue
+			}
+			if !matched1 && !pixelsDiffer(c1, color.RGBAModel.Convert(img2.At(nx, ny)).(color.RGBA), threshold) {
+				matched1 = true
+			}
+			if !matched2 && !pixelsDiffer(c2, color.RGBAModel.Convert(img1.At(nx, ny)).(color.RGBA), threshold) {
+				matched2 = true
+			}
+			if matched1 && matched2 {
+				return true
+			}
 		}
 	}
 
-	return diffBytes, nil
+	return false
 }
 
 func abs(n int) int {
@@ -267,3 +558,113 @@ func abs(n int) int {
 	}
 	return n
 }
+
+// verifyDevicePixelRatio checks that two PNG images were captured at the same
+// device pixel ratio. Images without DPR metadata (e.g. captured elsewhere) are
+// not checked.
+func verifyDevicePixelRatio(img1Bytes, img2Bytes []byte) error {
+	dpr1, ok1 := pngDevicePixelRatio(img1Bytes)
+	dpr2, ok2 := pngDevicePixelRatio(img2Bytes)
+	if !ok1 || !ok2 || dpr1 == dpr2 {
+		return nil
+	}
+	return fmt.Errorf("%w: first image captured at %v, second at %v", ErrDPRMismatch, dpr1, dpr2)
+}
+
+// setPNGDevicePixelRatio records dpr in a tEXt chunk placed right after the
+// PNG header chunk, replacing any previously recorded value
+func setPNGDevicePixelRatio(data []byte, dpr float64) ([]byte, error) {
+	const headerEnd = 8 + 4 + 4 + 13 + 4 // signature + IHDR chunk
+	if len(data) < headerEnd || !bytes.Equal(data[:8], pngSignature) {
+		return nil, fmt.Errorf("not a PNG image")
+	}
+
+	text := append([]byte(dprTextKeyword+"\x00"), strconv.FormatFloat(dpr, 'f', -1, 64)...)
+	chunk := make([]byte, 0, 12+len(text))
+	chunk = binary.BigEndian.AppendUint32(chunk, uint32(len(text)))
+	chunk = append(chunk, "tEXt"...)
+	chunk = append(chunk, text...)
+	chunk = binary.BigEndian.AppendUint32(chunk, crc32.ChecksumIEEE(chunk[4:]))
+
+	out := make([]byte, 0, len(data)+len(chunk))
+	out = append(out, data[:headerEnd]...)
+	out = append(out, chunk...)
+	for offset := headerEnd; offset+8 <= len(data); {
+		length := int(binary.BigEndian.Uint32(data[offset:]))
+		end := offset + 12 + length
+		if end > len(data) {
+			return nil, fmt.Errorf("truncated PNG chunk")
+		}
+		if _, ok := parseDPRChunk(data[offset:end]); !ok {
+			out = append(out, data[offset:end]...)
+		}
+		offset = end
+	}
+
+	return out, nil
+}
+
+// pngDevicePixelRatio returns the device pixel ratio recorded in a PNG image, if any
+func pngDevicePixelRatio(data []byte) (float64, bool) {
+	if len(data) < 8 || !bytes.Equal(data[:8], pngSignature) {
+		return 0, false
+	}
+
+	for offset := 8; offset+8 <= len(data); {
+		length := int(binary.BigEndian.Uint32(data[offset:]))
+		end := offset + 12 + length
+		if end > len(data) || string(data[offset+4:offset+8]) == "IDAT" {
+			return 0, false
+		}
+		if dpr, ok := parseDPRChunk(data[offset:end]); ok {
+			return dpr, true
+		}
+		offset = end
+	}
+
+	return 0, false
+}
+
+// parseDPRChunk parses a full PNG chunk and returns the DPR if it is our tEXt chunk
+func parseDPRChunk(chunk []byte) (float64, bool) {
+	if string(chunk[4:8]) != "tEXt" {
+		return 0, false
+	}
+	keyword, value, found := bytes.Cut(chunk[8:len(chunk)-4], []byte{0})
+	if !found || string(keyword) != dprTextKeyword {
+		return 0, false
+	}
+	dpr, err := strconv.ParseFloat(string(value), 64)
+	if err != nil {
+		return 0, false
+	}
+	return dpr, true
+}
+
+// CompareScreenshotsAsync is CompareImages run off the event loop, so that
+// comparing large screenshots doesn't stall the VU. It resolves to the similarity
+func (b *Browser) CompareScreenshotsAsync(img1Bytes, img2Bytes []byte, opts ...CompareOptions) *sobek.Promise {
+	return Promise(b.VU, func() (any, error) {
+		return CompareImages(img1Bytes, img2Bytes, opts...)
+	})
+}
+
+// CreateDiffImageAsync is CreateDiffImage run off the event loop. It resolves to
+// the diff image
+func (b *Browser) CreateDiffImageAsync(img1Bytes, img2Bytes []byte, filePath string, opts ...CompareOptions) *sobek.Promise {
+	return Promise(b.VU, func() (any, error) {
+		return CreateDiffImage(img1Bytes, img2Bytes, filePath, opts...)
+	})
+}
+
+// CreateDiffImageWithStatsAsync is CreateDiffImageWithStats run off the event
+// loop. It resolves to the [diff image, stats] pair the sync version returns
+func (b *Browser) CreateDiffImageWithStatsAsync(img1Bytes, img2Bytes []byte, filePath string, opts ...CompareOptions) *sobek.Promise {
+	return Promise(b.VU, func() (any, error) {
+		diff, stats, err := CreateDiffImageWithStats(img1Bytes, img2Bytes, filePath, opts...)
+		if err != nil {
+			return nil, err
+		}
+		return []any{diff, stats}, nil
+	})
+}
@@ -0,0 +1,382 @@
+package browser
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.k6.io/k6/js/modulestest"
+)
+
+// solidPNG creates a PNG of the given size filled with c, with the pixels
+// inside each of the marked rectangles painted with mark
+func solidPNG(t testing.TB, width, height int, c, mark color.RGBA, marked ...image.Rectangle) []byte {
+	t.Helper()
+
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.SetRGBA(x, y, c)
+			for _, r := range marked {
+				if image.Pt(x, y).In(r) {
+					img.SetRGBA(x, y, mark)
+				}
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, img))
+	return buf.Bytes()
+}
+
+var (
+	white = color.RGBA{R: 255, G: 255, B: 255, A: 255}
+	black = color.RGBA{A: 255}
+)
+
+func TestCompareImagesIgnoreRegions(t *testing.T) {
+	t.Parallel()
+
+	changed := image.Rect(2, 2, 6, 6)
+	img1 := solidPNG(t, 10, 10, white, white)
+	img2 := solidPNG(t, 10, 10, white, black, changed)
+
+	similarity, err := CompareImages(img1, img2)
+	require.NoError(t, err)
+	require.Less(t, similarity, 1.0)
+
+	similarity, err = CompareImages(img1, img2, CompareOptions{IgnoreRegions: []image.Rectangle{changed}})
+	require.NoError(t, err)
+	require.Equal(t, 1.0, similarity)
+
+	count, err := PixelDifferenceCount(img1, img2, 0)
+	require.NoError(t, err)
+	require.Equal(t, 16, count)
+
+	count, err = PixelDifferenceCount(img1, img2, 0, CompareOptions{IgnoreRegions: []image.Rectangle{image.Rect(2, 2, 4, 6)}})
+	require.NoError(t, err)
+	require.Equal(t, 8, count)
+}
+
+func TestCreateDiffImageIgnoreRegions(t *testing.T) {
+	t.Parallel()
+
+	changed := image.Rect(2, 2, 6, 6)
+	img1 := solidPNG(t, 10, 10, white, white)
+	img2 := solidPNG(t, 10, 10, white, black, changed)
+
+	diffBytes, err := CreateDiffImage(img1, img2, "", CompareOptions{IgnoreRegions: []image.Rectangle{changed}})
+	require.NoError(t, err)
+
+	diff, err := png.Decode(bytes.NewReader(diffBytes))
+	require.NoError(t, err)
+	require.Equal(t, ignoredRegionColor, color.RGBAModel.Convert(diff.At(3, 3)))
+	require.Equal(t, white, color.RGBAModel.Convert(diff.At(0, 0)))
+}
+
+func TestCreateDiffImageWithStats(t *testing.T) {
+	t.Parallel()
+
+	changed := image.Rect(2, 2, 6, 6)
+	img1 := solidPNG(t, 10, 10, white, white)
+	img2 := solidPNG(t, 10, 10, white, black, changed)
+
+	diffBytes, stats, err := CreateDiffImageWithStats(img1, img2, "")
+	require.NoError(t, err)
+	require.Equal(t, DiffStats{DiffPixelCount: 16, TotalPixels: 100, DiffPercentage: 16}, stats)
+
+	plain, err := CreateDiffImage(img1, img2, "")
+	require.NoError(t, err)
+	require.Equal(t, plain, diffBytes)
+
+	// Ignored pixels are neither compared nor counted
+	_, stats, err = CreateDiffImageWithStats(img1, img2, "", CompareOptions{IgnoreRegions: []image.Rectangle{image.Rect(0, 0, 4, 10)}})
+	require.NoError(t, err)
+	require.Equal(t, DiffStats{DiffPixelCount: 8, TotalPixels: 60, DiffPercentage: 8.0 / 60 * 100}, stats)
+}
+
+func TestBrowserCompareAsync(t *testing.T) {
+	t.Parallel()
+
+	runtime := modulestest.NewRuntime(t)
+	rt := runtime.VU.Runtime()
+	require.NoError(t, rt.Set("browser", &Browser{VU: runtime.VU}))
+	require.NoError(t, rt.Set("img1", rt.NewArrayBuffer(solidPNG(t, 10, 10, white, white))))
+	require.NoError(t, rt.Set("img2", rt.NewArrayBuffer(solidPNG(t, 10, 10, white, black, image.Rect(0, 0, 5, 2)))))
+	require.NoError(t, rt.Set("dir", t.TempDir()))
+
+	_, err := runtime.RunOnEventLoop(`
+		var similarity = null, stats = null, diffSize = 0, baseline = null, failure = "";
+		browser.compareScreenshotsAsync(img1, img2).then(function(s) { similarity = s; });
+		browser.createDiffImageAsync(img1, img2, "").then(function(d) { diffSize = d.length; });
+		browser.createDiffImageWithStatsAsync(img1, img2, "", { threshold: 0 }).then(function(r) { stats = r[1]; });
+		browser.compareAgainstBaselineAsync("home", img1, dir).then(function(r) { baseline = r; });
+		browser.compareScreenshotsAsync(img1, new ArrayBuffer(8)).catch(function(e) { failure = String(e); });
+	`)
+	require.NoError(t, err)
+
+	similarity, err := CompareImages(solidPNG(t, 10, 10, white, white), solidPNG(t, 10, 10, white, black, image.Rect(0, 0, 5, 2)))
+	require.NoError(t, err)
+	require.Equal(t, similarity, rt.Get("similarity").ToFloat())
+	require.Positive(t, rt.Get("diffSize").ToInteger())
+	require.Equal(t, DiffStats{DiffPixelCount: 10, TotalPixels: 100, DiffPercentage: 10}, rt.Get("stats").Export())
+	result, ok := rt.Get("baseline").Export().(*BaselineResult)
+	require.True(t, ok)
+	require.True(t, result.BaselineCreated)
+	require.Contains(t, rt.Get("failure").String(), "failed to decode")
+}
+
+func TestSplitRows(t *testing.T) {
+	t.Parallel()
+
+	for _, bounds := range []image.Rectangle{
+		image.Rect(0, 0, 10, 10),
+		image.Rect(0, 0, 100, 1000),
+		image.Rect(0, 5, 100, 4325),
+	} {
+		bands := splitRows(bounds)
+		require.NotEmpty(t, bands)
+		require.LessOrEqual(t, len(bands), runtime.GOMAXPROCS(0))
+
+		// The bands cover every row once, in order
+		next := bounds.Min.Y
+		for _, band := range bands {
+			require.Equal(t, next, band.minY)
+			require.Greater(t, band.maxY, band.minY)
+			if len(bands) > 1 {
+				require.GreaterOrEqual(t, band.maxY-band.minY, minRowsPerBand)
+			}
+			next = band.maxY
+		}
+		require.Equal(t, bounds.Max.Y, next)
+	}
+}
+
+func TestImageComparisonAcrossBands(t *testing.T) {
+	t.Parallel()
+
+	// Changes straddling band boundaries are counted once
+	changed := []image.Rectangle{image.Rect(0, 0, 10, 10), image.Rect(20, 60, 30, 200), image.Rect(0, 495, 100, 500)}
+	img1 := solidPNG(t, 100, 500, white, white)
+	img2 := solidPNG(t, 100, 500, white, black, changed...)
+	want := 100 + 1400 + 500
+
+	count, err := PixelDifferenceCount(img1, img2, 0)
+	require.NoError(t, err)
+	require.Equal(t, want, count)
+
+	diff, stats, err := CreateDiffImageWithStats(img1, img2, "", CompareOptions{IgnoreRegions: []image.Rectangle{image.Rect(0, 0, 10, 10)}})
+	require.NoError(t, err)
+	require.Equal(t, want-100, stats.DiffPixelCount)
+	require.Equal(t, 50000-100, stats.TotalPixels)
+
+	decoded, err := png.Decode(bytes.NewReader(diff))
+	require.NoError(t, err)
+	require.Equal(t, color.RGBAModel.Convert(decoded.At(25, 150)), defaultHighlightColor)
+	require.Equal(t, color.RGBAModel.Convert(decoded.At(5, 5)), ignoredRegionColor)
+
+	// MSE over (want) black pixels out of 50000, all 3 color channels off by 255
+	similarity, err := CompareImages(img1, img2)
+	require.NoError(t, err)
+	require.Equal(t, 1-float64(want)*3*255*255/(50000*4)/(255*255), similarity)
+}
+
+func BenchmarkImageComparison(b *testing.B) {
+	// A 4K screenshot at a device pixel ratio of 2
+	changed := image.Rect(1000, 1000, 2000, 1500)
+	img1 := solidPNG(b, 3840, 2160, white, white)
+	img2 := solidPNG(b, 3840, 2160, white, black, changed)
+
+	b.Run("CompareImages", func(b *testing.B) {
+		for b.Loop() {
+			if _, err := CompareImages(img1, img2); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("PixelDifferenceCount", func(b *testing.B) {
+		for b.Loop() {
+			if _, err := PixelDifferenceCount(img1, img2, 0); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("CreateDiffImage", func(b *testing.B) {
+		for b.Loop() {
+			if _, err := CreateDiffImage(img1, img2, ""); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func TestCompareImagesJPEG(t *testing.T) {
+	t.Parallel()
+
+	decoded, err := png.Decode(bytes.NewReader(solidPNG(t, 10, 10, white, black, image.Rect(0, 0, 5, 10))))
+	require.NoError(t, err)
+	var buf bytes.Buffer
+	require.NoError(t, jpeg.Encode(&buf, decoded, &jpeg.Options{Quality: 100}))
+	baseline := buf.Bytes()
+
+	// A JPEG baseline compares against a PNG capture of the same content
+	similarity, err := CompareImages(baseline, solidPNG(t, 10, 10, white, black, image.Rect(0, 0, 5, 10)))
+	require.NoError(t, err)
+	require.Greater(t, similarity, defaultMinSimilarity)
+
+	_, stats, err := CreateDiffImageWithStats(baseline, solidPNG(t, 10, 10, white, white), "")
+	require.NoError(t, err)
+	require.Equal(t, 50, stats.DiffPixelCount)
+}
+
+func TestPNGDevicePixelRatio(t *testing.T) {
+	t.Parallel()
+
+	img := solidPNG(t, 4, 4, white, white)
+
+	_, ok := pngDevicePixelRatio(img)
+	require.False(t, ok)
+
+	stamped, err := setPNGDevicePixelRatio(img, 2)
+	require.NoError(t, err)
+	dpr, ok := pngDevicePixelRatio(stamped)
+	require.True(t, ok)
+	require.Equal(t, 2.0, dpr)
+
+	// Re-stamping replaces the existing value and keeps the image decodable
+	restamped, err := setPNGDevicePixelRatio(stamped, 1.5)
+	require.NoError(t, err)
+	require.Len(t, restamped, len(stamped)+2) // "2" -> "1.5"
+	dpr, ok = pngDevicePixelRatio(restamped)
+	require.True(t, ok)
+	require.Equal(t, 1.5, dpr)
+	_, err = png.Decode(bytes.NewReader(restamped))
+	require.NoError(t, err)
+
+	_, err = setPNGDevicePixelRatio([]byte("not a png"), 1)
+	require.Error(t, err)
+}
+
+func TestCompareImagesDPRMismatch(t *testing.T) {
+	t.Parallel()
+
+	img := solidPNG(t, 4, 4, white, white)
+	at1, err := setPNGDevicePixelRatio(img, 1)
+	require.NoError(t, err)
+	at2, err := setPNGDevicePixelRatio(img, 2)
+	require.NoError(t, err)
+
+	_, err = CompareImages(at1, at2)
+	require.ErrorIs(t, err, ErrDPRMismatch)
+
+	_, err = CreateDiffImage(at1, at2, "")
+	require.ErrorIs(t, err, ErrDPRMismatch)
+
+	// Images without DPR metadata are compared as before
+	similarity, err := CompareImages(img, at2)
+	require.NoError(t, err)
+	require.Equal(t, 1.0, similarity)
+}
+
+func TestCreateDiffImageOptions(t *testing.T) {
+	t.Parallel()
+
+	gray := color.RGBA{R: 250, G: 250, B: 250, A: 255}
+	magenta := color.RGBA{R: 255, B: 255, A: 255}
+
+	img1 := solidPNG(t, 10, 10, white, white)
+	img2 := solidPNG(t, 10, 10, white, gray, image.Rect(0, 0, 1, 1))
+
+	decode := func(t *testing.T, diffBytes []byte) image.Image {
+		t.Helper()
+		diff, err := png.Decode(bytes.NewReader(diffBytes))
+		require.NoError(t, err)
+		return diff
+	}
+
+	// A difference of 5 is within the default threshold
+	diffBytes, err := CreateDiffImage(img1, img2, "")
+	require.NoError(t, err)
+	require.Equal(t, white, color.RGBAModel.Convert(decode(t, diffBytes).At(0, 0)))
+
+	threshold := 0
+	diffBytes, err = CreateDiffImage(img1, img2, "", CompareOptions{Threshold: &threshold, HighlightColor: &magenta})
+	require.NoError(t, err)
+	require.Equal(t, magenta, color.RGBAModel.Convert(decode(t, diffBytes).At(0, 0)))
+
+	// A line shifted by one pixel is antialiasing noise within a tolerance of 1
+	line1 := solidPNG(t, 10, 10, white, black, image.Rect(4, 0, 5, 10))
+	line2 := solidPNG(t, 10, 10, white, black, image.Rect(5, 0, 6, 10))
+
+	diffBytes, err = CreateDiffImage(line1, line2, "")
+	require.NoError(t, err)
+	require.Equal(t, defaultHighlightColor, color.RGBAModel.Convert(decode(t, diffBytes).At(4, 5)))
+
+	diffBytes, err = CreateDiffImage(line1, line2, "", CompareOptions{AntiAliasTolerance: 1})
+	require.NoError(t, err)
+	require.NotEqual(t, defaultHighlightColor, color.RGBAModel.Convert(decode(t, diffBytes).At(4, 5)))
+}
+
+func TestScaleImageBilinear(t *testing.T) {
+	t.Parallel()
+
+	src := image.NewRGBA(image.Rect(0, 0, 2, 1))
+	src.SetRGBA(0, 0, black)
+	src.SetRGBA(1, 0, white)
+
+	// Upscaling blends neighbouring pixels instead of duplicating them
+	scaled := scaleImageBilinear(src, 4, 1)
+	require.Equal(t, image.Rect(0, 0, 4, 1), scaled.Bounds())
+	require.Equal(t, black, color.RGBAModel.Convert(scaled.At(0, 0)))
+	require.Equal(t, white, color.RGBAModel.Convert(scaled.At(3, 0)))
+	mid := color.RGBAModel.Convert(scaled.At(1, 0)).(color.RGBA)
+	require.Greater(t, mid.R, black.R)
+	require.Less(t, mid.R, white.R)
+
+	nearest := scaleImage(src, 4, 1)
+	require.Equal(t, black, color.RGBAModel.Convert(nearest.At(1, 0)))
+
+	// Solid images stay solid
+	solid := image.NewRGBA(image.Rect(0, 0, 8, 8))
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 8; x++ {
+			solid.SetRGBA(x, y, white)
+		}
+	}
+	scaled = scaleImageBilinear(solid, 3, 5)
+	for y := 0; y < 5; y++ {
+		for x := 0; x < 3; x++ {
+			require.Equal(t, white, color.RGBAModel.Convert(scaled.At(x, y)))
+		}
+	}
+}
+
+func TestCompareImagesStrict(t *testing.T) {
+	t.Parallel()
+
+	small := solidPNG(t, 4, 4, white, white)
+	large := solidPNG(t, 8, 6, white, white)
+
+	// Lenient by default: the larger image is scaled down
+	similarity, err := CompareImages(small, large)
+	require.NoError(t, err)
+	require.Equal(t, 1.0, similarity)
+
+	_, err = CompareImages(small, large, CompareOptions{Strict: true})
+	require.ErrorIs(t, err, ErrDimensionMismatch)
+
+	var mismatch *DimensionMismatchError
+	require.ErrorAs(t, err, &mismatch)
+	require.Equal(t, image.Pt(4, 4), mismatch.First)
+	require.Equal(t, image.Pt(8, 6), mismatch.Second)
+	require.EqualError(t, err, "image dimension mismatch: first image is 4x4, second is 8x6")
+
+	_, err = CreateDiffImage(small, large, "", CompareOptions{Strict: true})
+	require.ErrorIs(t, err, ErrDimensionMismatch)
+}
@@ -70,6 +70,257 @@
     }
   };
   
-  console.log('[WebDriver] Injection script loaded');
+  // Track in-flight fetch and XMLHttpRequest requests for network idle detection,
+  // and buffer their requests and responses for the page's network handlers
+  // Guarded so re-injection doesn't wrap the patched functions again
+  if (!window.__webdriverNetwork) {
+    var network = window.__webdriverNetwork = {
+      inflight: 0,
+      lastActivity: Date.now(),
+      events: [],
+      dropped: 0
+    };
+    var maxNetworkEvents = 1000;
+    var requestStarted = function() {
+      network.inflight++;
+      network.lastActivity = Date.now();
+    };
+    var requestFinished = function() {
+      network.inflight = Math.max(0, network.inflight - 1);
+      network.lastActivity = Date.now();
+    };
+
+    var recordEvent = function(event) {
+      if (network.events.length < maxNetworkEvents) {
+        network.events.push(event);
+      } else {
+        network.dropped++;
+      }
+    };
+    var resolveURL = function(url) {
+      try {
+        return new URL(url, location.href).href;
+      } catch (e) {
+        return String(url);
+      }
+    };
+    var recordRequest = function(resourceType, method, url) {
+      var request = {
+        url: resolveURL(url),
+        method: String(method || 'GET').toUpperCase(),
+        resourceType: resourceType,
+        timestamp: Date.now(),
+        start: performance.now()
+      };
+      recordEvent({ event: 'request', request: request });
+      return request;
+    };
+    // Failed requests are reported with status 0
+    var recordResponse = function(request, status, statusText) {
+      recordEvent({
+        event: 'response',
+        request: request,
+        status: status,
+        statusText: statusText || '',
+        duration: performance.now() - request.start
+      });
+    };
+
+    // Routes added with context.route answer the requests matching them with a
+    // canned response instead of sending them. The last matching route wins
+    var findRoute = function(url) {
+      var routes = window.__webdriverRoutes || [];
+      for (var i = routes.length - 1; i >= 0; i--) {
+        try {
+          if (new RegExp(routes[i].pattern).test(url)) return routes[i];
+        } catch (e) {
+          // Go patterns JavaScript can't compile never match
+        }
+      }
+      return null;
+    };
+    var routeHeaders = function(route) {
+      var headers = {};
+      Object.keys(route.headers || {}).forEach(function(name) {
+        headers[name.toLowerCase()] = String(route.headers[name]);
+      });
+      if (route.contentType) headers['content-type'] = route.contentType;
+      return headers;
+    };
+    var routeBody = function(route) {
+      // Responses with these statuses can't have a body
+      var empty = route.abort || route.status === 204 || route.status === 205 || route.status === 304;
+      return empty ? null : route.body;
+    };
+    var fulfillFetch = function(route, url) {
+      return new Promise(function(resolve) {
+        if (route.abort) throw new TypeError('Load failed');
+        var response = new Response(routeBody(route), {
+          status: route.status,
+          statusText: route.statusText,
+          headers: routeHeaders(route)
+        });
+        Object.defineProperty(response, 'url', { value: url });
+        resolve(response);
+      });
+    };
+    // The XHR is never sent, so its response is set by shadowing the
+    // prototype's getters with properties of its own
+    var fulfillXHR = function(xhr, route, url) {
+      setTimeout(function() {
+        var headers = route.abort ? {} : routeHeaders(route);
+        var text = routeBody(route) || '';
+        var response = text;
+        if (xhr.responseType === 'json') {
+          try {
+            response = JSON.parse(text);
+          } catch (e) {
+            response = null;
+          }
+        } else if (xhr.responseType === 'blob') {
+          response = new Blob([text], { type: headers['content-type'] || '' });
+        } else if (xhr.responseType === 'arraybuffer') {
+          response = new TextEncoder().encode(text).buffer;
+        }
+        var properties = {
+          readyState: 4,
+          status: route.abort ? 0 : route.status,
+          statusText: route.abort ? '' : route.statusText,
+          responseURL: route.abort ? '' : url,
+          response: response,
+          responseText: text,
+          getResponseHeader: function(name) {
+            var value = headers[String(name).toLowerCase()];
+            return value === undefined ? null : value;
+          },
+          getAllResponseHeaders: function() {
+            return Object.keys(headers).map(function(name) {
+              return name + ': ' + headers[name] + '\r\n';
+            }).join('');
+          }
+        };
+        Object.keys(properties).forEach(function(name) {
+          Object.defineProperty(xhr, name, { value: properties[name], configurable: true });
+        });
+        xhr.dispatchEvent(new Event('readystatechange'));
+        xhr.dispatchEvent(new ProgressEvent(route.abort ? 'error' : 'load'));
+        xhr.dispatchEvent(new ProgressEvent('loadend'));
+      }, 0);
+    };
+
+    if (window.fetch) {
+      var originalFetch = window.fetch;
+      window.fetch = function(input, init) {
+        var method = (init && init.method) || (input && input.method);
+        var request = recordRequest('fetch', method, input && input.url ? input.url : input);
+        requestStarted();
+        var route = findRoute(request.url);
+        var pending = route ? fulfillFetch(route, request.url) : originalFetch.apply(this, arguments);
+        return pending.then(function(response) {
+          requestFinished();
+          recordResponse(request, response.status, response.statusText);
+          return response;
+        }, function(error) {
+          requestFinished();
+          recordResponse(request, 0, '');
+          throw error;
+        });
+      };
+    }
+
+    if (window.XMLHttpRequest) {
+      var originalOpen = XMLHttpRequest.prototype.open;
+      XMLHttpRequest.prototype.open = function(method, url) {
+        this.__webdriverRequest = { method: method, url: url };
+        return originalOpen.apply(this, arguments);
+      };
+
+      var originalSend = XMLHttpRequest.prototype.send;
+      XMLHttpRequest.prototype.send = function() {
+        requestStarted();
+        this.addEventListener('loadend', requestFinished);
+        // Requests made by the extension itself aren't reported
+        var opened = this.__webdriverRequest;
+        if (opened && !this.__webdriverInternal) {
+          var request = recordRequest('xhr', opened.method, opened.url);
+          this.addEventListener('loadend', function() {
+            recordResponse(request, this.status, this.statusText);
+          });
+          var route = findRoute(request.url);
+          if (route) {
+            fulfillXHR(this, route, request.url);
+            return;
+          }
+        }
+        return originalSend.apply(this, arguments);
+      };
+    }
+  }
+
+  // Buffer console messages until the extension collects them for the page's
+  // console handler. Guarded like the network tracking
+  if (!window.__webdriverConsole) {
+    var consoleBuffer = window.__webdriverConsole = { messages: [], dropped: 0, original: {} };
+    var maxConsoleMessages = 1000;
+
+    var formatArg = function(arg) {
+      if (typeof arg === 'string') return arg;
+      if (arg instanceof Error) return arg.stack || String(arg);
+      try {
+        var json = JSON.stringify(arg);
+        return json === undefined ? String(arg) : json;
+      } catch (e) {
+        return String(arg);
+      }
+    };
+
+    ['log', 'debug', 'info', 'warn', 'error'].forEach(function(method) {
+      var original = consoleBuffer.original[method] = console[method];
+      console[method] = function() {
+        if (consoleBuffer.messages.length < maxConsoleMessages) {
+          consoleBuffer.messages.push({
+            type: method === 'warn' ? 'warning' : method,
+            text: Array.prototype.map.call(arguments, formatArg).join(' '),
+            url: location.href
+          });
+        } else {
+          consoleBuffer.dropped++;
+        }
+        return original.apply(console, arguments);
+      };
+    });
+  }
+
+  // Buffer uncaught errors and unhandled promise rejections until the extension
+  // collects them for the page's error handler
+  if (!window.__webdriverPageErrors) {
+    var pageErrors = window.__webdriverPageErrors = { errors: [], dropped: 0 };
+    var maxPageErrors = 100;
+
+    var recordError = function(error, message, url, line, column) {
+      if (pageErrors.errors.length >= maxPageErrors) {
+        pageErrors.dropped++;
+        return;
+      }
+      pageErrors.errors.push({
+        message: (error && error.message) || message || String(error),
+        stack: (error && error.stack) || '',
+        url: url || location.href,
+        line: line || 0,
+        column: column || 0
+      });
+    };
+
+    window.addEventListener('error', function(event) {
+      recordError(event.error, event.message, event.filename, event.lineno, event.colno);
+    });
+    window.addEventListener('unhandledrejection', function(event) {
+      recordError(event.reason, 'Unhandled promise rejection: ' + String(event.reason));
+    });
+  }
+
+  // Logged with the original console.log so that it isn't reported to the
+  // page's console handler
+  window.__webdriverConsole.original.log.call(console, '[WebDriver] Injection script loaded');
 })();
 
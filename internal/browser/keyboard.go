@@ -0,0 +1,194 @@
+package browser
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/grafana/sobek"
+)
+
+// keyValues maps key names, as in KeyboardEvent.key, to the code points the
+// WebDriver Actions API uses for them. Meta is Command in Safari on macOS
+var keyValues = map[string]string{
+	"Backspace":  "\uE003",
+	"Tab":        "\uE004",
+	"Enter":      "\uE007",
+	"Shift":      "\uE008",
+	"Control":    "\uE009",
+	"Alt":        "\uE00A",
+	"Pause":      "\uE00B",
+	"Escape":     "\uE00C",
+	"Space":      " ",
+	"PageUp":     "\uE00E",
+	"PageDown":   "\uE00F",
+	"End":        "\uE010",
+	"Home":       "\uE011",
+	"ArrowLeft":  "\uE012",
+	"ArrowUp":    "\uE013",
+	"ArrowRight": "\uE014",
+	"ArrowDown":  "\uE015",
+	"Insert":     "\uE016",
+	"Delete":     "\uE017",
+	"F1":         "\uE031",
+	"F2":         "\uE032",
+	"F3":         "\uE033",
+	"F4":         "\uE034",
+	"F5":         "\uE035",
+	"F6":         "\uE036",
+	"F7":         "\uE037",
+	"F8":         "\uE038",
+	"F9":         "\uE039",
+	"F10":        "\uE03A",
+	"F11":        "\uE03B",
+	"F12":        "\uE03C",
+	"Meta":       "\uE03D",
+	"Command":    "\uE03D",
+	// The platform's shortcut modifier, Command as Safari only runs on macOS
+	"ControlOrMeta": "\uE03D",
+}
+
+// parseKeyCombination parses a key or a "+" joined combination of modifiers and
+// a key, like "Control+A" or "Shift+Tab", into the key values to press in order
+// A "+" key itself is written as the last part, like "Shift++"
+func parseKeyCombination(combination string) ([]string, error) {
+	if combination == "" {
+		return nil, fmt.Errorf("key must not be empty")
+	}
+
+	parts := strings.Split(combination, "+")
+	if strings.HasSuffix(combination, "++") || combination == "+" {
+		parts = append(parts[:len(parts)-2], "+")
+	}
+
+	values := make([]string, len(parts))
+	for i, part := range parts {
+		value, err := keyValue(part)
+		if err != nil {
+			return nil, fmt.Errorf("invalid key combination '%s': %w", combination, err)
+		}
+		values[i] = value
+	}
+	return values, nil
+}
+
+// keyValue returns the key value of a named key or a single character
+func keyValue(key string) (string, error) {
+	if value, ok := keyValues[key]; ok {
+		return value, nil
+	}
+	if utf8.RuneCountInString(key) == 1 {
+		return key, nil
+	}
+	return "", fmt.Errorf("unknown key '%s'", key)
+}
+
+// keyboardSource returns a keyboard input source performing the key actions
+func keyboardSource(actions []map[string]interface{}) map[string]interface{} {
+	return map[string]interface{}{
+		"type":    "key",
+		"id":      "keyboard",
+		"actions": actions,
+	}
+}
+
+// pressActions presses the keys in order and releases them in reverse order,
+// so modifiers are held while the last key is pressed
+func pressActions(values []string) []map[string]interface{} {
+	actions := make([]map[string]interface{}, 0, 2*len(values))
+	for _, value := range values {
+		actions = append(actions, map[string]interface{}{"type": "keyDown", "value": value})
+	}
+	for i := len(values) - 1; i >= 0; i-- {
+		actions = append(actions, map[string]interface{}{"type": "keyUp", "value": values[i]})
+	}
+	return actions
+}
+
+// focusScript focuses the element that receives the key presses
+const focusScript = `arguments[0].focus();`
+
+// focus waits for the element matched by the locator to be actionable and focuses it
+func (l *Locator) focus(ctx context.Context) error {
+	elementID, err := l.actionableElementID(ctx)
+	if err != nil {
+		return err
+	}
+	if _, err := l.page.client.ExecuteScript(ctx, focusScript, elementReferences([]string{elementID})); err != nil {
+		return fmt.Errorf("failed to focus element: %w", err)
+	}
+	return nil
+}
+
+// Press focuses the element matched by the locator and presses a key or a key
+// combination, like "Enter", "Control+A", "Meta+C" or "Shift+Tab"
+// Modifiers are held down while the key is pressed; Meta is Command
+func (l *Locator) Press(key string) (*sobek.Promise, error) {
+	values, err := parseKeyCombination(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return l.promise(func() (interface{}, error) {
+		if l.page.client == nil {
+			return nil, fmt.Errorf("browser session not initialized")
+		}
+
+		ctx := context.Background()
+		if err := l.focus(ctx); err != nil {
+			return nil, err
+		}
+
+		source := keyboardSource(pressActions(values))
+		if err := l.page.client.PerformActions(ctx, []map[string]interface{}{source}); err != nil {
+			return nil, fmt.Errorf("failed to press '%s': %w", key, err)
+		}
+
+		return nil, nil
+	}), nil
+}
+
+// PressSequentially focuses the element matched by the locator and presses the
+// key of each character of text, firing keydown, keypress and keyup events for
+// each. The delay option (ms) pauses between keystrokes
+func (l *Locator) PressSequentially(text string, options ...map[string]interface{}) (*sobek.Promise, error) {
+	var delay int64
+	if len(options) > 0 && options[0] != nil {
+		if ms, ok := toFloat64(options[0]["delay"]); ok && ms > 0 {
+			delay = int64(ms)
+		}
+	}
+
+	return l.promise(func() (interface{}, error) {
+		if l.page.client == nil {
+			return nil, fmt.Errorf("browser session not initialized")
+		}
+
+		ctx := context.Background()
+		if err := l.focus(ctx); err != nil {
+			return nil, err
+		}
+		if text == "" {
+			return nil, nil
+		}
+
+		// The pauses are performed by the driver, within a single command
+		var actions []map[string]interface{}
+		for i, char := range []rune(text) {
+			if i > 0 && delay > 0 {
+				actions = append(actions, map[string]interface{}{"type": "pause", "duration": delay})
+			}
+			actions = append(actions, pressActions([]string{string(char)})...)
+		}
+
+		source := keyboardSource(actions)
+		if err := l.page.client.PerformActions(ctx, []map[string]interface{}{source}); err != nil {
+			return nil, fmt.Errorf("failed to press keys: %w", err)
+		}
+
+		l.page.recordAction(TraceAction{Type: TraceActionType, Selector: l.selector, Text: text})
+
+		return nil, nil
+	}), nil
+}
@@ -0,0 +1,132 @@
+package browser
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"go.k6.io/k6/js/modulestest"
+)
+
+func TestParseKeyCombination(t *testing.T) {
+	tests := []struct {
+		combination string
+		expected    []string
+	}{
+		{"Enter", []string{"\uE007"}},
+		{"a", []string{"a"}},
+		{"Control+A", []string{"\uE009", "A"}},
+		{"Meta+C", []string{"\uE03D", "C"}},
+		{"Shift+Tab", []string{"\uE008", "\uE004"}},
+		{"Control+Shift+ArrowLeft", []string{"\uE009", "\uE008", "\uE012"}},
+		{"Shift++", []string{"\uE008", "+"}},
+		{"+", []string{"+"}},
+	}
+
+	for _, tt := range tests {
+		got, err := parseKeyCombination(tt.combination)
+		if err != nil {
+			t.Errorf("Unexpected error for %q: %v", tt.combination, err)
+			continue
+		}
+		if strings.Join(got, ",") != strings.Join(tt.expected, ",") {
+			t.Errorf("parseKeyCombination(%q) = %q, expected %q", tt.combination, got, tt.expected)
+		}
+	}
+
+	for _, invalid := range []string{"", "Control+", "Hyper+A", "Control+Enterprise"} {
+		if _, err := parseKeyCombination(invalid); err == nil {
+			t.Errorf("Expected an error for %q", invalid)
+		}
+	}
+}
+
+func TestLocatorPress(t *testing.T) {
+	var mu sync.Mutex
+	var requests []string
+	var actions [][]map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/element"):
+			_, _ = w.Write([]byte(`{"value":{"element-6066-11e4-a52e-4f735466cecf":"editor"}}`))
+		case strings.HasSuffix(r.URL.Path, "/actions"):
+			var payload struct {
+				Actions []struct {
+					Type    string                   `json:"type"`
+					Actions []map[string]interface{} `json:"actions"`
+				} `json:"actions"`
+			}
+			_ = json.NewDecoder(r.Body).Decode(&payload)
+			if len(payload.Actions) != 1 || payload.Actions[0].Type != "key" {
+				t.Errorf("Expected a single keyboard source, got %+v", payload.Actions)
+			} else {
+				actions = append(actions, payload.Actions[0].Actions)
+			}
+			requests = append(requests, "actions")
+			_, _ = w.Write([]byte(`{"value":null}`))
+		default:
+			var payload struct {
+				Script string `json:"script"`
+			}
+			_ = json.NewDecoder(r.Body).Decode(&payload)
+			if strings.Contains(payload.Script, "isConnected") {
+				requests = append(requests, "actionable")
+			} else {
+				requests = append(requests, "focus")
+			}
+			_, _ = w.Write([]byte(`{"value":null}`))
+		}
+	}))
+	defer server.Close()
+
+	runtime := modulestest.NewRuntime(t)
+	page := &Page{vu: runtime.VU, client: NewWebDriverClient(server.URL).forSession("session-1")}
+	if err := runtime.VU.Runtime().Set("page", page); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := runtime.RunOnEventLoop(`
+		var failure = "";
+		page.locator("#editor").press("Meta+A")
+			.then(function() { return page.locator("#editor").pressSequentially("hi", { delay: 50 }); })
+			.catch(function(e) { failure = String(e); });
+	`)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if failure := runtime.VU.Runtime().Get("failure").String(); failure != "" {
+		t.Fatalf("Unexpected failure: %s", failure)
+	}
+
+	if got := strings.Join(requests, ","); got != "actionable,focus,actions,actionable,focus,actions" {
+		t.Fatalf("Expected the element to be checked and focused before each press, got %s", got)
+	}
+
+	describe := func(actions []map[string]interface{}) string {
+		var parts []string
+		for _, action := range actions {
+			switch action["type"] {
+			case "pause":
+				parts = append(parts, "pause")
+			default:
+				parts = append(parts, action["type"].(string)+":"+action["value"].(string))
+			}
+		}
+		return strings.Join(parts, ",")
+	}
+
+	// The modifier is held down while the key is pressed
+	if got := describe(actions[0]); got != "keyDown:\uE03D,keyDown:A,keyUp:A,keyUp:\uE03D" {
+		t.Errorf("Unexpected press actions: %q", got)
+	}
+	if got := describe(actions[1]); got != "keyDown:h,keyUp:h,pause,keyDown:i,keyUp:i" {
+		t.Errorf("Unexpected sequential actions: %q", got)
+	}
+}
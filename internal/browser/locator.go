@@ -3,8 +3,15 @@ package browser
 import (
 	"context"
 	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/grafana/sobek"
+	"github.com/sirupsen/logrus"
 	"go.k6.io/k6/js/modules"
 )
 
@@ -12,93 +19,485 @@ import (
 type Locator struct {
 	page      *Page
 	selector  string
-	elementID string // If set, this locator refers to a specific element
+	elementID string         // If set, this locator refers to a specific element
+	parent    *Locator       // If set, the selector is matched within the parent's element
+	source    *Locator       // If set, this locator narrows down the elements matched by source
+	nth       *int           // If set, this locator refers to the nth element of source, negative counts from the end
+	hasText   *regexp.Regexp // If set, only elements of source whose text content matches are kept
+	frame     *Frame         // If set, the locator finds elements inside the frame
 	vu        modules.VU
 }
 
-// Click clicks on the element matched by the locator
-func (l *Locator) Click() (*sobek.Promise, error) {
-	return Promise(l.vu, func() (interface{}, error) {
+// promise runs fn asynchronously, with the session switched into the
+// locator's frame if it has one
+func (l *Locator) promise(fn func() (interface{}, error)) *sobek.Promise {
+	return l.page.promise(func() (interface{}, error) {
+		if l.frame == nil {
+			return fn()
+		}
+
+		var result interface{}
+		err := l.frame.within(context.Background(), func() error {
+			var err error
+			result, err = fn()
+			return err
+		})
+		return result, err
+	})
+}
+
+// resolveElementID returns the element this locator is bound to, or finds the
+// first element matching the selector now
+func (l *Locator) resolveElementID(ctx context.Context) (string, error) {
+	// If we already have a specific element ID, use it
+	if l.elementID != "" {
+		return l.elementID, nil
+	}
+
+	// Indexed locators pick one element among all matches
+	if l.nth != nil {
+		elementIDs, err := l.matchingElementIDs(ctx)
+		if err != nil {
+			return "", err
+		}
+
+		index, ok := resolveIndex(*l.nth, len(elementIDs))
+		if !ok {
+			return "", fmt.Errorf("no element at index %d for selector '%s' (found %d): %w", *l.nth, l.selector, len(elementIDs), ErrElementNotFound)
+		}
+		return elementIDs[index], nil
+	}
+
+	// Filtered locators pick the first remaining match
+	if l.source != nil {
+		elementIDs, err := l.resolveAllElementIDs(ctx)
+		if err != nil {
+			return "", err
+		}
+		if len(elementIDs) == 0 {
+			return "", fmt.Errorf("no element matching selector '%s' passes the filter: %w", l.selector, ErrElementNotFound)
+		}
+		return elementIDs[0], nil
+	}
+
+	// Scoped locators search within the parent's element
+	if l.parent != nil {
+		parentID, err := l.parent.resolveElementID(ctx)
+		if err != nil {
+			return "", err
+		}
+
+		elementID, err := l.page.client.FindElementFrom(ctx, parentID, l.selector)
+		if err != nil {
+			return "", fmt.Errorf("failed to find element with selector '%s' in '%s': %w", l.selector, l.parent.selector, err)
+		}
+		return elementID, nil
+	}
+
+	// Otherwise, find the element now
+	elementID, err := l.page.client.FindElement(ctx, l.selector)
+	if err != nil {
+		return "", fmt.Errorf("failed to find element with selector '%s': %w", l.selector, err)
+	}
+	return elementID, nil
+}
+
+// resolveAllElementIDs finds all elements this locator refers to now
+func (l *Locator) resolveAllElementIDs(ctx context.Context) ([]string, error) {
+	// A locator bound to an element only refers to that element
+	if l.elementID != "" {
+		return []string{l.elementID}, nil
+	}
+
+	elementIDs, err := l.matchingElementIDs(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if l.hasText != nil {
+		return l.filterByText(ctx, elementIDs)
+	}
+
+	if l.nth != nil {
+		index, ok := resolveIndex(*l.nth, len(elementIDs))
+		if !ok {
+			return []string{}, nil
+		}
+		return elementIDs[index : index+1], nil
+	}
+	return elementIDs, nil
+}
+
+// resolveIndex converts an index that may count from the end into a position
+// among n elements, reporting whether it is in range
+func resolveIndex(index, n int) (int, bool) {
+	if index < 0 {
+		index += n
+	}
+	return index, index >= 0 && index < n
+}
+
+// matchingElementIDs finds all elements matching the selector now, before
+// this locator's own narrowing is applied
+func (l *Locator) matchingElementIDs(ctx context.Context) ([]string, error) {
+	if l.source != nil {
+		return l.source.resolveAllElementIDs(ctx)
+	}
+
+	if l.parent == nil {
+		elementIDs, err := l.page.client.FindAllElements(ctx, l.selector)
+		if err != nil {
+			return nil, fmt.Errorf("failed to find elements with selector '%s': %w", l.selector, err)
+		}
+		return elementIDs, nil
+	}
+
+	parentID, err := l.parent.resolveElementID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	elementIDs, err := l.page.client.FindAllElementsFrom(ctx, parentID, l.selector)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find elements with selector '%s' in '%s': %w", l.selector, l.parent.selector, err)
+	}
+	return elementIDs, nil
+}
+
+// Locator returns a locator for the elements matching selector within this
+// locator's element, e.g. the Save button inside a specific card
+// Only CSS selectors are supported within a parent
+func (l *Locator) Locator(selector string) *Locator {
+	return &Locator{
+		page:     l.page,
+		selector: selector,
+		parent:   l,
+		frame:    l.frame,
+		vu:       l.vu,
+	}
+}
+
+// Nth returns a locator for the element at index among the matches of this
+// locator. Negative indexes count from the end, so -1 is the last match
+func (l *Locator) Nth(index int) *Locator {
+	return &Locator{
+		page:     l.page,
+		selector: l.selector,
+		source:   l,
+		nth:      &index,
+		frame:    l.frame,
+		vu:       l.vu,
+	}
+}
+
+// First returns a locator for the first match of this locator
+func (l *Locator) First() *Locator {
+	return l.Nth(0)
+}
+
+// Last returns a locator for the last match of this locator
+func (l *Locator) Last() *Locator {
+	return l.Nth(-1)
+}
+
+// Filter returns a locator that narrows down the matches of this locator
+// options.hasText keeps elements whose text content contains the string,
+// case-insensitively, or matches a /regex/
+func (l *Locator) Filter(options map[string]interface{}) (*Locator, error) {
+	filtered := &Locator{
+		page:     l.page,
+		selector: l.selector,
+		source:   l,
+		frame:    l.frame,
+		vu:       l.vu,
+	}
+
+	if hasText, ok := options["hasText"].(string); ok {
+		if IsRegex(hasText) {
+			re, err := ParseRegex(hasText)
+			if err != nil {
+				return nil, fmt.Errorf("invalid hasText pattern %s: %w", hasText, err)
+			}
+			filtered.hasText = re
+		} else {
+			filtered.hasText = regexp.MustCompile("(?i)" + regexp.QuoteMeta(hasText))
+		}
+	}
+
+	return filtered, nil
+}
+
+// EvaluateAll runs a JavaScript function with the array of all elements matching
+// the locator as its first argument, followed by args, in a single script call
+// script is a function expression, or a function body reading arguments[0]
+func (l *Locator) EvaluateAll(script string, args ...interface{}) (*sobek.Promise, error) {
+	return l.promise(func() (interface{}, error) {
 		if l.page.client == nil {
 			return nil, fmt.Errorf("browser session not initialized")
 		}
 
 		ctx := context.Background()
+		elementIDs, err := l.resolveAllElementIDs(ctx)
+		if err != nil {
+			return nil, err
+		}
 
-		// If we already have a specific element ID, use it
-		var elementID string
-		var err error
-		if l.elementID != "" {
-			elementID = l.elementID
-		} else {
-			// Otherwise, find the element now
-			elementID, err = l.page.client.FindElement(ctx, l.selector)
-			if err != nil {
-				return nil, fmt.Errorf("failed to find element with selector '%s': %w", l.selector, err)
-			}
+		scriptArgs := append([]interface{}{elementReferences(elementIDs)}, args...)
+		result, err := l.page.client.ExecuteScript(ctx, functionCallScript(script), scriptArgs)
+		if err != nil {
+			return nil, fmt.Errorf("failed to evaluate script for selector '%s': %w", l.selector, err)
+		}
+
+		return result, nil
+	}), nil
+}
+
+// filterByText keeps the elements whose text content matches hasText
+func (l *Locator) filterByText(ctx context.Context, elementIDs []string) ([]string, error) {
+	texts, err := l.page.client.textContents(ctx, elementIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	filtered := make([]string, 0, len(elementIDs))
+	for i, elementID := range elementIDs {
+		if l.hasText.MatchString(texts[i]) {
+			filtered = append(filtered, elementID)
+		}
+	}
+
+	return filtered, nil
+}
+
+// AllTextContents returns the text content of every element matching the locator
+func (l *Locator) AllTextContents() (*sobek.Promise, error) {
+	return l.promise(func() (interface{}, error) {
+		if l.page.client == nil {
+			return nil, fmt.Errorf("browser session not initialized")
 		}
 
-		err = l.page.client.ClickElement(ctx, elementID)
+		ctx := context.Background()
+		elementIDs, err := l.resolveAllElementIDs(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		return l.page.client.textContents(ctx, elementIDs)
+	}), nil
+}
+
+// Click clicks on the element matched by the locator
+func (l *Locator) Click() (*sobek.Promise, error) {
+	return l.promise(func() (interface{}, error) {
+		if l.page.client == nil {
+			return nil, fmt.Errorf("browser session not initialized")
+		}
+
+		ctx := context.Background()
+
+		err := l.withActionableElement(ctx, func(elementID string) error {
+			return l.page.client.ClickElement(ctx, elementID)
+		})
 		if err != nil {
 			return nil, fmt.Errorf("failed to click element: %w", err)
 		}
 
+		l.page.recordAction(TraceAction{Type: TraceActionClick, Selector: l.selector})
+
 		return nil, nil
 	}), nil
 }
 
-// Count returns the number of elements matching the locator
-func (l *Locator) Count() (*sobek.Promise, error) {
-	return Promise(l.vu, func() (interface{}, error) {
+// ClickAndWaitForNavigation clicks the element matched by the locator and waits
+// for the navigation it triggers, e.g. submitting a form, so that what follows
+// runs against the new document rather than the old one
+// Options: waitUntil and networkIdleTime like Goto, timeout (ms, default
+// DefaultTimeout) for the navigation to start
+func (l *Locator) ClickAndWaitForNavigation(options map[string]interface{}) (*sobek.Promise, error) {
+	navOptions := navigateOptionsFrom(options)
+	if navOptions == nil {
+		navOptions = &NavigateOptions{WaitUntil: "load"}
+	}
+	switch navOptions.WaitUntil {
+	case "load", "domcontentloaded", "networkidle", "commit":
+	default:
+		return nil, fmt.Errorf("invalid waitUntil option: %s", navOptions.WaitUntil)
+	}
+	timeout := timeoutFromOptions(options)
+	if timeout <= 0 {
+		timeout = DefaultTimeout()
+	}
+
+	return l.promise(func() (interface{}, error) {
 		if l.page.client == nil {
 			return nil, fmt.Errorf("browser session not initialized")
 		}
 
 		ctx := context.Background()
-		count, err := l.page.client.FindElements(ctx, l.selector)
+
+		err := l.page.clickAndWaitForNavigation(ctx, func() error {
+			return l.withActionableElement(ctx, func(elementID string) error {
+				return l.page.client.ClickElement(ctx, elementID)
+			})
+		}, navOptions, timeout)
 		if err != nil {
-			return nil, fmt.Errorf("failed to find elements with selector '%s': %w", l.selector, err)
+			return nil, fmt.Errorf("failed to click '%s' and wait for navigation: %w", l.selector, err)
 		}
 
-		return count, nil
+		l.page.recordAction(TraceAction{Type: TraceActionClick, Selector: l.selector, WaitUntil: navOptions.WaitUntil})
+
+		return nil, nil
 	}), nil
 }
 
+// Count returns the number of elements matching the locator. With options, it
+// first waits until at least min elements match (default 1), up to timeout, so
+// counting doesn't race with rendering
+func (l *Locator) Count(options ...map[string]interface{}) (*sobek.Promise, error) {
+	var opts map[string]interface{}
+	if len(options) > 0 {
+		opts = options[0]
+	}
+	var condition *CountCondition
+	if opts != nil {
+		minimum := 1
+		if v, ok := toFloat64(opts["min"]); ok {
+			if v < 0 {
+				return nil, fmt.Errorf("count min must not be negative, got %v", v)
+			}
+			minimum = int(v)
+		}
+		condition = &CountCondition{Operator: ">=", Count: minimum}
+	}
+
+	return l.promise(func() (interface{}, error) {
+		if l.page.client == nil {
+			return nil, fmt.Errorf("browser session not initialized")
+		}
+
+		ctx := context.Background()
+		if condition == nil {
+			return l.count(ctx)
+		}
+
+		timeout := timeoutFromOptions(opts)
+		if timeout <= 0 {
+			timeout = DefaultTimeout()
+		}
+		var last int
+		err := l.page.client.waitForCount(ctx, l.selector, *condition, timeout, func() (int, error) {
+			n, err := l.count(ctx)
+			last = n
+			return n, err
+		})
+		if err != nil {
+			return nil, fmt.Errorf("count failed for selector '%s': %w", l.selector, err)
+		}
+		return last, nil
+	}), nil
+}
+
+// count returns the number of elements the locator refers to now
+func (l *Locator) count(ctx context.Context) (int, error) {
+	// Plain selectors are counted in the page without resolving the elements
+	if l.elementID == "" && l.parent == nil && l.source == nil {
+		return l.page.client.FindElements(ctx, l.selector)
+	}
+
+	elementIDs, err := l.resolveAllElementIDs(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	return len(elementIDs), nil
+}
+
 // All returns all elements matching the locator as an array of Locators
 func (l *Locator) All() (*sobek.Promise, error) {
-	return Promise(l.vu, func() (interface{}, error) {
+	return l.promise(func() (interface{}, error) {
 		if l.page.client == nil {
 			return nil, fmt.Errorf("browser session not initialized")
 		}
 
 		ctx := context.Background()
-		elementIDs, err := l.page.client.FindAllElements(ctx, l.selector)
+		elementIDs, err := l.resolveAllElementIDs(ctx)
 		if err != nil {
-			return nil, fmt.Errorf("failed to find elements with selector '%s': %w", l.selector, err)
+			return nil, err
 		}
 
 		// Create a locator for each specific element
 		locators := make([]*Locator, len(elementIDs))
 		for i, elementID := range elementIDs {
-			locators[i] = &Locator{
-				page:      l.page,
-				selector:  l.selector,
-				elementID: elementID,
-				vu:        l.vu,
-			}
+			locators[i] = l.pinned(elementID)
 		}
 
 		return locators, nil
 	}), nil
 }
 
+// ElementHandle resolves the element matched by the locator once, and returns
+// a locator bound to it. Its methods act on that element without finding it
+// again, until it's removed from the document and its reference goes stale
+func (l *Locator) ElementHandle() (*sobek.Promise, error) {
+	return l.promise(func() (interface{}, error) {
+		if l.page.client == nil {
+			return nil, fmt.Errorf("browser session not initialized")
+		}
+
+		elementID, err := l.resolveElementID(context.Background())
+		if err != nil {
+			return nil, err
+		}
+
+		return l.pinned(elementID), nil
+	}), nil
+}
+
+// pinned returns a locator for a specific element matched by this locator
+func (l *Locator) pinned(elementID string) *Locator {
+	return &Locator{
+		page:      l.page,
+		selector:  l.selector,
+		elementID: elementID,
+		parent:    l.parent,
+		frame:     l.frame,
+		vu:        l.vu,
+	}
+}
+
 // WaitFor waits for the locator to satisfy the given state
 func (l *Locator) WaitFor(options map[string]interface{}) (*sobek.Promise, error) {
-	return Promise(l.vu, func() (interface{}, error) {
+	return l.promise(func() (interface{}, error) {
 		if l.page.client == nil {
 			return nil, fmt.Errorf("browser session not initialized")
 		}
 
+		ctx := context.Background()
+
+		// Scoped and narrowed locators can't be expressed as a single selector,
+		// so their elements are resolved again on every poll. Locators bound to
+		// an element check that element rather than the selector's matches
+		resolved := l.elementID != "" || l.parent != nil || l.source != nil
+
+		// A count condition takes precedence over the state
+		if options != nil && options["count"] != nil {
+			condition, err := countConditionFromOption(options["count"])
+			if err != nil {
+				return nil, err
+			}
+
+			if resolved {
+				err = l.waitForResolvedCount(ctx, condition, timeoutFromOptions(options))
+			} else {
+				err = l.page.client.WaitForCount(ctx, l.selector, condition, timeoutFromOptions(options))
+			}
+			if err != nil {
+				return nil, fmt.Errorf("waitFor failed for selector '%s': %w", l.selector, err)
+			}
+
+			return nil, nil
+		}
+
 		// Parse state option (default: "visible")
 		state := "visible"
 		if options != nil {
@@ -107,8 +506,12 @@ func (l *Locator) WaitFor(options map[string]interface{}) (*sobek.Promise, error
 			}
 		}
 
-		ctx := context.Background()
-		err := l.page.client.WaitForSelector(ctx, l.selector, state)
+		var err error
+		if resolved {
+			err = l.waitForResolvedState(ctx, state, timeoutFromOptions(options))
+		} else {
+			err = l.page.client.WaitForSelector(ctx, l.selector, state, timeoutFromOptions(options))
+		}
 		if err != nil {
 			return nil, fmt.Errorf("waitFor failed for selector '%s': %w", l.selector, err)
 		}
@@ -117,26 +520,90 @@ func (l *Locator) WaitFor(options map[string]interface{}) (*sobek.Promise, error
 	}), nil
 }
 
+// waitForResolvedCount waits until the number of elements this locator refers to
+// satisfies the condition
+func (l *Locator) waitForResolvedCount(ctx context.Context, condition CountCondition, timeout time.Duration) error {
+	if timeout <= 0 {
+		timeout = DefaultTimeout()
+	}
+
+	return l.page.client.waitForCount(ctx, l.selector, condition, timeout, func() (int, error) {
+		elementIDs, err := l.resolveAllElementIDs(ctx)
+		return len(elementIDs), err
+	})
+}
+
+// waitForResolvedState waits until the first element this locator refers to is in the state
+func (l *Locator) waitForResolvedState(ctx context.Context, state string, timeout time.Duration) error {
+	if timeout <= 0 {
+		timeout = DefaultTimeout()
+	}
+
+	// A missing element is passed as null, which the state check handles
+	script := generateStateCheckScript(`arguments[0]`, state)
+
+	ctxWithTimeout, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctxWithTimeout.Done():
+			return fmt.Errorf("%w waiting for selector '%s' to be %s after %s", ErrTimeout, l.selector, state, timeout)
+		case <-ticker.C:
+			elementIDs, err := l.resolveAllElementIDs(ctx)
+			if err != nil {
+				// Continue polling on error, e.g. while the parent is missing
+				continue
+			}
+
+			var element interface{}
+			if len(elementIDs) > 0 {
+				element = map[string]string{"element-6066-11e4-a52e-4f735466cecf": elementIDs[0]}
+			}
+
+			result, err := l.page.client.ExecuteScript(ctx, script, []interface{}{element})
+			if err != nil && element != nil && isStaleElement(err) {
+				// A bound element removed from the document is missing
+				result, err = l.page.client.ExecuteScript(ctx, script, []interface{}{nil})
+			}
+			if err != nil {
+				continue
+			}
+
+			if satisfied, ok := result.(bool); ok && satisfied {
+				return nil
+			}
+		}
+	}
+}
+
+// countConditionFromOption parses the count option of WaitFor, which is either
+// an exact number or a string with a comparison operator such as ">= 5"
+func countConditionFromOption(v interface{}) (CountCondition, error) {
+	if n, ok := toFloat64(v); ok {
+		return ParseCountCondition(strconv.Itoa(int(n)))
+	}
+	if str, ok := v.(string); ok {
+		return ParseCountCondition(str)
+	}
+	return CountCondition{}, fmt.Errorf("invalid count condition: %v", v)
+}
+
 // TextContent returns the text content of the element
 func (l *Locator) TextContent() (*sobek.Promise, error) {
-	return Promise(l.vu, func() (interface{}, error) {
+	return l.promise(func() (interface{}, error) {
 		if l.page.client == nil {
 			return nil, fmt.Errorf("browser session not initialized")
 		}
 
 		ctx := context.Background()
 
-		// If we already have a specific element ID, use it
-		var elementID string
-		var err error
-		if l.elementID != "" {
-			elementID = l.elementID
-		} else {
-			// Otherwise, find the element now
-			elementID, err = l.page.client.FindElement(ctx, l.selector)
-			if err != nil {
-				return nil, fmt.Errorf("failed to find element with selector '%s': %w", l.selector, err)
-			}
+		elementID, err := l.resolveElementID(ctx)
+		if err != nil {
+			return nil, err
 		}
 
 		// Get the text content using JavaScript
@@ -157,47 +624,422 @@ func (l *Locator) TextContent() (*sobek.Promise, error) {
 }
 
 // Type types text into the element character by character
+// With the delay option (ms), characters are sent one at a time, delay apart,
+// like a user typing; otherwise the text is sent in a single command
 func (l *Locator) Type(text string, options ...map[string]interface{}) (*sobek.Promise, error) {
-	return Promise(l.vu, func() (interface{}, error) {
+	var delay time.Duration
+	if len(options) > 0 && options[0] != nil {
+		if ms, ok := toFloat64(options[0]["delay"]); ok && ms > 0 {
+			delay = time.Duration(ms * float64(time.Millisecond))
+		}
+	}
+
+	return l.promise(func() (interface{}, error) {
 		if l.page.client == nil {
 			return nil, fmt.Errorf("browser session not initialized")
 		}
 
 		ctx := context.Background()
 
-		// If we already have a specific element ID, use it
-		var elementID string
-		var err error
-		if l.elementID != "" {
-			elementID = l.elementID
-		} else {
-			// Otherwise, find the element now
-			elementID, err = l.page.client.FindElement(ctx, l.selector)
+		// If the element goes stale midway, typing into the element found again
+		// resumes after the characters already sent
+		chars, sent := []rune(text), 0
+		err := l.withActionableElement(ctx, func(elementID string) error {
+			if delay == 0 {
+				return l.page.client.SendKeys(ctx, elementID, text)
+			}
+			for ; sent < len(chars); sent++ {
+				if sent > 0 {
+					time.Sleep(delay)
+				}
+				if err := l.page.client.SendKeys(ctx, elementID, string(chars[sent])); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to type text: %w", err)
+		}
+
+		l.page.recordAction(TraceAction{Type: TraceActionType, Selector: l.selector, Text: text})
+
+		return nil, nil
+	}), nil
+}
+
+// dispatchEventScript dispatches a synthetic event on the element, created with
+// the constructor matching its type so that e.g. clientX is kept for mouse
+// events. Events bubble and are cancelable unless eventInit says otherwise
+const dispatchEventScript = `
+	var el = arguments[0], type = arguments[1];
+	var init = Object.assign({ bubbles: true, cancelable: true, composed: true }, arguments[2] || {});
+	var constructors = [
+		[/^(auxclick|click|contextmenu|dblclick|mouse.*)$/, 'MouseEvent'],
+		[/^pointer/, 'PointerEvent'],
+		[/^drag|^drop$/, 'DragEvent'],
+		[/^key/, 'KeyboardEvent'],
+		[/^(focus|blur|focusin|focusout)$/, 'FocusEvent'],
+		[/^(input|beforeinput)$/, 'InputEvent'],
+		[/^wheel$/, 'WheelEvent'],
+		[/^touch/, 'TouchEvent']
+	];
+	var name = 'detail' in init ? 'CustomEvent' : 'Event';
+	for (var i = 0; i < constructors.length; i++) {
+		if (constructors[i][0].test(type) && typeof window[constructors[i][1]] === 'function') {
+			name = constructors[i][1];
+			break;
+		}
+	}
+	el.dispatchEvent(new window[name](type, init));
+`
+
+// DispatchEvent dispatches a synthetic DOM event of the type on the element
+// matched by the locator, with the properties of eventInit, e.g. for drag and
+// drop or custom component events the other methods don't cover
+func (l *Locator) DispatchEvent(eventType string, eventInit ...map[string]interface{}) (*sobek.Promise, error) {
+	if eventType == "" {
+		return nil, fmt.Errorf("event type must not be empty")
+	}
+	var init map[string]interface{}
+	if len(eventInit) > 0 {
+		init = eventInit[0]
+	}
+
+	return l.promise(func() (interface{}, error) {
+		if l.page.client == nil {
+			return nil, fmt.Errorf("browser session not initialized")
+		}
+
+		ctx := context.Background()
+
+		elementID, err := l.resolveElementID(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		args := append(elementReferences([]string{elementID}), eventType, init)
+		if _, err := l.page.client.ExecuteScript(ctx, dispatchEventScript, args); err != nil {
+			return nil, fmt.Errorf("failed to dispatch %s event: %w", eventType, err)
+		}
+
+		return nil, nil
+	}), nil
+}
+
+// defaultHighlightDuration is how long Highlight keeps elements highlighted
+const defaultHighlightDuration = 2 * time.Second
+
+// highlightScript outlines the elements in arguments[0] in red and covers them
+// with a translucent box. The highlight fades out after arguments[1]
+// milliseconds, or with arguments[2] set, stays until the next click, key
+// press or input. Returns what identifies each element, for logging.
+const highlightScript = `
+	var elements = arguments[0], duration = arguments[1], persist = arguments[2];
+	var cleanups = [], boxes = [], infos = [];
+	elements.forEach(function(el) {
+		var rect = el.getBoundingClientRect();
+		var outline = el.style.outline;
+		el.style.outline = '2px solid red';
+		var box = document.createElement('div');
+		box.setAttribute('data-xk6-highlight', '');
+		box.style.cssText = 'position:fixed;pointer-events:none;z-index:2147483647;' +
+			'background:rgba(255,0,0,0.2);transition:opacity 0.5s;' +
+			'left:' + rect.left + 'px;top:' + rect.top + 'px;width:' + rect.width + 'px;height:' + rect.height + 'px;';
+		document.documentElement.appendChild(box);
+		boxes.push(box);
+		cleanups.push(function() { el.style.outline = outline; box.remove(); });
+		infos.push({
+			tagName: el.tagName,
+			id: el.id,
+			className: el.getAttribute('class') || '',
+			text: el.textContent ? el.textContent.substring(0, 50) : ''
+		});
+	});
+	var clear = function() { cleanups.forEach(function(cleanup) { cleanup(); }); };
+	if (persist) {
+		['click', 'keydown', 'input'].forEach(function(type) {
+			document.addEventListener(type, clear, { capture: true, once: true });
+		});
+	} else {
+		setTimeout(function() {
+			boxes.forEach(function(box) { box.style.opacity = '0'; });
+			setTimeout(clear, 500);
+		}, duration);
+	}
+	return infos;
+`
+
+// Highlight outlines the elements matched by the locator and covers them with a
+// fading red box, to see which elements a selector picks while writing a test
+// The "duration" option sets how long the highlight lasts in milliseconds, and
+// "persist" keeps it until the next click, key press or input instead
+func (l *Locator) Highlight(options ...map[string]interface{}) (*sobek.Promise, error) {
+	duration := defaultHighlightDuration
+	persist := false
+	if len(options) > 0 && options[0] != nil {
+		if ms, ok := toFloat64(options[0]["duration"]); ok && ms > 0 {
+			duration = time.Duration(ms * float64(time.Millisecond))
+		}
+		persist, _ = options[0]["persist"].(bool)
+	}
+
+	return l.promise(func() (interface{}, error) {
+		if l.page.client == nil {
+			return nil, fmt.Errorf("browser session not initialized")
+		}
+
+		ctx := context.Background()
+		elementIDs, err := l.resolveAllElementIDs(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		args := []interface{}{elementReferences(elementIDs), duration.Milliseconds(), persist}
+		infos, err := l.page.client.ExecuteScript(ctx, highlightScript, args)
+		if err != nil {
+			return nil, fmt.Errorf("failed to highlight elements for selector '%s': %w", l.selector, err)
+		}
+
+		logf(l.vu, logrus.DebugLevel, "highlighted %d element(s) matching '%s': %v", len(elementIDs), l.selector, infos)
+
+		return nil, nil
+	}), nil
+}
+
+// editableScript returns whether the element's value can be edited by the user
+const editableScript = `
+	var el = arguments[0];
+	if (el.isContentEditable) return true;
+	if (el.disabled || el.readOnly) return false;
+	if (el.tagName === 'TEXTAREA') return true;
+	if (el.tagName !== 'INPUT') return false;
+	var nonText = ['button', 'checkbox', 'file', 'hidden', 'image', 'radio', 'reset', 'submit'];
+	return nonText.indexOf(el.type) === -1;
+`
+
+// inputEventsScript fires the events of a user editing the element, so that
+// reactive frameworks pick up its new value
+const inputEventsScript = `
+	var el = arguments[0];
+	el.dispatchEvent(new Event('input', { bubbles: true }));
+	el.dispatchEvent(new Event('change', { bubbles: true }));
+`
+
+// Clear empties the input, textarea or contenteditable element matched by the locator
+func (l *Locator) Clear() (*sobek.Promise, error) {
+	return l.promise(func() (interface{}, error) {
+		if l.page.client == nil {
+			return nil, fmt.Errorf("browser session not initialized")
+		}
+
+		ctx := context.Background()
+
+		elementID, err := l.actionableElementID(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		ref := elementReferences([]string{elementID})
+		result, err := l.page.client.ExecuteScript(ctx, editableScript, ref)
+		if err != nil {
+			return nil, fmt.Errorf("failed to inspect element: %w", err)
+		}
+		if editable, _ := result.(bool); !editable {
+			return nil, fmt.Errorf("element matching '%s' is not editable", l.selector)
+		}
+
+		if err := l.page.client.ClearElement(ctx, elementID); err != nil {
+			return nil, fmt.Errorf("failed to clear element: %w", err)
+		}
+		if _, err := l.page.client.ExecuteScript(ctx, inputEventsScript, ref); err != nil {
+			return nil, fmt.Errorf("failed to dispatch input events: %w", err)
+		}
+
+		return nil, nil
+	}), nil
+}
+
+// fileInputScript describes the element as a file input
+const fileInputScript = `
+	var el = arguments[0];
+	return { isFileInput: el.tagName === 'INPUT' && el.type === 'file', multiple: el.multiple === true };
+`
+
+// clearFileInputScript removes the selected files of a file input, firing the
+// events a user clearing it would
+const clearFileInputScript = `
+	var el = arguments[0];
+	el.value = '';
+	el.dispatchEvent(new Event('input', { bubbles: true }));
+	el.dispatchEvent(new Event('change', { bubbles: true }));
+`
+
+// SetInputFiles sets the files of the <input type="file"> matched by the locator
+// Relative paths are resolved against the working directory; an empty list clears the input
+func (l *Locator) SetInputFiles(paths []string) (*sobek.Promise, error) {
+	return l.promise(func() (interface{}, error) {
+		if l.page.client == nil {
+			return nil, fmt.Errorf("browser session not initialized")
+		}
+
+		absPaths := make([]string, len(paths))
+		for i, path := range paths {
+			absPath, err := filepath.Abs(path)
 			if err != nil {
-				return nil, fmt.Errorf("failed to find element with selector '%s': %w", l.selector, err)
+				return nil, fmt.Errorf("failed to resolve file path '%s': %w", path, err)
+			}
+			if info, err := os.Stat(absPath); err != nil {
+				return nil, fmt.Errorf("failed to read file '%s': %w", path, err)
+			} else if info.IsDir() {
+				return nil, fmt.Errorf("'%s' is a directory", path)
 			}
+			absPaths[i] = absPath
+		}
+
+		ctx := context.Background()
+
+		elementID, err := l.resolveElementID(ctx)
+		if err != nil {
+			return nil, err
 		}
 
-		// Parse delay option (default: 0ms between keystrokes)
-		// Note: delay is acknowledged but not implemented due to WebDriver limitations
-		_ = 0 // delay placeholder
-		if len(options) > 0 && options[0] != nil {
-			if delayVal, ok := options[0]["delay"].(float64); ok {
-				_ = int(delayVal)
-			} else if delayVal, ok := options[0]["delay"].(int); ok {
-				_ = delayVal
+		ref := elementReferences([]string{elementID})
+		result, err := l.page.client.ExecuteScript(ctx, fileInputScript, ref)
+		if err != nil {
+			return nil, fmt.Errorf("failed to inspect element: %w", err)
+		}
+		info, _ := result.(map[string]interface{})
+		if isFileInput, _ := info["isFileInput"].(bool); !isFileInput {
+			return nil, fmt.Errorf("element matching '%s' is not an <input type=\"file\">", l.selector)
+		}
+		if multiple, _ := info["multiple"].(bool); !multiple && len(absPaths) > 1 {
+			return nil, fmt.Errorf("file input matching '%s' doesn't accept multiple files", l.selector)
+		}
+
+		if len(absPaths) == 0 {
+			if _, err := l.page.client.ExecuteScript(ctx, clearFileInputScript, ref); err != nil {
+				return nil, fmt.Errorf("failed to clear file input: %w", err)
+			}
+			return nil, nil
+		}
+
+		// WebDriver sets the files of a file input from its newline separated paths
+		if err := l.page.client.SendKeys(ctx, elementID, strings.Join(absPaths, "\n")); err != nil {
+			return nil, fmt.Errorf("failed to set input files: %w", err)
+		}
+
+		return nil, nil
+	}), nil
+}
+
+// IsInViewport returns whether the element's bounding box intersects the current viewport
+// An optional ratio (0-1] sets the fraction of the element that must be inside the viewport
+func (l *Locator) IsInViewport(options ...map[string]interface{}) (*sobek.Promise, error) {
+	// Parse ratio option (default: any part of the element is visible)
+	ratio := 0.0
+	if len(options) > 0 && options[0] != nil {
+		if ratioVal, ok := toFloat64(options[0]["ratio"]); ok {
+			if ratioVal <= 0 || ratioVal > 1 {
+				return nil, fmt.Errorf("ratio must be greater than 0 and at most 1, got %v", ratioVal)
 			}
+			ratio = ratioVal
+		}
+	}
+
+	return l.promise(func() (interface{}, error) {
+		if l.page.client == nil {
+			return nil, fmt.Errorf("browser session not initialized")
 		}
 
-		// Use WebDriver's SendKeys command to type text
-		err = l.page.client.SendKeys(ctx, elementID, text)
+		ctx := context.Background()
+		elementID, err := l.resolveElementID(ctx)
 		if err != nil {
-			return nil, fmt.Errorf("failed to type text: %w", err)
+			return nil, err
 		}
 
-		// Note: WebDriver's SendKeys sends all text at once
-		// Per-character delays are not supported natively by WebDriver
+		script := `
+			var element = arguments[0];
+			var ratio = arguments[1];
+			if (!element) return false;
+			var rect = element.getBoundingClientRect();
+			if (rect.width === 0 || rect.height === 0) return false;
+			var visibleWidth = Math.min(rect.right, window.innerWidth) - Math.max(rect.left, 0);
+			var visibleHeight = Math.min(rect.bottom, window.innerHeight) - Math.max(rect.top, 0);
+			if (visibleWidth <= 0 || visibleHeight <= 0) return false;
+			return (visibleWidth * visibleHeight) / (rect.width * rect.height) >= ratio;
+		`
+
+		elementRef := map[string]string{"element-6066-11e4-a52e-4f735466cecf": elementID}
+		result, err := l.page.client.ExecuteScript(ctx, script, []interface{}{elementRef, ratio})
+		if err != nil {
+			return nil, fmt.Errorf("failed to check viewport intersection: %w", err)
+		}
+
+		inViewport, _ := result.(bool)
+		return inViewport, nil
+	}), nil
+}
+
+// WaitForAnimationEnd waits until the element and its descendants have no running
+// CSS animations or transitions, e.g. after a click that triggers an expand animation
+func (l *Locator) WaitForAnimationEnd(options ...map[string]interface{}) (*sobek.Promise, error) {
+	return l.promise(func() (interface{}, error) {
+		if l.page.client == nil {
+			return nil, fmt.Errorf("browser session not initialized")
+		}
+
+		var timeout time.Duration
+		if len(options) > 0 {
+			timeout = timeoutFromOptions(options[0])
+		}
+
+		ctx := context.Background()
+		elementID, err := l.resolveElementID(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		err = l.page.client.WaitForAnimationEnd(ctx, elementID, timeout)
+		if err != nil {
+			return nil, fmt.Errorf("waitForAnimationEnd failed for selector '%s': %w", l.selector, err)
+		}
 
 		return nil, nil
 	}), nil
 }
+
+// ExpectScreenshot screenshots the element and compares it against the baseline
+// image at baselinePath, creating the baseline on first run. The promise resolves
+// with the comparison result, or rejects with a ScreenshotMismatchError when the
+// screenshot doesn't match.
+func (l *Locator) ExpectScreenshot(baselinePath string, opts ...BaselineOptions) (*sobek.Promise, error) {
+	return l.promise(func() (interface{}, error) {
+		if l.page.client == nil {
+			return nil, fmt.Errorf("browser session not initialized")
+		}
+
+		ctx := context.Background()
+
+		elementID, err := l.resolveElementID(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		screenshot, err := l.page.client.TakeElementScreenshot(ctx, elementID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to take element screenshot: %w", err)
+		}
+
+		dir, name := splitBaselinePath(baselinePath)
+		result, err := CompareAgainstBaseline(name, screenshot, dir, opts...)
+		if err != nil {
+			return nil, err
+		}
+		if !result.Passed {
+			return nil, &ScreenshotMismatchError{Result: result}
+		}
+
+		return result, nil
+	}), nil
+}
@@ -1,7 +1,20 @@
 package browser
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
 	"testing"
+	"time"
+
+	"go.k6.io/k6/js/modulestest"
 )
 
 func TestLocatorCreation(t *testing.T) {
@@ -76,3 +89,1085 @@ func TestLocatorType(t *testing.T) {
 		t.Fatal("Expected locator to be created")
 	}
 }
+
+func TestLocatorIsInViewportRatio(t *testing.T) {
+	var mu sync.Mutex
+	var ratios []interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if strings.HasSuffix(r.URL.Path, "/element") {
+			_, _ = w.Write([]byte(`{"value":{"element-6066-11e4-a52e-4f735466cecf":"hero"}}`))
+			return
+		}
+
+		var payload struct {
+			Args []interface{} `json:"args"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&payload)
+		mu.Lock()
+		ratios = append(ratios, payload.Args[1])
+		mu.Unlock()
+		_, _ = w.Write([]byte(`{"value":true}`))
+	}))
+	defer server.Close()
+
+	runtime := modulestest.NewRuntime(t)
+	page := &Page{vu: runtime.VU, client: NewWebDriverClient(server.URL).forSession("session-1")}
+	if err := runtime.VU.Runtime().Set("page", page); err != nil {
+		t.Fatal(err)
+	}
+
+	// Integral ratios are exported as int64
+	_, err := runtime.RunOnEventLoop(`
+		var failure = "";
+		var hero = page.locator("img.hero");
+		hero.isInViewport()
+			.then(function() { return hero.isInViewport({ ratio: 0.5 }); })
+			.then(function() { return hero.isInViewport({ ratio: 1 }); })
+			.catch(function(e) { failure = String(e); });
+	`)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if failure := runtime.VU.Runtime().Get("failure").String(); failure != "" {
+		t.Fatalf("Unexpected failure: %s", failure)
+	}
+	if !reflect.DeepEqual(ratios, []interface{}{0.0, 0.5, 1.0}) {
+		t.Errorf("Expected ratios 0, 0.5 and 1, got %v", ratios)
+	}
+
+	for _, ratio := range []interface{}{0.0, int64(0), -0.5, 1.5, int64(2)} {
+		if _, err := page.Locator("img.hero").IsInViewport(map[string]interface{}{"ratio": ratio}); err == nil {
+			t.Errorf("Expected ratio %v to be rejected", ratio)
+		}
+	}
+}
+
+func TestLocatorResolveElementID(t *testing.T) {
+	page := &Page{
+		client: NewWebDriverClient("http://localhost:4444"),
+	}
+
+	// A locator bound to an element resolves without querying the driver
+	bound := &Locator{page: page, selector: "button", elementID: "test-element-id"}
+	elementID, err := bound.resolveElementID(context.Background())
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if elementID != "test-element-id" {
+		t.Errorf("Expected elementID to be 'test-element-id', got '%s'", elementID)
+	}
+
+	// An unbound locator has to find the element, which fails without a session
+	_, err = page.Locator("button").resolveElementID(context.Background())
+	if err == nil {
+		t.Error("Expected error when resolving element without session")
+	}
+}
+
+func TestCountConditionFromOption(t *testing.T) {
+	// Exact numbers come from JS as int64 or float64
+	for _, v := range []interface{}{int64(10), float64(10)} {
+		got, err := countConditionFromOption(v)
+		if err != nil {
+			t.Fatalf("Expected no error for %v, got: %v", v, err)
+		}
+		if got != (CountCondition{"==", 10}) {
+			t.Errorf("Expected == 10 for %v, got %+v", v, got)
+		}
+	}
+
+	got, err := countConditionFromOption(">= 1")
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if got != (CountCondition{">=", 1}) {
+		t.Errorf("Expected >= 1, got %+v", got)
+	}
+
+	if _, err := countConditionFromOption(true); err == nil {
+		t.Error("Expected error for a non-numeric count")
+	}
+}
+
+func TestLocatorChaining(t *testing.T) {
+	var scripts []string
+	var scriptArgs [][]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if strings.HasSuffix(r.URL.Path, "/element") {
+			_, _ = w.Write([]byte(`{"value":{"element-6066-11e4-a52e-4f735466cecf":"card-1"}}`))
+			return
+		}
+
+		var payload struct {
+			Script string        `json:"script"`
+			Args   []interface{} `json:"args"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&payload)
+		scripts = append(scripts, payload.Script)
+		scriptArgs = append(scriptArgs, payload.Args)
+
+		if strings.Contains(payload.Script, "querySelectorAll") {
+			_, _ = w.Write([]byte(`{"value":[{"element-6066-11e4-a52e-4f735466cecf":"save-1"},{"element-6066-11e4-a52e-4f735466cecf":"save-2"}]}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"value":{"element-6066-11e4-a52e-4f735466cecf":"save-1"}}`))
+	}))
+	defer server.Close()
+
+	page := &Page{client: NewWebDriverClient(server.URL).forSession("session-1")}
+	card := page.Locator(".card")
+	save := card.Locator("button.save")
+
+	if save.parent != card || save.selector != "button.save" {
+		t.Fatalf("Expected child locator to be scoped to the card, got %+v", save)
+	}
+
+	ctx := context.Background()
+	elementID, err := save.resolveElementID(ctx)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if elementID != "save-1" {
+		t.Errorf("Expected save-1, got %s", elementID)
+	}
+	if len(scripts) != 1 || !strings.Contains(scripts[0], "arguments[0].querySelector(arguments[1])") {
+		t.Fatalf("Expected a scoped querySelector script, got %v", scripts)
+	}
+
+	// The parent's element and the selector are passed as script arguments
+	parentRef, _ := scriptArgs[0][0].(map[string]interface{})
+	if parentRef["element-6066-11e4-a52e-4f735466cecf"] != "card-1" || scriptArgs[0][1] != "button.save" {
+		t.Errorf("Unexpected script arguments: %v", scriptArgs[0])
+	}
+
+	elementIDs, err := save.resolveAllElementIDs(ctx)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(elementIDs) != 2 {
+		t.Errorf("Expected 2 scoped elements, got %v", elementIDs)
+	}
+}
+
+func TestLocatorChainingRequiresCSS(t *testing.T) {
+	page := &Page{client: NewWebDriverClient("http://localhost:4444").forSession("session-1")}
+	locator := page.Locator(".card").Locator("text=Save")
+	locator.parent.elementID = "card-1"
+
+	_, err := locator.resolveElementID(context.Background())
+	if err == nil || !contains(err.Error(), "only support CSS selectors") {
+		t.Errorf("Expected CSS-only error, got %v", err)
+	}
+}
+
+func TestResolveIndex(t *testing.T) {
+	tests := []struct {
+		index    int
+		n        int
+		expected int
+		ok       bool
+	}{
+		{0, 3, 0, true},
+		{2, 3, 2, true},
+		{3, 3, 0, false},
+		{-1, 3, 2, true},
+		{-3, 3, 0, true},
+		{-4, 3, 0, false},
+		{0, 0, 0, false},
+	}
+
+	for _, tt := range tests {
+		index, ok := resolveIndex(tt.index, tt.n)
+		if ok != tt.ok || (ok && index != tt.expected) {
+			t.Errorf("resolveIndex(%d, %d) = %d, %v; expected %d, %v", tt.index, tt.n, index, ok, tt.expected, tt.ok)
+		}
+	}
+}
+
+func TestLocatorNth(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"value":[
+			{"element-6066-11e4-a52e-4f735466cecf":"row-1"},
+			{"element-6066-11e4-a52e-4f735466cecf":"row-2"},
+			{"element-6066-11e4-a52e-4f735466cecf":"row-3"}
+		]}`))
+	}))
+	defer server.Close()
+
+	page := &Page{client: NewWebDriverClient(server.URL).forSession("session-1")}
+	rows := page.Locator("tr")
+	ctx := context.Background()
+
+	tests := []struct {
+		name     string
+		locator  *Locator
+		expected string
+	}{
+		{"first", rows.First(), "row-1"},
+		{"nth", rows.Nth(1), "row-2"},
+		{"last", rows.Last(), "row-3"},
+		{"negative", rows.Nth(-2), "row-2"},
+		{"chained", rows.Nth(1).First(), "row-2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			elementID, err := tt.locator.resolveElementID(ctx)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if elementID != tt.expected {
+				t.Errorf("Expected %s, got %s", tt.expected, elementID)
+			}
+		})
+	}
+
+	// Out of range indexes match nothing
+	_, err := rows.Nth(5).resolveElementID(ctx)
+	if !errors.Is(err, ErrElementNotFound) {
+		t.Errorf("Expected ErrElementNotFound, got %v", err)
+	}
+
+	elementIDs, err := rows.Nth(5).resolveAllElementIDs(ctx)
+	if err != nil || len(elementIDs) != 0 {
+		t.Errorf("Expected no elements, got %v (%v)", elementIDs, err)
+	}
+
+	elementIDs, err = rows.Last().resolveAllElementIDs(ctx)
+	if err != nil || len(elementIDs) != 1 || elementIDs[0] != "row-3" {
+		t.Errorf("Expected only the last row, got %v (%v)", elementIDs, err)
+	}
+}
+
+func TestLocatorWaitForResolvedState(t *testing.T) {
+	var mu sync.Mutex
+	polls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if strings.HasSuffix(r.URL.Path, "/elements") {
+			_, _ = w.Write([]byte(`{"value":[{"element-6066-11e4-a52e-4f735466cecf":"row-1"},{"element-6066-11e4-a52e-4f735466cecf":"row-2"}]}`))
+			return
+		}
+
+		// The state check becomes true on the second poll
+		mu.Lock()
+		polls++
+		visible := polls >= 2
+		mu.Unlock()
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"value": visible})
+	}))
+	defer server.Close()
+
+	page := &Page{client: NewWebDriverClient(server.URL).forSession("session-1")}
+	ctx := context.Background()
+
+	if err := page.Locator("tr").Last().waitForResolvedState(ctx, "visible", time.Second); err != nil {
+		t.Errorf("Expected wait to succeed, got %v", err)
+	}
+
+	condition, _ := ParseCountCondition("1")
+	if err := page.Locator("tr").First().waitForResolvedCount(ctx, condition, time.Second); err != nil {
+		t.Errorf("Expected count wait to succeed, got %v", err)
+	}
+
+	condition, _ = ParseCountCondition("2")
+	err := page.Locator("tr").First().waitForResolvedCount(ctx, condition, 300*time.Millisecond)
+	if !errors.Is(err, ErrTimeout) {
+		t.Errorf("Expected ErrTimeout, got %v", err)
+	}
+}
+
+func TestLocatorCountWithMinimum(t *testing.T) {
+	var mu sync.Mutex
+	counts := []int{0, 2, 5}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Rows render over successive polls
+		mu.Lock()
+		n := counts[0]
+		if len(counts) > 1 {
+			counts = counts[1:]
+		}
+		mu.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"value": n})
+	}))
+	defer server.Close()
+
+	runtime := modulestest.NewRuntime(t)
+	page := &Page{vu: runtime.VU, client: NewWebDriverClient(server.URL).forSession("session-1")}
+	rt := runtime.VU.Runtime()
+	if err := rt.Set("page", page); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := runtime.RunOnEventLoop(`
+		var counts = [], failure = "";
+		var rows = page.locator("tr");
+		rows.count()
+			.then(function(n) { counts.push(n); return rows.count({ min: 5, timeout: 2000 }); })
+			.then(function(n) { counts.push(n); return rows.count({ min: 6, timeout: 300 }); })
+			.catch(function(e) { failure = String(e); });
+	`)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if got := rt.Get("counts").String(); got != "0,5" {
+		t.Errorf("Expected the count to be taken right away, then once 5 rows matched, got %s", got)
+	}
+	if failure := rt.Get("failure").String(); !strings.Contains(failure, "last count: 5") {
+		t.Errorf("Expected a timeout waiting for 6 rows, got %q", failure)
+	}
+
+	if _, err := page.Locator("tr").Count(map[string]interface{}{"min": -1}); err == nil {
+		t.Error("Expected error for a negative minimum")
+	}
+}
+
+func TestLocatorFilter(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if strings.HasSuffix(r.URL.Path, "/elements") {
+			_, _ = w.Write([]byte(`{"value":[
+				{"element-6066-11e4-a52e-4f735466cecf":"row-1"},
+				{"element-6066-11e4-a52e-4f735466cecf":"row-2"},
+				{"element-6066-11e4-a52e-4f735466cecf":"row-3"}
+			]}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"value":["Order #1233 Shipped", "Order #1234 Pending", "order #1234 refund"]}`))
+	}))
+	defer server.Close()
+
+	page := &Page{client: NewWebDriverClient(server.URL).forSession("session-1")}
+	rows := page.Locator("tr")
+	ctx := context.Background()
+
+	tests := []struct {
+		name     string
+		hasText  string
+		expected []string
+	}{
+		{"substring is case-insensitive", "ORDER #1234", []string{"row-2", "row-3"}},
+		{"regex", "/^Order #123\\d Pending$/", []string{"row-2"}},
+		{"no match", "Cancelled", []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filtered, err := rows.Filter(map[string]interface{}{"hasText": tt.hasText})
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			elementIDs, err := filtered.resolveAllElementIDs(ctx)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if strings.Join(elementIDs, ",") != strings.Join(tt.expected, ",") {
+				t.Errorf("Expected %v, got %v", tt.expected, elementIDs)
+			}
+		})
+	}
+
+	// Filters compose with nth and resolve to the first remaining match
+	filtered, _ := rows.Filter(map[string]interface{}{"hasText": "#1234"})
+	if elementID, err := filtered.resolveElementID(ctx); err != nil || elementID != "row-2" {
+		t.Errorf("Expected row-2, got %s (%v)", elementID, err)
+	}
+	if elementID, err := filtered.Last().resolveElementID(ctx); err != nil || elementID != "row-3" {
+		t.Errorf("Expected row-3, got %s (%v)", elementID, err)
+	}
+
+	filtered, _ = rows.Filter(map[string]interface{}{"hasText": "Cancelled"})
+	if _, err := filtered.resolveElementID(ctx); !errors.Is(err, ErrElementNotFound) {
+		t.Errorf("Expected ErrElementNotFound, got %v", err)
+	}
+
+	if _, err := rows.Filter(map[string]interface{}{"hasText": "/[/"}); err == nil {
+		t.Error("Expected error for an invalid regex")
+	}
+}
+
+func TestLocatorEvaluateAll(t *testing.T) {
+	runtime := modulestest.NewRuntime(t)
+
+	type scriptPayload struct {
+		Script string        `json:"script"`
+		Args   []interface{} `json:"args"`
+	}
+	executed := make(chan scriptPayload, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if strings.HasSuffix(r.URL.Path, "/elements") {
+			_, _ = w.Write([]byte(`{"value":[{"element-6066-11e4-a52e-4f735466cecf":"a"},{"element-6066-11e4-a52e-4f735466cecf":"b"}]}`))
+			return
+		}
+
+		var payload scriptPayload
+		_ = json.NewDecoder(r.Body).Decode(&payload)
+		executed <- payload
+		_, _ = w.Write([]byte(`{"value":[1,2]}`))
+	}))
+	defer server.Close()
+
+	page := &Page{vu: runtime.VU, client: NewWebDriverClient(server.URL).forSession("session-1")}
+	promise, err := page.Locator("li").EvaluateAll("(elements, attr) => elements.map(e => e.getAttribute(attr))", "data-id")
+	if err != nil || promise == nil {
+		t.Fatalf("Expected a promise, got %v", err)
+	}
+
+	select {
+	case payload := <-executed:
+		if payload.Script != "return ((elements, attr) => elements.map(e => e.getAttribute(attr))).apply(null, arguments);" {
+			t.Errorf("Unexpected script: %s", payload.Script)
+		}
+		if len(payload.Args) != 2 || payload.Args[1] != "data-id" {
+			t.Fatalf("Expected the elements and the extra argument, got %v", payload.Args)
+		}
+		elements, _ := payload.Args[0].([]interface{})
+		if len(elements) != 2 {
+			t.Errorf("Expected all matched elements in one call, got %v", payload.Args[0])
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected the script to be executed")
+	}
+}
+
+func TestLocatorSetInputFiles(t *testing.T) {
+	dir := t.TempDir()
+	avatar := filepath.Join(dir, "avatar.png")
+	resume := filepath.Join(dir, "resume.pdf")
+	for _, path := range []string{avatar, resume} {
+		if err := os.WriteFile(path, []byte("data"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var mu sync.Mutex
+	multiple := false
+	var sentKeys []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/element"):
+			_, _ = w.Write([]byte(`{"value":{"element-6066-11e4-a52e-4f735466cecf":"upload"}}`))
+		case strings.HasSuffix(r.URL.Path, "/value"):
+			var payload struct {
+				Text string `json:"text"`
+			}
+			_ = json.NewDecoder(r.Body).Decode(&payload)
+			sentKeys = append(sentKeys, payload.Text)
+			_, _ = w.Write([]byte(`{"value":null}`))
+		default:
+			data, _ := json.Marshal(map[string]interface{}{
+				"value": map[string]interface{}{"isFileInput": true, "multiple": multiple},
+			})
+			_, _ = w.Write(data)
+		}
+	}))
+	defer server.Close()
+
+	runtime := modulestest.NewRuntime(t)
+	page := &Page{vu: runtime.VU, client: NewWebDriverClient(server.URL).forSession("session-1")}
+	if err := runtime.VU.Runtime().Set("page", page); err != nil {
+		t.Fatal(err)
+	}
+	if err := runtime.VU.Runtime().Set("files", []string{avatar, resume}); err != nil {
+		t.Fatal(err)
+	}
+
+	run := func(script string) string {
+		t.Helper()
+		_, err := runtime.RunOnEventLoop(`
+			var failure = "";
+			` + script + `.catch(function(e) { failure = String(e); });
+		`)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		return runtime.VU.Runtime().Get("failure").String()
+	}
+
+	if failure := run(`page.locator("#upload").setInputFiles([files[0]])`); failure != "" {
+		t.Fatalf("Unexpected failure: %s", failure)
+	}
+	if failure := run(`page.locator("#upload").setInputFiles(files)`); !strings.Contains(failure, "doesn't accept multiple files") {
+		t.Errorf("Expected multiple files to be rejected, got %q", failure)
+	}
+	if failure := run(`page.locator("#upload").setInputFiles(["missing.txt"])`); !strings.Contains(failure, "failed to read file 'missing.txt'") {
+		t.Errorf("Expected a missing file to be rejected, got %q", failure)
+	}
+
+	mu.Lock()
+	multiple = true
+	mu.Unlock()
+	if failure := run(`page.locator("#upload").setInputFiles(files)`); failure != "" {
+		t.Fatalf("Unexpected failure: %s", failure)
+	}
+
+	// Paths are sent newline separated to the element's value endpoint
+	expected := []string{avatar, avatar + "\n" + resume}
+	if len(sentKeys) != len(expected) || sentKeys[0] != expected[0] || sentKeys[1] != expected[1] {
+		t.Errorf("Expected %q, got %q", expected, sentKeys)
+	}
+}
+
+func TestLocatorClear(t *testing.T) {
+	var mu sync.Mutex
+	editable := true
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		var payload map[string]interface{}
+		_ = json.NewDecoder(r.Body).Decode(&payload)
+		script, _ := payload["script"].(string)
+
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/element"):
+			_, _ = w.Write([]byte(`{"value":{"element-6066-11e4-a52e-4f735466cecf":"name"}}`))
+		case strings.HasSuffix(r.URL.Path, "/element/name/clear"):
+			requests = append(requests, "clear")
+			_, _ = w.Write([]byte(`{"value":null}`))
+		case strings.Contains(script, "isConnected"):
+			_, _ = w.Write([]byte(`{"value":null}`))
+		case strings.Contains(script, "isContentEditable"):
+			data, _ := json.Marshal(map[string]interface{}{"value": editable})
+			_, _ = w.Write(data)
+		case strings.Contains(script, "dispatchEvent"):
+			requests = append(requests, "events")
+			_, _ = w.Write([]byte(`{"value":null}`))
+		default:
+			t.Errorf("Unexpected request: %s", r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	runtime := modulestest.NewRuntime(t)
+	page := &Page{vu: runtime.VU, client: NewWebDriverClient(server.URL).forSession("session-1")}
+	if err := runtime.VU.Runtime().Set("page", page); err != nil {
+		t.Fatal(err)
+	}
+
+	run := func() string {
+		t.Helper()
+		_, err := runtime.RunOnEventLoop(`
+			var failure = "";
+			page.locator("#name").clear().catch(function(e) { failure = String(e); });
+		`)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		return runtime.VU.Runtime().Get("failure").String()
+	}
+
+	if failure := run(); failure != "" {
+		t.Fatalf("Unexpected failure: %s", failure)
+	}
+	if got := strings.Join(requests, ","); got != "clear,events" {
+		t.Errorf("Expected the element to be cleared and the events fired, got %s", got)
+	}
+
+	mu.Lock()
+	editable, requests = false, nil
+	mu.Unlock()
+	if failure := run(); !strings.Contains(failure, "is not editable") {
+		t.Errorf("Expected a non-editable element to be rejected, got %q", failure)
+	}
+	if len(requests) != 0 {
+		t.Errorf("Expected no clear request, got %v", requests)
+	}
+}
+
+func TestLocatorTypeDelay(t *testing.T) {
+	var mu sync.Mutex
+	var sent []string
+	var times []time.Time
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/element"):
+			_, _ = w.Write([]byte(`{"value":{"element-6066-11e4-a52e-4f735466cecf":"search"}}`))
+		case strings.HasSuffix(r.URL.Path, "/value"):
+			var payload struct {
+				Text string `json:"text"`
+			}
+			_ = json.NewDecoder(r.Body).Decode(&payload)
+			sent = append(sent, payload.Text)
+			times = append(times, time.Now())
+			_, _ = w.Write([]byte(`{"value":null}`))
+		case strings.HasSuffix(r.URL.Path, "/execute/sync"):
+			// The element is actionable
+			_, _ = w.Write([]byte(`{"value":null}`))
+		default:
+			t.Errorf("Unexpected request: %s", r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	runtime := modulestest.NewRuntime(t)
+	page := &Page{vu: runtime.VU, client: NewWebDriverClient(server.URL).forSession("session-1")}
+	if err := runtime.VU.Runtime().Set("page", page); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := runtime.RunOnEventLoop(`
+		var failure = "";
+		page.locator("#search").type("k6é", { delay: 30 }).catch(function(e) { failure = String(e); });
+	`)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if failure := runtime.VU.Runtime().Get("failure").String(); failure != "" {
+		t.Fatalf("Unexpected failure: %s", failure)
+	}
+
+	// Each character is sent on its own, delay apart
+	if got := strings.Join(sent, ","); got != "k,6,é" {
+		t.Errorf("Expected one command per character, got %q", got)
+	}
+	for i := 1; i < len(times); i++ {
+		if gap := times[i].Sub(times[i-1]); gap < 30*time.Millisecond {
+			t.Errorf("Expected keystrokes 30ms apart, got %v", gap)
+		}
+	}
+}
+
+func TestLocatorTypeDelayStale(t *testing.T) {
+	var mu sync.Mutex
+	var finds int
+	var typed []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/element"):
+			finds++
+			id := "old"
+			if finds > 1 {
+				id = "new"
+			}
+			_, _ = w.Write([]byte(`{"value":{"element-6066-11e4-a52e-4f735466cecf":"` + id + `"}}`))
+		case strings.HasSuffix(r.URL.Path, "/value"):
+			// The input is re-rendered after the first character
+			if strings.Contains(r.URL.Path, "/old/") && len(typed) > 0 {
+				w.WriteHeader(http.StatusNotFound)
+				_, _ = w.Write([]byte(`{"value":{"error":"stale element reference","message":"gone"}}`))
+				return
+			}
+			var payload struct {
+				Text string `json:"text"`
+			}
+			_ = json.NewDecoder(r.Body).Decode(&payload)
+			typed = append(typed, payload.Text)
+			_, _ = w.Write([]byte(`{"value":null}`))
+		default:
+			// The element is actionable
+			_, _ = w.Write([]byte(`{"value":null}`))
+		}
+	}))
+	defer server.Close()
+
+	runtime := modulestest.NewRuntime(t)
+	page := &Page{vu: runtime.VU, client: NewWebDriverClient(server.URL).forSession("session-1")}
+	if err := runtime.VU.Runtime().Set("page", page); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := runtime.RunOnEventLoop(`
+		var failure = "";
+		page.locator("#name").type("hello", { delay: 1 }).catch(function(e) { failure = String(e); });
+	`)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if failure := runtime.VU.Runtime().Get("failure").String(); failure != "" {
+		t.Fatalf("Unexpected failure: %s", failure)
+	}
+
+	// Typing resumes in the element found again instead of starting over
+	if got := strings.Join(typed, ""); finds != 2 || got != "hello" {
+		t.Errorf("Expected the text to be typed once across %d lookups, got %q", finds, got)
+	}
+}
+
+func TestLocatorDispatchEvent(t *testing.T) {
+	var mu sync.Mutex
+	var args []interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		if strings.HasSuffix(r.URL.Path, "/element") {
+			_, _ = w.Write([]byte(`{"value":{"element-6066-11e4-a52e-4f735466cecf":"card"}}`))
+			return
+		}
+
+		var payload struct {
+			Script string        `json:"script"`
+			Args   []interface{} `json:"args"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&payload)
+		if !strings.Contains(payload.Script, "dispatchEvent") {
+			t.Errorf("Unexpected script: %s", payload.Script)
+		}
+		args = payload.Args
+		_, _ = w.Write([]byte(`{"value":null}`))
+	}))
+	defer server.Close()
+
+	runtime := modulestest.NewRuntime(t)
+	page := &Page{vu: runtime.VU, client: NewWebDriverClient(server.URL).forSession("session-1")}
+	if err := runtime.VU.Runtime().Set("page", page); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := runtime.RunOnEventLoop(`
+		var failure = "";
+		page.locator("#card").dispatchEvent("card:select", { detail: { id: 7 } })
+			.catch(function(e) { failure = String(e); });
+	`)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if failure := runtime.VU.Runtime().Get("failure").String(); failure != "" {
+		t.Fatalf("Unexpected failure: %s", failure)
+	}
+
+	// The element, the event type and its init dictionary are passed to the script
+	if len(args) != 3 || args[1] != "card:select" {
+		t.Fatalf("Unexpected script arguments: %v", args)
+	}
+	if ref, _ := args[0].(map[string]interface{}); ref["element-6066-11e4-a52e-4f735466cecf"] != "card" {
+		t.Errorf("Expected the element reference, got %v", args[0])
+	}
+	init, _ := args[2].(map[string]interface{})
+	if detail, _ := init["detail"].(map[string]interface{}); detail["id"] != 7.0 {
+		t.Errorf("Expected the event init to be passed, got %v", args[2])
+	}
+
+	if _, err := page.Locator("#card").DispatchEvent(""); err == nil {
+		t.Error("Expected an empty event type to be rejected")
+	}
+}
+
+func TestLocatorClickAndWaitForNavigation(t *testing.T) {
+	tests := []struct {
+		name      string
+		options   string
+		navigates bool
+		expected  string
+		failure   string
+	}{
+		{"load", "", true, "mark,click,committed,load,inject", ""},
+		{"commit", `{ waitUntil: "commit" }`, true, "mark,click,committed,inject", ""},
+		{"no navigation", "{ timeout: 100 }", false, "mark,click", "no navigation was committed"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var mu sync.Mutex
+			var events []string
+			marker := ""
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				mu.Lock()
+				defer mu.Unlock()
+
+				w.Header().Set("Content-Type", "application/json")
+				if strings.HasSuffix(r.URL.Path, "/element") {
+					_, _ = w.Write([]byte(`{"value":{"element-6066-11e4-a52e-4f735466cecf":"submit"}}`))
+					return
+				}
+
+				var payload struct {
+					Script string        `json:"script"`
+					Args   []interface{} `json:"args"`
+				}
+				_ = json.NewDecoder(r.Body).Decode(&payload)
+
+				var value interface{}
+				switch {
+				case strings.HasPrefix(payload.Script, "document.__webdriverNavigation ="):
+					marker, _ = payload.Args[0].(string)
+					events = append(events, "mark")
+				case strings.Contains(payload.Script, "element.click()"):
+					events = append(events, "click")
+					if tt.navigates {
+						// The new document doesn't carry the marker
+						marker = ""
+					}
+				case strings.Contains(payload.Script, "__webdriverNavigation !=="):
+					value = marker == ""
+					if marker == "" {
+						events = append(events, "committed")
+					}
+				case strings.Contains(payload.Script, "readyState === 'complete'"):
+					events = append(events, "load")
+					value = true
+				case payload.Script == injectionScript:
+					events = append(events, "inject")
+				}
+				data, _ := json.Marshal(map[string]interface{}{"value": value})
+				_, _ = w.Write(data)
+			}))
+			defer server.Close()
+
+			runtime := modulestest.NewRuntime(t)
+			page := &Page{vu: runtime.VU, client: NewWebDriverClient(server.URL).forSession("session-1")}
+			if err := runtime.VU.Runtime().Set("page", page); err != nil {
+				t.Fatal(err)
+			}
+
+			_, err := runtime.RunOnEventLoop(`
+				var failure = "";
+				page.locator("button[type=submit]").clickAndWaitForNavigation(` + tt.options + `)
+					.catch(function(e) { failure = String(e); });
+			`)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			failure := runtime.VU.Runtime().Get("failure").String()
+			if tt.failure == "" && failure != "" || !strings.Contains(failure, tt.failure) {
+				t.Errorf("Expected failure %q, got %q", tt.failure, failure)
+			}
+			if got := strings.Join(events, ","); got != tt.expected {
+				t.Errorf("Expected %s, got %s", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestLocatorHighlight(t *testing.T) {
+	var mu sync.Mutex
+	var args []interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		if strings.HasSuffix(r.URL.Path, "/elements") {
+			_, _ = w.Write([]byte(`{"value":[{"element-6066-11e4-a52e-4f735466cecf":"a"},{"element-6066-11e4-a52e-4f735466cecf":"b"}]}`))
+			return
+		}
+
+		var payload struct {
+			Script string        `json:"script"`
+			Args   []interface{} `json:"args"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&payload)
+		if !strings.Contains(payload.Script, "data-xk6-highlight") {
+			t.Errorf("Unexpected script: %s", payload.Script)
+		}
+		args = payload.Args
+		_, _ = w.Write([]byte(`{"value":[]}`))
+	}))
+	defer server.Close()
+
+	runtime := modulestest.NewRuntime(t)
+	page := &Page{vu: runtime.VU, client: NewWebDriverClient(server.URL).forSession("session-1")}
+	if err := runtime.VU.Runtime().Set("page", page); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		options  string
+		duration float64
+		persist  bool
+	}{
+		{"", 2000, false},
+		{"{ duration: 500 }", 500, false},
+		{"{ persist: true }", 2000, true},
+	}
+
+	for _, tt := range tests {
+		_, err := runtime.RunOnEventLoop(`
+			var failure = "";
+			page.locator("li").highlight(` + tt.options + `).catch(function(e) { failure = String(e); });
+		`)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if failure := runtime.VU.Runtime().Get("failure").String(); failure != "" {
+			t.Fatalf("Unexpected failure: %s", failure)
+		}
+
+		// Every matched element is highlighted in one script call
+		if elements, _ := args[0].([]interface{}); len(elements) != 2 {
+			t.Errorf("Expected both elements to be highlighted, got %v", args[0])
+		}
+		if args[1] != tt.duration || args[2] != tt.persist {
+			t.Errorf("Options %q: expected duration %v and persist %v, got %v", tt.options, tt.duration, tt.persist, args[1:])
+		}
+	}
+}
+
+func TestLocatorElementHandle(t *testing.T) {
+	var mu sync.Mutex
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/element"):
+			requests = append(requests, "find")
+			_, _ = w.Write([]byte(`{"value":{"element-6066-11e4-a52e-4f735466cecf":"row"}}`))
+		default:
+			var payload struct {
+				Script string `json:"script"`
+			}
+			_ = json.NewDecoder(r.Body).Decode(&payload)
+			if strings.Contains(payload.Script, "isConnected") {
+				// The element is actionable
+				_, _ = w.Write([]byte(`{"value":null}`))
+				return
+			}
+			// Clicks and text content are both scripts
+			requests = append(requests, "script")
+			_, _ = w.Write([]byte(`{"value":"Order 42"}`))
+		}
+	}))
+	defer server.Close()
+
+	runtime := modulestest.NewRuntime(t)
+	page := &Page{vu: runtime.VU, client: NewWebDriverClient(server.URL).forSession("session-1")}
+	if err := runtime.VU.Runtime().Set("page", page); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := runtime.RunOnEventLoop(`
+		var text, failure = "";
+		page.locator("tr.order").elementHandle().then(function(row) {
+			return row.click().then(function() { return row.textContent(); });
+		}).then(function(t) { text = t; }).catch(function(e) { failure = String(e); });
+	`)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if failure := runtime.VU.Runtime().Get("failure").String(); failure != "" {
+		t.Fatalf("Unexpected failure: %s", failure)
+	}
+	if text := runtime.VU.Runtime().Get("text").String(); text != "Order 42" {
+		t.Errorf("Expected the handle's text content, got %q", text)
+	}
+	if got := strings.Join(requests, ","); got != "find,script,script" {
+		t.Errorf("Expected the element to be found once, got %s", got)
+	}
+}
+
+func TestLocatorElementHandleStaysBound(t *testing.T) {
+	var mu sync.Mutex
+	removed := false
+	var checked []interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		if strings.HasSuffix(r.URL.Path, "/element") {
+			_, _ = w.Write([]byte(`{"value":{"element-6066-11e4-a52e-4f735466cecf":"row-2"}}`))
+			return
+		}
+		if strings.HasSuffix(r.URL.Path, "/elements") {
+			// The selector matches other rows too
+			_, _ = w.Write([]byte(`{"value":[{"element-6066-11e4-a52e-4f735466cecf":"row-1"},{"element-6066-11e4-a52e-4f735466cecf":"row-2"}]}`))
+			return
+		}
+
+		var payload struct {
+			Script string        `json:"script"`
+			Args   []interface{} `json:"args"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&payload)
+		switch {
+		case strings.Contains(payload.Script, "el.textContent"):
+			elements, _ := payload.Args[0].([]interface{})
+			texts := make([]string, len(elements))
+			for i, element := range elements {
+				texts[i] = "Order " + element.(map[string]interface{})["element-6066-11e4-a52e-4f735466cecf"].(string)
+			}
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"value": texts})
+		case strings.Contains(payload.Script, "arguments[0]") && len(payload.Args) == 1:
+			// State checks get the bound element, until it's removed
+			checked = append(checked, payload.Args[0])
+			if removed && payload.Args[0] != nil {
+				w.WriteHeader(http.StatusNotFound)
+				_, _ = w.Write([]byte(`{"value":{"error":"stale element reference","message":"gone"}}`))
+				return
+			}
+			_, _ = w.Write([]byte(`{"value":true}`))
+		default:
+			// Selector scripts see every row
+			_, _ = w.Write([]byte(`{"value":2}`))
+		}
+	}))
+	defer server.Close()
+
+	runtime := modulestest.NewRuntime(t)
+	page := &Page{vu: runtime.VU, client: NewWebDriverClient(server.URL).forSession("session-1")}
+	rt := runtime.VU.Runtime()
+	if err := rt.Set("page", page); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := runtime.RunOnEventLoop(`
+		var row, count, texts, failure = "";
+		page.locator("tr.order").elementHandle()
+			.then(function(handle) { row = handle; return row.count(); })
+			.then(function(n) { count = n; return row.allTextContents(); })
+			.then(function(t) { texts = t; return row.waitFor({ state: "hidden", timeout: 1000 }); })
+			.catch(function(e) { failure = String(e); });
+	`)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if failure := rt.Get("failure").String(); failure != "" {
+		t.Fatalf("Unexpected failure: %s", failure)
+	}
+	if count := rt.Get("count").ToInteger(); count != 1 {
+		t.Errorf("Expected the handle to count its element only, got %d", count)
+	}
+	if texts := rt.Get("texts").String(); texts != "Order row-2" {
+		t.Errorf("Expected the handle's text content only, got %q", texts)
+	}
+	bound := map[string]interface{}{"element-6066-11e4-a52e-4f735466cecf": "row-2"}
+	if len(checked) != 1 || !reflect.DeepEqual(checked[0], bound) {
+		t.Errorf("Expected the state of the bound element to be checked, got %v", checked)
+	}
+
+	// Once removed, the element is detached
+	mu.Lock()
+	removed, checked = true, nil
+	mu.Unlock()
+	_, err = runtime.RunOnEventLoop(`
+		row.waitFor({ state: "detached", timeout: 1000 }).catch(function(e) { failure = String(e); });
+	`)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if failure := rt.Get("failure").String(); failure != "" {
+		t.Fatalf("Unexpected failure: %s", failure)
+	}
+	if len(checked) != 2 || checked[1] != nil {
+		t.Errorf("Expected a removed element to be checked as missing, got %v", checked)
+	}
+}
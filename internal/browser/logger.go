@@ -0,0 +1,81 @@
+package browser
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/sirupsen/logrus"
+	"go.k6.io/k6/js/modules"
+)
+
+// logLevelEnv sets the lowest level of the messages logged by the module
+const logLevelEnv = "XK6_SAFARI_LOG_LEVEL"
+
+// logLevels are the levels SetLogLevel accepts. "none" keeps the module quiet,
+// as it doesn't log at the panic level
+var logLevels = map[string]logrus.Level{
+	"debug": logrus.DebugLevel,
+	"info":  logrus.InfoLevel,
+	"warn":  logrus.WarnLevel,
+	"error": logrus.ErrorLevel,
+	"none":  logrus.PanicLevel,
+}
+
+var (
+	logLevel        atomic.Uint32 // Lowest level logged, warnings by default
+	logLevelEnvOnce sync.Once     // Applies XK6_SAFARI_LOG_LEVEL before the level is first used
+)
+
+// SetLogLevel sets the lowest level of the messages logged by the module:
+// "debug", "info", "warn" (the default), "error" or "none"
+// The k6 logger's own level, e.g. --verbose for debug messages, still applies
+func SetLogLevel(level string) error {
+	logLevelEnvOnce.Do(applyLogLevelEnv)
+	return setLogLevel(level)
+}
+
+func setLogLevel(level string) error {
+	l, ok := logLevels[strings.ToLower(level)]
+	if !ok {
+		return fmt.Errorf("invalid log level %q, expected debug, info, warn, error or none", level)
+	}
+	logLevel.Store(uint32(l))
+	return nil
+}
+
+// applyLogLevelEnv sets the log level from XK6_SAFARI_LOG_LEVEL, if set
+func applyLogLevelEnv() {
+	logLevel.Store(uint32(logrus.WarnLevel))
+	if v := os.Getenv(logLevelEnv); v != "" {
+		if err := setLogLevel(v); err != nil {
+			logger(nil).Warnf("ignoring invalid %s=%q", logLevelEnv, v)
+		}
+	}
+}
+
+// logger returns the logger of the VU's iteration, or of its init context
+// Outside of a VU, e.g. when the driver is stopped, it's the standard logger
+func logger(vu modules.VU) *logrus.Entry {
+	var l logrus.FieldLogger = logrus.StandardLogger()
+	if vu != nil {
+		if state := vu.State(); state != nil && state.Logger != nil {
+			l = state.Logger
+		} else if env := vu.InitEnv(); env != nil && env.Logger != nil {
+			l = env.Logger
+		}
+	}
+	return l.WithField("source", "browser-safari")
+}
+
+// logf logs a message with the VU's logger, unless its level is below the
+// module's log level
+func logf(vu modules.VU, level logrus.Level, format string, args ...interface{}) {
+	logLevelEnvOnce.Do(applyLogLevelEnv)
+	if level > logrus.Level(logLevel.Load()) {
+		return
+	}
+	logger(vu).Logf(level, format, args...)
+}
@@ -0,0 +1,55 @@
+package browser
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestLogf(t *testing.T) {
+	var buf bytes.Buffer
+	std := logrus.StandardLogger()
+	out, level := std.Out, std.Level
+	std.SetOutput(&buf)
+	std.SetLevel(logrus.DebugLevel)
+	t.Cleanup(func() {
+		std.SetOutput(out)
+		std.SetLevel(level)
+		_ = SetLogLevel("warn")
+	})
+
+	// Warnings are logged by default, debug messages like clicks aren't
+	logf(nil, logrus.WarnLevel, "failed to set viewport size: %v", "boom")
+	logf(nil, logrus.DebugLevel, "clicked element %v", "button")
+	got := buf.String()
+	if !strings.Contains(got, "failed to set viewport size: boom") || !strings.Contains(got, "source=browser-safari") {
+		t.Errorf("Expected the warning to be logged with its source, got %q", got)
+	}
+	if strings.Contains(got, "clicked element") {
+		t.Errorf("Expected debug messages to be filtered out, got %q", got)
+	}
+
+	buf.Reset()
+	if err := SetLogLevel("DEBUG"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	logf(nil, logrus.DebugLevel, "clicked element %v", "button")
+	if !strings.Contains(buf.String(), "clicked element button") {
+		t.Errorf("Expected debug messages to be logged, got %q", buf.String())
+	}
+
+	buf.Reset()
+	if err := SetLogLevel("none"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	logf(nil, logrus.ErrorLevel, "failed")
+	if buf.Len() != 0 {
+		t.Errorf("Expected nothing to be logged, got %q", buf.String())
+	}
+
+	if err := SetLogLevel("verbose"); err == nil {
+		t.Error("Expected an invalid level to be rejected")
+	}
+}
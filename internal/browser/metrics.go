@@ -0,0 +1,208 @@
+package browser
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/grafana/sobek"
+	"go.k6.io/k6/js/modules"
+	"go.k6.io/k6/metrics"
+)
+
+// K6Metrics are the custom k6 metrics emitted by the extension
+type K6Metrics struct {
+	// Load timings of documents
+	TTFB                 *metrics.Metric
+	DOMContentLoaded     *metrics.Metric
+	Load                 *metrics.Metric
+	FirstPaint           *metrics.Metric
+	FirstContentfulPaint *metrics.Metric
+
+	// Durations of WebDriver operations
+	NavigationDuration  *metrics.Metric
+	ClickDuration       *metrics.Metric
+	FindElementDuration *metrics.Metric
+	SendKeysDuration    *metrics.Metric
+	ScreenshotDuration  *metrics.Metric
+}
+
+// RegisterK6Metrics registers the extension's metrics in the registry
+// It must be called in the init context, e.g. by NewModuleInstance
+func RegisterK6Metrics(registry *metrics.Registry) *K6Metrics {
+	return &K6Metrics{
+		TTFB:                 registry.MustNewMetric("safari_ttfb", metrics.Trend, metrics.Time),
+		DOMContentLoaded:     registry.MustNewMetric("safari_dom_content_loaded", metrics.Trend, metrics.Time),
+		Load:                 registry.MustNewMetric("safari_load", metrics.Trend, metrics.Time),
+		FirstPaint:           registry.MustNewMetric("safari_first_paint", metrics.Trend, metrics.Time),
+		FirstContentfulPaint: registry.MustNewMetric("safari_first_contentful_paint", metrics.Trend, metrics.Time),
+
+		NavigationDuration:  registry.MustNewMetric("safari_navigation_duration", metrics.Trend, metrics.Time),
+		ClickDuration:       registry.MustNewMetric("safari_click_duration", metrics.Trend, metrics.Time),
+		FindElementDuration: registry.MustNewMetric("safari_find_element_duration", metrics.Trend, metrics.Time),
+		SendKeysDuration:    registry.MustNewMetric("safari_send_keys_duration", metrics.Trend, metrics.Time),
+		ScreenshotDuration:  registry.MustNewMetric("safari_screenshot_duration", metrics.Trend, metrics.Time),
+	}
+}
+
+// operation is a WebDriver operation whose duration is emitted as a k6 metric
+type operation int
+
+const (
+	opNavigation operation = iota
+	opClick
+	opFindElement
+	opSendKeys
+	opScreenshot
+)
+
+// durationMetric returns the metric of the operation's duration
+func (m *K6Metrics) durationMetric(op operation) *metrics.Metric {
+	switch op {
+	case opNavigation:
+		return m.NavigationDuration
+	case opClick:
+		return m.ClickDuration
+	case opFindElement:
+		return m.FindElementDuration
+	case opSendKeys:
+		return m.SendKeysDuration
+	case opScreenshot:
+		return m.ScreenshotDuration
+	}
+	return nil
+}
+
+// push emits the values, in milliseconds, with the VU's current tags
+// Nothing is emitted outside of a VU iteration, e.g. in the init context
+func (m *K6Metrics) push(vu modules.VU, values map[*metrics.Metric]float64) {
+	if m == nil || vu == nil {
+		return
+	}
+	state := vu.State()
+	if state == nil {
+		return
+	}
+
+	tags := state.Tags.GetCurrentValues().Tags
+	now := time.Now()
+
+	samples := make(metrics.Samples, 0, len(values))
+	for metric, value := range values {
+		samples = append(samples, metrics.Sample{
+			TimeSeries: metrics.TimeSeries{Metric: metric, Tags: tags},
+			Time:       now,
+			Value:      value,
+		})
+	}
+	metrics.PushIfNotDone(vu.Context(), state.Samples, samples)
+}
+
+// observe emits the duration of the operation, started at start, if the client
+// emits metrics. Failed operations are included, as their time was spent too
+func (c *WebDriverClient) observe(op operation, start time.Time) {
+	if c.metrics == nil {
+		return
+	}
+	c.metrics.push(c.vu, map[*metrics.Metric]float64{
+		c.metrics.durationMetric(op): metrics.D(time.Since(start)),
+	})
+}
+
+// PageMetrics are the load timings of the page's document, in milliseconds
+// since the navigation started. Timings of events that didn't happen yet are 0
+type PageMetrics struct {
+	TTFB                 float64 `js:"ttfb"` // Time to the first byte of the response
+	DOMContentLoaded     float64 `js:"domContentLoaded"`
+	Load                 float64 `js:"load"`
+	FirstPaint           float64 `js:"firstPaint"`
+	FirstContentfulPaint float64 `js:"firstContentfulPaint"`
+}
+
+// pageMetricsScript reads the load timings from Navigation Timing Level 2, falling
+// back to the deprecated performance.timing, and from Paint Timing
+const pageMetricsScript = `
+	var result = { ttfb: 0, domContentLoaded: 0, load: 0, firstPaint: 0, firstContentfulPaint: 0 };
+	var nav = performance.getEntriesByType ? performance.getEntriesByType('navigation')[0] : null;
+	if (nav) {
+		result.ttfb = nav.responseStart;
+		result.domContentLoaded = nav.domContentLoadedEventEnd;
+		result.load = nav.loadEventEnd;
+	} else if (performance.timing) {
+		var t = performance.timing;
+		var since = function(end) { return end > 0 ? end - t.navigationStart : 0; };
+		result.ttfb = since(t.responseStart);
+		result.domContentLoaded = since(t.domContentLoadedEventEnd);
+		result.load = since(t.loadEventEnd);
+	}
+	if (performance.getEntriesByType) {
+		performance.getEntriesByType('paint').forEach(function(entry) {
+			if (entry.name === 'first-paint') result.firstPaint = entry.startTime;
+			if (entry.name === 'first-contentful-paint') result.firstContentfulPaint = entry.startTime;
+		});
+	}
+	return result;
+`
+
+// Metrics returns the load timings of the page's current document
+func (p *Page) Metrics() (*sobek.Promise, error) {
+	if p.client == nil {
+		return nil, fmt.Errorf("browser session not initialized")
+	}
+
+	return p.promise(func() (any, error) {
+		return p.loadMetrics(context.Background())
+	}), nil
+}
+
+// loadMetrics reads the load timings of the page's current document
+func (p *Page) loadMetrics(ctx context.Context) (*PageMetrics, error) {
+	result, err := p.client.ExecuteScript(ctx, pageMetricsScript, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read page metrics: %w", err)
+	}
+
+	values, _ := result.(map[string]interface{})
+	timing := func(key string) float64 {
+		v, _ := toFloat64(values[key])
+		return v
+	}
+
+	return &PageMetrics{
+		TTFB:                 timing("ttfb"),
+		DOMContentLoaded:     timing("domContentLoaded"),
+		Load:                 timing("load"),
+		FirstPaint:           timing("firstPaint"),
+		FirstContentfulPaint: timing("firstContentfulPaint"),
+	}, nil
+}
+
+// emitLoadMetrics emits the load timings of the page's current document as k6
+// metrics, skipping the events that didn't happen yet
+func (p *Page) emitLoadMetrics(ctx context.Context) error {
+	if p.browser == nil || p.browser.Metrics == nil {
+		return nil
+	}
+
+	pm, err := p.loadMetrics(ctx)
+	if err != nil {
+		return err
+	}
+
+	m := p.browser.Metrics
+	values := make(map[*metrics.Metric]float64)
+	for metric, value := range map[*metrics.Metric]float64{
+		m.TTFB:                 pm.TTFB,
+		m.DOMContentLoaded:     pm.DOMContentLoaded,
+		m.Load:                 pm.Load,
+		m.FirstPaint:           pm.FirstPaint,
+		m.FirstContentfulPaint: pm.FirstContentfulPaint,
+	} {
+		if value > 0 {
+			values[metric] = value
+		}
+	}
+	m.push(p.vu, values)
+
+	return nil
+}
@@ -0,0 +1,138 @@
+package browser
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.k6.io/k6/js/modulestest"
+	"go.k6.io/k6/lib"
+	"go.k6.io/k6/metrics"
+)
+
+func newMetricsServer(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"value":{"ttfb":42.5,"domContentLoaded":310,"load":0,"firstPaint":120,"firstContentfulPaint":135}}`))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestPageLoadMetrics(t *testing.T) {
+	server := newMetricsServer(t)
+	page := &Page{client: NewWebDriverClient(server.URL).forSession("session-1")}
+
+	pm, err := page.loadMetrics(context.Background())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := PageMetrics{TTFB: 42.5, DOMContentLoaded: 310, FirstPaint: 120, FirstContentfulPaint: 135}
+	if *pm != expected {
+		t.Errorf("Expected %+v, got %+v", expected, *pm)
+	}
+}
+
+func TestPageEmitLoadMetrics(t *testing.T) {
+	server := newMetricsServer(t)
+	runtime := modulestest.NewRuntime(t)
+	registry := runtime.VU.InitEnv().Registry
+	k6Metrics := RegisterK6Metrics(registry)
+
+	samples := make(chan metrics.SampleContainer, 10)
+	runtime.MoveToVUContext(&lib.State{
+		Samples: samples,
+		Tags:    lib.NewVUStateTags(registry.RootTagSet().With("scenario", "checkout")),
+	})
+
+	page := &Page{
+		vu:      runtime.VU,
+		client:  NewWebDriverClient(server.URL).forSession("session-1"),
+		browser: &Browser{Metrics: k6Metrics},
+	}
+	if err := page.emitLoadMetrics(context.Background()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	emitted := make(map[string]float64)
+	for _, sample := range (<-samples).GetSamples() {
+		emitted[sample.Metric.Name] = sample.Value
+		if scenario, _ := sample.Tags.Get("scenario"); scenario != "checkout" {
+			t.Errorf("Expected the VU's tags on %s, got %v", sample.Metric.Name, sample.Tags.Map())
+		}
+	}
+
+	// The load event didn't happen yet, so it isn't emitted
+	expected := map[string]float64{
+		"safari_ttfb":                   42.5,
+		"safari_dom_content_loaded":     310,
+		"safari_first_paint":            120,
+		"safari_first_contentful_paint": 135,
+	}
+	if len(emitted) != len(expected) {
+		t.Errorf("Expected %v, got %v", expected, emitted)
+	}
+	for name, value := range expected {
+		if emitted[name] != value {
+			t.Errorf("Expected %s to be %v, got %v", name, value, emitted[name])
+		}
+	}
+}
+
+func TestWebDriverClientOperationMetrics(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"value":[]}`))
+	}))
+	defer server.Close()
+
+	runtime := modulestest.NewRuntime(t)
+	registry := runtime.VU.InitEnv().Registry
+	k6Metrics := RegisterK6Metrics(registry)
+
+	samples := make(chan metrics.SampleContainer, 10)
+	runtime.MoveToVUContext(&lib.State{
+		Samples: samples,
+		Tags:    lib.NewVUStateTags(registry.RootTagSet()),
+	})
+
+	client := NewWebDriverClient(server.URL)
+	client.metrics, client.vu = k6Metrics, runtime.VU
+	client = client.forSession("session-1")
+
+	ctx := context.Background()
+	if _, err := client.FindAllElements(ctx, "li"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := client.SendKeys(ctx, "input-1", "hello"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var emitted []string
+	for len(samples) > 0 {
+		for _, sample := range (<-samples).GetSamples() {
+			emitted = append(emitted, sample.Metric.Name)
+			if sample.Value < 0 {
+				t.Errorf("Expected a duration, got %v", sample.Value)
+			}
+		}
+	}
+
+	expected := []string{"safari_find_element_duration", "safari_send_keys_duration"}
+	if len(emitted) != len(expected) || emitted[0] != expected[0] || emitted[1] != expected[1] {
+		t.Errorf("Expected %v, got %v", expected, emitted)
+	}
+
+	// Clients without metrics don't emit anything
+	client.metrics = nil
+	if _, err := client.FindAllElements(ctx, "li"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(samples) != 0 {
+		t.Errorf("Expected no samples, got %d", len(samples))
+	}
+}
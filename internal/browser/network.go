@@ -0,0 +1,158 @@
+package browser
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/grafana/sobek"
+	"github.com/sirupsen/logrus"
+)
+
+// Request is a fetch or XMLHttpRequest request made by a page, passed to the
+// handler set with Page.OnRequest. WebDriver can't observe the network, so
+// requests are recorded by the injection script's fetch and XHR instrumentation
+type Request struct {
+	URL          string `js:"url"`
+	Method       string `js:"method"`
+	ResourceType string `js:"resourceType"` // "fetch" or "xhr"
+	Timestamp    int64  `js:"timestamp"`    // When the request started, in ms since the Unix epoch
+}
+
+// takeNetworkEventsScript returns and clears the request and response events
+// buffered by the injection script, with the number of events dropped once it
+// was full
+const takeNetworkEventsScript = `
+	var buffer = window.__webdriverNetwork;
+	if (!buffer || !buffer.events) return { events: [], dropped: 0 };
+	var result = { events: buffer.events, dropped: buffer.dropped };
+	buffer.events = [];
+	buffer.dropped = 0;
+	return result;
+`
+
+// networkEvent is a request, or the response to it if response isn't nil
+type networkEvent struct {
+	request  *Request
+	response *Response
+}
+
+// OnRequest sets the handler called with every fetch and XMLHttpRequest request
+// the page makes, or clears it if handler is null. Like console messages,
+// requests are collected at the end of each page operation
+func (p *Page) OnRequest(handler sobek.Value) error {
+	callable, err := handlerFrom("request", handler)
+	if err != nil {
+		return err
+	}
+
+	p.handlerMu.Lock()
+	defer p.handlerMu.Unlock()
+
+	p.requestHandler = callable
+	return nil
+}
+
+// OnResponse sets the handler called with the response to every fetch and
+// XMLHttpRequest request the page makes, or clears it if handler is null.
+// Requests that failed without a response, e.g. on network errors, are passed
+// with status 0
+func (p *Page) OnResponse(handler sobek.Value) error {
+	callable, err := handlerFrom("response", handler)
+	if err != nil {
+		return err
+	}
+
+	p.handlerMu.Lock()
+	defer p.handlerMu.Unlock()
+
+	p.responseHandler = callable
+	return nil
+}
+
+// dispatchNetworkEvents passes the requests and responses buffered by the page
+// to the request and response handlers, if any is set. It must be called in
+// the page's window
+func (p *Page) dispatchNetworkEvents(ctx context.Context) {
+	p.handlerMu.Lock()
+	onRequest, onResponse := p.requestHandler, p.responseHandler
+	p.handlerMu.Unlock()
+	if (onRequest == nil && onResponse == nil) || p.client == nil {
+		return
+	}
+
+	events, dropped, err := p.client.takeNetworkEvents(ctx)
+	if err != nil {
+		logf(p.vu, logrus.DebugLevel, "failed to collect network events: %v", err)
+		return
+	}
+	if dropped > 0 {
+		logf(p.vu, logrus.WarnLevel, "dropped %d network events recorded while the page's buffer was full", dropped)
+	}
+
+	for _, event := range events {
+		handler, arg := onRequest, any(event.request)
+		if event.response != nil {
+			handler, arg = onResponse, event.response
+		}
+		if handler == nil {
+			continue
+		}
+
+		err := p.callHandler(func(rt *sobek.Runtime) error {
+			_, err := handler(sobek.Undefined(), rt.ToValue(arg))
+			return err
+		})
+		if errors.Is(err, errNoCallback) {
+			return
+		}
+		if err != nil {
+			logf(p.vu, logrus.WarnLevel, "network handler failed: %v", err)
+		}
+	}
+}
+
+// takeNetworkEvents returns and clears the request and response events
+// buffered by the injection script, and the number of events it dropped
+func (c *WebDriverClient) takeNetworkEvents(ctx context.Context) ([]networkEvent, int, error) {
+	result, err := c.ExecuteScript(ctx, takeNetworkEventsScript, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	buffer, ok := result.(map[string]interface{})
+	if !ok {
+		return nil, 0, fmt.Errorf("unexpected network events result: %v", result)
+	}
+
+	var events []networkEvent
+	items, _ := buffer["events"].([]interface{})
+	for _, item := range items {
+		fields, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		requestFields, _ := fields["request"].(map[string]interface{})
+		request := &Request{}
+		request.URL, _ = requestFields["url"].(string)
+		request.Method, _ = requestFields["method"].(string)
+		request.ResourceType, _ = requestFields["resourceType"].(string)
+		if timestamp, ok := toFloat64(requestFields["timestamp"]); ok {
+			request.Timestamp = int64(timestamp)
+		}
+
+		event := networkEvent{request: request}
+		if fields["event"] == "response" {
+			event.response = &Response{URL: request.URL, Request: request}
+			if status, ok := toFloat64(fields["status"]); ok {
+				event.response.Status = int(status)
+			}
+			event.response.StatusText, _ = fields["statusText"].(string)
+			event.response.Duration, _ = toFloat64(fields["duration"])
+		}
+		events = append(events, event)
+	}
+
+	dropped, _ := toFloat64(buffer["dropped"])
+	return events, int(dropped), nil
+}
@@ -0,0 +1,93 @@
+package browser
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"go.k6.io/k6/js/modulestest"
+)
+
+func TestPageOnRequestAndResponse(t *testing.T) {
+	var mu sync.Mutex
+	buffered := `[
+		{"event":"request","request":{"url":"https://example.com/api/cart","method":"POST","resourceType":"fetch","timestamp":1700000000000}},
+		{"event":"request","request":{"url":"https://example.com/data.json","method":"GET","resourceType":"xhr","timestamp":1700000000005}},
+		{"event":"response","request":{"url":"https://example.com/api/cart","method":"POST","resourceType":"fetch","timestamp":1700000000000},"status":201,"statusText":"Created","duration":42.5},
+		{"event":"response","request":{"url":"https://example.com/data.json","method":"GET","resourceType":"xhr","timestamp":1700000000005},"status":0,"statusText":"","duration":3}
+	]`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		if !strings.Contains(string(body), "__webdriverNetwork") {
+			_, _ = w.Write([]byte(`{"value":1}`))
+			return
+		}
+		events := buffered
+		buffered = "[]"
+		_, _ = w.Write([]byte(`{"value":{"events":` + events + `,"dropped":0}}`))
+	}))
+	defer server.Close()
+
+	runtime := modulestest.NewRuntime(t)
+	page := &Page{vu: runtime.VU, client: NewWebDriverClient(server.URL).forSession("session-1")}
+	rt := runtime.VU.Runtime()
+	if err := rt.Set("page", page); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := runtime.RunOnEventLoop(`
+		var events = [];
+		page.onRequest(function(request) {
+			events.push(request.method + " " + request.url + " (" + request.resourceType + ") at " + request.timestamp);
+		});
+		page.onResponse(function(response) {
+			events.push(response.status + " " + response.statusText + " " + response.request.method + " " +
+				response.url + " ok=" + response.ok() + " in " + response.duration + "ms");
+		});
+		page.evaluate("return 1;");
+	`)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := []string{
+		"POST https://example.com/api/cart (fetch) at 1700000000000",
+		"GET https://example.com/data.json (xhr) at 1700000000005",
+		"201 Created POST https://example.com/api/cart ok=true in 42.5ms",
+		"0  GET https://example.com/data.json ok=false in 3ms",
+	}
+	if got := rt.Get("events").String(); got != strings.Join(expected, ",") {
+		t.Errorf("Expected events %q, got %q", expected, got)
+	}
+
+	// With only one of the handlers set, the other events are skipped
+	mu.Lock()
+	buffered = `[
+		{"event":"request","request":{"url":"https://example.com/a","method":"GET","resourceType":"fetch","timestamp":1}},
+		{"event":"response","request":{"url":"https://example.com/a","method":"GET","resourceType":"fetch","timestamp":1},"status":200,"statusText":"OK","duration":1}
+	]`
+	mu.Unlock()
+	_, err = runtime.RunOnEventLoop(`
+		events = [];
+		page.onRequest(null);
+		page.evaluate("return 1;");
+	`)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got := rt.Get("events").String(); got != "200 OK GET https://example.com/a ok=true in 1ms" {
+		t.Errorf("Expected only the response, got %q", got)
+	}
+
+	if err := page.OnResponse(rt.ToValue("not a function")); err == nil || err.Error() != "response handler must be a function" {
+		t.Errorf("Expected error for a handler that isn't a function, got %v", err)
+	}
+}
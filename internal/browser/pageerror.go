@@ -0,0 +1,143 @@
+package browser
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/grafana/sobek"
+	"github.com/sirupsen/logrus"
+)
+
+// SourceLocation is where in a page's scripts an error was thrown
+type SourceLocation struct {
+	URL          string `js:"url"`
+	LineNumber   int    `js:"lineNumber"`   // 0 if unknown
+	ColumnNumber int    `js:"columnNumber"` // 0 if unknown
+}
+
+// PageError is an uncaught exception or unhandled promise rejection in a page,
+// passed to the handler set with Page.OnPageError
+type PageError struct {
+	message  string
+	stack    string
+	location SourceLocation
+}
+
+// Message returns the error's message
+func (e *PageError) Message() string {
+	return e.message
+}
+
+// Stack returns the error's stack trace, or "" if the page threw a value that
+// isn't an Error
+func (e *PageError) Stack() string {
+	return e.stack
+}
+
+// Location returns where the error was thrown. Unhandled rejections are
+// located at the page's URL only
+func (e *PageError) Location() SourceLocation {
+	return e.location
+}
+
+// takePageErrorsScript returns and clears the errors buffered by the injection
+// script, with the number of errors dropped once it was full
+const takePageErrorsScript = `
+	var buffer = window.__webdriverPageErrors;
+	if (!buffer) return { errors: [], dropped: 0 };
+	var result = { errors: buffer.errors, dropped: buffer.dropped };
+	buffer.errors = [];
+	buffer.dropped = 0;
+	return result;
+`
+
+// OnPageError sets the handler called with every uncaught exception and
+// unhandled promise rejection in the page, or clears it if handler is null.
+// Like console messages, errors are collected at the end of each page
+// operation. If the handler throws, the operation fails with its exception
+func (p *Page) OnPageError(handler sobek.Value) error {
+	callable, err := handlerFrom("page error", handler)
+	if err != nil {
+		return err
+	}
+
+	p.handlerMu.Lock()
+	defer p.handlerMu.Unlock()
+
+	p.pageErrorHandler = callable
+	return nil
+}
+
+// dispatchPageErrors passes the errors buffered by the page to the page error
+// handler, if one is set, returning the first exception the handler threw. It
+// must be called in the page's window
+func (p *Page) dispatchPageErrors(ctx context.Context) error {
+	p.handlerMu.Lock()
+	handler := p.pageErrorHandler
+	p.handlerMu.Unlock()
+	if handler == nil || p.client == nil {
+		return nil
+	}
+
+	pageErrors, dropped, err := p.client.takePageErrors(ctx)
+	if err != nil {
+		logf(p.vu, logrus.DebugLevel, "failed to collect page errors: %v", err)
+		return nil
+	}
+	if dropped > 0 {
+		logf(p.vu, logrus.WarnLevel, "dropped %d page errors thrown while the page's buffer was full", dropped)
+	}
+
+	var handlerErr error
+	for _, pageErr := range pageErrors {
+		err := p.callHandler(func(rt *sobek.Runtime) error {
+			_, err := handler(sobek.Undefined(), rt.ToValue(pageErr))
+			return err
+		})
+		if errors.Is(err, errNoCallback) {
+			return nil
+		}
+		if err != nil && handlerErr == nil {
+			handlerErr = err
+		}
+	}
+	return handlerErr
+}
+
+// takePageErrors returns and clears the errors buffered by the injection
+// script, and the number of errors it dropped
+func (c *WebDriverClient) takePageErrors(ctx context.Context) ([]*PageError, int, error) {
+	result, err := c.ExecuteScript(ctx, takePageErrorsScript, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	buffer, ok := result.(map[string]interface{})
+	if !ok {
+		return nil, 0, fmt.Errorf("unexpected page errors result: %v", result)
+	}
+
+	var pageErrors []*PageError
+	items, _ := buffer["errors"].([]interface{})
+	for _, item := range items {
+		fields, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		pageErr := &PageError{}
+		pageErr.message, _ = fields["message"].(string)
+		pageErr.stack, _ = fields["stack"].(string)
+		pageErr.location.URL, _ = fields["url"].(string)
+		if line, ok := toFloat64(fields["line"]); ok {
+			pageErr.location.LineNumber = int(line)
+		}
+		if column, ok := toFloat64(fields["column"]); ok {
+			pageErr.location.ColumnNumber = int(column)
+		}
+		pageErrors = append(pageErrors, pageErr)
+	}
+
+	dropped, _ := toFloat64(buffer["dropped"])
+	return pageErrors, int(dropped), nil
+}
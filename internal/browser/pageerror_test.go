@@ -0,0 +1,80 @@
+package browser
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"go.k6.io/k6/js/modulestest"
+)
+
+func TestPageOnPageError(t *testing.T) {
+	var mu sync.Mutex
+	buffered := `[{"message":"boom","stack":"Error: boom\n    at load (https://example.com/app.js:3:7)","url":"https://example.com/app.js","line":3,"column":7}]`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		if !strings.Contains(string(body), "__webdriverPageErrors") {
+			_, _ = w.Write([]byte(`{"value":1}`))
+			return
+		}
+		errors := buffered
+		buffered = "[]"
+		_, _ = w.Write([]byte(`{"value":{"errors":` + errors + `,"dropped":0}}`))
+	}))
+	defer server.Close()
+
+	runtime := modulestest.NewRuntime(t)
+	page := &Page{vu: runtime.VU, client: NewWebDriverClient(server.URL).forSession("session-1")}
+	rt := runtime.VU.Runtime()
+	if err := rt.Set("page", page); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := runtime.RunOnEventLoop(`
+		var seen = [], result, failure = "";
+		page.onPageError(function(error) {
+			var location = error.location();
+			seen.push(error.message() + " at " + location.url + ":" + location.lineNumber + ":" + location.columnNumber);
+			seen.push(error.stack().split("\n")[0]);
+		});
+		page.evaluate("return 1;").then(function(value) { result = value; });
+	`)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := "boom at https://example.com/app.js:3:7,Error: boom"
+	if got := rt.Get("seen").String(); got != expected {
+		t.Errorf("Expected the handler to get %s, got %s", expected, got)
+	}
+	if got := rt.Get("result").ToInteger(); got != 1 {
+		t.Errorf("Expected the operation to resolve, got %d", got)
+	}
+
+	// A throwing handler fails the operation, so page errors can fail the test
+	mu.Lock()
+	buffered = `[{"message":"undefined is not an object","stack":"","url":"https://example.com/","line":0,"column":0}]`
+	mu.Unlock()
+	_, err = runtime.RunOnEventLoop(`
+		page.onPageError(function(error) { throw new Error("page threw: " + error.message()); });
+		page.evaluate("return 1;").catch(function(e) { failure = String(e); });
+	`)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got := rt.Get("failure").String(); !strings.Contains(got, "page threw: undefined is not an object") {
+		t.Errorf("Expected the operation to fail with the handler's exception, got %q", got)
+	}
+
+	if err := page.OnPageError(rt.ToValue(42)); err == nil {
+		t.Error("Expected error for a handler that isn't a function")
+	}
+}
@@ -0,0 +1,162 @@
+package browser
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/grafana/sobek"
+)
+
+// paperFormats are the page sizes accepted by the format option of Pdf, in centimeters
+var paperFormats = map[string][2]float64{
+	"letter":  {21.59, 27.94},
+	"legal":   {21.59, 35.56},
+	"tabloid": {27.94, 43.18},
+	"ledger":  {43.18, 27.94},
+	"a0":      {84.1, 118.9},
+	"a1":      {59.4, 84.1},
+	"a2":      {42, 59.4},
+	"a3":      {29.7, 42},
+	"a4":      {21, 29.7},
+	"a5":      {14.8, 21},
+	"a6":      {10.5, 14.8},
+}
+
+// cmPerUnit converts the CSS units accepted for PDF sizes to centimeters
+var cmPerUnit = map[string]float64{
+	"px": 2.54 / 96,
+	"in": 2.54,
+	"cm": 1,
+	"mm": 0.1,
+}
+
+// Pdf renders the page as a PDF, using its print stylesheet, and returns it
+// If options.path is set, the PDF is also written to that file
+// Options: format ("Letter", "A4", ...), width and height (override format),
+// margin ({ top, right, bottom, left }), landscape, printBackground, scale
+// and pageRanges ("1-3, 5"). Sizes are numbers in pixels or strings with a
+// px, in, cm or mm unit
+func (p *Page) Pdf(options map[string]interface{}) (*sobek.Promise, error) {
+	if p.client == nil {
+		return nil, fmt.Errorf("browser session not initialized")
+	}
+
+	printOptions, err := printOptionsFrom(options)
+	if err != nil {
+		return nil, err
+	}
+
+	return p.promise(func() (any, error) {
+		ctx := context.Background()
+		pdfData, err := p.client.PrintPage(ctx, printOptions)
+		if err != nil {
+			return nil, fmt.Errorf("failed to print PDF: %w", err)
+		}
+
+		if path, ok := options["path"].(string); ok && path != "" {
+			if err := os.WriteFile(path, pdfData, 0644); err != nil {
+				return nil, fmt.Errorf("failed to write PDF to file: %w", err)
+			}
+		}
+
+		return pdfData, nil
+	}), nil
+}
+
+// printOptionsFrom parses the options of Pdf
+func printOptionsFrom(options map[string]interface{}) (PrintOptions, error) {
+	var po PrintOptions
+	if options == nil {
+		return po, nil
+	}
+
+	po.Landscape, _ = options["landscape"].(bool)
+	po.Background, _ = options["printBackground"].(bool)
+
+	if scale, ok := toFloat64(options["scale"]); ok {
+		if scale < 0.1 || scale > 2 {
+			return po, fmt.Errorf("pdf scale must be between 0.1 and 2, got %v", scale)
+		}
+		po.Scale = scale
+	}
+
+	if format, ok := options["format"].(string); ok && format != "" {
+		size, ok := paperFormats[strings.ToLower(format)]
+		if !ok {
+			return po, fmt.Errorf("unknown pdf format '%s'", format)
+		}
+		po.PageWidth, po.PageHeight = size[0], size[1]
+	}
+
+	var err error
+	if value, ok := options["width"]; ok {
+		if po.PageWidth, err = parsePrintSize(value); err != nil {
+			return po, fmt.Errorf("invalid pdf width: %w", err)
+		}
+	}
+	if value, ok := options["height"]; ok {
+		if po.PageHeight, err = parsePrintSize(value); err != nil {
+			return po, fmt.Errorf("invalid pdf height: %w", err)
+		}
+	}
+
+	if margin, ok := options["margin"].(map[string]interface{}); ok {
+		for side, target := range map[string]**float64{
+			"top": &po.MarginTop, "bottom": &po.MarginBottom, "left": &po.MarginLeft, "right": &po.MarginRight,
+		} {
+			value, ok := margin[side]
+			if !ok {
+				continue
+			}
+			size, err := parsePrintSize(value)
+			if err != nil {
+				return po, fmt.Errorf("invalid pdf %s margin: %w", side, err)
+			}
+			*target = &size
+		}
+	}
+
+	if ranges, ok := options["pageRanges"].(string); ok {
+		for _, r := range strings.Split(ranges, ",") {
+			if r = strings.TrimSpace(r); r != "" {
+				po.PageRanges = append(po.PageRanges, r)
+			}
+		}
+	}
+
+	return po, nil
+}
+
+// parsePrintSize converts a size given as a number of pixels or as a string
+// with a px, in, cm or mm unit (default px) to centimeters
+func parsePrintSize(value interface{}) (float64, error) {
+	if px, ok := toFloat64(value); ok {
+		if px < 0 {
+			return 0, fmt.Errorf("size must not be negative, got %v", px)
+		}
+		return px * cmPerUnit["px"], nil
+	}
+
+	s, ok := value.(string)
+	if !ok {
+		return 0, fmt.Errorf("size must be a number or a string, got %T", value)
+	}
+	s = strings.TrimSpace(strings.ToLower(s))
+
+	unit := "px"
+	if len(s) > 2 {
+		if _, known := cmPerUnit[s[len(s)-2:]]; known {
+			unit = s[len(s)-2:]
+			s = strings.TrimSpace(s[:len(s)-2])
+		}
+	}
+
+	n, err := strconv.ParseFloat(s, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid size '%v'", value)
+	}
+	return n * cmPerUnit[unit], nil
+}
@@ -0,0 +1,80 @@
+package browser
+
+import (
+	"math"
+	"testing"
+)
+
+func TestPrintOptionsFrom(t *testing.T) {
+	po, err := printOptionsFrom(map[string]interface{}{
+		"format":          "A4",
+		"landscape":       true,
+		"printBackground": true,
+		"scale":           0.5,
+		"margin":          map[string]interface{}{"top": "1in", "bottom": "10mm", "left": int64(96), "right": "0"},
+		"pageRanges":      "1-3, 5",
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if po.PageWidth != 21 || po.PageHeight != 29.7 {
+		t.Errorf("Expected A4 page size, got %vx%v", po.PageWidth, po.PageHeight)
+	}
+	if !po.Landscape || !po.Background || po.Scale != 0.5 {
+		t.Errorf("Unexpected options: %+v", po)
+	}
+	margins := map[string]*float64{"top": po.MarginTop, "bottom": po.MarginBottom, "left": po.MarginLeft, "right": po.MarginRight}
+	for side, want := range map[string]float64{"top": 2.54, "bottom": 1, "left": 2.54, "right": 0} {
+		if got := margins[side]; got == nil || math.Abs(*got-want) > 1e-9 {
+			t.Errorf("Expected %s margin %vcm, got %v", side, want, got)
+		}
+	}
+	if len(po.PageRanges) != 2 || po.PageRanges[0] != "1-3" || po.PageRanges[1] != "5" {
+		t.Errorf("Unexpected page ranges: %q", po.PageRanges)
+	}
+
+	// width and height override the format
+	po, err = printOptionsFrom(map[string]interface{}{"format": "Letter", "width": "10cm", "height": 378})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if po.PageWidth != 10 || math.Abs(po.PageHeight-10.0012) > 1e-3 {
+		t.Errorf("Expected 10cm x 10cm, got %vx%v", po.PageWidth, po.PageHeight)
+	}
+
+	// Margins that aren't given keep the WebDriver defaults
+	if po.MarginTop != nil {
+		t.Errorf("Expected default top margin, got %v", *po.MarginTop)
+	}
+
+	for _, options := range []map[string]interface{}{
+		{"format": "B5"},
+		{"scale": 3.0},
+		{"width": "wide"},
+		{"margin": map[string]interface{}{"top": "-1cm"}},
+		{"height": true},
+	} {
+		if _, err := printOptionsFrom(options); err == nil {
+			t.Errorf("Expected error for %v", options)
+		}
+	}
+}
+
+func TestPrintOptionsPayload(t *testing.T) {
+	zero := 0.0
+	payload := PrintOptions{Landscape: true, PageWidth: 21, MarginLeft: &zero}.payload()
+
+	if payload["orientation"] != "landscape" || payload["background"] != false {
+		t.Errorf("Unexpected payload: %v", payload)
+	}
+	if page, _ := payload["page"].(map[string]interface{}); page["width"] != 21.0 || page["height"] != nil {
+		t.Errorf("Expected only the page width, got %v", payload["page"])
+	}
+	if margin, _ := payload["margin"].(map[string]interface{}); len(margin) != 1 || margin["left"] != 0.0 {
+		t.Errorf("Expected only the left margin, got %v", payload["margin"])
+	}
+	if _, ok := (PrintOptions{}).payload()["scale"]; ok {
+		t.Error("Expected the default scale to be left out")
+	}
+}
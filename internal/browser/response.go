@@ -0,0 +1,69 @@
+package browser
+
+import (
+	"context"
+	"fmt"
+)
+
+// Response describes the HTTP response the page's current document was loaded
+// from, or the response to a request passed to Page.OnResponse
+type Response struct {
+	Status int    `js:"status"`
+	URL    string `js:"url"`
+
+	// Only set for the responses passed to Page.OnResponse
+	StatusText string   `js:"statusText"`
+	Request    *Request `js:"request"`
+	Duration   float64  `js:"duration"` // Time from the request's start to its response, in ms
+}
+
+// Ok returns whether the status is in the 2xx range
+func (r *Response) Ok() bool {
+	return r.Status >= 200 && r.Status <= 299
+}
+
+// navigationResponseScript approximates the response of the current document,
+// as WebDriver doesn't expose HTTP statuses. It reads the status from Navigation
+// Timing where supported, and otherwise requests the document again, with HEAD
+// unless the server doesn't allow it. Documents that weren't loaded over HTTP,
+// like about:blank, have no response
+const navigationResponseScript = `
+	if (location.protocol !== 'http:' && location.protocol !== 'https:') return null;
+	var nav = performance.getEntriesByType ? performance.getEntriesByType('navigation')[0] : null;
+	if (nav && nav.responseStatus) return { status: nav.responseStatus, url: location.href };
+	var request = function(method) {
+		var xhr = new XMLHttpRequest();
+		xhr.open(method, location.href, false);
+		xhr.__webdriverInternal = true;
+		xhr.send();
+		return xhr.status;
+	};
+	try {
+		var status = request('HEAD');
+		if (status === 405 || status === 501) status = request('GET');
+		return { status: status, url: location.href };
+	} catch (e) {
+		return null;
+	}
+`
+
+// navigationResponse returns the response the current document was loaded
+// from, or nil when it wasn't loaded over HTTP or the status can't be read
+func (c *WebDriverClient) navigationResponse(ctx context.Context) (*Response, error) {
+	result, err := c.ExecuteScript(ctx, navigationResponseScript, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the navigation response: %w", err)
+	}
+
+	values, ok := result.(map[string]interface{})
+	if !ok {
+		return nil, nil
+	}
+	status, _ := toFloat64(values["status"])
+	url, _ := values["url"].(string)
+	if status == 0 {
+		return nil, nil
+	}
+
+	return &Response{Status: int(status), URL: url}, nil
+}
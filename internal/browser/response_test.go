@@ -0,0 +1,65 @@
+package browser
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go.k6.io/k6/js/modulestest"
+)
+
+func TestPageGotoResponse(t *testing.T) {
+	tests := []struct {
+		name     string
+		response interface{} // Returned by the navigation response script
+		expected string
+	}{
+		{"found", map[string]interface{}{"status": 200, "url": "https://example.com/"}, "200 https://example.com/ true"},
+		{"not found", map[string]interface{}{"status": 404, "url": "https://example.com/missing"}, "404 https://example.com/missing false"},
+		{"no response", nil, "null"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				var payload struct {
+					Script string `json:"script"`
+				}
+				_ = json.NewDecoder(r.Body).Decode(&payload)
+
+				var value interface{}
+				if strings.Contains(payload.Script, "responseStatus") {
+					value = tt.response
+				}
+				w.Header().Set("Content-Type", "application/json")
+				data, _ := json.Marshal(map[string]interface{}{"value": value})
+				_, _ = w.Write(data)
+			}))
+			defer server.Close()
+
+			runtime := modulestest.NewRuntime(t)
+			page := &Page{vu: runtime.VU, client: NewWebDriverClient(server.URL).forSession("session-1")}
+			if err := runtime.VU.Runtime().Set("page", page); err != nil {
+				t.Fatal(err)
+			}
+
+			_, err := runtime.RunOnEventLoop(`
+				var got = "", failure = "";
+				page.goto("https://example.com/")
+					.then(function(r) { got = r === null ? "null" : r.status + " " + r.url + " " + r.ok(); })
+					.catch(function(e) { failure = String(e); });
+			`)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if failure := runtime.VU.Runtime().Get("failure").String(); failure != "" {
+				t.Fatalf("Unexpected failure: %s", failure)
+			}
+			if got := runtime.VU.Runtime().Get("got").String(); got != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,178 @@
+// ARIA role matching for role= selectors
+// These functions are prepended to the generated selector script
+
+// implicitRole returns the ARIA role an element has without a role attribute
+function implicitRole(el) {
+  var tag = el.tagName.toLowerCase();
+  switch (tag) {
+    case 'a':
+    case 'area':
+      return el.hasAttribute('href') ? 'link' : null;
+    case 'button':
+      return 'button';
+    case 'input': {
+      var type = (el.getAttribute('type') || 'text').toLowerCase();
+      switch (type) {
+        case 'button':
+        case 'submit':
+        case 'reset':
+        case 'image':
+          return 'button';
+        case 'checkbox':
+          return 'checkbox';
+        case 'radio':
+          return 'radio';
+        case 'range':
+          return 'slider';
+        case 'number':
+          return 'spinbutton';
+        case 'search':
+          return el.hasAttribute('list') ? 'combobox' : 'searchbox';
+        case 'email':
+        case 'tel':
+        case 'text':
+        case 'url':
+          return el.hasAttribute('list') ? 'combobox' : 'textbox';
+        default:
+          return null;
+      }
+    }
+    case 'select':
+      return el.multiple || el.size > 1 ? 'listbox' : 'combobox';
+    case 'textarea':
+      return 'textbox';
+    case 'option':
+      return 'option';
+    case 'h1':
+    case 'h2':
+    case 'h3':
+    case 'h4':
+    case 'h5':
+    case 'h6':
+      return 'heading';
+    case 'img':
+      return el.getAttribute('alt') === '' ? 'presentation' : 'img';
+    case 'ul':
+    case 'ol':
+    case 'menu':
+      return 'list';
+    case 'li':
+      return 'listitem';
+    case 'nav':
+      return 'navigation';
+    case 'main':
+      return 'main';
+    case 'aside':
+      return 'complementary';
+    case 'header':
+      return el.closest('article, aside, main, nav, section') ? null : 'banner';
+    case 'footer':
+      return el.closest('article, aside, main, nav, section') ? null : 'contentinfo';
+    case 'section':
+      return el.hasAttribute('aria-label') || el.hasAttribute('aria-labelledby') ? 'region' : null;
+    case 'form':
+      return 'form';
+    case 'article':
+      return 'article';
+    case 'dialog':
+      return 'dialog';
+    case 'table':
+      return 'table';
+    case 'tr':
+      return 'row';
+    case 'td':
+      return 'cell';
+    case 'th':
+      return 'columnheader';
+    case 'fieldset':
+    case 'details':
+      return 'group';
+    case 'summary':
+      return 'button';
+    case 'progress':
+      return 'progressbar';
+    case 'hr':
+      return 'separator';
+    case 'output':
+      return 'status';
+    default:
+      return null;
+  }
+}
+
+// elementRole returns the explicit role attribute, falling back to the implicit role
+function elementRole(el) {
+  var explicit = (el.getAttribute('role') || '').trim().split(/\s+/)[0];
+  return explicit || implicitRole(el);
+}
+
+// Roles whose accessible name is computed from their content
+var nameFromContentRoles = {
+  button: true, cell: true, checkbox: true, columnheader: true, heading: true,
+  link: true, menuitem: true, option: true, radio: true, row: true, switch: true,
+  tab: true, tooltip: true, treeitem: true
+};
+
+function normalizeWhitespace(text) {
+  return (text || '').replace(/\s+/g, ' ').trim();
+}
+
+// accessibleName computes a simplified accessible name from aria-labelledby,
+// aria-label, associated labels, alt text, content and title, in that order
+function accessibleName(el, role) {
+  var labelledBy = el.getAttribute('aria-labelledby');
+  if (labelledBy) {
+    var text = labelledBy.split(/\s+/).map(function(id) {
+      var ref = document.getElementById(id);
+      return ref ? ref.textContent : '';
+    }).join(' ');
+    if (normalizeWhitespace(text)) return normalizeWhitespace(text);
+  }
+
+  var label = el.getAttribute('aria-label');
+  if (label && label.trim()) return normalizeWhitespace(label);
+
+  var tag = el.tagName.toLowerCase();
+  if (tag === 'input' || tag === 'select' || tag === 'textarea') {
+    var type = (el.getAttribute('type') || '').toLowerCase();
+    if (type === 'button' || type === 'submit' || type === 'reset') {
+      return normalizeWhitespace(el.value || (type === 'submit' ? 'Submit' : type === 'reset' ? 'Reset' : ''));
+    }
+    if (type === 'image') {
+      return normalizeWhitespace(el.getAttribute('alt') || el.value || 'Submit');
+    }
+    if (el.labels && el.labels.length > 0) {
+      return normalizeWhitespace(Array.from(el.labels).map(function(l) { return l.textContent; }).join(' '));
+    }
+    if (el.getAttribute('placeholder')) return normalizeWhitespace(el.getAttribute('placeholder'));
+  }
+
+  if (tag === 'img' || tag === 'area') {
+    var alt = el.getAttribute('alt');
+    if (alt) return normalizeWhitespace(alt);
+  }
+
+  if (nameFromContentRoles[role]) {
+    var content = normalizeWhitespace(el.textContent);
+    if (content) return content;
+  }
+
+  return normalizeWhitespace(el.getAttribute('title'));
+}
+
+// isAriaHidden reports whether the element is excluded from the accessibility tree
+function isAriaHidden(el) {
+  return !!el.closest('[aria-hidden="true"], [hidden]');
+}
+
+// matchesRole reports whether el has the role and, if given, a matching accessible name
+// nameFilter is matched as a case-insensitive substring, nameRegex as a regular expression
+function matchesRole(el, role, nameFilter, nameRegex) {
+  var elRole = elementRole(el);
+  if (elRole !== role || isAriaHidden(el)) return false;
+  if (nameFilter === null && nameRegex === null) return true;
+
+  var name = accessibleName(el, elRole);
+  if (nameRegex !== null) return new RegExp(nameRegex).test(name);
+  return name.toLowerCase().indexOf(nameFilter.toLowerCase()) !== -1;
+}
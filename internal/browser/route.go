@@ -0,0 +1,133 @@
+package browser
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/grafana/sobek"
+)
+
+// route is a canned answer to the fetch and XMLHttpRequest requests whose URL
+// matches a pattern, passed to the injection script's network instrumentation
+type route struct {
+	Pattern     string            `json:"pattern"` // Source of a JavaScript RegExp
+	Abort       bool              `json:"abort"`
+	Status      int               `json:"status"`
+	StatusText  string            `json:"statusText"`
+	Body        string            `json:"body"`
+	ContentType string            `json:"contentType"`
+	Headers     map[string]string `json:"headers"`
+}
+
+// routesScript sets the routes the injection script answers requests with.
+// Running it again replaces them
+const routesScript = `window.__webdriverRoutes = arguments[0];`
+
+// Route answers the fetch and XMLHttpRequest requests of the pages of the
+// context whose URL matches pattern, a glob or /regex/, with a canned response
+// instead of sending them, e.g. to stub API calls. The handler is either
+// { abort: true }, which fails the request like a network error, or a response
+// with status (default 200), statusText, headers, contentType and a string body
+// or a json value. Routes added later take precedence. WebDriver can't
+// intercept the network, so the routes are applied by the injection script and
+// only cover requests made by page scripts, not subresources like images,
+// stylesheets or scripts, nor navigations
+func (bc *BrowserContext) Route(pattern string, handler map[string]interface{}) (*sobek.Promise, error) {
+	r, err := routeFrom(pattern, handler)
+	if err != nil {
+		return nil, err
+	}
+
+	bc.routesMu.Lock()
+	bc.routes = append(bc.routes, r)
+	routes := append([]route(nil), bc.routes...)
+	bc.routesMu.Unlock()
+
+	return Promise(bc.vu, func() (interface{}, error) {
+		ctx := context.Background()
+		for _, page := range bc.currentPages() {
+			err := page.inWindow(ctx, func() error { return page.applyRoutes(ctx, routes) })
+			if err != nil {
+				return nil, fmt.Errorf("failed to add route: %w", err)
+			}
+		}
+		return nil, nil
+	}), nil
+}
+
+// currentRoutes returns the routes added with Route, in order
+func (bc *BrowserContext) currentRoutes() []route {
+	bc.routesMu.Lock()
+	defer bc.routesMu.Unlock()
+
+	return append([]route(nil), bc.routes...)
+}
+
+// routeFrom validates a route's URL pattern and handler
+func routeFrom(pattern string, handler map[string]interface{}) (route, error) {
+	if pattern == "" {
+		return route{}, fmt.Errorf("route pattern must not be empty")
+	}
+	re, err := urlPatternRegex(pattern)
+	if err != nil {
+		return route{}, fmt.Errorf("invalid route pattern %q: %w", pattern, err)
+	}
+	r := route{Pattern: re.String()}
+
+	if abort, _ := handler["abort"].(bool); abort {
+		r.Abort = true
+		return r, nil
+	}
+
+	r.Status = http.StatusOK
+	if status, ok := toFloat64(handler["status"]); ok {
+		// Responses created by scripts can't have another status
+		if status < 200 || status > 599 {
+			return route{}, fmt.Errorf("route status must be between 200 and 599, got %v", status)
+		}
+		r.Status = int(status)
+	}
+	r.StatusText, _ = handler["statusText"].(string)
+	if r.StatusText == "" {
+		r.StatusText = http.StatusText(r.Status)
+	}
+
+	body, hasBody := handler["body"]
+	value, hasJSON := handler["json"]
+	switch {
+	case hasBody && hasJSON:
+		return route{}, fmt.Errorf("route can't have both a body and a json value")
+	case hasJSON:
+		encoded, err := json.Marshal(value)
+		if err != nil {
+			return route{}, fmt.Errorf("failed to encode the route's json value: %w", err)
+		}
+		r.Body = string(encoded)
+		r.ContentType = "application/json"
+	case hasBody:
+		if r.Body, hasBody = body.(string); !hasBody {
+			return route{}, fmt.Errorf("route body must be a string")
+		}
+		r.ContentType = "text/plain"
+	}
+	if contentType, ok := handler["contentType"].(string); ok && contentType != "" {
+		r.ContentType = contentType
+	}
+
+	if headers, ok := handler["headers"].(map[string]interface{}); ok {
+		r.Headers = make(map[string]string, len(headers))
+		for name, value := range headers {
+			r.Headers[name] = fmt.Sprint(value)
+		}
+	}
+	return r, nil
+}
+
+// applyRoutes makes the page's fetch and XMLHttpRequest answer requests with
+// the routes
+func (p *Page) applyRoutes(ctx context.Context, routes []route) error {
+	_, err := p.client.ExecuteScript(ctx, routesScript, []interface{}{routes})
+	return err
+}
@@ -0,0 +1,142 @@
+package browser
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+
+	"go.k6.io/k6/js/modulestest"
+)
+
+func TestRouteFrom(t *testing.T) {
+	tests := []struct {
+		name    string
+		pattern string
+		handler map[string]interface{}
+		want    route
+		wantErr bool
+	}{
+		{
+			name:    "json body",
+			pattern: "**/api/cart",
+			handler: map[string]interface{}{"status": int64(201), "json": map[string]interface{}{"items": []interface{}{}}},
+			want: route{
+				Pattern: `^.*/api/cart$`, Status: 201, StatusText: "Created",
+				Body: `{"items":[]}`, ContentType: "application/json",
+			},
+		},
+		{
+			name:    "text body with headers",
+			pattern: "/\\.csv$/",
+			handler: map[string]interface{}{
+				"body": "a,b", "contentType": "text/csv", "statusText": "Fine",
+				"headers": map[string]interface{}{"X-Count": int64(2)},
+			},
+			want: route{
+				Pattern: `\.csv$`, Status: 200, StatusText: "Fine",
+				Body: "a,b", ContentType: "text/csv", Headers: map[string]string{"X-Count": "2"},
+			},
+		},
+		{
+			name:    "empty response",
+			pattern: "https://example.com/ping",
+			handler: nil,
+			want:    route{Pattern: `^https://example\.com/ping$`, Status: 200, StatusText: "OK"},
+		},
+		{
+			name:    "abort",
+			pattern: "**/*.analytics.js",
+			handler: map[string]interface{}{"abort": true, "status": int64(500)},
+			want:    route{Pattern: `^.*/[^/]*\.analytics\.js$`, Abort: true},
+		},
+		{name: "empty pattern", pattern: "", wantErr: true},
+		{name: "invalid regex", pattern: "/[/", wantErr: true},
+		{name: "status out of range", pattern: "**", handler: map[string]interface{}{"status": int64(101)}, wantErr: true},
+		{name: "body and json", pattern: "**", handler: map[string]interface{}{"body": "", "json": 1}, wantErr: true},
+		{name: "body not a string", pattern: "**", handler: map[string]interface{}{"body": int64(1)}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := routeFrom(tt.pattern, tt.handler)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("Expected error, got %+v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Expected %+v, got %+v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestBrowserContextRoute(t *testing.T) {
+	var mu sync.Mutex
+	var applied [][]route
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload struct {
+			Script string    `json:"script"`
+			Args   [][]route `json:"args"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&payload)
+		if payload.Script == routesScript {
+			mu.Lock()
+			applied = append(applied, payload.Args[0])
+			mu.Unlock()
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"value":null}`))
+	}))
+	defer server.Close()
+
+	runtime := modulestest.NewRuntime(t)
+	browserContext := &BrowserContext{vu: runtime.VU}
+	page := &Page{vu: runtime.VU, client: NewWebDriverClient(server.URL).forSession("session-1"), context: browserContext}
+	browserContext.addPage(page)
+	if err := runtime.VU.Runtime().Set("context", browserContext); err != nil {
+		t.Fatal(err)
+	}
+
+	// Open pages get every route added so far right away
+	_, err := runtime.RunOnEventLoop(`
+		var failure = "";
+		context.route("**/api/**", { json: { ok: true } })
+			.then(function() { return context.route("/tracking/", { abort: true }); })
+			.catch(function(e) { failure = String(e); });
+	`)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if failure := runtime.VU.Runtime().Get("failure").String(); failure != "" {
+		t.Fatalf("Unexpected failure: %s", failure)
+	}
+	want := []route{
+		{Pattern: `^.*/api/.*$`, Status: 200, StatusText: "OK", Body: `{"ok":true}`, ContentType: "application/json"},
+		{Pattern: "tracking", Abort: true},
+	}
+	if len(applied) != 2 || !reflect.DeepEqual(applied[1], want) {
+		t.Fatalf("Expected the routes to be applied to the open page, got %+v", applied)
+	}
+
+	// Pages get them again after each navigation
+	if err := page.injectScript(context.Background()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(applied) != 3 || !reflect.DeepEqual(applied[2], want) {
+		t.Errorf("Expected the routes to be injected again, got %+v", applied)
+	}
+
+	if _, err := browserContext.Route("/(/", nil); err == nil {
+		t.Error("Expected an invalid pattern to be rejected")
+	}
+}
@@ -3,11 +3,15 @@ package browser
 import (
 	"bytes"
 	"context"
+	_ "embed"
 	"encoding/json"
 	"fmt"
 	"net/http"
 	"regexp"
 	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
 )
 
 // SelectorStrategy represents a selector type
@@ -24,13 +28,50 @@ const (
 	StrategyTagName         SelectorStrategy = "tag name"
 
 	// Custom JavaScript-based strategies
-	StrategyText        SelectorStrategy = "text"
-	StrategyDataTestID  SelectorStrategy = "data-testid"
-	StrategyAriaLabel   SelectorStrategy = "aria-label"
-	StrategyRole        SelectorStrategy = "role"
-	StrategyVisibleText SelectorStrategy = "visible-text"
+	StrategyText           SelectorStrategy = "text"
+	StrategyTextIgnoreCase SelectorStrategy = "text-i"
+	StrategyDataTestID     SelectorStrategy = "data-testid"
+	StrategyAriaLabel      SelectorStrategy = "aria-label"
+	StrategyPlaceholder    SelectorStrategy = "placeholder"
+	StrategyTitle          SelectorStrategy = "title"
+	StrategyRole           SelectorStrategy = "role"
+	StrategyVisibleText    SelectorStrategy = "visible-text"
+	StrategyPierce         SelectorStrategy = "pierce"
 )
 
+//go:embed role_matcher.js
+var roleMatcherScript string
+
+// pierceQueryAllScript defines pierceQueryAll(root, selector), which returns the
+// elements matching the CSS selector in root and in every open shadow root
+// within it, recursively. Each element is matched within its own tree, so a
+// selector can't combine elements on both sides of a shadow boundary
+const pierceQueryAllScript = `
+	var pierceQueryAll = function(root, selector) {
+		var matches = [];
+		var walk = function(node) {
+			var elements = node.querySelectorAll('*');
+			for (var i = 0; i < elements.length; i++) {
+				if (elements[i].matches(selector)) matches.push(elements[i]);
+				if (elements[i].shadowRoot) walk(elements[i].shadowRoot);
+			}
+		};
+		walk(root);
+		return matches;
+	};
+`
+
+// isVisibleScript defines isVisible(element), which reports whether the element
+// has a size and isn't hidden by its display, visibility or opacity. Every
+// script checking visibility uses it, so that they agree
+const isVisibleScript = `
+	var isVisible = function(element) {
+		if (element.offsetWidth === 0 || element.offsetHeight === 0) return false;
+		var style = window.getComputedStyle(element);
+		return style.display !== 'none' && style.visibility !== 'hidden' && style.opacity !== '0';
+	};
+`
+
 // ParsedSelector contains the parsed selector information
 type ParsedSelector struct {
 	Strategy SelectorStrategy
@@ -50,6 +91,9 @@ func ParseSelector(selector string) ParsedSelector {
 	if strings.HasPrefix(selector, "text=") {
 		return ParsedSelector{StrategyText, strings.TrimPrefix(selector, "text="), false}
 	}
+	if strings.HasPrefix(selector, "text-i=") {
+		return ParsedSelector{StrategyTextIgnoreCase, strings.TrimPrefix(selector, "text-i="), false}
+	}
 	if strings.HasPrefix(selector, "visible-text=") {
 		return ParsedSelector{StrategyVisibleText, strings.TrimPrefix(selector, "visible-text="), false}
 	}
@@ -74,9 +118,26 @@ func ParseSelector(selector string) ParsedSelector {
 	if strings.HasPrefix(selector, "aria-label=") {
 		return ParsedSelector{StrategyAriaLabel, strings.TrimPrefix(selector, "aria-label="), false}
 	}
+	if strings.HasPrefix(selector, "placeholder=") {
+		return ParsedSelector{StrategyPlaceholder, strings.TrimPrefix(selector, "placeholder="), false}
+	}
+	if strings.HasPrefix(selector, "title=") {
+		return ParsedSelector{StrategyTitle, strings.TrimPrefix(selector, "title="), false}
+	}
 	if strings.HasPrefix(selector, "role=") {
 		return ParsedSelector{StrategyRole, strings.TrimPrefix(selector, "role="), false}
 	}
+	if strings.HasPrefix(selector, "pierce=") {
+		return ParsedSelector{StrategyPierce, strings.TrimPrefix(selector, "pierce="), false}
+	}
+	if strings.HasPrefix(selector, ">>>") {
+		return ParsedSelector{StrategyPierce, strings.TrimSpace(strings.TrimPrefix(selector, ">>>")), false}
+	}
+
+	// CSS selectors using :visible or :has-text() are matched from JavaScript
+	if hasEnginePseudos(selector) {
+		return ParsedSelector{StrategyCSSSelector, selector, false}
+	}
 
 	// Default to CSS selector
 	return ParsedSelector{StrategyCSSSelector, selector, true}
@@ -84,6 +145,8 @@ func ParseSelector(selector string) ParsedSelector {
 
 // FindElementWithStrategy finds an element using the parsed selector strategy
 func (c *WebDriverClient) FindElementWithStrategy(ctx context.Context, selector string) (string, error) {
+	defer c.observe(opFindElement, time.Now())
+
 	parsed := ParseSelector(selector)
 
 	if parsed.IsNative {
@@ -95,8 +158,9 @@ func (c *WebDriverClient) FindElementWithStrategy(ctx context.Context, selector
 
 // findElementNative uses WebDriver's native element finding
 func (c *WebDriverClient) findElementNative(ctx context.Context, strategy, value string) (string, error) {
-	if c.sessionID == "" {
-		return "", fmt.Errorf("no active session")
+	sessionID := c.session()
+	if sessionID == "" {
+		return "", ErrNoSession
 	}
 
 	payload := map[string]string{"using": strategy, "value": value}
@@ -106,30 +170,21 @@ func (c *WebDriverClient) findElementNative(ctx context.Context, strategy, value
 	}
 
 	req, err := http.NewRequestWithContext(ctx, "POST",
-		c.baseURL+"/session/"+c.sessionID+"/element", bytes.NewBuffer(jsonData))
+		c.baseURL+"/session/"+sessionID+"/element", bytes.NewBuffer(jsonData))
 	if err != nil {
 		return "", fmt.Errorf("failed to create find element request: %w", err)
 	}
 
 	req.Header.Set("Content-Type", "application/json")
 
-	resp, err := c.httpClient.Do(req)
+	resp, err := c.doWithRetry(req)
 	if err != nil {
 		return "", fmt.Errorf("failed to find element: %w", err)
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != 200 {
-		// Try to get error details from response
-		var errorBody map[string]interface{}
-		if decodeErr := json.NewDecoder(resp.Body).Decode(&errorBody); decodeErr == nil {
-			if value, ok := errorBody["value"].(map[string]interface{}); ok {
-				if message, ok := value["message"].(string); ok {
-					return "", fmt.Errorf("find element failed with status %d: strategy=%s, selector=%s, error=%s", resp.StatusCode, strategy, value, message)
-				}
-			}
-		}
-		return "", fmt.Errorf("find element failed with status %d: strategy=%s, selector=%s", resp.StatusCode, strategy, value)
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("find element failed with status %d: strategy=%s, selector=%s: %w", resp.StatusCode, strategy, value, newWebDriverError(resp))
 	}
 
 	var elementResp struct {
@@ -151,7 +206,7 @@ func (c *WebDriverClient) findElementNative(ctx context.Context, strategy, value
 		return elementResp.Value.ELEMENT, nil
 	}
 
-	return "", fmt.Errorf("element not found")
+	return "", ErrElementNotFound
 }
 
 // findElementCustom uses JavaScript to find elements with custom strategies
@@ -165,10 +220,10 @@ func (c *WebDriverClient) findElementCustom(ctx context.Context, strategy Select
 
 	// Check if element was found
 	if result == nil {
-		return "", fmt.Errorf("element not found")
+		return "", ErrElementNotFound
 	}
 
-	fmt.Println("Found element:", result)
+	logf(c.vu, logrus.DebugLevel, "found element with %s selector '%s': %v", strategy, value, result)
 
 	// WebDriver returns element references as maps
 	if elemMap, ok := result.(map[string]interface{}); ok {
@@ -187,10 +242,9 @@ func (c *WebDriverClient) findElementCustom(ctx context.Context, strategy Select
 
 // generateSelectorScript generates JavaScript code for custom selector strategies
 func generateSelectorScript(strategy SelectorStrategy, value string) string {
-	escapedValue := strings.ReplaceAll(value, `"`, `\"`)
-
 	switch strategy {
-	case StrategyText:
+	case StrategyText, StrategyTextIgnoreCase:
+		ignoreCase := strategy == StrategyTextIgnoreCase
 		return fmt.Sprintf(`
 			// Find the most specific (deepest) element with exact matching text
 			var elements = Array.from(document.querySelectorAll('*'));
@@ -200,28 +254,25 @@ func generateSelectorScript(strategy SelectorStrategy, value string) string {
 					.filter(function(node) { return node.nodeType === 3; })
 					.map(function(node) { return node.textContent; })
 					.join('').trim();
-				return directText === "%s" || el.textContent.trim() === "%s";
+				return %s || %s;
 			});
 			// Return the deepest (most specific) match
 			if (matches.length > 0) {
 				return matches[matches.length - 1];
 			}
 			return null;
-		`, escapedValue, escapedValue)
+		`, textMatchExpression("directText", value, false, ignoreCase), textMatchExpression("el.textContent", value, false, ignoreCase))
 
 	case StrategyVisibleText:
-		return fmt.Sprintf(`
+		return fmt.Sprintf(`%s
 			// Find the most specific visible element containing the text
 			var elements = Array.from(document.querySelectorAll('*'));
 			var matches = elements.filter(function(el) {
-				// Check visibility
-				if (el.offsetWidth === 0 || el.offsetHeight === 0) return false;
-				var style = window.getComputedStyle(el);
-				if (style.display === 'none' || style.visibility === 'hidden') return false;
+				if (!isVisible(el)) return false;
 				
 				// Check text content
 				var text = el.textContent ? el.textContent.trim() : '';
-				return text.includes("%s");
+				return %s;
 			});
 			
 			// Return the smallest (most specific) element
@@ -231,29 +282,48 @@ func generateSelectorScript(strategy SelectorStrategy, value string) string {
 			});
 			
 			return matches.length > 0 ? matches[0] : null;
-		`, escapedValue)
+		`, isVisibleScript, textMatchExpression("text", value, true, false))
 
 	case StrategyDataTestID:
-		return fmt.Sprintf(`return document.querySelector('[data-testid="%s"]');`, escapedValue)
+		return fmt.Sprintf(`return document.querySelector(%s);`, jsStringLiteral(cssAttributeSelector("data-testid", value)))
 
 	case StrategyAriaLabel:
-		return fmt.Sprintf(`return document.querySelector('[aria-label="%s"]');`, escapedValue)
+		return fmt.Sprintf(`return document.querySelector(%s);`, jsStringLiteral(cssAttributeSelector("aria-label", value)))
+
+	case StrategyPlaceholder:
+		return fmt.Sprintf(`return document.querySelector(%s);`, jsStringLiteral(placeholderSelector(value)))
+
+	case StrategyTitle:
+		return fmt.Sprintf(`return document.querySelector(%s);`, jsStringLiteral(cssAttributeSelector("title", value)))
 
 	case StrategyRole:
-		return fmt.Sprintf(`return document.querySelector('[role="%s"]');`, escapedValue)
+		return generateRoleSelectorScript(ParseRoleSelector(value), false)
+
+	case StrategyPierce:
+		return fmt.Sprintf(`%s
+			return pierceQueryAll(document, %s)[0] || null;
+		`, pierceQueryAllScript, jsStringLiteral(value))
+
+	case StrategyXPath:
+		return fmt.Sprintf(`
+			var node = document.evaluate(%s, document, null, XPathResult.FIRST_ORDERED_NODE_TYPE, null).singleNodeValue;
+			return node && node.nodeType === Node.ELEMENT_NODE ? node : null;
+		`, jsStringLiteral(value))
 
 	default:
+		if hasEnginePseudos(value) {
+			return generateEnginePseudoScript(value, false)
+		}
 		// Fallback to CSS selector
-		return fmt.Sprintf(`return document.querySelector("%s");`, escapedValue)
+		return fmt.Sprintf(`return document.querySelector(%s);`, jsStringLiteral(value))
 	}
 }
 
 // generateAllSelectorScript generates JavaScript code to find ALL elements (not just one)
 func generateAllSelectorScript(strategy SelectorStrategy, value string) string {
-	escapedValue := strings.ReplaceAll(value, `"`, `\"`)
-
 	switch strategy {
-	case StrategyText:
+	case StrategyText, StrategyTextIgnoreCase:
+		ignoreCase := strategy == StrategyTextIgnoreCase
 		return fmt.Sprintf(`
 			var elements = Array.from(document.querySelectorAll('*'));
 			return elements.filter(function(el) {
@@ -261,35 +331,480 @@ func generateAllSelectorScript(strategy SelectorStrategy, value string) string {
 					.filter(function(node) { return node.nodeType === 3; })
 					.map(function(node) { return node.textContent; })
 					.join('').trim();
-				return directText === "%s" || el.textContent.trim() === "%s";
+				return %s || %s;
 			});
-		`, escapedValue, escapedValue)
+		`, textMatchExpression("directText", value, false, ignoreCase), textMatchExpression("el.textContent", value, false, ignoreCase))
 
 	case StrategyVisibleText:
-		return fmt.Sprintf(`
+		return fmt.Sprintf(`%s
 			var elements = Array.from(document.querySelectorAll('*'));
 			return elements.filter(function(el) {
-				if (el.offsetWidth === 0 || el.offsetHeight === 0) return false;
-				var style = window.getComputedStyle(el);
-				if (style.display === 'none' || style.visibility === 'hidden') return false;
+				if (!isVisible(el)) return false;
 				var text = el.textContent ? el.textContent.trim() : '';
-				return text.includes("%s");
+				return %s;
 			});
-		`, escapedValue)
+		`, isVisibleScript, textMatchExpression("text", value, true, false))
 
 	case StrategyDataTestID:
-		return fmt.Sprintf(`return Array.from(document.querySelectorAll('[data-testid="%s"]'));`, escapedValue)
+		return fmt.Sprintf(`return Array.from(document.querySelectorAll(%s));`, jsStringLiteral(cssAttributeSelector("data-testid", value)))
 
 	case StrategyAriaLabel:
-		return fmt.Sprintf(`return Array.from(document.querySelectorAll('[aria-label="%s"]'));`, escapedValue)
+		return fmt.Sprintf(`return Array.from(document.querySelectorAll(%s));`, jsStringLiteral(cssAttributeSelector("aria-label", value)))
+
+	case StrategyPlaceholder:
+		return fmt.Sprintf(`return Array.from(document.querySelectorAll(%s));`, jsStringLiteral(placeholderSelector(value)))
+
+	case StrategyTitle:
+		return fmt.Sprintf(`return Array.from(document.querySelectorAll(%s));`, jsStringLiteral(cssAttributeSelector("title", value)))
 
 	case StrategyRole:
-		return fmt.Sprintf(`return Array.from(document.querySelectorAll('[role="%s"]'));`, escapedValue)
+		return generateRoleSelectorScript(ParseRoleSelector(value), true)
+
+	case StrategyPierce:
+		return fmt.Sprintf(`%s
+			return pierceQueryAll(document, %s);
+		`, pierceQueryAllScript, jsStringLiteral(value))
+
+	case StrategyXPath:
+		// Snapshot every match in document order, skipping non-element nodes
+		// such as text() or @attribute results
+		return fmt.Sprintf(`
+			var snapshot = document.evaluate(%s, document, null, XPathResult.ORDERED_NODE_SNAPSHOT_TYPE, null);
+			var elements = [];
+			for (var i = 0; i < snapshot.snapshotLength; i++) {
+				var node = snapshot.snapshotItem(i);
+				if (node.nodeType === Node.ELEMENT_NODE) elements.push(node);
+			}
+			return elements;
+		`, jsStringLiteral(value))
 
 	default:
+		if hasEnginePseudos(value) {
+			return generateEnginePseudoScript(value, true)
+		}
 		// Fallback to CSS selector for all
-		return fmt.Sprintf(`return Array.from(document.querySelectorAll("%s"));`, escapedValue)
+		return fmt.Sprintf(`return Array.from(document.querySelectorAll(%s));`, jsStringLiteral(value))
+	}
+}
+
+// enginePseudoSelector is a selector of a CSS selector list, with the
+// Playwright-style :visible and :has-text() pseudo-classes of its subject, which
+// the browser's CSS engine doesn't know, moved out of its CSS
+type enginePseudoSelector struct {
+	CSS     string   // The selector without the pseudo-classes
+	Visible bool     // :visible keeps visible elements only
+	HasText []string // :has-text("...") keeps elements containing each text
+}
+
+// hasEnginePseudos reports whether a CSS selector uses :visible or :has-text()
+func hasEnginePseudos(selector string) bool {
+	return pseudoIndex(selector) >= 0
+}
+
+// parseEnginePseudos splits a CSS selector list into its selectors and moves
+// the :visible and :has-text() pseudo-classes out of their CSS. They are only
+// supported on the subject, i.e. the last compound selector
+func parseEnginePseudos(selector string) ([]enginePseudoSelector, error) {
+	var selectors []enginePseudoSelector
+	for _, part := range splitSelectorList(selector) {
+		part = strings.TrimSpace(part)
+		subject := subjectStart(part)
+		if pseudoIndex(part[:subject]) >= 0 {
+			return nil, fmt.Errorf(":visible and :has-text() are only supported on the last compound selector, got '%s'", part)
+		}
+
+		var sel enginePseudoSelector
+		var subjectCSS strings.Builder
+		rest := part[subject:]
+		for {
+			i := pseudoIndex(rest)
+			if i < 0 {
+				subjectCSS.WriteString(rest)
+				break
+			}
+			subjectCSS.WriteString(rest[:i])
+			rest = rest[i:]
+
+			if strings.HasPrefix(rest, ":visible") {
+				sel.Visible = true
+				rest = rest[len(":visible"):]
+				continue
+			}
+			text, n, err := parseHasTextArgument(rest[len(":has-text("):])
+			if err != nil {
+				return nil, fmt.Errorf("invalid :has-text() in '%s': %w", part, err)
+			}
+			sel.HasText = append(sel.HasText, text)
+			rest = rest[len(":has-text(")+n:]
+		}
+
+		// A subject made of pseudo-classes only matches any element
+		if subjectCSS.Len() == 0 {
+			subjectCSS.WriteString("*")
+		}
+		sel.CSS = part[:subject] + subjectCSS.String()
+		selectors = append(selectors, sel)
+	}
+	return selectors, nil
+}
+
+// scanSelector calls fn with the index of every byte of a CSS selector that is
+// outside of strings, brackets and parentheses, until fn returns false
+func scanSelector(selector string, fn func(i int) bool) {
+	var quote byte
+	depth := 0
+	for i := 0; i < len(selector); i++ {
+		c := selector[i]
+		switch {
+		case c == '\\':
+			i++
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '(' || c == '[':
+			depth++
+		case c == ')' || c == ']':
+			depth--
+		case depth == 0:
+			if !fn(i) {
+				return
+			}
+		}
+	}
+}
+
+// splitSelectorList splits a CSS selector list on its top-level commas
+func splitSelectorList(selector string) []string {
+	var parts []string
+	start := 0
+	scanSelector(selector, func(i int) bool {
+		if selector[i] == ',' {
+			parts = append(parts, selector[start:i])
+			start = i + 1
+		}
+		return true
+	})
+	return append(parts, selector[start:])
+}
+
+// subjectStart returns the index where the last compound selector of a complex
+// selector starts, i.e. after its last combinator
+func subjectStart(selector string) int {
+	start := 0
+	scanSelector(selector, func(i int) bool {
+		switch selector[i] {
+		case ' ', '\t', '\n', '>', '+', '~':
+			start = i + 1
+		}
+		return true
+	})
+	return start
+}
+
+// pseudoIndex returns the index of the first top-level :visible or :has-text(
+// pseudo-class in a CSS selector, or -1
+func pseudoIndex(selector string) int {
+	index := -1
+	scanSelector(selector, func(i int) bool {
+		if selector[i] != ':' {
+			return true
+		}
+		rest := selector[i:]
+		visible := strings.HasPrefix(rest, ":visible") &&
+			(len(rest) == len(":visible") || !isIdentByte(rest[len(":visible")]))
+		if visible || strings.HasPrefix(rest, ":has-text(") {
+			index = i
+			return false
+		}
+		return true
+	})
+	return index
+}
+
+// isIdentByte reports whether c can be part of a CSS identifier
+func isIdentByte(c byte) bool {
+	return c == '-' || c == '_' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= 0x80
+}
+
+// parseHasTextArgument parses the argument of :has-text(, a quoted or bare
+// text followed by the closing parenthesis, returning the text and the number
+// of bytes read
+func parseHasTextArgument(s string) (string, int, error) {
+	i := len(s) - len(strings.TrimLeft(s, " "))
+	if i < len(s) && (s[i] == '"' || s[i] == '\'') {
+		quote := s[i]
+		var text strings.Builder
+		for i++; i < len(s) && s[i] != quote; i++ {
+			if s[i] == '\\' && i+1 < len(s) {
+				i++
+			}
+			text.WriteByte(s[i])
+		}
+		if i == len(s) {
+			return "", 0, fmt.Errorf("unterminated string")
+		}
+		i++
+		i += len(s[i:]) - len(strings.TrimLeft(s[i:], " "))
+		if i == len(s) || s[i] != ')' {
+			return "", 0, fmt.Errorf("expected ')' after the text")
+		}
+		return text.String(), i + 1, nil
 	}
+
+	end := strings.IndexByte(s, ')')
+	if end < 0 {
+		return "", 0, fmt.Errorf("expected ')' after the text")
+	}
+	return strings.TrimSpace(s[:end]), end + 1, nil
+}
+
+// generateEnginePseudoScript generates JavaScript code that finds the elements
+// matching a CSS selector using :visible or :has-text(), in document order
+// :has-text() matches a case-insensitive substring of the element's text
+// content, or a /regex/. If all is true, the script returns every match
+// instead of the first one
+func generateEnginePseudoScript(selector string, all bool) string {
+	selectors, err := parseEnginePseudos(selector)
+	if err != nil {
+		return fmt.Sprintf(`throw new Error(%s);`, jsStringLiteral(err.Error()))
+	}
+
+	var b strings.Builder
+	b.WriteString(isVisibleScript)
+	b.WriteString(`
+		var matches = [];
+	`)
+	for _, sel := range selectors {
+		conditions := []string{"matches.indexOf(el) === -1"}
+		if sel.Visible {
+			conditions = append(conditions, "isVisible(el)")
+		}
+		for _, text := range sel.HasText {
+			conditions = append(conditions, textMatchExpression("(el.textContent || '')", text, true, true))
+		}
+		fmt.Fprintf(&b, `
+		Array.from(document.querySelectorAll(%s)).forEach(function(el) {
+			if (%s) matches.push(el);
+		});`, jsStringLiteral(sel.CSS), strings.Join(conditions, " && "))
+	}
+	if len(selectors) > 1 {
+		b.WriteString(`
+		matches.sort(function(a, b) {
+			return a.compareDocumentPosition(b) & Node.DOCUMENT_POSITION_FOLLOWING ? -1 : 1;
+		});`)
+	}
+
+	if all {
+		b.WriteString(`
+		return matches;
+	`)
+	} else {
+		b.WriteString(`
+		return matches.length > 0 ? matches[0] : null;
+	`)
+	}
+	return b.String()
+}
+
+// textMatchExpression returns a JavaScript expression testing the text expression
+// against value. A /regex/ value is tested as a regular expression, otherwise
+// whitespace is collapsed on both sides and the text must equal value, or
+// contain it if substring is true
+func textMatchExpression(text, value string, substring, ignoreCase bool) string {
+	if IsRegex(value) {
+		flags := ""
+		if ignoreCase {
+			flags = `, "i"`
+		}
+		return fmt.Sprintf(`new RegExp(%s%s).test(%s)`, jsStringLiteral(value[1:len(value)-1]), flags, text)
+	}
+
+	value = normalizeWhitespace(value)
+	text = fmt.Sprintf(`%s.replace(/\s+/g, ' ').trim()`, text)
+	if ignoreCase {
+		value = strings.ToLower(value)
+		text += ".toLowerCase()"
+	}
+
+	if substring {
+		return fmt.Sprintf(`%s.includes(%s)`, text, jsStringLiteral(value))
+	}
+	return fmt.Sprintf(`%s === %s`, text, jsStringLiteral(value))
+}
+
+// cssAttributeSelector returns a CSS selector matching elements whose attribute equals value
+func cssAttributeSelector(attr, value string) string {
+	return fmt.Sprintf(`[%s="%s"]`, attr, cssStringEscaper.Replace(value))
+}
+
+// placeholderSelector returns a CSS selector matching inputs and textareas by placeholder
+func placeholderSelector(value string) string {
+	return "input" + cssAttributeSelector("placeholder", value) + ", textarea" + cssAttributeSelector("placeholder", value)
+}
+
+// cssStringEscaper escapes a value for use inside a double-quoted CSS string
+var cssStringEscaper = strings.NewReplacer(
+	`\`, `\\`,
+	`"`, `\"`,
+	"\n", `\a `,
+	"\r", `\d `,
+	"\f", `\c `,
+)
+
+// normalizeWhitespace collapses runs of whitespace into single spaces and trims the ends
+func normalizeWhitespace(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// RoleSelector is a parsed role= selector value such as button[name="Submit"]
+type RoleSelector struct {
+	Role string
+	Name string // Accessible name filter: a case-insensitive substring, or a /regex/
+}
+
+// roleNameFilterRegex matches the optional accessible name filter of a role selector
+var roleNameFilterRegex = regexp.MustCompile(`^\[\s*name\s*=\s*(.*?)\s*\]$`)
+
+// ParseRoleSelector parses a role selector value such as "button",
+// `button[name="Submit"]` or "link[name=/^Docs/]"
+func ParseRoleSelector(value string) RoleSelector {
+	value = strings.TrimSpace(value)
+	bracket := strings.Index(value, "[")
+	if bracket == -1 {
+		return RoleSelector{Role: value}
+	}
+
+	rs := RoleSelector{Role: strings.TrimSpace(value[:bracket])}
+	if m := roleNameFilterRegex.FindStringSubmatch(value[bracket:]); m != nil {
+		name := m[1]
+		if len(name) >= 2 && (name[0] == '"' || name[0] == '\'') && name[len(name)-1] == name[0] {
+			name = name[1 : len(name)-1]
+		}
+		rs.Name = name
+	}
+	return rs
+}
+
+// generateRoleSelectorScript generates JavaScript code that finds elements by
+// explicit or implicit ARIA role, optionally filtered by accessible name
+// If all is true, the script returns every match instead of the first one
+func generateRoleSelectorScript(rs RoleSelector, all bool) string {
+	nameFilter, nameRegex := "null", "null"
+	switch {
+	case IsRegex(rs.Name):
+		nameRegex = jsStringLiteral(rs.Name[1 : len(rs.Name)-1])
+	case rs.Name != "":
+		nameFilter = jsStringLiteral(rs.Name)
+	}
+
+	result := `return matches.length > 0 ? matches[0] : null;`
+	if all {
+		result = `return matches;`
+	}
+
+	return fmt.Sprintf(`%s
+		var matches = Array.from(document.querySelectorAll('*')).filter(function(el) {
+			return matchesRole(el, %s, %s, %s);
+		});
+		%s
+	`, roleMatcherScript, jsStringLiteral(rs.Role), nameFilter, nameRegex, result)
+}
+
+// FrameSelector identifies an iframe by its name attribute, its src URL, or a CSS selector
+type FrameSelector struct {
+	Name string // Matches the frame's name attribute
+	URL  string // Matches the frame's src URL as an exact URL, glob, or /regex/
+	CSS  string // Matches the frame element with a CSS selector
+}
+
+// ParseFrameSelector parses a frame selector string such as "name=payment",
+// "url=https://pay.example.com/**", "url=/checkout/" or a plain CSS selector
+func ParseFrameSelector(selector string) FrameSelector {
+	if strings.HasPrefix(selector, "name=") {
+		return FrameSelector{Name: strings.TrimPrefix(selector, "name=")}
+	}
+	if strings.HasPrefix(selector, "url=") {
+		return FrameSelector{URL: strings.TrimPrefix(selector, "url=")}
+	}
+	return FrameSelector{CSS: selector}
+}
+
+// String returns the frame selector in the form accepted by ParseFrameSelector
+func (fs FrameSelector) String() string {
+	switch {
+	case fs.Name != "":
+		return "name=" + fs.Name
+	case fs.URL != "":
+		return "url=" + fs.URL
+	default:
+		return fs.CSS
+	}
+}
+
+// generateFrameSelectorScript generates JavaScript code that returns the first
+// frame element matching the frame selector, or null
+func generateFrameSelectorScript(fs FrameSelector) string {
+	var matcher string
+	switch {
+	case fs.Name != "":
+		matcher = fmt.Sprintf(`return frame.name === %s;`, jsStringLiteral(fs.Name))
+	case fs.URL != "":
+		pattern := globToRegex(fs.URL)
+		if IsRegex(fs.URL) {
+			pattern = fs.URL[1 : len(fs.URL)-1]
+		}
+		matcher = fmt.Sprintf(`return new RegExp(%s).test(frame.src);`, jsStringLiteral(pattern))
+	default:
+		return fmt.Sprintf(`return document.querySelector(%s);`, jsStringLiteral(fs.CSS))
+	}
+
+	return fmt.Sprintf(`
+		var frames = Array.from(document.querySelectorAll('iframe, frame'));
+		var match = frames.find(function(frame) {
+			%s
+		});
+		return match || null;
+	`, matcher)
+}
+
+// globToRegex converts a URL glob into an anchored regular expression pattern
+// "**" matches any characters, "*" matches any characters except "/"
+func globToRegex(glob string) string {
+	var b strings.Builder
+	b.WriteString("^")
+	for i := 0; i < len(glob); i++ {
+		if glob[i] != '*' {
+			b.WriteString(regexp.QuoteMeta(glob[i : i+1]))
+			continue
+		}
+		if i+1 < len(glob) && glob[i+1] == '*' {
+			b.WriteString(".*")
+			i++
+		} else {
+			b.WriteString("[^/]*")
+		}
+	}
+	b.WriteString("$")
+	return b.String()
+}
+
+// urlPatternRegex compiles a URL pattern, a /regex/ matching anywhere in the URL
+// or a glob matching the whole URL
+func urlPatternRegex(pattern string) (*regexp.Regexp, error) {
+	if IsRegex(pattern) {
+		return ParseRegex(pattern)
+	}
+	return regexp.Compile(globToRegex(pattern))
+}
+
+// jsStringLiteral encodes s as a JavaScript string literal, escaping quotes,
+// backslashes, control characters and HTML-sensitive characters
+func jsStringLiteral(s string) string {
+	encoded, _ := json.Marshal(s)
+	return string(encoded)
 }
 
 // IsRegex checks if a string is a regex pattern (enclosed in /)
@@ -1,6 +1,14 @@
 package browser
 
 import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"regexp"
+	"strings"
 	"testing"
 )
 
@@ -30,6 +38,11 @@ func TestParseSelector(t *testing.T) {
 			selector: "text=Submit Form",
 			want:     ParsedSelector{StrategyText, "Submit Form", false},
 		},
+		{
+			name:     "Case-insensitive text selector",
+			selector: "text-i=submit form",
+			want:     ParsedSelector{StrategyTextIgnoreCase, "submit form", false},
+		},
 		{
 			name:     "Visible text selector",
 			selector: "visible-text=Submit",
@@ -45,6 +58,16 @@ func TestParseSelector(t *testing.T) {
 			selector: "aria-label=Close dialog",
 			want:     ParsedSelector{StrategyAriaLabel, "Close dialog", false},
 		},
+		{
+			name:     "Placeholder",
+			selector: "placeholder=Email address",
+			want:     ParsedSelector{StrategyPlaceholder, "Email address", false},
+		},
+		{
+			name:     "Title",
+			selector: "title=Delete item",
+			want:     ParsedSelector{StrategyTitle, "Delete item", false},
+		},
 		{
 			name:     "ARIA role",
 			selector: "role=button",
@@ -75,6 +98,36 @@ func TestParseSelector(t *testing.T) {
 			selector: "partial-link=Click",
 			want:     ParsedSelector{StrategyPartialLinkText, "Click", true},
 		},
+		{
+			name:     "Shadow-piercing CSS",
+			selector: "pierce=button.save",
+			want:     ParsedSelector{StrategyPierce, "button.save", false},
+		},
+		{
+			name:     "Shadow-piercing CSS shorthand",
+			selector: ">>> my-card button",
+			want:     ParsedSelector{StrategyPierce, "my-card button", false},
+		},
+		{
+			name:     "CSS with :has-text()",
+			selector: `button:has-text("Save")`,
+			want:     ParsedSelector{StrategyCSSSelector, `button:has-text("Save")`, false},
+		},
+		{
+			name:     "CSS with :visible",
+			selector: "li.item:visible",
+			want:     ParsedSelector{StrategyCSSSelector, "li.item:visible", false},
+		},
+		{
+			name:     "CSS with :visited stays native",
+			selector: "a:visited",
+			want:     ParsedSelector{StrategyCSSSelector, "a:visited", true},
+		},
+		{
+			name:     "CSS with :visible in an attribute value stays native",
+			selector: `[data-state=":visible"]`,
+			want:     ParsedSelector{StrategyCSSSelector, `[data-state=":visible"]`, true},
+		},
 	}
 
 	for _, tt := range tests {
@@ -104,7 +157,13 @@ func TestGenerateSelectorScript(t *testing.T) {
 			name:          "Text selector",
 			strategy:      StrategyText,
 			value:         "Submit",
-			wantSubstring: "textContent.trim() === \"Submit\"",
+			wantSubstring: "el.textContent.replace(/\\s+/g, ' ').trim() === \"Submit\"",
+		},
+		{
+			name:          "Case-insensitive text selector",
+			strategy:      StrategyTextIgnoreCase,
+			value:         "Submit ",
+			wantSubstring: "el.textContent.replace(/\\s+/g, ' ').trim().toLowerCase() === \"submit\"",
 		},
 		{
 			name:          "Visible text selector",
@@ -112,23 +171,71 @@ func TestGenerateSelectorScript(t *testing.T) {
 			value:         "Submit",
 			wantSubstring: "offsetWidth",
 		},
+		{
+			name:          "Text selector with regex",
+			strategy:      StrategyText,
+			value:         `/Order #\d+/`,
+			wantSubstring: `new RegExp("Order #\\d+").test(el.textContent)`,
+		},
+		{
+			name:          "Visible text selector with regex",
+			strategy:      StrategyVisibleText,
+			value:         "/^Total: \\$/",
+			wantSubstring: `new RegExp("^Total: \\$").test(text)`,
+		},
 		{
 			name:          "Data test ID",
 			strategy:      StrategyDataTestID,
 			value:         "submit-btn",
-			wantSubstring: "[data-testid=\"submit-btn\"]",
+			wantSubstring: `querySelector("[data-testid=\"submit-btn\"]")`,
 		},
 		{
 			name:          "ARIA label",
 			strategy:      StrategyAriaLabel,
 			value:         "Close",
-			wantSubstring: "[aria-label=\"Close\"]",
+			wantSubstring: `querySelector("[aria-label=\"Close\"]")`,
+		},
+		{
+			name:          "Placeholder",
+			strategy:      StrategyPlaceholder,
+			value:         "Email address",
+			wantSubstring: `textarea[placeholder=\"Email address\"]")`,
+		},
+		{
+			name:          "Title",
+			strategy:      StrategyTitle,
+			value:         "Delete item",
+			wantSubstring: `querySelector("[title=\"Delete item\"]")`,
 		},
 		{
 			name:          "ARIA role",
 			strategy:      StrategyRole,
 			value:         "button",
-			wantSubstring: "[role=\"button\"]",
+			wantSubstring: "matchesRole(el, \"button\", null, null)",
+		},
+		{
+			name:          "XPath",
+			strategy:      StrategyXPath,
+			value:         "//button[text()=\"Go\"]",
+			wantSubstring: `document.evaluate("//button[text()=\"Go\"]", document, null, XPathResult.FIRST_ORDERED_NODE_TYPE, null)`,
+		},
+		{
+			name:          "Shadow-piercing CSS",
+			strategy:      StrategyPierce,
+			value:         "button.save",
+			wantSubstring: `return pierceQueryAll(document, "button.save")[0] || null;`,
+		},
+		{
+			name:          "CSS with :has-text()",
+			strategy:      StrategyCSSSelector,
+			value:         `button:has-text("Save")`,
+			wantSubstring: `document.querySelectorAll("button")`,
+		},
+		{
+			name:          "CSS with :visible",
+			strategy:      StrategyCSSSelector,
+			value:         "li:visible",
+			wantSubstring: `if (matches.indexOf(el) === -1 && isVisible(el)) matches.push(el);`,
 		},
 	}
 
@@ -142,6 +249,263 @@ func TestGenerateSelectorScript(t *testing.T) {
 	}
 }
 
+func TestGenerateAllSelectorScript(t *testing.T) {
+	tests := []struct {
+		name          string
+		strategy      SelectorStrategy
+		value         string
+		wantSubstring string
+	}{
+		{
+			name:          "Placeholder",
+			strategy:      StrategyPlaceholder,
+			value:         "Search",
+			wantSubstring: `querySelectorAll("input[placeholder=\"Search\"], textarea[placeholder=\"Search\"]")`,
+		},
+		{
+			name:          "XPath",
+			strategy:      StrategyXPath,
+			value:         "//tr[@class='row']",
+			wantSubstring: `document.evaluate("//tr[@class='row']", document, null, XPathResult.ORDERED_NODE_SNAPSHOT_TYPE, null)`,
+		},
+		{
+			name:          "Title",
+			strategy:      StrategyTitle,
+			value:         "Delete item",
+			wantSubstring: `querySelectorAll("[title=\"Delete item\"]")`,
+		},
+		{
+			name:          "Shadow-piercing CSS",
+			strategy:      StrategyPierce,
+			value:         "li.item",
+			wantSubstring: `return pierceQueryAll(document, "li.item");`,
+		},
+		{
+			name:          "CSS with :has-text()",
+			strategy:      StrategyCSSSelector,
+			value:         `li:has-text("Buy")`,
+			wantSubstring: `(el.textContent || '').replace(/\s+/g, ' ').trim().toLowerCase().includes("buy")`,
+		},
+		{
+			name:          "CSS list with pseudos is in document order",
+			strategy:      StrategyCSSSelector,
+			value:         "h1:visible, h2:visible",
+			wantSubstring: `a.compareDocumentPosition(b) & Node.DOCUMENT_POSITION_FOLLOWING`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := generateAllSelectorScript(tt.strategy, tt.value)
+			if !contains(got, tt.wantSubstring) {
+				t.Errorf("generateAllSelectorScript(%v, %q) = %v, want to contain %v", tt.strategy, tt.value, got, tt.wantSubstring)
+			}
+		})
+	}
+}
+
+func TestParseEnginePseudos(t *testing.T) {
+	tests := []struct {
+		name     string
+		selector string
+		want     []enginePseudoSelector
+	}{
+		{
+			name:     "has-text",
+			selector: `button:has-text("Save")`,
+			want:     []enginePseudoSelector{{CSS: "button", HasText: []string{"Save"}}},
+		},
+		{
+			name:     "visible",
+			selector: "li.item:visible",
+			want:     []enginePseudoSelector{{CSS: "li.item", Visible: true}},
+		},
+		{
+			name:     "subject with combinators",
+			selector: `#cart > li:visible:has-text('Total')`,
+			want:     []enginePseudoSelector{{CSS: "#cart > li", Visible: true, HasText: []string{"Total"}}},
+		},
+		{
+			name:     "pseudos only",
+			selector: "form :visible",
+			want:     []enginePseudoSelector{{CSS: "form *", Visible: true}},
+		},
+		{
+			name:     "text with parentheses, commas and quotes",
+			selector: `a:has-text("Open (1, \"new\")")`,
+			want:     []enginePseudoSelector{{CSS: "a", HasText: []string{`Open (1, "new")`}}},
+		},
+		{
+			name:     "unquoted text",
+			selector: "button:has-text(Save)",
+			want:     []enginePseudoSelector{{CSS: "button", HasText: []string{"Save"}}},
+		},
+		{
+			name:     "selector list",
+			selector: `button:has-text("Save"), a.save`,
+			want: []enginePseudoSelector{
+				{CSS: "button", HasText: []string{"Save"}},
+				{CSS: "a.save"},
+			},
+		},
+		{
+			name:     "other pseudo-classes are kept",
+			selector: "input:not([disabled]):visible",
+			want:     []enginePseudoSelector{{CSS: "input:not([disabled])", Visible: true}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseEnginePseudos(tt.selector)
+			if err != nil {
+				t.Fatalf("parseEnginePseudos(%q) error: %v", tt.selector, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseEnginePseudos(%q) = %+v, want %+v", tt.selector, got, tt.want)
+			}
+		})
+	}
+
+	for _, selector := range []string{
+		"div:visible > button",
+		`button:has-text("Save)`,
+		`button:has-text("Save"`,
+	} {
+		if _, err := parseEnginePseudos(selector); err == nil {
+			t.Errorf("parseEnginePseudos(%q) expected an error", selector)
+		}
+	}
+
+	// Unsupported selectors fail from the generated script with the parse error
+	script := generateSelectorScript(StrategyCSSSelector, "div:visible > button")
+	if !contains(script, "throw new Error(") || !contains(script, "last compound selector") {
+		t.Errorf("Expected the script to throw the parse error, got %s", script)
+	}
+}
+
+func TestTextMatchExpression(t *testing.T) {
+	tests := []struct {
+		name       string
+		value      string
+		substring  bool
+		ignoreCase bool
+		want       string
+	}{
+		{"exact", "Submit", false, false, `text.replace(/\s+/g, ' ').trim() === "Submit"`},
+		{"substring", "Submit", true, false, `text.replace(/\s+/g, ' ').trim().includes("Submit")`},
+		{"whitespace is collapsed", "  Sign \n in ", false, false, `text.replace(/\s+/g, ' ').trim() === "Sign in"`},
+		{"ignore case", "Submit Form", false, true, `text.replace(/\s+/g, ' ').trim().toLowerCase() === "submit form"`},
+		{"regex", `/Order #\d+/`, false, false, `new RegExp("Order #\\d+").test(text)`},
+		{"regex ignores substring", "/^Sub/", true, false, `new RegExp("^Sub").test(text)`},
+		{"regex ignore case", "/^sub/", false, true, `new RegExp("^sub", "i").test(text)`},
+		{"regex with quotes", `/say "hi"/`, false, false, `new RegExp("say \"hi\"").test(text)`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := textMatchExpression("text", tt.value, tt.substring, tt.ignoreCase); got != tt.want {
+				t.Errorf("textMatchExpression(%q) = %s, want %s", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSelectorScriptEscaping(t *testing.T) {
+	values := []string{
+		`say "hi"`,
+		`back\slash`,
+		"line\nbreak",
+		"</script><script>alert(1)</script>",
+		"it's",
+		"héllo wörld ✓",
+		"\u2028separator",
+	}
+	strategies := []SelectorStrategy{
+		StrategyText, StrategyTextIgnoreCase, StrategyVisibleText, StrategyDataTestID,
+		StrategyAriaLabel, StrategyPlaceholder, StrategyTitle, StrategyCSSSelector,
+	}
+
+	for _, value := range values {
+		for _, strategy := range strategies {
+			for _, script := range []string{generateSelectorScript(strategy, value), generateAllSelectorScript(strategy, value)} {
+				// Values are embedded as JSON string literals, so quotes, backslashes,
+				// line breaks and markup never appear unescaped in the script
+				if strings.ContainsAny(value, "\"\\\n<\u2028") && contains(script, value) {
+					t.Errorf("%s script for %q contains the raw value: %s", strategy, value, script)
+				}
+			}
+		}
+	}
+
+	// Text values are embedded as exactly the JSON encoding of the normalized value
+	script := generateSelectorScript(StrategyText, `say "hi" \o/`)
+	if !contains(script, `=== "say \"hi\" \\o/"`) {
+		t.Errorf("Expected the JSON-encoded text value, got %s", script)
+	}
+}
+
+func TestCSSAttributeSelector(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{"submit", `[data-testid="submit"]`},
+		{`say "hi"`, `[data-testid="say \"hi\""]`},
+		{`back\slash`, `[data-testid="back\\slash"]`},
+		{"line\nbreak", `[data-testid="line\a break"]`},
+	}
+
+	for _, tt := range tests {
+		if got := cssAttributeSelector("data-testid", tt.value); got != tt.want {
+			t.Errorf("cssAttributeSelector(%q) = %s, want %s", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestParseRoleSelector(t *testing.T) {
+	tests := []struct {
+		value string
+		want  RoleSelector
+	}{
+		{"button", RoleSelector{Role: "button"}},
+		{`button[name="Submit"]`, RoleSelector{Role: "button", Name: "Submit"}},
+		{`button[name='Sign in']`, RoleSelector{Role: "button", Name: "Sign in"}},
+		{"link[name=/^Docs/]", RoleSelector{Role: "link", Name: "/^Docs/"}},
+		{`heading [ name = "Welcome" ]`, RoleSelector{Role: "heading", Name: "Welcome"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got := ParseRoleSelector(tt.value)
+			if got != tt.want {
+				t.Errorf("ParseRoleSelector(%q) = %+v, want %+v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGenerateRoleSelectorScript(t *testing.T) {
+	script := generateRoleSelectorScript(RoleSelector{Role: "button", Name: `Say "hi"`}, false)
+	if !contains(script, `matchesRole(el, "button", "Say \"hi\"", null)`) {
+		t.Errorf("Expected name filter to be passed as a string literal, got %v", script)
+	}
+	if !contains(script, "function implicitRole(el)") {
+		t.Error("Expected role matcher functions to be included")
+	}
+	if !contains(script, "matches[0]") {
+		t.Error("Expected first match to be returned")
+	}
+
+	script = generateRoleSelectorScript(RoleSelector{Role: "link", Name: "/^Docs/"}, true)
+	if !contains(script, `matchesRole(el, "link", null, "^Docs")`) {
+		t.Errorf("Expected name regex to be passed as a pattern, got %v", script)
+	}
+	if !contains(script, "return matches;") {
+		t.Error("Expected all matches to be returned")
+	}
+}
+
 func TestIsRegex(t *testing.T) {
 	tests := []struct {
 		name  string
@@ -201,3 +565,129 @@ func containsMiddle(s, substr string) bool {
 	}
 	return false
 }
+
+func TestParseFrameSelector(t *testing.T) {
+	tests := []struct {
+		name     string
+		selector string
+		want     FrameSelector
+	}{
+		{"Name", "name=payment", FrameSelector{Name: "payment"}},
+		{"URL glob", "url=https://pay.example.com/**", FrameSelector{URL: "https://pay.example.com/**"}},
+		{"URL regex", "url=/checkout/", FrameSelector{URL: "/checkout/"}},
+		{"CSS (default)", "iframe#payment", FrameSelector{CSS: "iframe#payment"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ParseFrameSelector(tt.selector)
+			if got != tt.want {
+				t.Errorf("ParseFrameSelector(%q) = %+v, want %+v", tt.selector, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGenerateFrameSelectorScript(t *testing.T) {
+	tests := []struct {
+		name          string
+		selector      string
+		wantSubstring string
+	}{
+		{"Name", "name=payment", `frame.name === "payment"`},
+		{"URL glob", "url=https://pay.example.com/*", `new RegExp("^https://pay\\.example\\.com/[^/]*$")`},
+		{"URL regex", "url=/checkout\\?step=\\d/", `new RegExp("checkout\\?step=\\d")`},
+		{"CSS", `iframe[title="Pay"]`, `document.querySelector("iframe[title=\"Pay\"]")`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := generateFrameSelectorScript(ParseFrameSelector(tt.selector))
+			if !contains(got, tt.wantSubstring) {
+				t.Errorf("generateFrameSelectorScript(%q) = %v, want to contain %v", tt.selector, got, tt.wantSubstring)
+			}
+		})
+	}
+}
+
+func TestGlobToRegex(t *testing.T) {
+	tests := []struct {
+		glob  string
+		url   string
+		match bool
+	}{
+		{"https://example.com/pay", "https://example.com/pay", true},
+		{"https://example.com/pay", "https://example.com/pay/extra", false},
+		{"https://example.com/*", "https://example.com/pay", true},
+		{"https://example.com/*", "https://example.com/pay/extra", false},
+		{"https://example.com/**", "https://example.com/pay/extra", true},
+		{"**/checkout?step=1", "https://example.com/checkout?step=1", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.glob+" "+tt.url, func(t *testing.T) {
+			re := regexp.MustCompile(globToRegex(tt.glob))
+			if got := re.MatchString(tt.url); got != tt.match {
+				t.Errorf("globToRegex(%q) matching %q = %v, want %v", tt.glob, tt.url, got, tt.match)
+			}
+		})
+	}
+}
+
+func TestURLPatternRegex(t *testing.T) {
+	tests := []struct {
+		pattern string
+		url     string
+		match   bool
+	}{
+		{"**/orders/*", "https://shop.example.com/orders/42", true},
+		{"**/orders/*", "https://shop.example.com/orders/42/items", false},
+		{"/orders\\/\\d+$/", "https://shop.example.com/orders/42", true},
+		{"/orders\\/\\d+$/", "https://shop.example.com/orders/new", false},
+	}
+
+	for _, tt := range tests {
+		re, err := urlPatternRegex(tt.pattern)
+		if err != nil {
+			t.Fatalf("Unexpected error for %q: %v", tt.pattern, err)
+		}
+		if got := re.MatchString(tt.url); got != tt.match {
+			t.Errorf("urlPatternRegex(%q) matching %q = %v, want %v", tt.pattern, tt.url, got, tt.match)
+		}
+	}
+
+	if _, err := urlPatternRegex("/orders(/"); err == nil {
+		t.Error("Expected an error for an invalid regex")
+	}
+}
+
+func TestFindElementCustomKeepsStdoutClean(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"value":{"element-6066-11e4-a52e-4f735466cecf":"heading"}}`))
+	}))
+	defer server.Close()
+
+	// k6 users parse its JSON output from stdout
+	stdout := os.Stdout
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.Stdout = w
+	t.Cleanup(func() { os.Stdout = stdout })
+
+	client := NewWebDriverClient(server.URL).forSession("session-1")
+	elementID, err := client.FindElement(context.Background(), "text=Welcome")
+
+	os.Stdout = stdout
+	_ = w.Close()
+	written, _ := io.ReadAll(r)
+
+	if err != nil || elementID != "heading" {
+		t.Fatalf("Expected the element to be found, got %q, %v", elementID, err)
+	}
+	if len(written) != 0 {
+		t.Errorf("Expected nothing to be written to stdout, got %q", written)
+	}
+}
@@ -0,0 +1,104 @@
+package browser
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/grafana/sobek"
+	"github.com/sirupsen/logrus"
+)
+
+// touchSource returns a touch pointer input source tapping the center of the element
+func touchSource(elementID string) map[string]interface{} {
+	origin := map[string]string{"element-6066-11e4-a52e-4f735466cecf": elementID}
+	return map[string]interface{}{
+		"type":       "pointer",
+		"id":         "finger",
+		"parameters": map[string]string{"pointerType": "touch"},
+		"actions": []map[string]interface{}{
+			{"type": "pointerMove", "duration": 0, "origin": origin, "x": 0, "y": 0},
+			{"type": "pointerDown", "button": 0},
+			{"type": "pointerUp", "button": 0},
+		},
+	}
+}
+
+// isUnsupportedAction returns whether err is the driver rejecting an action it
+// doesn't implement, rather than the action failing
+func isUnsupportedAction(err error) bool {
+	var wdErr *WebDriverError
+	if !errors.As(err, &wdErr) {
+		return false
+	}
+	switch wdErr.Code {
+	case "unsupported operation", "invalid argument", "unknown command":
+		return true
+	}
+	return false
+}
+
+// touchFallbackWarning warns once that taps fall back to synthetic touch events
+var touchFallbackWarning sync.Once
+
+// touchTapScript taps the center of the element with synthetic touch events,
+// followed by a click unless a touch handler prevented it, like a browser does
+// Desktop Safari has no Touch constructor, so the touches are plain objects
+const touchTapScript = `
+	var el = arguments[0];
+	el.scrollIntoView({ block: 'center', inline: 'center' });
+	var rect = el.getBoundingClientRect();
+	var x = rect.left + rect.width / 2, y = rect.top + rect.height / 2;
+	var touch = {
+		identifier: Date.now(), target: el, clientX: x, clientY: y,
+		pageX: x + window.scrollX, pageY: y + window.scrollY,
+		screenX: x + window.screenX, screenY: y + window.screenY,
+		radiusX: 1, radiusY: 1, rotationAngle: 0, force: 1
+	};
+	var touchEvent = function(type, touches) {
+		var event = new Event(type, { bubbles: true, cancelable: true, composed: true });
+		Object.defineProperty(event, 'touches', { value: touches });
+		Object.defineProperty(event, 'targetTouches', { value: touches });
+		Object.defineProperty(event, 'changedTouches', { value: [touch] });
+		return el.dispatchEvent(event);
+	};
+	var started = touchEvent('touchstart', [touch]);
+	var ended = touchEvent('touchend', []);
+	if (started && ended) el.click();
+`
+
+// Tap taps the center of the element matched by the locator with a touch
+// pointer, for touch handlers that don't respond to mouse clicks. If the driver
+// doesn't support touch input, touchstart and touchend events are dispatched
+func (l *Locator) Tap() (*sobek.Promise, error) {
+	return l.promise(func() (interface{}, error) {
+		if l.page.client == nil {
+			return nil, fmt.Errorf("browser session not initialized")
+		}
+
+		ctx := context.Background()
+
+		elementID, err := l.actionableElementID(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		err = l.page.client.PerformActions(ctx, []map[string]interface{}{touchSource(elementID)})
+		if err == nil {
+			return nil, nil
+		}
+		if !isUnsupportedAction(err) {
+			return nil, fmt.Errorf("failed to tap element: %w", err)
+		}
+
+		touchFallbackWarning.Do(func() {
+			logf(l.vu, logrus.WarnLevel, "touch input isn't supported by the driver, dispatching touch events instead: %v", err)
+		})
+		if _, err := l.page.client.ExecuteScript(ctx, touchTapScript, elementReferences([]string{elementID})); err != nil {
+			return nil, fmt.Errorf("failed to tap element: %w", err)
+		}
+
+		return nil, nil
+	}), nil
+}
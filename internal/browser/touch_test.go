@@ -0,0 +1,86 @@
+package browser
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"go.k6.io/k6/js/modulestest"
+)
+
+func TestLocatorTap(t *testing.T) {
+	tests := []struct {
+		name          string
+		actionsStatus int
+		actionsError  string
+		expected      string // Requests after finding the element
+		failure       string
+	}{
+		{"touch pointer", http.StatusOK, "", "actions:touch", ""},
+		{"touch unsupported", http.StatusBadRequest, "invalid argument", "actions:touch,script:touchstart", ""},
+		{"actions failing", http.StatusInternalServerError, "unknown error", "actions:touch", "failed to tap element"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var mu sync.Mutex
+			var requests []string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				mu.Lock()
+				defer mu.Unlock()
+
+				var payload map[string]interface{}
+				_ = json.NewDecoder(r.Body).Decode(&payload)
+
+				w.Header().Set("Content-Type", "application/json")
+				switch {
+				case strings.HasSuffix(r.URL.Path, "/element"):
+					_, _ = w.Write([]byte(`{"value":{"element-6066-11e4-a52e-4f735466cecf":"button"}}`))
+				case strings.HasSuffix(r.URL.Path, "/actions"):
+					sources, _ := payload["actions"].([]interface{})
+					source, _ := sources[0].(map[string]interface{})
+					parameters, _ := source["parameters"].(map[string]interface{})
+					requests = append(requests, fmt.Sprintf("actions:%v", parameters["pointerType"]))
+					if tt.actionsStatus != http.StatusOK {
+						w.WriteHeader(tt.actionsStatus)
+						_, _ = w.Write([]byte(`{"value":{"error":"` + tt.actionsError + `","message":"touch"}}`))
+						return
+					}
+					_, _ = w.Write([]byte(`{"value":null}`))
+				default:
+					if script, _ := payload["script"].(string); strings.Contains(script, "'touchstart'") {
+						requests = append(requests, "script:touchstart")
+					}
+					_, _ = w.Write([]byte(`{"value":null}`))
+				}
+			}))
+			defer server.Close()
+
+			runtime := modulestest.NewRuntime(t)
+			page := &Page{vu: runtime.VU, client: NewWebDriverClient(server.URL).forSession("session-1")}
+			if err := runtime.VU.Runtime().Set("page", page); err != nil {
+				t.Fatal(err)
+			}
+
+			_, err := runtime.RunOnEventLoop(`
+				var failure = "";
+				page.locator("#menu").tap().catch(function(e) { failure = String(e); });
+			`)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			failure := runtime.VU.Runtime().Get("failure").String()
+			if tt.failure == "" && failure != "" || !strings.Contains(failure, tt.failure) {
+				t.Errorf("Expected failure %q, got %q", tt.failure, failure)
+			}
+			if got := strings.Join(requests, ","); got != tt.expected {
+				t.Errorf("Expected requests %s, got %s", tt.expected, got)
+			}
+		})
+	}
+}
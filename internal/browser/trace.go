@@ -0,0 +1,156 @@
+package browser
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/grafana/sobek"
+)
+
+// Trace action types
+const (
+	TraceActionGoto  = "goto"
+	TraceActionClick = "click"
+	TraceActionFill  = "fill"
+	TraceActionType  = "type"
+)
+
+// TraceAction is a single recorded high-level page action
+type TraceAction struct {
+	Type      string `json:"type"`
+	Timestamp int64  `json:"timestamp"` // Unix milliseconds
+	URL       string `json:"url,omitempty"`
+	WaitUntil string `json:"waitUntil,omitempty"`
+	Selector  string `json:"selector,omitempty"`
+	Text      string `json:"text,omitempty"`
+}
+
+// actionTrace records the actions performed on a page
+type actionTrace struct {
+	mu      sync.Mutex
+	actions []TraceAction
+}
+
+// record appends an action to the trace, stamping it with the current time
+func (t *actionTrace) record(action TraceAction) {
+	if t == nil {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	action.Timestamp = time.Now().UnixMilli()
+	t.actions = append(t.actions, action)
+}
+
+// StartTracing starts recording the high-level actions (navigate, click, fill, type)
+// performed on the page, discarding any previous recording
+func (p *Page) StartTracing() {
+	p.traceMu.Lock()
+	defer p.traceMu.Unlock()
+
+	p.trace = &actionTrace{}
+}
+
+// StopTracing stops recording and returns the trace serialized as JSON
+// If options.path is set, the trace is also written to that file
+func (p *Page) StopTracing(options ...map[string]interface{}) (string, error) {
+	p.traceMu.Lock()
+	trace := p.trace
+	p.trace = nil
+	p.traceMu.Unlock()
+
+	if trace == nil {
+		return "", fmt.Errorf("tracing was not started")
+	}
+
+	trace.mu.Lock()
+	data, err := json.MarshalIndent(trace.actions, "", "  ")
+	trace.mu.Unlock()
+	if err != nil {
+		return "", fmt.Errorf("failed to serialize trace: %w", err)
+	}
+
+	if len(options) > 0 && options[0] != nil {
+		if path, ok := options[0]["path"].(string); ok && path != "" {
+			if err := os.WriteFile(path, data, 0o644); err != nil {
+				return "", fmt.Errorf("failed to write trace to %s: %w", path, err)
+			}
+		}
+	}
+
+	return string(data), nil
+}
+
+// Replay performs the actions of a trace returned by StopTracing, in order
+func (p *Page) Replay(trace string) (*sobek.Promise, error) {
+	if p.client == nil {
+		return nil, fmt.Errorf("browser session not initialized")
+	}
+
+	var actions []TraceAction
+	if err := json.Unmarshal([]byte(trace), &actions); err != nil {
+		return nil, fmt.Errorf("failed to parse trace: %w", err)
+	}
+
+	return p.promise(func() (any, error) {
+		ctx := context.Background()
+
+		for i, action := range actions {
+			if err := p.replayAction(ctx, action); err != nil {
+				return nil, fmt.Errorf("failed to replay action %d (%s): %w", i, action.Type, err)
+			}
+		}
+
+		return nil, nil
+	}), nil
+}
+
+// replayAction performs a single recorded action
+func (p *Page) replayAction(ctx context.Context, action TraceAction) error {
+	switch action.Type {
+	case TraceActionGoto:
+		err := p.client.Navigate(ctx, action.URL, &NavigateOptions{WaitUntil: action.WaitUntil})
+		if err != nil {
+			return err
+		}
+
+		// Re-inject the script after navigation
+		return p.reinjectScript(ctx, "after navigation")
+
+	case TraceActionClick:
+		elementID, err := p.client.FindElement(ctx, action.Selector)
+		if err != nil {
+			return fmt.Errorf("failed to find element with selector '%s': %w", action.Selector, err)
+		}
+		click := func() error { return p.client.ClickElement(ctx, elementID) }
+		if action.WaitUntil == "" {
+			return click()
+		}
+		return p.clickAndWaitForNavigation(ctx, click, &NavigateOptions{WaitUntil: action.WaitUntil}, DefaultTimeout())
+
+	case TraceActionFill, TraceActionType:
+		elementID, err := p.client.FindElement(ctx, action.Selector)
+		if err != nil {
+			return fmt.Errorf("failed to find element with selector '%s': %w", action.Selector, err)
+		}
+		return p.client.SendKeys(ctx, elementID, action.Text)
+
+	default:
+		return fmt.Errorf("unknown action type: %s", action.Type)
+	}
+}
+
+// recordAction records an action if tracing is active
+func (p *Page) recordAction(action TraceAction) {
+	p.traceMu.Lock()
+	trace := p.trace
+	p.traceMu.Unlock()
+
+	trace.record(action)
+}
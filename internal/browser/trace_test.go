@@ -0,0 +1,78 @@
+package browser
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPageTracing(t *testing.T) {
+	page := &Page{}
+
+	// Actions are not recorded before tracing starts
+	page.recordAction(TraceAction{Type: TraceActionGoto, URL: "https://example.com"})
+
+	if _, err := page.StopTracing(); err == nil {
+		t.Error("Expected error when stopping tracing that was not started")
+	}
+
+	page.StartTracing()
+	page.recordAction(TraceAction{Type: TraceActionGoto, URL: "https://example.com", WaitUntil: "load"})
+	page.recordAction(TraceAction{Type: TraceActionClick, Selector: "#login"})
+	page.recordAction(TraceAction{Type: TraceActionFill, Selector: "#user", Text: "admin"})
+
+	path := filepath.Join(t.TempDir(), "trace.json")
+	trace, err := page.StopTracing(map[string]interface{}{"path": path})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var actions []TraceAction
+	if err := json.Unmarshal([]byte(trace), &actions); err != nil {
+		t.Fatalf("Expected trace to be valid JSON: %v", err)
+	}
+	if len(actions) != 3 {
+		t.Fatalf("Expected 3 actions, got %d", len(actions))
+	}
+	if actions[0].Type != TraceActionGoto || actions[0].URL != "https://example.com" {
+		t.Errorf("Unexpected first action: %+v", actions[0])
+	}
+	if actions[2].Selector != "#user" || actions[2].Text != "admin" {
+		t.Errorf("Unexpected last action: %+v", actions[2])
+	}
+	if actions[0].Timestamp == 0 {
+		t.Error("Expected actions to be timestamped")
+	}
+
+	written, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Expected trace file to be written: %v", err)
+	}
+	if string(written) != trace {
+		t.Error("Expected trace file to match the returned trace")
+	}
+
+	// Stopping clears the recording
+	if _, err := page.StopTracing(); err == nil {
+		t.Error("Expected error when stopping tracing twice")
+	}
+}
+
+func TestPageReplayWithoutSession(t *testing.T) {
+	page := &Page{}
+
+	if _, err := page.Replay("[]"); err == nil {
+		t.Error("Expected error when replaying without session")
+	}
+}
+
+func TestPageReplayUnknownAction(t *testing.T) {
+	page := &Page{client: NewWebDriverClient("http://localhost:4444")}
+
+	err := page.replayAction(context.Background(), TraceAction{Type: "hover"})
+	if err == nil || !contains(err.Error(), "unknown action type") {
+		t.Errorf("Expected unknown action error, got %v", err)
+	}
+}
@@ -0,0 +1,117 @@
+package browser
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/grafana/sobek"
+)
+
+// defaultChromeHeight is the first guess of the height of Safari's toolbars,
+// which the window is sized to on top of the viewport before it's measured
+const defaultChromeHeight = 52
+
+// viewportSizeScript measures the viewport and the window around it
+const viewportSizeScript = `
+	return {
+		innerWidth: window.innerWidth, innerHeight: window.innerHeight,
+		outerWidth: window.outerWidth, outerHeight: window.outerHeight
+	};
+`
+
+// windowSizes are the sizes of the viewport and of the window around it
+type windowSizes struct {
+	inner Viewport
+	outer Viewport
+}
+
+// measureWindow returns the current sizes of the viewport and the window
+func (c *WebDriverClient) measureWindow(ctx context.Context) (windowSizes, error) {
+	result, err := c.ExecuteScript(ctx, viewportSizeScript, nil)
+	if err != nil {
+		return windowSizes{}, fmt.Errorf("failed to measure the viewport: %w", err)
+	}
+
+	values, _ := result.(map[string]interface{})
+	size := func(key string) int {
+		v, _ := toFloat64(values[key])
+		return int(v)
+	}
+	return windowSizes{
+		inner: Viewport{Width: size("innerWidth"), Height: size("innerHeight")},
+		outer: Viewport{Width: size("outerWidth"), Height: size("outerHeight")},
+	}, nil
+}
+
+// setViewportSize sizes the window so that its viewport has the given size
+// The size of Safari's toolbars varies with the macOS version and toolbar
+// settings, so it's measured once the window was sized with a first guess, and
+// the window is resized if the guess was off
+func (c *WebDriverClient) setViewportSize(ctx context.Context, viewport Viewport) error {
+	if err := c.SetWindowSize(ctx, viewport.Width, viewport.Height+defaultChromeHeight); err != nil {
+		return err
+	}
+
+	sizes, err := c.measureWindow(ctx)
+	if err != nil {
+		return err
+	}
+	if sizes.inner == viewport {
+		return nil
+	}
+
+	chromeWidth := sizes.outer.Width - sizes.inner.Width
+	chromeHeight := sizes.outer.Height - sizes.inner.Height
+	if err := c.SetWindowSize(ctx, viewport.Width+chromeWidth, viewport.Height+chromeHeight); err != nil {
+		return err
+	}
+
+	// The window can't be larger than the screen
+	if sizes, err = c.measureWindow(ctx); err != nil {
+		return err
+	}
+	if sizes.inner != viewport {
+		return fmt.Errorf("viewport is %dx%d instead of %dx%d, the screen may be too small",
+			sizes.inner.Width, sizes.inner.Height, viewport.Width, viewport.Height)
+	}
+	return nil
+}
+
+// ViewportSize returns the current size of the page's viewport, or null if it
+// can't be measured
+func (p *Page) ViewportSize() *Viewport {
+	if p.client == nil {
+		return nil
+	}
+
+	ctx := context.Background()
+	var sizes windowSizes
+	err := p.inWindow(ctx, func() error {
+		var err error
+		sizes, err = p.client.measureWindow(ctx)
+		return err
+	})
+	if err != nil {
+		return nil
+	}
+	return &sizes.inner
+}
+
+// SetViewportSize resizes the page's window so that its viewport has the given
+// size, e.g. to check the breakpoints of a responsive layout mid-test
+func (p *Page) SetViewportSize(width, height int) (*sobek.Promise, error) {
+	if p.client == nil {
+		return nil, fmt.Errorf("browser session not initialized")
+	}
+	if width <= 0 || height <= 0 {
+		return nil, fmt.Errorf("viewport size must be positive, got %dx%d", width, height)
+	}
+
+	return p.promise(func() (any, error) {
+		ctx := context.Background()
+		if err := p.client.setViewportSize(ctx, Viewport{Width: width, Height: height}); err != nil {
+			return nil, fmt.Errorf("failed to set viewport size: %w", err)
+		}
+		return nil, nil
+	}), nil
+}
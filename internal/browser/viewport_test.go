@@ -0,0 +1,118 @@
+package browser
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"go.k6.io/k6/js/modulestest"
+)
+
+// resizeServer fakes a Safari window whose toolbars take chromeHeight pixels,
+// on a screen of at most maxHeight pixels
+type resizeServer struct {
+	mu           sync.Mutex
+	chromeHeight int
+	maxHeight    int
+	window       Viewport
+	resizes      []Viewport
+}
+
+func (rs *resizeServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var payload map[string]interface{}
+	_ = json.NewDecoder(r.Body).Decode(&payload)
+
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	var value interface{}
+	if strings.HasSuffix(r.URL.Path, "/window/rect") {
+		width, _ := toFloat64(payload["width"])
+		height, _ := toFloat64(payload["height"])
+		rs.window = Viewport{Width: int(width), Height: min(int(height), rs.maxHeight)}
+		rs.resizes = append(rs.resizes, Viewport{Width: int(width), Height: int(height)})
+	} else {
+		value = map[string]interface{}{
+			"innerWidth": rs.window.Width, "innerHeight": rs.window.Height - rs.chromeHeight,
+			"outerWidth": rs.window.Width, "outerHeight": rs.window.Height,
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	data, _ := json.Marshal(map[string]interface{}{"value": value})
+	_, _ = w.Write(data)
+}
+
+func TestWebDriverClientSetViewportSize(t *testing.T) {
+	ctx := context.Background()
+	viewport := Viewport{Width: 1280, Height: 720}
+
+	// The first guess is right
+	rs := &resizeServer{chromeHeight: defaultChromeHeight, maxHeight: 2000}
+	server := httptest.NewServer(rs)
+	defer server.Close()
+
+	client := NewWebDriverClient(server.URL).forSession("session-1")
+	if err := client.setViewportSize(ctx, viewport); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(rs.resizes) != 1 {
+		t.Errorf("Expected a single resize, got %v", rs.resizes)
+	}
+
+	// Toolbars taller than the guess are measured and compensated
+	rs.chromeHeight, rs.resizes = 87, nil
+	if err := client.setViewportSize(ctx, viewport); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(rs.resizes) != 2 || rs.resizes[1] != (Viewport{Width: 1280, Height: 807}) {
+		t.Errorf("Expected the window to be resized for 87px toolbars, got %v", rs.resizes)
+	}
+
+	// A screen too small for the window is reported
+	rs.maxHeight = 600
+	if err := client.setViewportSize(ctx, viewport); err == nil || !strings.Contains(err.Error(), "viewport is 1280x513") {
+		t.Errorf("Expected the viewport size mismatch to be reported, got %v", err)
+	}
+}
+
+func TestPageSetViewportSize(t *testing.T) {
+	rs := &resizeServer{chromeHeight: 87, maxHeight: 2000, window: Viewport{Width: 1280, Height: 807}}
+	server := httptest.NewServer(rs)
+	defer server.Close()
+
+	runtime := modulestest.NewRuntime(t)
+	page := &Page{vu: runtime.VU, client: NewWebDriverClient(server.URL).forSession("session-1")}
+	if err := runtime.VU.Runtime().Set("page", page); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := runtime.RunOnEventLoop(`
+		var before = page.viewportSize();
+		var after, failure = "";
+		page.setViewportSize(375, 667).then(function() {
+			after = page.viewportSize();
+		}).catch(function(e) { failure = String(e); });
+	`)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if failure := runtime.VU.Runtime().Get("failure").String(); failure != "" {
+		t.Fatalf("Unexpected failure: %s", failure)
+	}
+	for name, expected := range map[string]string{"before": "1280x720", "after": "375x667"} {
+		size := runtime.VU.Runtime().Get(name).ToObject(runtime.VU.Runtime())
+		if got := size.Get("width").String() + "x" + size.Get("height").String(); got != expected {
+			t.Errorf("Expected the viewport %s resizing to be %s, got %s", name, expected, got)
+		}
+	}
+
+	if _, err := page.SetViewportSize(0, 667); err == nil {
+		t.Error("Expected an empty viewport to be rejected")
+	}
+}
@@ -5,20 +5,61 @@ import (
 	"context"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"image"
 	"image/png"
-	"log"
+	"io"
+	"math"
 	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
+
+	"github.com/sirupsen/logrus"
+	"go.k6.io/k6/js/modules"
 )
 
 // WebDriverClient handles communication with Safari WebDriver
 type WebDriverClient struct {
-	baseURL    string
-	httpClient *http.Client
-	sessionID  string
+	baseURL      string
+	httpClient   *http.Client
+	maxRetries   int
+	retryBackoff time.Duration
+
+	// dialogHandler closes a dialog that blocks a command, which is then sent again
+	// nil leaves the command failing with "unexpected alert open"
+	dialogHandler func(ctx context.Context) error
+
+	metrics *K6Metrics // nil to not emit operation durations
+	vu      modules.VU // VU the operation durations are emitted for
+
+	// deviceScaleFactor is the device pixel ratio requested with the
+	// safari:devicePixelRatio capability, 0 if the session didn't request one
+	deviceScaleFactor float64
+
+	// autoReconnect replaces a session invalidated by safaridriver, e.g. after
+	// a long idle time, with a new one created with the same capabilities
+	autoReconnect bool
+	reconnectMu   sync.Mutex // Held while replacing the session
+
+	// onReconnect, if set, is called once reconnect replaced the session
+	onReconnect func()
+	// replaceSession, if set, replaces an invalidated session instead of
+	// reconnect creating one, for clients that follow the session of a page
+	replaceSession func(ctx context.Context, invalidID string) (string, error)
+
+	// The browser's client is shared by the VU's pages and iterations, which
+	// create and delete sessions concurrently
+	sessionMu    sync.RWMutex
+	sessionID    string
+	capabilities map[string]interface{} // Capabilities the session was created with
+	windowSize   [2]int                 // Last window size set in the session, restored on reconnect
 }
 
 // WebDriverSession represents a WebDriver session
@@ -33,36 +74,294 @@ type WebDriverResponse struct {
 	SessionID string      `json:"sessionId,omitempty"`
 }
 
+// Errors that callers can check for with errors.Is
+var (
+	ErrElementNotFound = errors.New("element not found")
+	ErrNoSession       = errors.New("no active session")
+	ErrTimeout         = errors.New("timeout")
+)
+
+// WebDriverError is the W3C error object returned by a failed WebDriver command
+type WebDriverError struct {
+	StatusCode int    // HTTP status code of the response
+	Code       string // W3C error code, e.g. "no such element" or "invalid selector"
+	Message    string
+	Stacktrace string
+}
+
+func (e *WebDriverError) Error() string {
+	if e.Message == "" {
+		return e.Code
+	}
+	return e.Code + ": " + e.Message
+}
+
+// Is maps W3C error codes onto the sentinel errors, so that errors.Is works
+// for failures reported by safaridriver as well as those detected locally
+func (e *WebDriverError) Is(target error) bool {
+	switch target {
+	case ErrElementNotFound:
+		return e.Code == "no such element" || e.Code == "stale element reference"
+	case ErrNoSession:
+		return e.Code == "invalid session id"
+	case ErrTimeout:
+		return e.Code == "timeout" || e.Code == "script timeout"
+	}
+	return false
+}
+
+// newWebDriverError reads the W3C error object from the body of a failed response
+// The code falls back to "unknown error" when the body isn't a W3C error
+func newWebDriverError(resp *http.Response) *WebDriverError {
+	wdErr := &WebDriverError{StatusCode: resp.StatusCode}
+
+	var errorResp struct {
+		Value struct {
+			Error      string `json:"error"`
+			Message    string `json:"message"`
+			Stacktrace string `json:"stacktrace"`
+		} `json:"value"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&errorResp); err == nil {
+		wdErr.Code = errorResp.Value.Error
+		wdErr.Message = errorResp.Value.Message
+		wdErr.Stacktrace = errorResp.Value.Stacktrace
+	}
+	if wdErr.Code == "" {
+		wdErr.Code = "unknown error"
+	}
+
+	return wdErr
+}
+
+// ClientOptions tunes the HTTP client used to talk to safaridriver
+// Each VU sends many small sequential requests to the same host, so idle
+// connections are kept alive and reused instead of being set up per request
+type ClientOptions struct {
+	Timeout             time.Duration // Per-request timeout
+	MaxIdleConns        int           // Idle connections kept across all hosts
+	MaxIdleConnsPerHost int           // Idle connections kept to safaridriver
+	IdleConnTimeout     time.Duration // How long idle connections are kept
+	DisableKeepAlives   bool          // Open a new connection for every request
+	MaxRetries          int           // Retries for transient failures, 0 disables retrying
+	RetryBackoff        time.Duration // Delay before the first retry, doubled on each attempt
+	AutoReconnect       bool          // Replace a session invalidated by safaridriver and send the command again
+}
+
+// Environment variables overriding the default client options
+const (
+	maxIdleConnsPerHostEnv = "XK6_SAFARI_MAX_IDLE_CONNS_PER_HOST"
+	disableKeepAlivesEnv   = "XK6_SAFARI_DISABLE_KEEPALIVES"
+	maxRetriesEnv          = "XK6_SAFARI_MAX_RETRIES"
+	autoReconnectEnv       = "XK6_SAFARI_AUTO_RECONNECT"
+)
+
+// DefaultClientOptions returns the client options used by NewWebDriverClient
+func DefaultClientOptions() ClientOptions {
+	return ClientOptions{
+		Timeout:             30 * time.Second,
+		MaxIdleConns:        16,
+		MaxIdleConnsPerHost: 16,
+		IdleConnTimeout:     90 * time.Second,
+		RetryBackoff:        100 * time.Millisecond,
+	}
+}
+
+// ClientOptionsFromEnv returns the default client options, overridden by
+// XK6_SAFARI_MAX_IDLE_CONNS_PER_HOST, XK6_SAFARI_DISABLE_KEEPALIVES,
+// XK6_SAFARI_MAX_RETRIES and XK6_SAFARI_AUTO_RECONNECT when set
+func ClientOptionsFromEnv() ClientOptions {
+	opts := DefaultClientOptions()
+
+	if v := os.Getenv(maxIdleConnsPerHostEnv); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			opts.MaxIdleConnsPerHost = n
+			if n > opts.MaxIdleConns {
+				opts.MaxIdleConns = n
+			}
+		} else {
+			logf(nil, logrus.WarnLevel, "ignoring invalid %s=%q", maxIdleConnsPerHostEnv, v)
+		}
+	}
+	if v := os.Getenv(disableKeepAlivesEnv); v != "" {
+		if disable, err := strconv.ParseBool(v); err == nil {
+			opts.DisableKeepAlives = disable
+		} else {
+			logf(nil, logrus.WarnLevel, "ignoring invalid %s=%q", disableKeepAlivesEnv, v)
+		}
+	}
+	if v := os.Getenv(maxRetriesEnv); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n >= 0 {
+			opts.MaxRetries = n
+		} else {
+			logf(nil, logrus.WarnLevel, "ignoring invalid %s=%q", maxRetriesEnv, v)
+		}
+	}
+	if v := os.Getenv(autoReconnectEnv); v != "" {
+		if reconnect, err := strconv.ParseBool(v); err == nil {
+			opts.AutoReconnect = reconnect
+		} else {
+			logf(nil, logrus.WarnLevel, "ignoring invalid %s=%q", autoReconnectEnv, v)
+		}
+	}
+
+	return opts
+}
+
 // NewWebDriverClient creates a new WebDriver client for Safari
 func NewWebDriverClient(baseURL string) *WebDriverClient {
+	return NewWebDriverClientWithOptions(baseURL, DefaultClientOptions())
+}
+
+// NewWebDriverClientWithOptions creates a new WebDriver client for Safari with a tuned HTTP transport
+func NewWebDriverClientWithOptions(baseURL string, opts ClientOptions) *WebDriverClient {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.MaxIdleConns = opts.MaxIdleConns
+	transport.MaxIdleConnsPerHost = opts.MaxIdleConnsPerHost
+	transport.IdleConnTimeout = opts.IdleConnTimeout
+	transport.DisableKeepAlives = opts.DisableKeepAlives
+
 	return &WebDriverClient{
 		baseURL: baseURL,
 		httpClient: &http.Client{
-			Timeout: 30 * time.Second,
+			Timeout:   opts.Timeout,
+			Transport: transport,
 		},
+		maxRetries:    opts.MaxRetries,
+		retryBackoff:  opts.RetryBackoff,
+		autoReconnect: opts.AutoReconnect,
+	}
+}
+
+// forSession returns a client bound to the given session that shares this
+// client's HTTP connection pool
+// Pages use their own bound client so that commands, and the injected state
+// they read, always target the page's session rather than the latest one created
+func (c *WebDriverClient) forSession(sessionID string) *WebDriverClient {
+	c.sessionMu.RLock()
+	capabilities := c.capabilities
+	c.sessionMu.RUnlock()
+
+	return &WebDriverClient{
+		baseURL:      c.baseURL,
+		httpClient:   c.httpClient,
+		sessionID:    sessionID,
+		maxRetries:   c.maxRetries,
+		retryBackoff: c.retryBackoff,
+		metrics:      c.metrics,
+		vu:           c.vu,
+
+		deviceScaleFactor: c.deviceScaleFactor,
+		autoReconnect:     c.autoReconnect,
+		capabilities:      capabilities,
+	}
+}
+
+// session returns the ID of the client's session, or "" if it has none
+func (c *WebDriverClient) session() string {
+	c.sessionMu.RLock()
+	defer c.sessionMu.RUnlock()
+
+	return c.sessionID
+}
+
+// setSession binds the client to a session, or unbinds it with ""
+func (c *WebDriverClient) setSession(sessionID string) {
+	c.sessionMu.Lock()
+	defer c.sessionMu.Unlock()
+
+	c.sessionID = sessionID
+	c.windowSize = [2]int{}
+}
+
+// clearSession unbinds the client from the session, unless it was bound to
+// another one since
+func (c *WebDriverClient) clearSession(sessionID string) {
+	c.sessionMu.Lock()
+	defer c.sessionMu.Unlock()
+
+	if c.sessionID == sessionID {
+		c.sessionID = ""
+	}
+}
+
+// DriverStatus is the readiness state reported by the WebDriver status endpoint
+type DriverStatus struct {
+	Ready   bool   `json:"ready"`
+	Message string `json:"message"`
+}
+
+// Status reports whether the driver can create new sessions
+func (c *WebDriverClient) Status(ctx context.Context) (*DriverStatus, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", c.baseURL+"/status", nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create status request: %w", err)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get status: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("status failed with status %d: %w", resp.StatusCode, newWebDriverError(resp))
+	}
+
+	var statusResp struct {
+		Value DriverStatus `json:"value"`
 	}
+
+	if err := json.NewDecoder(resp.Body).Decode(&statusResp); err != nil {
+		return nil, fmt.Errorf("failed to decode status response: %w", err)
+	}
+
+	return &statusResp.Value, nil
+}
+
+// WaitForReady polls Status until the driver reports it is ready or the timeout elapses
+func (c *WebDriverClient) WaitForReady(ctx context.Context, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+
+	var lastErr error
+	for time.Now().Before(deadline) {
+		status, err := c.Status(ctx)
+		switch {
+		case err != nil:
+			lastErr = err
+		case status.Ready:
+			return nil
+		default:
+			lastErr = fmt.Errorf("driver not ready: %s", status.Message)
+		}
+
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	return fmt.Errorf("driver did not become ready within %v: %w", timeout, lastErr)
 }
 
 // GetAllCookies retrieves all cookies for the current session
 func (c *WebDriverClient) GetAllCookies(ctx context.Context) ([]map[string]interface{}, error) {
-	if c.sessionID == "" {
-		return nil, fmt.Errorf("no active session")
+	sessionID := c.session()
+	if sessionID == "" {
+		return nil, ErrNoSession
 	}
 
 	req, err := http.NewRequestWithContext(ctx, "GET",
-		c.baseURL+"/session/"+c.sessionID+"/cookie", nil)
+		c.baseURL+"/session/"+sessionID+"/cookie", nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
-	resp, err := c.httpClient.Do(req)
+	resp, err := c.do(req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get cookies: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("get cookies failed with status: %d", resp.StatusCode)
+		return nil, fmt.Errorf("get cookies failed with status %d: %w", resp.StatusCode, newWebDriverError(resp))
 	}
 
 	var result struct {
@@ -76,10 +375,368 @@ func (c *WebDriverClient) GetAllCookies(ctx context.Context) ([]map[string]inter
 	return result.Value, nil
 }
 
+// AddCookie adds a cookie to the current browsing context
+// The cookie's domain must match the current page, so navigate first
+func (c *WebDriverClient) AddCookie(ctx context.Context, cookie map[string]interface{}) error {
+	sessionID := c.session()
+	if sessionID == "" {
+		return ErrNoSession
+	}
+
+	payload := map[string]interface{}{"cookie": cookie}
+	jsonData, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal cookie payload: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST",
+		c.baseURL+"/session/"+sessionID+"/cookie", bytes.NewBuffer(jsonData))
+	if err != nil {
+		return fmt.Errorf("failed to create add cookie request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.do(req)
+	if err != nil {
+		return fmt.Errorf("failed to add cookie: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("add cookie failed with status %d: %w", resp.StatusCode, newWebDriverError(resp))
+	}
+
+	return nil
+}
+
+// DeleteAllCookies deletes all cookies visible to the current page
+func (c *WebDriverClient) DeleteAllCookies(ctx context.Context) error {
+	sessionID := c.session()
+	if sessionID == "" {
+		return ErrNoSession
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "DELETE",
+		c.baseURL+"/session/"+sessionID+"/cookie", nil)
+	if err != nil {
+		return fmt.Errorf("failed to create delete cookies request: %w", err)
+	}
+
+	resp, err := c.do(req)
+	if err != nil {
+		return fmt.Errorf("failed to delete cookies: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("delete cookies failed with status %d: %w", resp.StatusCode, newWebDriverError(resp))
+	}
+
+	return nil
+}
+
+// DeleteCookie deletes the cookie with the given name
+func (c *WebDriverClient) DeleteCookie(ctx context.Context, name string) error {
+	sessionID := c.session()
+	if sessionID == "" {
+		return ErrNoSession
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "DELETE",
+		c.baseURL+"/session/"+sessionID+"/cookie/"+url.PathEscape(name), nil)
+	if err != nil {
+		return fmt.Errorf("failed to create delete cookie request: %w", err)
+	}
+
+	resp, err := c.do(req)
+	if err != nil {
+		return fmt.Errorf("failed to delete cookie: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("delete cookie failed with status %d: %w", resp.StatusCode, newWebDriverError(resp))
+	}
+
+	return nil
+}
+
+// SwitchToFrame switches the session's browsing context into the frame element
+// An empty elementID switches back to the top-level document
+func (c *WebDriverClient) SwitchToFrame(ctx context.Context, elementID string) error {
+	sessionID := c.session()
+	if sessionID == "" {
+		return ErrNoSession
+	}
+
+	payload := map[string]interface{}{"id": nil}
+	if elementID != "" {
+		payload["id"] = map[string]string{"element-6066-11e4-a52e-4f735466cecf": elementID}
+	}
+
+	jsonData, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal switch frame payload: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST",
+		c.baseURL+"/session/"+sessionID+"/frame", bytes.NewBuffer(jsonData))
+	if err != nil {
+		return fmt.Errorf("failed to create switch frame request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.do(req)
+	if err != nil {
+		return fmt.Errorf("failed to switch frame: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("switch frame failed with status %d: %w", resp.StatusCode, newWebDriverError(resp))
+	}
+
+	return nil
+}
+
+// SwitchToParentFrame switches the session's browsing context to the parent of the current frame
+func (c *WebDriverClient) SwitchToParentFrame(ctx context.Context) error {
+	sessionID := c.session()
+	if sessionID == "" {
+		return ErrNoSession
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST",
+		c.baseURL+"/session/"+sessionID+"/frame/parent", bytes.NewBufferString("{}"))
+	if err != nil {
+		return fmt.Errorf("failed to create switch to parent frame request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.do(req)
+	if err != nil {
+		return fmt.Errorf("failed to switch to parent frame: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("switch to parent frame failed with status %d: %w", resp.StatusCode, newWebDriverError(resp))
+	}
+
+	return nil
+}
+
+// GetWindowHandle returns the handle of the window the session's commands go to
+func (c *WebDriverClient) GetWindowHandle(ctx context.Context) (string, error) {
+	var handle string
+	if err := c.getWindow(ctx, "/window", "window handle", &handle); err != nil {
+		return "", err
+	}
+	return handle, nil
+}
+
+// GetWindowHandles returns the handles of all windows and tabs of the session
+func (c *WebDriverClient) GetWindowHandles(ctx context.Context) ([]string, error) {
+	var handles []string
+	if err := c.getWindow(ctx, "/window/handles", "window handles", &handles); err != nil {
+		return nil, err
+	}
+	return handles, nil
+}
+
+// getWindow sends a GET for a window endpoint and decodes the response value into value
+func (c *WebDriverClient) getWindow(ctx context.Context, path, what string, value interface{}) error {
+	sessionID := c.session()
+	if sessionID == "" {
+		return ErrNoSession
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET",
+		c.baseURL+"/session/"+sessionID+path, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create get %s request: %w", what, err)
+	}
+
+	resp, err := c.do(req)
+	if err != nil {
+		return fmt.Errorf("failed to get %s: %w", what, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("get %s failed with status %d: %w", what, resp.StatusCode, newWebDriverError(resp))
+	}
+
+	windowResp := struct {
+		Value interface{} `json:"value"`
+	}{Value: value}
+	if err := json.NewDecoder(resp.Body).Decode(&windowResp); err != nil {
+		return fmt.Errorf("failed to decode %s response: %w", what, err)
+	}
+
+	return nil
+}
+
+// SwitchToWindow switches the session's commands to the window with the given handle
+func (c *WebDriverClient) SwitchToWindow(ctx context.Context, handle string) error {
+	sessionID := c.session()
+	if sessionID == "" {
+		return ErrNoSession
+	}
+
+	jsonData, err := json.Marshal(map[string]string{"handle": handle})
+	if err != nil {
+		return fmt.Errorf("failed to marshal switch window payload: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST",
+		c.baseURL+"/session/"+sessionID+"/window", bytes.NewBuffer(jsonData))
+	if err != nil {
+		return fmt.Errorf("failed to create switch window request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.do(req)
+	if err != nil {
+		return fmt.Errorf("failed to switch window: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("switch window failed with status %d: %w", resp.StatusCode, newWebDriverError(resp))
+	}
+
+	return nil
+}
+
+// CloseWindow closes the current window and returns the handles of the windows
+// left open. The session ends when its last window is closed
+func (c *WebDriverClient) CloseWindow(ctx context.Context) ([]string, error) {
+	sessionID := c.session()
+	if sessionID == "" {
+		return nil, ErrNoSession
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "DELETE",
+		c.baseURL+"/session/"+sessionID+"/window", nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create close window request: %w", err)
+	}
+
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to close window: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("close window failed with status %d: %w", resp.StatusCode, newWebDriverError(resp))
+	}
+
+	var handlesResp struct {
+		Value []string `json:"value"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&handlesResp); err != nil {
+		return nil, fmt.Errorf("failed to decode close window response: %w", err)
+	}
+
+	return handlesResp.Value, nil
+}
+
+// AcceptAlert accepts the open dialog, as if its OK button was clicked
+func (c *WebDriverClient) AcceptAlert(ctx context.Context) error {
+	return c.postAlert(ctx, "accept", nil)
+}
+
+// DismissAlert dismisses the open dialog, as if its Cancel button was clicked
+// Dialogs without a Cancel button, like alert(), are accepted instead
+func (c *WebDriverClient) DismissAlert(ctx context.Context) error {
+	return c.postAlert(ctx, "dismiss", nil)
+}
+
+// SendAlertText types text into the open prompt() dialog
+func (c *WebDriverClient) SendAlertText(ctx context.Context, text string) error {
+	return c.postAlert(ctx, "text", map[string]string{"text": text})
+}
+
+// postAlert sends a command to the /alert/{command} endpoint
+func (c *WebDriverClient) postAlert(ctx context.Context, command string, payload interface{}) error {
+	sessionID := c.session()
+	if sessionID == "" {
+		return ErrNoSession
+	}
+
+	if payload == nil {
+		payload = map[string]interface{}{}
+	}
+	jsonData, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal alert %s payload: %w", command, err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST",
+		c.baseURL+"/session/"+sessionID+"/alert/"+command, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return fmt.Errorf("failed to create alert %s request: %w", command, err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send alert %s: %w", command, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("alert %s failed with status %d: %w", command, resp.StatusCode, newWebDriverError(resp))
+	}
+
+	return nil
+}
+
+// GetAlertText returns the message of the open dialog
+func (c *WebDriverClient) GetAlertText(ctx context.Context) (string, error) {
+	sessionID := c.session()
+	if sessionID == "" {
+		return "", ErrNoSession
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET",
+		c.baseURL+"/session/"+sessionID+"/alert/text", nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to create get alert text request: %w", err)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to get alert text: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("get alert text failed with status %d: %w", resp.StatusCode, newWebDriverError(resp))
+	}
+
+	var textResp struct {
+		Value string `json:"value"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&textResp); err != nil {
+		return "", fmt.Errorf("failed to decode alert text response: %w", err)
+	}
+
+	return textResp.Value, nil
+}
+
 // SetWindowSize sets the browser window size
 func (c *WebDriverClient) SetWindowSize(ctx context.Context, width, height int) error {
-	if c.sessionID == "" {
-		return fmt.Errorf("no active session")
+	sessionID := c.session()
+	if sessionID == "" {
+		return ErrNoSession
 	}
 
 	payload := map[string]interface{}{
@@ -93,26 +750,208 @@ func (c *WebDriverClient) SetWindowSize(ctx context.Context, width, height int)
 	}
 
 	req, err := http.NewRequestWithContext(ctx, "POST",
-		c.baseURL+"/session/"+c.sessionID+"/window/rect", bytes.NewBuffer(jsonData))
+		c.baseURL+"/session/"+sessionID+"/window/rect", bytes.NewBuffer(jsonData))
 	if err != nil {
 		return fmt.Errorf("failed to create set window size request: %w", err)
 	}
 
 	req.Header.Set("Content-Type", "application/json")
 
-	resp, err := c.httpClient.Do(req)
+	resp, err := c.do(req)
 	if err != nil {
 		return fmt.Errorf("failed to set window size: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("set window size failed with status: %d", resp.StatusCode)
+		return fmt.Errorf("set window size failed with status %d: %w", resp.StatusCode, newWebDriverError(resp))
 	}
 
+	c.sessionMu.Lock()
+	if c.sessionID == sessionID {
+		c.windowSize = [2]int{width, height}
+	}
+	c.sessionMu.Unlock()
+
 	return nil
 }
 
+// maxDialogsPerCommand bounds how many dialogs are closed for a single command,
+// in case a page keeps opening them
+const maxDialogsPerCommand = 5
+
+// do sends a request, closing dialogs that block it with the client's dialog
+// handler and sending the request again. With auto-reconnect, a request to a
+// session invalidated by safaridriver is sent once more to a new session
+func (c *WebDriverClient) do(req *http.Request) (*http.Response, error) {
+	reconnected := false
+	for dialogs := 0; ; {
+		resp, err := c.httpClient.Do(req)
+		if err != nil || resp.StatusCode < http.StatusBadRequest {
+			return resp, err
+		}
+
+		switch code := peekErrorCode(resp); {
+		case code == "invalid session id" && c.autoReconnect && !reconnected:
+			invalidID := sessionIDFromPath(req.URL.Path)
+			if invalidID == "" {
+				return resp, nil
+			}
+			resp.Body.Close()
+
+			sessionID, err := c.reconnect(req.Context(), invalidID)
+			if err != nil {
+				return nil, err
+			}
+			req.URL.Path = strings.Replace(req.URL.Path, "/session/"+invalidID, "/session/"+sessionID, 1)
+			reconnected = true
+		case code == "unexpected alert open" && c.dialogHandler != nil && dialogs < maxDialogsPerCommand:
+			resp.Body.Close()
+
+			if err := c.dialogHandler(req.Context()); err != nil {
+				return nil, fmt.Errorf("failed to handle dialog: %w", err)
+			}
+			dialogs++
+		default:
+			return resp, nil
+		}
+
+		if err := rewindBody(req); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// sessionIDFromPath returns the session ID of a WebDriver command's path, or
+// "" if the command isn't sent to a session
+func sessionIDFromPath(path string) string {
+	// The base URL may have a path of its own
+	i := strings.Index(path, "/session/")
+	if i < 0 {
+		return ""
+	}
+	sessionID, _, _ := strings.Cut(path[i+len("/session/"):], "/")
+	return sessionID
+}
+
+// reconnect replaces the client's invalidated session with a new one, created
+// with the same capabilities and resized to the last window size set, and
+// returns its ID. The new session starts on a blank page
+func (c *WebDriverClient) reconnect(ctx context.Context, invalidID string) (string, error) {
+	if c.replaceSession != nil {
+		return c.replaceSession(ctx, invalidID)
+	}
+
+	c.reconnectMu.Lock()
+	defer c.reconnectMu.Unlock()
+
+	// Another command may have replaced the session already
+	c.sessionMu.RLock()
+	sessionID, windowSize, capabilities := c.sessionID, c.windowSize, c.capabilities
+	c.sessionMu.RUnlock()
+	if sessionID != invalidID {
+		if sessionID == "" {
+			return "", ErrNoSession
+		}
+		return sessionID, nil
+	}
+
+	logf(c.vu, logrus.WarnLevel, "session %s is no longer valid, creating a new session", invalidID)
+	session, err := c.CreateSession(ctx, capabilities)
+	if err != nil {
+		return "", fmt.Errorf("failed to reconnect: %w", err)
+	}
+
+	if windowSize != [2]int{} {
+		if err := c.SetWindowSize(ctx, windowSize[0], windowSize[1]); err != nil {
+			logf(c.vu, logrus.WarnLevel, "failed to restore the window size after reconnecting: %v", err)
+		}
+	}
+	if c.onReconnect != nil {
+		c.onReconnect()
+	}
+	return session.SessionID, nil
+}
+
+// doWithRetry sends a request, retrying transport errors and 5xx responses up to
+// maxRetries times with exponential backoff. 4xx responses are returned as is,
+// as are script errors, which would fail the same way again
+func (c *WebDriverClient) doWithRetry(req *http.Request) (*http.Response, error) {
+	backoff := c.retryBackoff
+	for attempt := 0; ; attempt++ {
+		resp, err := c.do(req)
+		if attempt >= c.maxRetries || !isTransientFailure(resp, err) {
+			return resp, err
+		}
+		if resp != nil {
+			resp.Body.Close()
+		}
+
+		select {
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+
+		if err := rewindBody(req); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// rewindBody resets the body of a request consumed by a previous attempt
+func rewindBody(req *http.Request) error {
+	if req.GetBody == nil {
+		return nil
+	}
+
+	body, err := req.GetBody()
+	if err != nil {
+		return fmt.Errorf("failed to rewind request body: %w", err)
+	}
+	req.Body = body
+	return nil
+}
+
+// isTransientFailure reports whether a request failed in a way worth retrying
+func isTransientFailure(resp *http.Response, err error) bool {
+	if err != nil {
+		return !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded)
+	}
+	if resp.StatusCode < http.StatusInternalServerError {
+		return false
+	}
+
+	// safaridriver reports exceptions thrown by scripts and open dialogs as 500s too
+	switch peekErrorCode(resp) {
+	case "javascript error", "script timeout", "unexpected alert open":
+		return false
+	}
+	return true
+}
+
+// peekErrorCode returns the W3C error code of a failed response, restoring the
+// body so callers can still decode it. It's empty if the body isn't a W3C error
+func peekErrorCode(resp *http.Response) string {
+	data, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	resp.Body = io.NopCloser(bytes.NewReader(data))
+	if err != nil {
+		return ""
+	}
+
+	var errorResp struct {
+		Value struct {
+			Error string `json:"error"`
+		} `json:"value"`
+	}
+	if json.Unmarshal(data, &errorResp) != nil {
+		return ""
+	}
+	return errorResp.Value.Error
+}
+
 // CreateSession creates a new WebDriver session
 func (c *WebDriverClient) CreateSession(ctx context.Context, capabilities map[string]interface{}) (*WebDriverSession, error) {
 	payload := map[string]interface{}{
@@ -134,14 +973,14 @@ func (c *WebDriverClient) CreateSession(ctx context.Context, capabilities map[st
 
 	req.Header.Set("Content-Type", "application/json")
 
-	resp, err := c.httpClient.Do(req)
+	resp, err := c.doWithRetry(req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create session: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("session creation failed with status: %d", resp.StatusCode)
+		return nil, fmt.Errorf("session creation failed with status %d: %w", resp.StatusCode, newWebDriverError(resp))
 	}
 
 	var sessionResp struct {
@@ -152,50 +991,65 @@ func (c *WebDriverClient) CreateSession(ctx context.Context, capabilities map[st
 		return nil, fmt.Errorf("failed to decode session response: %w", err)
 	}
 
-	c.sessionID = sessionResp.Value.SessionID
+	c.setSession(sessionResp.Value.SessionID)
+	c.sessionMu.Lock()
+	c.capabilities = capabilities
+	c.sessionMu.Unlock()
 	return &sessionResp.Value, nil
 }
 
 // DeleteSession deletes the current WebDriver session
 func (c *WebDriverClient) DeleteSession(ctx context.Context) error {
-	if c.sessionID == "" {
-		log.Println("WARN: attempted to delete session, but no active session exists")
+	sessionID := c.session()
+	if sessionID == "" {
+		logf(c.vu, logrus.DebugLevel, "attempted to delete session, but no active session exists")
 		return nil
 	}
 
 	req, err := http.NewRequestWithContext(ctx, "DELETE",
-		c.baseURL+"/session/"+c.sessionID, nil)
+		c.baseURL+"/session/"+sessionID, nil)
 	if err != nil {
-		log.Printf("WARN: failed to create delete request: %v\n", err)
+		logf(c.vu, logrus.WarnLevel, "failed to create delete request: %v", err)
 		return nil
 	}
 
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
-		log.Printf("WARN: failed to delete session: %v\n", err)
+		logf(c.vu, logrus.WarnLevel, "failed to delete session: %v", err)
 		return nil
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		log.Printf("WARN: session deletion failed with status: %d\n", resp.StatusCode)
-		c.sessionID = ""
+		logf(c.vu, logrus.WarnLevel, "session deletion failed with status %d: %v", resp.StatusCode, newWebDriverError(resp))
+		c.clearSession(sessionID)
 		return nil
 	}
 
-	c.sessionID = ""
+	c.clearSession(sessionID)
 	return nil
 }
 
+// defaultNetworkIdleTime is how long the network must be quiet for "networkidle"
+const defaultNetworkIdleTime = 500 * time.Millisecond
+
 // NavigateOptions contains options for navigation
 type NavigateOptions struct {
-	WaitUntil string // "load" (default), "domcontentloaded", "networkidle"
+	WaitUntil       string        // "load" (default), "domcontentloaded", "networkidle", "commit"
+	NetworkIdleTime time.Duration // Quiet window for "networkidle" (default 500ms)
 }
 
 // Navigate navigates to a URL with optional wait conditions
 func (c *WebDriverClient) Navigate(ctx context.Context, url string, options *NavigateOptions) error {
-	if c.sessionID == "" {
-		return fmt.Errorf("no active session")
+	defer c.observe(opNavigation, time.Now())
+
+	sessionID := c.session()
+	if sessionID == "" {
+		return ErrNoSession
+	}
+
+	if err := validateNavigationURL(url); err != nil {
+		return err
 	}
 
 	// Set defaults
@@ -208,6 +1062,11 @@ func (c *WebDriverClient) Navigate(ctx context.Context, url string, options *Nav
 		options.WaitUntil = "load"
 	}
 
+	// The navigate command blocks until load, so it can't return any earlier
+	if options.WaitUntil == "commit" {
+		return c.navigateUntilCommit(ctx, url)
+	}
+
 	payload := map[string]string{"url": url}
 	jsonData, err := json.Marshal(payload)
 	if err != nil {
@@ -215,21 +1074,21 @@ func (c *WebDriverClient) Navigate(ctx context.Context, url string, options *Nav
 	}
 
 	req, err := http.NewRequestWithContext(ctx, "POST",
-		c.baseURL+"/session/"+c.sessionID+"/url", bytes.NewBuffer(jsonData))
+		c.baseURL+"/session/"+sessionID+"/url", bytes.NewBuffer(jsonData))
 	if err != nil {
 		return fmt.Errorf("failed to create navigate request: %w", err)
 	}
 
 	req.Header.Set("Content-Type", "application/json")
 
-	resp, err := c.httpClient.Do(req)
+	resp, err := c.do(req)
 	if err != nil {
 		return fmt.Errorf("failed to navigate: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("navigation failed with status: %d", resp.StatusCode)
+		return fmt.Errorf("navigation failed with status %d: %w", resp.StatusCode, newWebDriverError(resp))
 	}
 
 	// WebDriver's Navigate command waits for "load" by default
@@ -241,10 +1100,164 @@ func (c *WebDriverClient) Navigate(ctx context.Context, url string, options *Nav
 	case "domcontentloaded":
 		return c.waitForDOMContentLoaded(ctx)
 	case "networkidle":
-		return c.waitForNetworkIdle(ctx)
+		return c.waitForNetworkIdle(ctx, options.NetworkIdleTime)
+	default:
+		return fmt.Errorf("invalid waitUntil option: %s", options.WaitUntil)
+	}
+}
+
+// navigationSchemes are the URL schemes Safari can navigate to
+var navigationSchemes = map[string]bool{
+	"http":  true,
+	"https": true,
+	"file":  true,
+	"data":  true,
+	"about": true,
+}
+
+// validateNavigationURL checks that rawURL is absolute and uses a scheme Safari
+// can navigate to, as safaridriver reports other URLs with an opaque error
+// file: URLs must have an absolute path on the machine safaridriver runs on
+func validateNavigationURL(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return fmt.Errorf("invalid URL %q: %w", rawURL, err)
+	}
+	if u.Scheme == "" {
+		return fmt.Errorf("invalid URL %q: missing scheme, e.g. https:// or file://", rawURL)
+	}
+	if !navigationSchemes[strings.ToLower(u.Scheme)] {
+		return fmt.Errorf("unsupported URL scheme %q in %q, expected http, https, file, data or about", u.Scheme, rawURL)
+	}
+	if strings.EqualFold(u.Scheme, "file") {
+		if u.Host != "" && u.Host != "localhost" {
+			return fmt.Errorf("invalid file URL %q: only local files are supported", rawURL)
+		}
+		if !strings.HasPrefix(u.Path, "/") {
+			return fmt.Errorf("invalid file URL %q: the path must be absolute, e.g. file:///path/to/page.html", rawURL)
+		}
+	}
+	return nil
+}
+
+// navigateUntilCommit starts navigating to url from a script and returns as soon
+// as the new document has replaced the current one, i.e. once the response was
+// received, without waiting for the document to load
+// The current document is marked so that its replacement can be recognized, even
+// across redirects; navigations within the document only change the URL
+func (c *WebDriverClient) navigateUntilCommit(ctx context.Context, url string) error {
+	token := strconv.FormatInt(time.Now().UnixNano(), 36)
+
+	start := `
+		document.__webdriverNavigation = arguments[1];
+		var from = location.href;
+		window.location.assign(arguments[0]);
+		return from;
+	`
+	result, err := c.ExecuteScript(ctx, start, []interface{}{url, token})
+	if err != nil {
+		return fmt.Errorf("failed to navigate: %w", err)
+	}
+
+	// Reloading the current URL must not be mistaken for a navigation within it
+	from, _ := result.(string)
+	script := fmt.Sprintf(`return document.__webdriverNavigation !== %s || (%t && location.href === %s);`,
+		jsStringLiteral(token), from != url, jsStringLiteral(url))
+
+	if err := c.pollForConditionWithOptions(ctx, script, 20*time.Millisecond, DefaultTimeout()); err != nil {
+		return fmt.Errorf("navigation to %s was not committed: %w", url, err)
+	}
+	return nil
+}
+
+// markDocument tags the current document with a token, like navigateUntilCommit
+// does, so that waitForNewDocument can recognize its replacement by a
+// navigation that is started some other way, e.g. by clicking a link
+func (c *WebDriverClient) markDocument(ctx context.Context) (string, error) {
+	token := strconv.FormatInt(time.Now().UnixNano(), 36)
+	if _, err := c.ExecuteScript(ctx, `document.__webdriverNavigation = arguments[0];`, []interface{}{token}); err != nil {
+		return "", fmt.Errorf("failed to mark the current document: %w", err)
+	}
+	return token, nil
+}
+
+// waitForNewDocument waits up to timeout for the document marked with token to
+// be replaced by a new one, i.e. for a navigation to be committed
+func (c *WebDriverClient) waitForNewDocument(ctx context.Context, token string, timeout time.Duration) error {
+	script := fmt.Sprintf(`return document.__webdriverNavigation !== %s;`, jsStringLiteral(token))
+	if err := c.pollForConditionWithOptions(ctx, script, 20*time.Millisecond, timeout); err != nil {
+		return fmt.Errorf("no navigation was committed: %w", err)
+	}
+	return nil
+}
+
+// SetContent replaces the current document with the given HTML and waits for the requested state
+func (c *WebDriverClient) SetContent(ctx context.Context, html string, options *NavigateOptions) error {
+	sessionID := c.session()
+	if sessionID == "" {
+		return ErrNoSession
+	}
+
+	// Set defaults
+	if options == nil {
+		options = &NavigateOptions{
+			WaitUntil: "load",
+		}
+	}
+	if options.WaitUntil == "" {
+		options.WaitUntil = "load"
+	}
+
+	var wait func(ctx context.Context) error
+	switch options.WaitUntil {
+	case "commit":
+		// The document is replaced as soon as it's written
+		wait = func(context.Context) error { return nil }
+	case "load":
+		wait = c.waitForLoad
+	case "domcontentloaded":
+		wait = c.waitForDOMContentLoaded
+	case "networkidle":
+		wait = func(ctx context.Context) error {
+			return c.waitForNetworkIdle(ctx, options.NetworkIdleTime)
+		}
 	default:
 		return fmt.Errorf("invalid waitUntil option: %s", options.WaitUntil)
 	}
+
+	script := `document.open(); document.write(arguments[0]); document.close();`
+	if _, err := c.ExecuteScript(ctx, script, []interface{}{html}); err != nil {
+		return fmt.Errorf("failed to set content: %w", err)
+	}
+
+	// Unlike navigation, writing the document doesn't block until load
+	return wait(ctx)
+}
+
+// WaitForLoadState waits for the current document to reach state: "load"
+// (default), "domcontentloaded" or "networkidle", e.g. after a click started a
+// navigation. It returns right away if the document is already in that state
+func (c *WebDriverClient) WaitForLoadState(ctx context.Context, state string, networkIdleTime time.Duration) error {
+	if c.session() == "" {
+		return ErrNoSession
+	}
+
+	switch state {
+	case "", "load":
+		return c.waitForLoad(ctx)
+	case "domcontentloaded":
+		return c.waitForDOMContentLoaded(ctx)
+	case "networkidle":
+		return c.waitForNetworkIdle(ctx, networkIdleTime)
+	default:
+		return fmt.Errorf("invalid load state: %s", state)
+	}
+}
+
+// waitForLoad waits for the document to be complete
+func (c *WebDriverClient) waitForLoad(ctx context.Context) error {
+	script := `return document.readyState === 'complete';`
+	return c.pollForCondition(ctx, script)
 }
 
 // waitForDOMContentLoaded waits for the document to be interactive or complete
@@ -253,28 +1266,46 @@ func (c *WebDriverClient) waitForDOMContentLoaded(ctx context.Context) error {
 	return c.pollForCondition(ctx, script)
 }
 
-// waitForNetworkIdle waits for network activity to settle
-// This is a simplified implementation that waits for document.readyState === 'complete'
-// and then waits an additional 500ms for any async operations
-func (c *WebDriverClient) waitForNetworkIdle(ctx context.Context) error {
-	// First wait for document to be complete
-	script := `return document.readyState === 'complete';`
-	err := c.pollForCondition(ctx, script)
-	if err != nil {
-		return err
+// waitForNetworkIdle waits until the document is complete and no fetch or
+// XMLHttpRequest has been in flight for the quiet window
+// Requests are tracked by the injection script; resources that finished before
+// it was injected are accounted for through the Resource Timing API
+func (c *WebDriverClient) waitForNetworkIdle(ctx context.Context, quietWindow time.Duration) error {
+	if quietWindow <= 0 {
+		quietWindow = defaultNetworkIdleTime
 	}
 
-	// Then wait 500ms for any pending network activity to complete
-	// This is a simple heuristic approach since WebDriver doesn't have native network idle detection
-	time.Sleep(500 * time.Millisecond)
+	// Make sure requests are tracked, the page may not have been injected yet
+	if _, err := c.ExecuteScript(ctx, injectionScript, nil); err != nil {
+		return fmt.Errorf("failed to install network tracking: %w", err)
+	}
 
-	return nil
+	script := fmt.Sprintf(`
+		var quietWindow = %d;
+		var network = window.__webdriverNetwork;
+		if (document.readyState !== 'complete' || !network) return false;
+
+		var lastActivity = network.lastActivity;
+		var timeOrigin = performance.timeOrigin || performance.timing.navigationStart;
+		var entries = performance.getEntriesByType('resource');
+		for (var i = 0; i < entries.length; i++) {
+			lastActivity = Math.max(lastActivity, timeOrigin + entries[i].responseEnd);
+		}
+
+		return network.inflight === 0 && Date.now() - lastActivity >= quietWindow;
+	`, quietWindow.Milliseconds())
+
+	return c.pollForCondition(ctx, script)
 }
 
 // pollForCondition polls a JavaScript condition until it returns true or times out
 func (c *WebDriverClient) pollForCondition(ctx context.Context, script string) error {
-	interval := 100 * time.Millisecond
-	timeout := 30 * time.Second // Fixed 30 second timeout
+	return c.pollForConditionWithOptions(ctx, script, 100*time.Millisecond, DefaultTimeout())
+}
+
+// pollForConditionWithOptions polls a JavaScript condition every interval until it
+// returns true or the timeout elapses
+func (c *WebDriverClient) pollForConditionWithOptions(ctx context.Context, script string, interval, timeout time.Duration) error {
 	deadline := time.Now().Add(timeout)
 
 	for time.Now().Before(deadline) {
@@ -290,29 +1321,54 @@ func (c *WebDriverClient) pollForCondition(ctx context.Context, script string) e
 		time.Sleep(interval)
 	}
 
-	return fmt.Errorf("timeout waiting for condition after 30s")
+	return fmt.Errorf("%w waiting for condition after %s", ErrTimeout, timeout)
+}
+
+// waitForURL polls the current URL every interval until it matches or the timeout
+// elapses. Navigations within the page, like client-side route changes, count
+func (c *WebDriverClient) waitForURL(ctx context.Context, re *regexp.Regexp, interval, timeout time.Duration) (string, error) {
+	deadline := time.Now().Add(timeout)
+
+	var current string
+	for {
+		var err error
+		if current, err = c.GetCurrentURL(ctx); err != nil {
+			return "", err
+		}
+		if re.MatchString(current) {
+			return current, nil
+		}
+		if !time.Now().Add(interval).Before(deadline) {
+			break
+		}
+
+		time.Sleep(interval)
+	}
+
+	return "", fmt.Errorf("%w waiting for URL after %s, last URL was '%s'", ErrTimeout, timeout, current)
 }
 
 // GetCurrentURL returns the current page URL
 func (c *WebDriverClient) GetCurrentURL(ctx context.Context) (string, error) {
-	if c.sessionID == "" {
-		return "", fmt.Errorf("no active session")
+	sessionID := c.session()
+	if sessionID == "" {
+		return "", ErrNoSession
 	}
 
 	req, err := http.NewRequestWithContext(ctx, "GET",
-		c.baseURL+"/session/"+c.sessionID+"/url", nil)
+		c.baseURL+"/session/"+sessionID+"/url", nil)
 	if err != nil {
 		return "", fmt.Errorf("failed to create get URL request: %w", err)
 	}
 
-	resp, err := c.httpClient.Do(req)
+	resp, err := c.do(req)
 	if err != nil {
 		return "", fmt.Errorf("failed to get current URL: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("get URL failed with status: %d", resp.StatusCode)
+		return "", fmt.Errorf("get URL failed with status %d: %w", resp.StatusCode, newWebDriverError(resp))
 	}
 
 	var urlResp struct {
@@ -328,24 +1384,25 @@ func (c *WebDriverClient) GetCurrentURL(ctx context.Context) (string, error) {
 
 // GetTitle returns the current page title
 func (c *WebDriverClient) GetTitle(ctx context.Context) (string, error) {
-	if c.sessionID == "" {
-		return "", fmt.Errorf("no active session")
+	sessionID := c.session()
+	if sessionID == "" {
+		return "", ErrNoSession
 	}
 
 	req, err := http.NewRequestWithContext(ctx, "GET",
-		c.baseURL+"/session/"+c.sessionID+"/title", nil)
+		c.baseURL+"/session/"+sessionID+"/title", nil)
 	if err != nil {
 		return "", fmt.Errorf("failed to create get title request: %w", err)
 	}
 
-	resp, err := c.httpClient.Do(req)
+	resp, err := c.do(req)
 	if err != nil {
 		return "", fmt.Errorf("failed to get title: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("get title failed with status: %d", resp.StatusCode)
+		return "", fmt.Errorf("get title failed with status %d: %w", resp.StatusCode, newWebDriverError(resp))
 	}
 
 	var titleResp struct {
@@ -361,8 +1418,9 @@ func (c *WebDriverClient) GetTitle(ctx context.Context) (string, error) {
 
 // ExecuteScript executes JavaScript in the browser
 func (c *WebDriverClient) ExecuteScript(ctx context.Context, script string, args []interface{}) (interface{}, error) {
-	if c.sessionID == "" {
-		return nil, fmt.Errorf("no active session")
+	sessionID := c.session()
+	if sessionID == "" {
+		return nil, ErrNoSession
 	}
 
 	// Ensure args is always an array, even if empty
@@ -380,30 +1438,21 @@ func (c *WebDriverClient) ExecuteScript(ctx context.Context, script string, args
 	}
 
 	req, err := http.NewRequestWithContext(ctx, "POST",
-		c.baseURL+"/session/"+c.sessionID+"/execute/sync", bytes.NewBuffer(jsonData))
+		c.baseURL+"/session/"+sessionID+"/execute/sync", bytes.NewBuffer(jsonData))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create execute script request: %w", err)
 	}
 
 	req.Header.Set("Content-Type", "application/json")
 
-	resp, err := c.httpClient.Do(req)
+	resp, err := c.doWithRetry(req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to execute script: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		// Try to get error details from response body
-		var errorBody map[string]interface{}
-		if err := json.NewDecoder(resp.Body).Decode(&errorBody); err == nil {
-			if value, ok := errorBody["value"].(map[string]interface{}); ok {
-				if message, ok := value["message"].(string); ok {
-					return nil, fmt.Errorf("script execution failed with status %d: %s", resp.StatusCode, message)
-				}
-			}
-		}
-		return nil, fmt.Errorf("script execution failed with status: %d", resp.StatusCode)
+		return nil, fmt.Errorf("script execution failed with status %d: %w", resp.StatusCode, newWebDriverError(resp))
 	}
 
 	var scriptResp struct {
@@ -424,16 +1473,58 @@ func (c *WebDriverClient) FindElement(ctx context.Context, selector string) (str
 }
 
 // FindElements returns the count of elements matching the selector
+// CSS and XPath selectors are counted in the page, without transferring a
+// reference for every element
 func (c *WebDriverClient) FindElements(ctx context.Context, selector string) (int, error) {
-	elementIDs, err := c.FindAllElements(ctx, selector)
+	script := generateCountScript(ParseSelector(selector))
+	if script == "" {
+		elementIDs, err := c.FindAllElements(ctx, selector)
+		if err != nil {
+			return 0, err
+		}
+		return len(elementIDs), nil
+	}
+
+	defer c.observe(opFindElement, time.Now())
+
+	result, err := c.ExecuteScript(ctx, script, nil)
 	if err != nil {
-		return 0, err
+		return 0, fmt.Errorf("failed to count elements: %w", err)
+	}
+
+	count, ok := toFloat64(result)
+	if !ok {
+		return 0, fmt.Errorf("unexpected count result: %v", result)
 	}
-	return len(elementIDs), nil
+	return int(count), nil
+}
+
+// generateCountScript returns a script counting the elements matching a CSS,
+// shadow-piercing CSS or XPath selector, or "" for the other strategies
+func generateCountScript(parsed ParsedSelector) string {
+	switch parsed.Strategy {
+	case StrategyCSSSelector:
+		if !parsed.IsNative {
+			// :visible and :has-text() are filtered from JavaScript
+			return fmt.Sprintf(`return (function() {%s})().length;`, generateAllSelectorScript(parsed.Strategy, parsed.Value))
+		}
+		return fmt.Sprintf(`return document.querySelectorAll(%s).length;`, jsStringLiteral(parsed.Value))
+	case StrategyPierce:
+		return fmt.Sprintf(`%s
+			return pierceQueryAll(document, %s).length;
+		`, pierceQueryAllScript, jsStringLiteral(parsed.Value))
+	case StrategyXPath:
+		// Only element nodes, like the elements the other finders return
+		return fmt.Sprintf(`return document.evaluate(%s, document, null, XPathResult.NUMBER_TYPE, null).numberValue;`,
+			jsStringLiteral("count(("+parsed.Value+")[self::*])"))
+	}
+	return ""
 }
 
 // FindAllElements finds all elements matching the selector and returns their IDs
 func (c *WebDriverClient) FindAllElements(ctx context.Context, selector string) ([]string, error) {
+	defer c.observe(opFindElement, time.Now())
+
 	parsed := ParseSelector(selector)
 
 	if parsed.IsNative {
@@ -445,8 +1536,9 @@ func (c *WebDriverClient) FindAllElements(ctx context.Context, selector string)
 
 // findAllElementsNative uses WebDriver's native element finding for multiple elements
 func (c *WebDriverClient) findAllElementsNative(ctx context.Context, strategy, value string) ([]string, error) {
-	if c.sessionID == "" {
-		return nil, fmt.Errorf("no active session")
+	sessionID := c.session()
+	if sessionID == "" {
+		return nil, ErrNoSession
 	}
 
 	payload := map[string]string{"using": strategy, "value": value}
@@ -456,91 +1548,370 @@ func (c *WebDriverClient) findAllElementsNative(ctx context.Context, strategy, v
 	}
 
 	req, err := http.NewRequestWithContext(ctx, "POST",
-		c.baseURL+"/session/"+c.sessionID+"/elements", bytes.NewBuffer(jsonData))
+		c.baseURL+"/session/"+sessionID+"/elements", bytes.NewBuffer(jsonData))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create find elements request: %w", err)
 	}
 
 	req.Header.Set("Content-Type", "application/json")
 
-	resp, err := c.httpClient.Do(req)
+	resp, err := c.do(req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to find elements: %w", err)
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != 200 {
-		return nil, fmt.Errorf("find elements failed with status: %d", resp.StatusCode)
+	if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("find elements failed with status %d: %w", resp.StatusCode, newWebDriverError(resp))
+	}
+
+	var elementsResp struct {
+		Value []struct {
+			ElementID string `json:"element-6066-11e4-a52e-4f735466cecf"`
+			ELEMENT   string `json:"ELEMENT"` // Fallback for older WebDriver
+		} `json:"value"`
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(&elementsResp); err != nil {
+		return nil, fmt.Errorf("failed to decode elements response: %w", err)
+	}
+
+	elementIDs := make([]string, 0, len(elementsResp.Value))
+	for _, elem := range elementsResp.Value {
+		// Try W3C standard field first, fallback to older ELEMENT field
+		if elem.ElementID != "" {
+			elementIDs = append(elementIDs, elem.ElementID)
+		} else if elem.ELEMENT != "" {
+			elementIDs = append(elementIDs, elem.ELEMENT)
+		}
+	}
+
+	return elementIDs, nil
+}
+
+// findAllElementsCustom uses JavaScript to find all elements with custom strategies
+func (c *WebDriverClient) findAllElementsCustom(ctx context.Context, strategy SelectorStrategy, value string) ([]string, error) {
+	script := generateAllSelectorScript(strategy, value)
+
+	result, err := c.ExecuteScript(ctx, script, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute selector script: %w", err)
+	}
+
+	return elementIDsFromResult(result), nil
+}
+
+// elementIDsFromResult extracts the element IDs from a script result holding an
+// array of element references
+func elementIDsFromResult(result interface{}) []string {
+	// Handle array of element references
+	if elemArray, ok := result.([]interface{}); ok {
+		elementIDs := make([]string, 0, len(elemArray))
+		for _, elem := range elemArray {
+			if elemMap, ok := elem.(map[string]interface{}); ok {
+				// Try W3C standard key
+				if elemID, ok := elemMap["element-6066-11e4-a52e-4f735466cecf"].(string); ok {
+					elementIDs = append(elementIDs, elemID)
+				} else if elemID, ok := elemMap["ELEMENT"].(string); ok {
+					elementIDs = append(elementIDs, elemID)
+				}
+			}
+		}
+		return elementIDs
+	}
+
+	return []string{}
+}
+
+// elementReferences converts element IDs into WebDriver element references, which
+// scripts receive as an array of elements
+func elementReferences(elementIDs []string) []interface{} {
+	elementRefs := make([]interface{}, len(elementIDs))
+	for i, elementID := range elementIDs {
+		elementRefs[i] = map[string]string{"element-6066-11e4-a52e-4f735466cecf": elementID}
+	}
+	return elementRefs
+}
+
+// textContents returns the text content of each element in one script call
+// rather than one round-trip per element
+func (c *WebDriverClient) textContents(ctx context.Context, elementIDs []string) ([]string, error) {
+	texts := make([]string, len(elementIDs))
+	if len(elementIDs) == 0 {
+		return texts, nil
+	}
+
+	script := `return arguments[0].map(function(el) { return el.textContent; });`
+	result, err := c.ExecuteScript(ctx, script, []interface{}{elementReferences(elementIDs)})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get text contents: %w", err)
+	}
+
+	values, _ := result.([]interface{})
+	for i := range texts {
+		if i < len(values) {
+			texts[i], _ = values[i].(string)
+		}
+	}
+
+	return texts, nil
+}
+
+// scopedCSSSelector returns the CSS selector to query within a parent element
+// Scoped queries run querySelector on the parent, so only CSS selectors are supported
+func scopedCSSSelector(selector string) (string, error) {
+	parsed := ParseSelector(selector)
+	if parsed.Strategy != StrategyCSSSelector {
+		return "", fmt.Errorf("scoped locators only support CSS selectors, got %s selector '%s'", parsed.Strategy, selector)
+	}
+	if !parsed.IsNative {
+		return "", fmt.Errorf("scoped locators don't support :visible and :has-text(), got '%s'", selector)
+	}
+	return parsed.Value, nil
+}
+
+// FindElementFrom finds the first descendant of the parent element matching the CSS selector
+func (c *WebDriverClient) FindElementFrom(ctx context.Context, parentID, selector string) (string, error) {
+	defer c.observe(opFindElement, time.Now())
+
+	css, err := scopedCSSSelector(selector)
+	if err != nil {
+		return "", err
+	}
+
+	parentRef := map[string]string{"element-6066-11e4-a52e-4f735466cecf": parentID}
+	result, err := c.ExecuteScript(ctx, `return arguments[0].querySelector(arguments[1]);`, []interface{}{parentRef, css})
+	if err != nil {
+		return "", fmt.Errorf("failed to execute scoped selector script: %w", err)
+	}
+
+	elementIDs := elementIDsFromResult([]interface{}{result})
+	if len(elementIDs) == 0 {
+		return "", ErrElementNotFound
+	}
+	return elementIDs[0], nil
+}
+
+// FindAllElementsFrom finds all descendants of the parent element matching the CSS selector
+func (c *WebDriverClient) FindAllElementsFrom(ctx context.Context, parentID, selector string) ([]string, error) {
+	defer c.observe(opFindElement, time.Now())
+
+	css, err := scopedCSSSelector(selector)
+	if err != nil {
+		return nil, err
+	}
+
+	parentRef := map[string]string{"element-6066-11e4-a52e-4f735466cecf": parentID}
+	result, err := c.ExecuteScript(ctx, `return Array.from(arguments[0].querySelectorAll(arguments[1]));`, []interface{}{parentRef, css})
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute scoped selector script: %w", err)
+	}
+
+	return elementIDsFromResult(result), nil
+}
+
+// fallbackTimeout is the wait timeout used until SetDefaultTimeout is called
+const fallbackTimeout = 30 * time.Second
+
+// defaultTimeout holds the wait timeout set by SetDefaultTimeout, in nanoseconds
+var defaultTimeout atomic.Int64
+
+// DefaultTimeout returns the timeout used by waits that aren't given one
+func DefaultTimeout() time.Duration {
+	if d := time.Duration(defaultTimeout.Load()); d > 0 {
+		return d
+	}
+	return fallbackTimeout
+}
+
+// SetDefaultTimeout sets the timeout used by waits that aren't given one
+// A zero or negative duration restores the 30s fallback
+func SetDefaultTimeout(d time.Duration) {
+	defaultTimeout.Store(int64(d))
+}
+
+// WaitForSelector waits for an element matching the selector to reach the specified state
+// A zero timeout uses DefaultTimeout
+func (c *WebDriverClient) WaitForSelector(ctx context.Context, selector, state string, timeout time.Duration) error {
+	sessionID := c.session()
+	if sessionID == "" {
+		return ErrNoSession
+	}
+	if timeout <= 0 {
+		timeout = DefaultTimeout()
+	}
+
+	// Generate the wait script based on state
+	script := generateWaitScript(selector, state)
+
+	ctxWithTimeout, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	// Poll until condition is met or timeout
+	pollInterval := 100 * time.Millisecond
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctxWithTimeout.Done():
+			return fmt.Errorf("%w waiting for selector '%s' to be %s after %s", ErrTimeout, selector, state, timeout)
+		case <-ticker.C:
+			// Execute the check script
+			result, err := c.ExecuteScript(ctx, script, nil)
+			if err != nil {
+				// Continue polling on error
+				continue
+			}
+
+			// Check if condition is met
+			if satisfied, ok := result.(bool); ok && satisfied {
+				return nil
+			}
+		}
+	}
+}
+
+// CountCondition compares the number of elements matching a selector against an expected count
+type CountCondition struct {
+	Operator string // One of "==", "!=", ">", ">=", "<", "<="
+	Count    int
+}
+
+// String returns the condition in its parseable form, e.g. ">= 5"
+func (cc CountCondition) String() string {
+	return fmt.Sprintf("%s %d", cc.Operator, cc.Count)
+}
+
+// Matches reports whether n satisfies the condition
+func (cc CountCondition) Matches(n int) bool {
+	switch cc.Operator {
+	case "==":
+		return n == cc.Count
+	case "!=":
+		return n != cc.Count
+	case ">":
+		return n > cc.Count
+	case ">=":
+		return n >= cc.Count
+	case "<":
+		return n < cc.Count
+	case "<=":
+		return n <= cc.Count
+	default:
+		return false
 	}
+}
 
-	var elementsResp struct {
-		Value []struct {
-			ElementID string `json:"element-6066-11e4-a52e-4f735466cecf"`
-			ELEMENT   string `json:"ELEMENT"` // Fallback for older WebDriver
-		} `json:"value"`
+// ParseCountCondition parses a count condition such as ">= 5", "==0" or "10"
+// A bare number means an exact count
+func ParseCountCondition(condition string) (CountCondition, error) {
+	condition = strings.TrimSpace(condition)
+
+	operator := "=="
+	// Check two-character operators before their one-character prefixes
+	for _, op := range []string{"==", "!=", ">=", "<=", ">", "<"} {
+		if strings.HasPrefix(condition, op) {
+			operator = op
+			condition = strings.TrimSpace(strings.TrimPrefix(condition, op))
+			break
+		}
 	}
 
-	if err := json.NewDecoder(resp.Body).Decode(&elementsResp); err != nil {
-		return nil, fmt.Errorf("failed to decode elements response: %w", err)
+	count, err := strconv.Atoi(condition)
+	if err != nil || count < 0 {
+		return CountCondition{}, fmt.Errorf("invalid count condition: %q", condition)
 	}
 
-	elementIDs := make([]string, 0, len(elementsResp.Value))
-	for _, elem := range elementsResp.Value {
-		// Try W3C standard field first, fallback to older ELEMENT field
-		if elem.ElementID != "" {
-			elementIDs = append(elementIDs, elem.ElementID)
-		} else if elem.ELEMENT != "" {
-			elementIDs = append(elementIDs, elem.ELEMENT)
-		}
+	return CountCondition{Operator: operator, Count: count}, nil
+}
+
+// WaitForCount waits until the number of elements matching the selector satisfies the condition
+// A zero timeout uses DefaultTimeout
+func (c *WebDriverClient) WaitForCount(ctx context.Context, selector string, condition CountCondition, timeout time.Duration) error {
+	sessionID := c.session()
+	if sessionID == "" {
+		return ErrNoSession
+	}
+	if timeout <= 0 {
+		timeout = DefaultTimeout()
 	}
 
-	return elementIDs, nil
+	return c.waitForCount(ctx, selector, condition, timeout, func() (int, error) {
+		return c.FindElements(ctx, selector)
+	})
 }
 
-// findAllElementsCustom uses JavaScript to find all elements with custom strategies
-func (c *WebDriverClient) findAllElementsCustom(ctx context.Context, strategy SelectorStrategy, value string) ([]string, error) {
-	script := generateAllSelectorScript(strategy, value)
+// waitForCount polls count until the condition is satisfied or the timeout elapses
+func (c *WebDriverClient) waitForCount(ctx context.Context, selector string, condition CountCondition, timeout time.Duration, count func() (int, error)) error {
+	ctxWithTimeout, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
 
-	result, err := c.ExecuteScript(ctx, script, nil)
-	if err != nil {
-		return nil, fmt.Errorf("failed to execute selector script: %w", err)
-	}
+	// Poll until condition is met or timeout
+	pollInterval := 100 * time.Millisecond
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
 
-	// Check if result is an array
-	if result == nil {
-		return []string{}, nil
-	}
+	lastCount := -1
+	for {
+		select {
+		case <-ctxWithTimeout.Done():
+			return fmt.Errorf("%w waiting for selector '%s' count %s after %s (last count: %d)", ErrTimeout, selector, condition, timeout, lastCount)
+		case <-ticker.C:
+			n, err := count()
+			if err != nil {
+				// Continue polling on error
+				continue
+			}
+			lastCount = n
 
-	// Handle array of element references
-	if elemArray, ok := result.([]interface{}); ok {
-		elementIDs := make([]string, 0, len(elemArray))
-		for _, elem := range elemArray {
-			if elemMap, ok := elem.(map[string]interface{}); ok {
-				// Try W3C standard key
-				if elemID, ok := elemMap["element-6066-11e4-a52e-4f735466cecf"].(string); ok {
-					elementIDs = append(elementIDs, elemID)
-				} else if elemID, ok := elemMap["ELEMENT"].(string); ok {
-					elementIDs = append(elementIDs, elemID)
-				}
+			if condition.Matches(n) {
+				return nil
 			}
 		}
-		return elementIDs, nil
 	}
-
-	return []string{}, nil
 }
 
-// WaitForSelector waits for an element matching the selector to reach the specified state
-func (c *WebDriverClient) WaitForSelector(ctx context.Context, selector, state string) error {
-	if c.sessionID == "" {
-		return fmt.Errorf("no active session")
+// animationsFinishedScript reports whether the element and its descendants have no
+// running finite CSS animations or transitions. Without the Web Animations API,
+// it falls back to checking that the computed style is unchanged since the last poll.
+const animationsFinishedScript = `
+	var element = arguments[0];
+	if (!element) return false;
+
+	if (typeof element.getAnimations === 'function') {
+		return element.getAnimations({ subtree: true }).every(function(animation) {
+			if (animation.playState !== 'running' && animation.playState !== 'pending') return true;
+			// Infinite animations never finish, so they don't block
+			var timing = animation.effect ? animation.effect.getComputedTiming() : null;
+			return timing !== null && timing.endTime === Infinity;
+		});
+	}
+
+	var style = window.getComputedStyle(element);
+	var snapshot = [
+		style.transform, style.opacity, style.width, style.height,
+		style.top, style.left, style.color, style.backgroundColor
+	].join('|');
+	var stable = element.__webdriverStyleSnapshot === snapshot;
+	element.__webdriverStyleSnapshot = snapshot;
+	return stable;
+`
+
+// WaitForAnimationEnd waits until the element has no running CSS animations or transitions
+// A zero timeout uses DefaultTimeout
+func (c *WebDriverClient) WaitForAnimationEnd(ctx context.Context, elementID string, timeout time.Duration) error {
+	sessionID := c.session()
+	if sessionID == "" {
+		return ErrNoSession
+	}
+	if timeout <= 0 {
+		timeout = DefaultTimeout()
 	}
 
-	// Generate the wait script based on state
-	script := generateWaitScript(selector, state)
+	elementRef := map[string]string{"element-6066-11e4-a52e-4f735466cecf": elementID}
 
-	// Create a context with fixed 30 second timeout
-	ctxWithTimeout, cancel := context.WithTimeout(ctx, 30*time.Second)
+	ctxWithTimeout, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
 
 	// Poll until condition is met or timeout
@@ -551,17 +1922,14 @@ func (c *WebDriverClient) WaitForSelector(ctx context.Context, selector, state s
 	for {
 		select {
 		case <-ctxWithTimeout.Done():
-			return fmt.Errorf("timeout waiting for selector '%s' to be %s", selector, state)
+			return fmt.Errorf("%w waiting for animations to finish after %s", ErrTimeout, timeout)
 		case <-ticker.C:
-			// Execute the check script
-			result, err := c.ExecuteScript(ctx, script, nil)
+			result, err := c.ExecuteScript(ctx, animationsFinishedScript, []interface{}{elementRef})
 			if err != nil {
-				// Continue polling on error
-				continue
+				return fmt.Errorf("failed to check animations: %w", err)
 			}
 
-			// Check if condition is met
-			if satisfied, ok := result.(bool); ok && satisfied {
+			if finished, ok := result.(bool); ok && finished {
 				return nil
 			}
 		}
@@ -572,27 +1940,39 @@ func (c *WebDriverClient) WaitForSelector(ctx context.Context, selector, state s
 func generateWaitScript(selector, state string) string {
 	parsed := ParseSelector(selector)
 
-	// Build the element finding logic
-	var findElementScript string
-	if parsed.IsNative {
-		switch parsed.Strategy {
-		case StrategyCSSSelector:
-			// Use querySelector for CSS selectors
-			findElementScript = fmt.Sprintf(`document.querySelector("%s")`,
-				strings.ReplaceAll(parsed.Value, `"`, `\"`))
-		case StrategyXPath:
-			// Use XPath evaluation for XPath selectors
-			escapedXPath := strings.ReplaceAll(parsed.Value, `'`, `\'`)
-			findElementScript = fmt.Sprintf(`document.evaluate('%s', document, null, XPathResult.FIRST_ORDERED_NODE_TYPE, null).singleNodeValue`, escapedXPath)
-		default:
-			// For other native strategies, use the selector script
-			findElementScript = fmt.Sprintf(`(%s)`, generateSelectorScript(parsed.Strategy, parsed.Value))
-		}
-	} else {
-		// Use custom selector script
-		findElementScript = fmt.Sprintf(`(%s)`, generateSelectorScript(parsed.Strategy, parsed.Value))
+	if parsed.Strategy == StrategyCSSSelector && parsed.IsNative {
+		// Use querySelector for CSS selectors
+		findElementScript := fmt.Sprintf(`document.querySelector(%s)`, jsStringLiteral(parsed.Value))
+		return generateStateCheckScript(findElementScript, state)
 	}
 
+	// Custom strategies check every match, like they're counted, rather than
+	// the single element they find. Selector scripts are function bodies, so
+	// call them as one
+	findElementsScript := fmt.Sprintf(`(function() {%s})()`, generateAllSelectorScript(parsed.Strategy, parsed.Value))
+	return generateAnyStateCheckScript(findElementsScript, state)
+}
+
+// generateAnyStateCheckScript generates JavaScript code that checks whether the
+// elements returned by the findElementsScript expression are in the given
+// state. States an element has to be in, e.g. visible, are satisfied by any
+// element, while hidden and detached are satisfied once every element is, so
+// also by no elements at all
+func generateAnyStateCheckScript(findElementsScript, state string) string {
+	quantifier := "some"
+	if state == "hidden" || state == "detached" {
+		quantifier = "every"
+	}
+
+	return fmt.Sprintf(`
+		var elements = Array.prototype.slice.call(%s || []);
+		return elements.%s(function(element) {%s});
+	`, findElementsScript, quantifier, generateStateCheckScript("element", state))
+}
+
+// generateStateCheckScript generates JavaScript code that checks whether the
+// element returned by the findElementScript expression is in the given state
+func generateStateCheckScript(findElementScript, state string) string {
 	// Build the state check based on the requested state
 	switch state {
 	case "attached":
@@ -608,45 +1988,49 @@ func generateWaitScript(selector, state string) string {
 		`, findElementScript)
 
 	case "visible":
+		return fmt.Sprintf(`%s
+			var element = %s;
+			return !!element && isVisible(element);
+		`, isVisibleScript, findElementScript)
+
+	case "stable":
+		// Each check stores the bounding box on the element, so the element is
+		// stable once two consecutive checks see the same box
 		return fmt.Sprintf(`
 			var element = %s;
 			if (!element) return false;
-			if (element.offsetWidth === 0 || element.offsetHeight === 0) return false;
-			var style = window.getComputedStyle(element);
-			return style.display !== 'none' && style.visibility !== 'hidden' && style.opacity !== '0';
+			var rect = element.getBoundingClientRect();
+			var box = [rect.x, rect.y, rect.width, rect.height].join(',');
+			var previous = element.__xk6StableBox;
+			element.__xk6StableBox = box;
+			return previous === box;
 		`, findElementScript)
 
 	case "hidden":
-		return fmt.Sprintf(`
+		return fmt.Sprintf(`%s
 			var element = %s;
-			if (!element) return true;
-			if (element.offsetWidth === 0 || element.offsetHeight === 0) return true;
-			var style = window.getComputedStyle(element);
-			return style.display === 'none' || style.visibility === 'hidden' || style.opacity === '0';
-		`, findElementScript)
+			return !element || !isVisible(element);
+		`, isVisibleScript, findElementScript)
 
 	default:
 		// Default to visible
-		return fmt.Sprintf(`
-			var element = %s;
-			if (!element) return false;
-			if (element.offsetWidth === 0 || element.offsetHeight === 0) return false;
-			var style = window.getComputedStyle(element);
-			return style.display !== 'none' && style.visibility !== 'hidden';
-		`, findElementScript)
+		return generateStateCheckScript(findElementScript, "visible")
 	}
 }
 
 // ClickElement clicks an element by its ID
 func (c *WebDriverClient) ClickElement(ctx context.Context, elementID string) error {
-	if c.sessionID == "" {
-		return fmt.Errorf("no active session")
+	defer c.observe(opClick, time.Now())
+
+	sessionID := c.session()
+	if sessionID == "" {
+		return ErrNoSession
 	}
 
 	elementRef := map[string]string{"element-6066-11e4-a52e-4f735466cecf": elementID}
 
 	// Scroll, highlight, and click the element with detailed logging
-	clickScript := `
+	clickScript := isVisibleScript + `
 		var element = arguments[0];
 		if (!element) {
 			return {success: false, error: "Element not found"};
@@ -658,7 +2042,7 @@ func (c *WebDriverClient) ClickElement(ctx context.Context, elementID string) er
 			id: element.id,
 			className: element.className,
 			text: element.textContent ? element.textContent.substring(0, 50) : "",
-			visible: element.offsetWidth > 0 && element.offsetHeight > 0,
+			visible: isVisible(element),
 			disabled: element.disabled,
 			type: element.type
 		};
@@ -691,6 +2075,7 @@ func (c *WebDriverClient) ClickElement(ctx context.Context, elementID string) er
 			return fmt.Errorf("click failed: %s", errorMsg)
 		}
 
+		logf(c.vu, logrus.DebugLevel, "clicked element %v", resultMap["info"])
 	}
 
 	return nil
@@ -698,8 +2083,11 @@ func (c *WebDriverClient) ClickElement(ctx context.Context, elementID string) er
 
 // SendKeys sends text to an element
 func (c *WebDriverClient) SendKeys(ctx context.Context, elementID, text string) error {
-	if c.sessionID == "" {
-		return fmt.Errorf("no active session")
+	defer c.observe(opSendKeys, time.Now())
+
+	sessionID := c.session()
+	if sessionID == "" {
+		return ErrNoSession
 	}
 
 	payload := map[string]string{"text": text}
@@ -709,30 +2097,156 @@ func (c *WebDriverClient) SendKeys(ctx context.Context, elementID, text string)
 	}
 
 	req, err := http.NewRequestWithContext(ctx, "POST",
-		c.baseURL+"/session/"+c.sessionID+"/element/"+elementID+"/value", bytes.NewBuffer(jsonData))
+		c.baseURL+"/session/"+sessionID+"/element/"+elementID+"/value", bytes.NewBuffer(jsonData))
 	if err != nil {
 		return fmt.Errorf("failed to create send keys request: %w", err)
 	}
 
 	req.Header.Set("Content-Type", "application/json")
 
-	resp, err := c.httpClient.Do(req)
+	resp, err := c.do(req)
 	if err != nil {
 		return fmt.Errorf("failed to send keys: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("send keys failed with status: %d", resp.StatusCode)
+		return fmt.Errorf("send keys failed with status %d: %w", resp.StatusCode, newWebDriverError(resp))
+	}
+
+	return nil
+}
+
+// PerformActions performs a sequence of input source actions (keyboard, pointer, ...)
+// with the WebDriver Actions API
+func (c *WebDriverClient) PerformActions(ctx context.Context, sources []map[string]interface{}) error {
+	defer c.observe(opSendKeys, time.Now())
+
+	sessionID := c.session()
+	if sessionID == "" {
+		return ErrNoSession
+	}
+
+	payload := map[string]interface{}{"actions": sources}
+	jsonData, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal actions payload: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST",
+		c.baseURL+"/session/"+sessionID+"/actions", bytes.NewBuffer(jsonData))
+	if err != nil {
+		return fmt.Errorf("failed to create actions request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.do(req)
+	if err != nil {
+		return fmt.Errorf("failed to perform actions: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("actions failed with status %d: %w", resp.StatusCode, newWebDriverError(resp))
+	}
+
+	return nil
+}
+
+// ClearElement empties the value of an editable element
+func (c *WebDriverClient) ClearElement(ctx context.Context, elementID string) error {
+	sessionID := c.session()
+	if sessionID == "" {
+		return ErrNoSession
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST",
+		c.baseURL+"/session/"+sessionID+"/element/"+elementID+"/clear", bytes.NewBufferString("{}"))
+	if err != nil {
+		return fmt.Errorf("failed to create clear request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.do(req)
+	if err != nil {
+		return fmt.Errorf("failed to clear element: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("clear failed with status %d: %w", resp.StatusCode, newWebDriverError(resp))
 	}
 
 	return nil
 }
 
+// ExecuteCommand sends a WebDriver command to an endpoint of the session, for
+// commands the client doesn't implement. The path is relative to the session,
+// e.g. "/source", and a nil body is sent as an empty object for POST requests
+// Returns the decoded value of the response
+func (c *WebDriverClient) ExecuteCommand(ctx context.Context, method, path string, body interface{}) (interface{}, error) {
+	sessionID := c.session()
+	if sessionID == "" {
+		return nil, ErrNoSession
+	}
+
+	method = strings.ToUpper(method)
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+
+	var reqBody io.Reader
+	if body != nil || method == http.MethodPost {
+		if body == nil {
+			body = map[string]interface{}{}
+		}
+		jsonData, err := json.Marshal(body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal command payload: %w", err)
+		}
+		reqBody = bytes.NewBuffer(jsonData)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method,
+		c.baseURL+"/session/"+sessionID+path, reqBody)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create command request: %w", err)
+	}
+
+	if reqBody != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute command: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s %s failed with status %d: %w", method, path, resp.StatusCode, newWebDriverError(resp))
+	}
+
+	var commandResp struct {
+		Value interface{} `json:"value"`
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(&commandResp); err != nil {
+		return nil, fmt.Errorf("failed to decode command response: %w", err)
+	}
+
+	return commandResp.Value, nil
+}
+
 // TakeScreenshot takes a screenshot of the current page, clipped to viewport size
 func (c *WebDriverClient) TakeScreenshot(ctx context.Context) ([]byte, error) {
-	if c.sessionID == "" {
-		return nil, fmt.Errorf("no active session")
+	defer c.observe(opScreenshot, time.Now())
+
+	sessionID := c.session()
+	if sessionID == "" {
+		return nil, ErrNoSession
 	}
 
 	// Get viewport dimensions using JavaScript
@@ -780,34 +2294,112 @@ func (c *WebDriverClient) TakeScreenshot(ctx context.Context) ([]byte, error) {
 		return nil, err
 	}
 
+	dpr = c.capturedScale(fullScreenshot, float64(width), dpr)
+
 	// Crop to viewport size accounting for device pixel ratio
 	targetWidth := int(float64(width) * dpr)
 	targetHeight := int(float64(height) * dpr)
 
-	croppedScreenshot, err := c.cropImage(fullScreenshot, targetWidth, targetHeight)
+	screenshot, err := c.cropImage(fullScreenshot, targetWidth, targetHeight)
+	if err != nil {
+		screenshot = fullScreenshot
+	}
+
+	// Record the DPR so comparisons can detect baselines captured at a different scale
+	stamped, err := setPNGDevicePixelRatio(screenshot, dpr)
+	if err != nil {
+		return screenshot, nil
+	}
+
+	return stamped, nil
+}
+
+// TakeElementScreenshot takes a screenshot of the element's bounding box
+func (c *WebDriverClient) TakeElementScreenshot(ctx context.Context, elementID string) ([]byte, error) {
+	defer c.observe(opScreenshot, time.Now())
+
+	sessionID := c.session()
+	if sessionID == "" {
+		return nil, ErrNoSession
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET",
+		c.baseURL+"/session/"+sessionID+"/element/"+elementID+"/screenshot", nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create element screenshot request: %w", err)
+	}
+
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to take element screenshot: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("element screenshot failed with status %d: %w", resp.StatusCode, newWebDriverError(resp))
+	}
+
+	var screenshotResp struct {
+		Value string `json:"value"`
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(&screenshotResp); err != nil {
+		return nil, fmt.Errorf("failed to decode element screenshot response: %w", err)
+	}
+
+	screenshot, err := base64.StdEncoding.DecodeString(screenshotResp.Value)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode base64 screenshot: %w", err)
+	}
+
+	// Record the DPR so comparisons can detect baselines captured at a different
+	// scale, derived like for viewport screenshots from the element's CSS width
+	elementRef := map[string]string{"element-6066-11e4-a52e-4f735466cecf": elementID}
+	result, err := c.ExecuteScript(ctx, `
+		return {
+			width: arguments[0].getBoundingClientRect().width,
+			devicePixelRatio: window.devicePixelRatio || 1
+		};
+	`, []interface{}{elementRef})
+	if err != nil {
+		return screenshot, nil
+	}
+	element, _ := result.(map[string]interface{})
+	width, _ := toFloat64(element["width"])
+	dpr, ok := toFloat64(element["devicePixelRatio"])
+	if !ok {
+		return screenshot, nil
+	}
+	dpr = c.capturedScale(screenshot, width, dpr)
+	stamped, err := setPNGDevicePixelRatio(screenshot, dpr)
 	if err != nil {
-		return fullScreenshot, nil
+		return screenshot, nil
 	}
 
-	return croppedScreenshot, nil
+	return stamped, nil
 }
 
 // takeFullScreenshot takes a full page screenshot
 func (c *WebDriverClient) takeFullScreenshot(ctx context.Context) ([]byte, error) {
+	sessionID := c.session()
+	if sessionID == "" {
+		return nil, ErrNoSession
+	}
+
 	req, err := http.NewRequestWithContext(ctx, "GET",
-		c.baseURL+"/session/"+c.sessionID+"/screenshot", nil)
+		c.baseURL+"/session/"+sessionID+"/screenshot", nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create screenshot request: %w", err)
 	}
 
-	resp, err := c.httpClient.Do(req)
+	resp, err := c.do(req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to take screenshot: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("screenshot failed with status: %d", resp.StatusCode)
+		return nil, fmt.Errorf("screenshot failed with status %d: %w", resp.StatusCode, newWebDriverError(resp))
 	}
 
 	var screenshotResp struct {
@@ -827,6 +2419,109 @@ func (c *WebDriverClient) takeFullScreenshot(ctx context.Context) ([]byte, error
 	return decoded, nil
 }
 
+// PrintOptions are the parameters of WebDriver's Print Page command
+// Sizes are in centimeters, as WebDriver expects; zero values use the defaults
+// (US Letter, 1cm margins, scale 1)
+type PrintOptions struct {
+	Landscape    bool
+	Background   bool    // Print background colors and images
+	Scale        float64 // 0.1 to 2
+	PageWidth    float64
+	PageHeight   float64
+	MarginTop    *float64 // nil for the default, so that 0 removes the margin
+	MarginBottom *float64
+	MarginLeft   *float64
+	MarginRight  *float64
+	PageRanges   []string // e.g. "1-3" or "5"
+}
+
+// payload returns the body of the Print Page command
+func (o PrintOptions) payload() map[string]interface{} {
+	payload := map[string]interface{}{
+		"background": o.Background,
+	}
+	if o.Landscape {
+		payload["orientation"] = "landscape"
+	}
+	if o.Scale > 0 {
+		payload["scale"] = o.Scale
+	}
+
+	page := map[string]interface{}{}
+	if o.PageWidth > 0 {
+		page["width"] = o.PageWidth
+	}
+	if o.PageHeight > 0 {
+		page["height"] = o.PageHeight
+	}
+	if len(page) > 0 {
+		payload["page"] = page
+	}
+
+	margin := map[string]interface{}{}
+	for side, value := range map[string]*float64{
+		"top": o.MarginTop, "bottom": o.MarginBottom, "left": o.MarginLeft, "right": o.MarginRight,
+	} {
+		if value != nil {
+			margin[side] = *value
+		}
+	}
+	if len(margin) > 0 {
+		payload["margin"] = margin
+	}
+
+	if len(o.PageRanges) > 0 {
+		payload["pageRanges"] = o.PageRanges
+	}
+
+	return payload
+}
+
+// PrintPage renders the current page as a PDF with the print stylesheet
+func (c *WebDriverClient) PrintPage(ctx context.Context, options PrintOptions) ([]byte, error) {
+	sessionID := c.session()
+	if sessionID == "" {
+		return nil, ErrNoSession
+	}
+
+	jsonData, err := json.Marshal(options.payload())
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal print payload: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST",
+		c.baseURL+"/session/"+sessionID+"/print", bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create print request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to print page: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("print failed with status %d: %w", resp.StatusCode, newWebDriverError(resp))
+	}
+
+	var printResp struct {
+		Value string `json:"value"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&printResp); err != nil {
+		return nil, fmt.Errorf("failed to decode print response: %w", err)
+	}
+
+	decoded, err := base64.StdEncoding.DecodeString(printResp.Value)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode base64 PDF: %w", err)
+	}
+
+	return decoded, nil
+}
+
 // cropImage crops a PNG image to the specified width and height
 func (c *WebDriverClient) cropImage(imageData []byte, width, height int) ([]byte, error) {
 	img, err := decodePNG(imageData)
@@ -861,7 +2556,37 @@ func (c *WebDriverClient) cropImage(imageData []byte, width, height int) ([]byte
 	return encodePNG(croppedImg)
 }
 
+// capturedScale returns the device pixel ratio a screenshot spanning cssWidth
+// CSS pixels, of the viewport or an element, was captured at: the requested
+// ratio, or the page's reported one without a request. Safari sometimes ignores
+// the requested ratio and captures at the screen's real one, so the captured
+// image's size is trusted over the ratio when they disagree by more than a
+// rounded pixel
+func (c *WebDriverClient) capturedScale(screenshot []byte, cssWidth, reportedDPR float64) float64 {
+	dpr := reportedDPR
+	if c.deviceScaleFactor > 0 {
+		dpr = c.deviceScaleFactor
+	}
+
+	if scale, ok := screenshotScale(screenshot, cssWidth); ok && math.Abs(scale-dpr) > 1/cssWidth {
+		logf(c.vu, logrus.DebugLevel, "screenshot captured at device pixel ratio %v instead of %v", scale, dpr)
+		dpr = scale
+	}
+	return dpr
+}
+
 // Helper functions for image manipulation
+// screenshotScale derives the device pixel ratio a screenshot was captured at
+// from its width and the width it spans in CSS pixels, rounded to hundredths.
+// It only reads the PNG header
+func screenshotScale(screenshot []byte, cssWidth float64) (float64, bool) {
+	config, err := png.DecodeConfig(bytes.NewReader(screenshot))
+	if err != nil || cssWidth <= 0 || config.Width == 0 {
+		return 0, false
+	}
+	return math.Round(float64(config.Width)/cssWidth*100) / 100, true
+}
+
 func decodePNG(data []byte) (*image.RGBA, error) {
 	img, err := png.Decode(bytes.NewReader(data))
 	if err != nil {
@@ -1,8 +1,24 @@
 package browser
 
 import (
+	"bytes"
 	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image/png"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
+
+	"github.com/grafana/sobek"
 )
 
 func TestNewWebDriverClient(t *testing.T) {
@@ -20,8 +36,70 @@ func TestNewWebDriverClient(t *testing.T) {
 		t.Fatal("Expected httpClient to be initialized")
 	}
 
-	if client.sessionID != "" {
-		t.Errorf("Expected sessionID to be empty initially, got '%s'", client.sessionID)
+	if client.session() != "" {
+		t.Errorf("Expected sessionID to be empty initially, got '%s'", client.session())
+	}
+}
+
+func TestNewWebDriverClientWithOptions(t *testing.T) {
+	opts := DefaultClientOptions()
+	opts.MaxIdleConnsPerHost = 64
+	opts.DisableKeepAlives = true
+
+	client := NewWebDriverClientWithOptions("http://localhost:4444", opts)
+
+	transport, ok := client.httpClient.Transport.(*http.Transport)
+	if !ok {
+		t.Fatalf("Expected *http.Transport, got %T", client.httpClient.Transport)
+	}
+	if transport.MaxIdleConnsPerHost != 64 {
+		t.Errorf("Expected MaxIdleConnsPerHost to be 64, got %d", transport.MaxIdleConnsPerHost)
+	}
+	if !transport.DisableKeepAlives {
+		t.Error("Expected keep-alives to be disabled")
+	}
+	if client.httpClient.Timeout != opts.Timeout {
+		t.Errorf("Expected timeout to be %v, got %v", opts.Timeout, client.httpClient.Timeout)
+	}
+
+	// The default transport must not be modified
+	if http.DefaultTransport.(*http.Transport).DisableKeepAlives {
+		t.Error("Expected default transport to be left untouched")
+	}
+}
+
+func TestClientOptionsFromEnv(t *testing.T) {
+	t.Setenv(maxIdleConnsPerHostEnv, "128")
+	t.Setenv(disableKeepAlivesEnv, "true")
+	t.Setenv(maxRetriesEnv, "3")
+	t.Setenv(autoReconnectEnv, "true")
+
+	opts := ClientOptionsFromEnv()
+	if opts.MaxIdleConnsPerHost != 128 {
+		t.Errorf("Expected MaxIdleConnsPerHost to be 128, got %d", opts.MaxIdleConnsPerHost)
+	}
+	if opts.MaxIdleConns < 128 {
+		t.Errorf("Expected MaxIdleConns to be raised to at least 128, got %d", opts.MaxIdleConns)
+	}
+	if !opts.DisableKeepAlives {
+		t.Error("Expected keep-alives to be disabled")
+	}
+	if opts.MaxRetries != 3 {
+		t.Errorf("Expected MaxRetries to be 3, got %d", opts.MaxRetries)
+	}
+	if !opts.AutoReconnect {
+		t.Error("Expected auto-reconnect to be enabled")
+	}
+
+	// Invalid values fall back to the defaults
+	t.Setenv(maxIdleConnsPerHostEnv, "lots")
+	t.Setenv(disableKeepAlivesEnv, "nope")
+	t.Setenv(maxRetriesEnv, "-1")
+	t.Setenv(autoReconnectEnv, "sometimes")
+
+	opts = ClientOptionsFromEnv()
+	if opts != DefaultClientOptions() {
+		t.Errorf("Expected default options, got %+v", opts)
 	}
 }
 
@@ -41,6 +119,12 @@ func TestWebDriverClientSessionManagement(t *testing.T) {
 		t.Error("Expected error when navigating without session")
 	}
 
+	// Test that we can't set content without a session
+	err = client.SetContent(ctx, "<p>hello</p>", nil)
+	if err == nil {
+		t.Error("Expected error when setting content without session")
+	}
+
 	// Test that we can't get URL without a session
 	_, err = client.GetCurrentURL(ctx)
 	if err == nil {
@@ -58,6 +142,24 @@ func TestWebDriverClientSessionManagement(t *testing.T) {
 	if err == nil {
 		t.Error("Expected error when executing script without session")
 	}
+
+	// Test that we can't add cookies without a session
+	err = client.AddCookie(ctx, map[string]interface{}{"name": "session", "value": "abc"})
+	if err == nil {
+		t.Error("Expected error when adding cookie without session")
+	}
+
+	// Test that we can't delete cookies without a session
+	err = client.DeleteAllCookies(ctx)
+	if err == nil {
+		t.Error("Expected error when deleting cookies without session")
+	}
+
+	// Test that we can't delete a single cookie without a session
+	err = client.DeleteCookie(ctx, "session")
+	if err == nil {
+		t.Error("Expected error when deleting cookie without session")
+	}
 }
 
 func TestWebDriverClientElementOperations(t *testing.T) {
@@ -81,6 +183,12 @@ func TestWebDriverClientElementOperations(t *testing.T) {
 	if err == nil {
 		t.Error("Expected error when sending keys without session")
 	}
+
+	// Test that we can't wait for animations without a session
+	err = client.WaitForAnimationEnd(ctx, "element-id", time.Second)
+	if err == nil {
+		t.Error("Expected error when waiting for animations without session")
+	}
 }
 
 func TestWebDriverClientScreenshot(t *testing.T) {
@@ -92,4 +200,1160 @@ func TestWebDriverClientScreenshot(t *testing.T) {
 	if err == nil {
 		t.Error("Expected error when taking screenshot without session")
 	}
+
+	// Test that we can't take an element screenshot without a session
+	_, err = client.TakeElementScreenshot(ctx, "element-id")
+	if err == nil {
+		t.Error("Expected error when taking element screenshot without session")
+	}
+}
+
+func TestWebDriverClientTakeScreenshotScale(t *testing.T) {
+	tests := []struct {
+		name              string
+		deviceScaleFactor float64 // Requested from Safari, 0 for none
+		reportedDPR       int     // window.devicePixelRatio
+		fullWidth         int     // Width of the screenshot Safari captures
+		width, height     int
+		dpr               float64
+	}{
+		{"page ratio", 0, 1, 10, 10, 8, 1},
+		{"requested ratio", 2, 2, 20, 20, 16, 2},
+		{"requested ratio ignored", 1, 1, 20, 20, 16, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			full := solidPNG(t, tt.fullWidth, tt.fullWidth, white, white)
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				if strings.HasSuffix(r.URL.Path, "/screenshot") {
+					_, _ = w.Write([]byte(`{"value":"` + base64.StdEncoding.EncodeToString(full) + `"}`))
+					return
+				}
+				_, _ = fmt.Fprintf(w, `{"value":{"width":10,"height":8,"devicePixelRatio":%d}}`, tt.reportedDPR)
+			}))
+			defer server.Close()
+
+			client := NewWebDriverClient(server.URL).forSession("session-1")
+			client.deviceScaleFactor = tt.deviceScaleFactor
+
+			screenshot, err := client.TakeScreenshot(context.Background())
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			img, err := png.Decode(bytes.NewReader(screenshot))
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if size := img.Bounds().Size(); size.X != tt.width || size.Y != tt.height {
+				t.Errorf("Expected a %dx%d screenshot, got %v", tt.width, tt.height, size)
+			}
+			if dpr, _ := pngDevicePixelRatio(screenshot); dpr != tt.dpr {
+				t.Errorf("Expected the screenshot to record ratio %v, got %v", tt.dpr, dpr)
+			}
+		})
+	}
+}
+
+func TestWebDriverClientTakeElementScreenshotScale(t *testing.T) {
+	tests := []struct {
+		name              string
+		deviceScaleFactor float64 // Requested from Safari, 0 for none
+		reportedDPR       int     // window.devicePixelRatio
+		cssWidth          float64 // The element's width in CSS pixels
+		imageWidth        int     // Width of the screenshot Safari captures
+		dpr               float64
+	}{
+		{"page ratio", 0, 1, 10, 10, 1},
+		{"requested ratio", 2, 2, 10, 20, 2},
+		{"requested ratio ignored", 1, 1, 10, 20, 2},
+		{"fractional width", 2, 2, 10.4, 21, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			image := solidPNG(t, tt.imageWidth, tt.imageWidth, white, white)
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				if strings.HasSuffix(r.URL.Path, "/screenshot") {
+					_, _ = w.Write([]byte(`{"value":"` + base64.StdEncoding.EncodeToString(image) + `"}`))
+					return
+				}
+				_, _ = fmt.Fprintf(w, `{"value":{"width":%v,"devicePixelRatio":%d}}`, tt.cssWidth, tt.reportedDPR)
+			}))
+			defer server.Close()
+
+			client := NewWebDriverClient(server.URL).forSession("session-1")
+			client.deviceScaleFactor = tt.deviceScaleFactor
+
+			screenshot, err := client.TakeElementScreenshot(context.Background(), "element-id")
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if dpr, _ := pngDevicePixelRatio(screenshot); dpr != tt.dpr {
+				t.Errorf("Expected the screenshot to record ratio %v, got %v", tt.dpr, dpr)
+			}
+		})
+	}
+}
+
+func TestParseCountCondition(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    CountCondition
+		wantErr bool
+	}{
+		{input: ">= 5", want: CountCondition{">=", 5}},
+		{input: "==0", want: CountCondition{"==", 0}},
+		{input: "10", want: CountCondition{"==", 10}},
+		{input: " != 3 ", want: CountCondition{"!=", 3}},
+		{input: "> 1", want: CountCondition{">", 1}},
+		{input: "<2", want: CountCondition{"<", 2}},
+		{input: "<= 4", want: CountCondition{"<=", 4}},
+		{input: ">= many", wantErr: true},
+		{input: "-1", wantErr: true},
+		{input: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ParseCountCondition(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseCountCondition(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseCountCondition(%q) = %+v, want %+v", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCountConditionMatches(t *testing.T) {
+	tests := []struct {
+		condition CountCondition
+		n         int
+		want      bool
+	}{
+		{CountCondition{"==", 0}, 0, true},
+		{CountCondition{"==", 0}, 1, false},
+		{CountCondition{"!=", 0}, 1, true},
+		{CountCondition{">", 1}, 1, false},
+		{CountCondition{">=", 5}, 5, true},
+		{CountCondition{"<", 5}, 5, false},
+		{CountCondition{"<=", 5}, 5, true},
+		{CountCondition{"~", 5}, 5, false},
+	}
+
+	for _, tt := range tests {
+		if got := tt.condition.Matches(tt.n); got != tt.want {
+			t.Errorf("%s matching %d = %v, want %v", tt.condition, tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestDefaultTimeout(t *testing.T) {
+	t.Cleanup(func() { SetDefaultTimeout(0) })
+
+	if got := DefaultTimeout(); got != 30*time.Second {
+		t.Errorf("Expected fallback timeout of 30s, got %v", got)
+	}
+
+	SetDefaultTimeout(5 * time.Second)
+	if got := DefaultTimeout(); got != 5*time.Second {
+		t.Errorf("Expected default timeout of 5s, got %v", got)
+	}
+
+	SetDefaultTimeout(0)
+	if got := DefaultTimeout(); got != 30*time.Second {
+		t.Errorf("Expected fallback timeout to be restored, got %v", got)
+	}
+}
+
+func TestWebDriverErrorFromResponse(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/title") {
+			w.WriteHeader(http.StatusInternalServerError)
+			_, _ = w.Write([]byte("not json"))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"value":{"error":"no such element","message":"Unable to locate element","stacktrace":"at find"}}`))
+	}))
+	defer server.Close()
+
+	client := NewWebDriverClient(server.URL)
+	client.setSession("session-1")
+	ctx := context.Background()
+
+	_, err := client.FindElement(ctx, "#missing")
+	var wdErr *WebDriverError
+	if !errors.As(err, &wdErr) {
+		t.Fatalf("Expected a WebDriverError, got %v", err)
+	}
+	if wdErr.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected status 404, got %d", wdErr.StatusCode)
+	}
+	if wdErr.Code != "no such element" {
+		t.Errorf("Expected code 'no such element', got %q", wdErr.Code)
+	}
+	if wdErr.Message != "Unable to locate element" || wdErr.Stacktrace != "at find" {
+		t.Errorf("Unexpected error details: %+v", wdErr)
+	}
+	if !strings.Contains(err.Error(), "no such element: Unable to locate element") {
+		t.Errorf("Expected error message to include the code and message, got %q", err.Error())
+	}
+
+	// Bodies that aren't W3C errors still produce a WebDriverError
+	_, err = client.GetTitle(ctx)
+	if !errors.As(err, &wdErr) {
+		t.Fatalf("Expected a WebDriverError, got %v", err)
+	}
+	if wdErr.Code != "unknown error" || wdErr.StatusCode != http.StatusInternalServerError {
+		t.Errorf("Unexpected error details: %+v", wdErr)
+	}
+}
+
+func TestWebDriverClientForSession(t *testing.T) {
+	var mu sync.Mutex
+	var paths []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		paths = append(paths, r.URL.Path)
+		mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"value":0}`))
+	}))
+	defer server.Close()
+
+	client := NewWebDriverClient(server.URL)
+	client.setSession("session-2") // The latest session created
+	ctx := context.Background()
+
+	page1 := client.forSession("session-1")
+	page2 := client.forSession("session-2")
+
+	if page1.httpClient != client.httpClient {
+		t.Error("Expected bound clients to share the HTTP client")
+	}
+
+	// Reading injected state targets each page's own session
+	script := "return window.__webdriverNetwork.inflight;"
+	if _, err := page1.ExecuteScript(ctx, script, nil); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := page2.ExecuteScript(ctx, script, nil); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := []string{"/session/session-1/execute/sync", "/session/session-2/execute/sync"}
+	if len(paths) != len(expected) || paths[0] != expected[0] || paths[1] != expected[1] {
+		t.Errorf("Expected requests to %v, got %v", expected, paths)
+	}
+
+	// Deleting one page's session leaves the other bound client usable
+	if err := page1.DeleteSession(ctx); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if page2.session() != "session-2" || client.session() != "session-2" {
+		t.Error("Expected other clients to keep their sessions")
+	}
+}
+
+// Run with -race: the browser's client is shared by the VU's pages, which
+// create and delete sessions concurrently
+func TestWebDriverClientConcurrentSessions(t *testing.T) {
+	var created atomic.Int64
+	var mu sync.Mutex
+	titles := make(map[string]string) // Session of each title request
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/session":
+			id := fmt.Sprintf("session-%d", created.Add(1))
+			_, _ = w.Write([]byte(`{"value":{"sessionId":"` + id + `","capabilities":{}}}`))
+		case strings.HasSuffix(r.URL.Path, "/title"):
+			id := strings.Split(r.URL.Path, "/")[2]
+			mu.Lock()
+			titles[id] = r.URL.Path
+			mu.Unlock()
+			_, _ = w.Write([]byte(`{"value":"` + id + `"}`))
+		default:
+			_, _ = w.Write([]byte(`{"value":null}`))
+		}
+	}))
+	defer server.Close()
+
+	client := NewWebDriverClient(server.URL)
+	ctx := context.Background()
+
+	const pages = 20
+	var wg sync.WaitGroup
+	errs := make(chan error, pages)
+	for range pages {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			session, err := client.CreateSession(ctx, map[string]interface{}{"browserName": "Safari"})
+			if err != nil {
+				errs <- err
+				return
+			}
+			page := client.forSession(session.SessionID)
+			title, err := page.GetTitle(ctx)
+			if err != nil {
+				errs <- err
+				return
+			}
+			if title != session.SessionID {
+				errs <- fmt.Errorf("expected the title of %s, got %s", session.SessionID, title)
+			}
+			_ = page.DeleteSession(ctx)
+			_ = client.DeleteSession(ctx)
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Error(err)
+	}
+	if len(titles) != pages {
+		t.Errorf("Expected each page to get the title of its own session, got %d sessions", len(titles))
+	}
+}
+
+func TestWebDriverClientStatus(t *testing.T) {
+	var mu sync.Mutex
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/status" {
+			t.Errorf("Expected request to /status, got %s", r.URL.Path)
+		}
+
+		mu.Lock()
+		calls++
+		ready := calls >= 3
+		mu.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		if ready {
+			_, _ = w.Write([]byte(`{"value":{"ready":true,"message":"ready"}}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"value":{"ready":false,"message":"starting"}}`))
+	}))
+	defer server.Close()
+
+	// Status does not require a session
+	client := NewWebDriverClient(server.URL)
+	ctx := context.Background()
+
+	status, err := client.Status(ctx)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if status.Ready || status.Message != "starting" {
+		t.Errorf("Unexpected status: %+v", status)
+	}
+
+	if err := client.WaitForReady(ctx, 5*time.Second); err != nil {
+		t.Errorf("Expected driver to become ready, got %v", err)
+	}
+}
+
+func TestWebDriverClientWaitForReadyTimeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"value":{"ready":false,"message":"session in progress"}}`))
+	}))
+	defer server.Close()
+
+	client := NewWebDriverClient(server.URL)
+	err := client.WaitForReady(context.Background(), 300*time.Millisecond)
+	if err == nil || !strings.Contains(err.Error(), "session in progress") {
+		t.Errorf("Expected not ready error, got %v", err)
+	}
+}
+
+func TestWebDriverClientRetry(t *testing.T) {
+	var mu sync.Mutex
+	attempts := map[string]int{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		attempts[r.URL.Path]++
+		n := attempts[r.URL.Path]
+		mu.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/element"):
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"value":{"error":"no such element","message":"missing"}}`))
+		case strings.HasSuffix(r.URL.Path, "/execute/sync") && n < 3:
+			w.WriteHeader(http.StatusInternalServerError)
+			_, _ = w.Write([]byte(`{"value":{"error":"unknown error","message":"try again"}}`))
+		case strings.HasSuffix(r.URL.Path, "/execute/sync"):
+			_, _ = w.Write([]byte(`{"value":42}`))
+		case r.URL.Path == "/session":
+			w.WriteHeader(http.StatusInternalServerError)
+			_, _ = w.Write([]byte(`{"value":{"error":"session not created","message":"busy"}}`))
+		}
+	}))
+	defer server.Close()
+
+	opts := DefaultClientOptions()
+	opts.MaxRetries = 2
+	opts.RetryBackoff = time.Millisecond
+	client := NewWebDriverClientWithOptions(server.URL, opts).forSession("session-1")
+	ctx := context.Background()
+
+	// 5xx responses are retried until they succeed
+	result, err := client.ExecuteScript(ctx, "return 42", nil)
+	if err != nil {
+		t.Fatalf("Expected script to succeed after retrying, got %v", err)
+	}
+	if result != float64(42) {
+		t.Errorf("Expected 42, got %v", result)
+	}
+
+	// 4xx responses are not retried
+	if _, err := client.findElementNative(ctx, "css selector", "#missing"); err == nil {
+		t.Error("Expected element not found error")
+	}
+
+	// Retries give up after MaxRetries, returning the last error
+	_, err = client.CreateSession(ctx, map[string]interface{}{})
+	var wdErr *WebDriverError
+	if !errors.As(err, &wdErr) || wdErr.Code != "session not created" {
+		t.Errorf("Expected the last WebDriverError, got %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if attempts["/session/session-1/execute/sync"] != 3 {
+		t.Errorf("Expected 3 script attempts, got %d", attempts["/session/session-1/execute/sync"])
+	}
+	if attempts["/session/session-1/element"] != 1 {
+		t.Errorf("Expected 1 find attempt, got %d", attempts["/session/session-1/element"])
+	}
+	if attempts["/session"] != 3 {
+		t.Errorf("Expected 3 session attempts, got %d", attempts["/session"])
+	}
+}
+
+func TestWebDriverClientAutoReconnect(t *testing.T) {
+	var mu sync.Mutex
+	var requests []string
+	sessions := 0
+	valid := ""
+	var capabilities []interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+
+		mu.Lock()
+		defer mu.Unlock()
+		requests = append(requests, r.Method+" "+r.URL.Path+" "+string(body))
+
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.URL.Path == "/session":
+			var payload map[string]interface{}
+			_ = json.Unmarshal(body, &payload)
+			capabilities = append(capabilities, payload["capabilities"])
+			sessions++
+			valid = fmt.Sprintf("session-%d", sessions)
+			_, _ = fmt.Fprintf(w, `{"value":{"sessionId":%q,"capabilities":{}}}`, valid)
+		case !strings.HasPrefix(r.URL.Path, "/session/"+valid+"/"):
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"value":{"error":"invalid session id","message":"expired"}}`))
+		case strings.HasSuffix(r.URL.Path, "/title"):
+			_, _ = w.Write([]byte(`{"value":"Home"}`))
+		default:
+			_, _ = w.Write([]byte(`{"value":null}`))
+		}
+	}))
+	defer server.Close()
+
+	opts := DefaultClientOptions()
+	opts.AutoReconnect = true
+	client := NewWebDriverClientWithOptions(server.URL, opts)
+	ctx := context.Background()
+
+	if _, err := client.CreateSession(ctx, map[string]interface{}{"browserName": "Safari"}); err != nil {
+		t.Fatalf("Unexpected error creating the session: %v", err)
+	}
+	if err := client.SetWindowSize(ctx, 800, 600); err != nil {
+		t.Fatalf("Unexpected error setting the window size: %v", err)
+	}
+
+	// safaridriver invalidates the session while it's idle
+	mu.Lock()
+	valid, requests = "", nil
+	mu.Unlock()
+
+	title, err := client.GetTitle(ctx)
+	if err != nil {
+		t.Fatalf("Expected the command to succeed in a new session, got %v", err)
+	}
+	if title != "Home" {
+		t.Errorf("Expected the title from the new session, got %q", title)
+	}
+	if client.session() != "session-2" {
+		t.Errorf("Expected the client to be bound to the new session, got %q", client.session())
+	}
+
+	mu.Lock()
+	got := requests
+	if len(capabilities) != 2 || !reflect.DeepEqual(capabilities[0], capabilities[1]) {
+		t.Errorf("Expected the new session to be created with the same capabilities, got %v", capabilities)
+	}
+	mu.Unlock()
+	want := []string{
+		"GET /session/session-1/title ",
+		"POST /session " + `{"capabilities":{"alwaysMatch":{"browserName":"Safari"}}}`,
+		"POST /session/session-2/window/rect " + `{"height":600,"width":800}`,
+		"GET /session/session-2/title ",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected requests %q, got %q", want, got)
+	}
+
+	// Without auto-reconnect, the invalid session is reported
+	plain := NewWebDriverClient(server.URL).forSession("session-3")
+	if _, err := plain.GetTitle(ctx); !errors.Is(err, ErrNoSession) {
+		t.Errorf("Expected ErrNoSession without auto-reconnect, got %v", err)
+	}
+}
+
+func TestIsTransientFailure(t *testing.T) {
+	newResponse := func(status int, body string) *http.Response {
+		return &http.Response{StatusCode: status, Body: io.NopCloser(strings.NewReader(body))}
+	}
+
+	tests := []struct {
+		name     string
+		resp     *http.Response
+		err      error
+		expected bool
+	}{
+		{"connection error", nil, errors.New("connection refused"), true},
+		{"canceled", nil, context.Canceled, false},
+		{"server error", newResponse(500, `{"value":{"error":"unknown error"}}`), nil, true},
+		{"bad gateway", newResponse(502, "not json"), nil, true},
+		{"script error", newResponse(500, `{"value":{"error":"javascript error"}}`), nil, false},
+		{"dialog open", newResponse(500, `{"value":{"error":"unexpected alert open"}}`), nil, false},
+		{"not found", newResponse(404, `{"value":{"error":"no such element"}}`), nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isTransientFailure(tt.resp, tt.err); got != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+
+	// The body is still readable after peeking at it
+	resp := newResponse(500, `{"value":{"error":"javascript error","message":"boom"}}`)
+	isTransientFailure(resp, nil)
+	if wdErr := newWebDriverError(resp); wdErr.Message != "boom" {
+		t.Errorf("Expected body to be restored, got %+v", wdErr)
+	}
+}
+
+func TestWebDriverClientAlerts(t *testing.T) {
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		requests = append(requests, r.Method+" "+r.URL.Path+" "+string(body))
+
+		w.Header().Set("Content-Type", "application/json")
+		if r.Method == "GET" {
+			_, _ = w.Write([]byte(`{"value":"Delete this item?"}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"value":null}`))
+	}))
+	defer server.Close()
+
+	client := NewWebDriverClient(server.URL).forSession("session-1")
+	ctx := context.Background()
+
+	text, err := client.GetAlertText(ctx)
+	if err != nil || text != "Delete this item?" {
+		t.Errorf("Expected the alert text, got %q, %v", text, err)
+	}
+	if err := client.SendAlertText(ctx, "Ada"); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if err := client.AcceptAlert(ctx); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if err := client.DismissAlert(ctx); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	expected := []string{
+		"GET /session/session-1/alert/text ",
+		`POST /session/session-1/alert/text {"text":"Ada"}`,
+		"POST /session/session-1/alert/accept {}",
+		"POST /session/session-1/alert/dismiss {}",
+	}
+	if strings.Join(requests, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Expected requests %v, got %v", expected, requests)
+	}
+
+	if err := NewWebDriverClient(server.URL).AcceptAlert(ctx); !errors.Is(err, ErrNoSession) {
+		t.Errorf("Expected ErrNoSession, got %v", err)
+	}
+}
+
+func TestWebDriverClientDialogHandler(t *testing.T) {
+	dialogOpen := true
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if dialogOpen {
+			w.WriteHeader(http.StatusInternalServerError)
+			_, _ = w.Write([]byte(`{"value":{"error":"unexpected alert open","message":"A dialog is open"}}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"value":"done"}`))
+	}))
+	defer server.Close()
+
+	// Without a handler the command fails
+	client := NewWebDriverClient(server.URL).forSession("session-1")
+	if _, err := client.ExecuteScript(context.Background(), "return 1;", nil); err == nil {
+		t.Error("Expected error while a dialog is open")
+	}
+
+	handled := 0
+	client.dialogHandler = func(ctx context.Context) error {
+		handled++
+		dialogOpen = false
+		return nil
+	}
+
+	result, err := client.ExecuteScript(context.Background(), "return 1;", nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if result != "done" {
+		t.Errorf("Expected the command to be sent again after the dialog closed, got %v", result)
+	}
+	if handled != 1 {
+		t.Errorf("Expected the handler to be called once, got %d", handled)
+	}
+
+	// A page that keeps opening dialogs doesn't block the command forever
+	dialogOpen = true
+	handled = 0
+	client.dialogHandler = func(ctx context.Context) error {
+		handled++
+		return nil
+	}
+	if _, err := client.ExecuteScript(context.Background(), "return 1;", nil); err == nil {
+		t.Error("Expected error when dialogs keep opening")
+	}
+	if handled != maxDialogsPerCommand {
+		t.Errorf("Expected %d dialogs to be handled, got %d", maxDialogsPerCommand, handled)
+	}
+}
+
+func TestWebDriverClientPrintPage(t *testing.T) {
+	var payload map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasSuffix(r.URL.Path, "/session/session-1/print") {
+			t.Errorf("Unexpected path: %s", r.URL.Path)
+		}
+		_ = json.NewDecoder(r.Body).Decode(&payload)
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"value":"JVBERi0xLjQ="}`)) // "%PDF-1.4"
+	}))
+	defer server.Close()
+
+	client := NewWebDriverClient(server.URL).forSession("session-1")
+	pdf, err := client.PrintPage(context.Background(), PrintOptions{Landscape: true, PageRanges: []string{"1-2"}})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if string(pdf) != "%PDF-1.4" {
+		t.Errorf("Expected the decoded PDF, got %q", pdf)
+	}
+	if payload["orientation"] != "landscape" {
+		t.Errorf("Expected landscape orientation, got %v", payload)
+	}
+
+	if _, err := NewWebDriverClient(server.URL).PrintPage(context.Background(), PrintOptions{}); !errors.Is(err, ErrNoSession) {
+		t.Errorf("Expected ErrNoSession, got %v", err)
+	}
+}
+
+func TestValidateNavigationURL(t *testing.T) {
+	tests := []struct {
+		url     string
+		wantErr string
+	}{
+		{"https://example.com/login", ""},
+		{"HTTP://example.com", ""},
+		{"file:///Users/k6/fixtures/form.html", ""},
+		{"file://localhost/tmp/page.html", ""},
+		{"data:text/html,<h1>Hello</h1>", ""},
+		{"about:blank", ""},
+		{"example.com", "missing scheme"},
+		{"ftp://example.com/file.txt", `unsupported URL scheme "ftp"`},
+		{"javascript:alert(1)", `unsupported URL scheme "javascript"`},
+		{"file://server/share/page.html", "only local files"},
+		{"file:fixtures/page.html", "the path must be absolute"},
+	}
+
+	for _, tt := range tests {
+		err := validateNavigationURL(tt.url)
+		if tt.wantErr == "" && err != nil || tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+			t.Errorf("validateNavigationURL(%q) = %v, want error containing %q", tt.url, err, tt.wantErr)
+		}
+	}
+
+	// Unsupported URLs aren't sent to the driver
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("Unexpected request: %s", r.URL.Path)
+	}))
+	defer server.Close()
+
+	client := NewWebDriverClient(server.URL).forSession("session-1")
+	if err := client.Navigate(context.Background(), "ftp://example.com", nil); err == nil {
+		t.Error("Expected an unsupported scheme to be rejected")
+	}
+}
+
+func TestWebDriverClientNavigateCommit(t *testing.T) {
+	var mu sync.Mutex
+	var paths []string
+	polls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		paths = append(paths, r.URL.Path)
+
+		var payload map[string]interface{}
+		_ = json.NewDecoder(r.Body).Decode(&payload)
+		script, _ := payload["script"].(string)
+
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case strings.Contains(script, "location.assign"):
+			_, _ = w.Write([]byte(`{"value":"about:blank"}`))
+		case strings.Contains(script, "__webdriverNavigation"):
+			// The new document commits on the third poll
+			polls++
+			_, _ = w.Write([]byte(fmt.Sprintf(`{"value":%t}`, polls == 3)))
+		default:
+			t.Errorf("Unexpected request: %s %s", r.URL.Path, script)
+		}
+	}))
+	defer server.Close()
+
+	client := NewWebDriverClient(server.URL).forSession("session-1")
+	if err := client.Navigate(context.Background(), "https://example.com", &NavigateOptions{WaitUntil: "commit"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if polls != 3 {
+		t.Errorf("Expected to poll until the navigation committed, got %d polls", polls)
+	}
+	for _, path := range paths {
+		if strings.HasSuffix(path, "/url") {
+			t.Errorf("Expected the blocking navigate command not to be used")
+		}
+	}
+}
+
+func TestWebDriverClientWaitForURL(t *testing.T) {
+	var mu sync.Mutex
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		// The client-side router changes the URL on the third poll
+		calls++
+		url := "https://shop.example.com/cart"
+		if calls >= 3 {
+			url = "https://shop.example.com/orders/42"
+		}
+		w.Header().Set("Content-Type", "application/json")
+		data, _ := json.Marshal(map[string]interface{}{"value": url})
+		_, _ = w.Write(data)
+	}))
+	defer server.Close()
+
+	client := NewWebDriverClient(server.URL).forSession("session-1")
+	ctx := context.Background()
+
+	re, _ := urlPatternRegex("**/orders/*")
+	url, err := client.waitForURL(ctx, re, 10*time.Millisecond, time.Second)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if url != "https://shop.example.com/orders/42" || calls != 3 {
+		t.Errorf("Expected the matching URL after 3 polls, got %q after %d", url, calls)
+	}
+
+	re, _ = urlPatternRegex("/checkout/")
+	_, err = client.waitForURL(ctx, re, 10*time.Millisecond, 50*time.Millisecond)
+	if !errors.Is(err, ErrTimeout) || !strings.Contains(err.Error(), "orders/42") {
+		t.Errorf("Expected a timeout reporting the last URL, got %v", err)
+	}
+}
+
+func TestWebDriverClientWaitForLoadState(t *testing.T) {
+	var mu sync.Mutex
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		var payload struct {
+			Script string `json:"script"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&payload)
+
+		// The document is interactive, and completes on the third poll
+		calls++
+		interactive := strings.Contains(payload.Script, "'interactive'")
+		w.Header().Set("Content-Type", "application/json")
+		data, _ := json.Marshal(map[string]interface{}{"value": interactive || calls >= 3})
+		_, _ = w.Write(data)
+	}))
+	defer server.Close()
+
+	client := NewWebDriverClient(server.URL).forSession("session-1")
+	ctx := context.Background()
+
+	// Already in the state, so no further polls
+	if err := client.WaitForLoadState(ctx, "domcontentloaded", 0); err != nil || calls != 1 {
+		t.Fatalf("Expected to resolve right away, got %v after %d polls", err, calls)
+	}
+
+	calls = 0
+	if err := client.WaitForLoadState(ctx, "", 0); err != nil || calls != 3 {
+		t.Fatalf("Expected to wait for load, got %v after %d polls", err, calls)
+	}
+
+	if err := client.WaitForLoadState(ctx, "commit", 0); err == nil {
+		t.Error("Expected an invalid load state to be rejected")
+	}
+}
+
+func TestWebDriverClientExecuteCommand(t *testing.T) {
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		requests = append(requests, r.Method+" "+r.URL.Path+" "+string(body))
+
+		w.Header().Set("Content-Type", "application/json")
+		if strings.HasSuffix(r.URL.Path, "/unknown") {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"value":{"error":"unknown command","message":"not implemented"}}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"value":{"x":0,"y":0,"width":1280,"height":772}}`))
+	}))
+	defer server.Close()
+
+	client := NewWebDriverClient(server.URL).forSession("session-1")
+	ctx := context.Background()
+
+	value, err := client.ExecuteCommand(ctx, "get", "window/rect", nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if rect, _ := value.(map[string]interface{}); rect["height"] != 772.0 {
+		t.Errorf("Expected the decoded value, got %v", value)
+	}
+
+	if _, err := client.ExecuteCommand(ctx, "POST", "/window/maximize", nil); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := client.ExecuteCommand(ctx, "POST", "/window/rect", map[string]interface{}{"width": 800}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := []string{
+		"GET /session/session-1/window/rect ",
+		"POST /session/session-1/window/maximize {}",
+		`POST /session/session-1/window/rect {"width":800}`,
+	}
+	if strings.Join(requests, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Expected requests %q, got %q", expected, requests)
+	}
+
+	_, err = client.ExecuteCommand(ctx, "GET", "/unknown", nil)
+	var wdErr *WebDriverError
+	if !errors.As(err, &wdErr) || wdErr.Code != "unknown command" {
+		t.Errorf("Expected the WebDriver error to be returned, got %v", err)
+	}
+}
+
+func TestWebDriverClientFindElementsCount(t *testing.T) {
+	var paths, scripts []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/execute/sync"):
+			var payload map[string]interface{}
+			_ = json.NewDecoder(r.Body).Decode(&payload)
+			script, _ := payload["script"].(string)
+			scripts = append(scripts, script)
+			_, _ = w.Write([]byte(`{"value":3}`))
+		case strings.HasSuffix(r.URL.Path, "/elements"):
+			_, _ = w.Write([]byte(`{"value":[{"element-6066-11e4-a52e-4f735466cecf":"e1"},{"element-6066-11e4-a52e-4f735466cecf":"e2"}]}`))
+		default:
+			_, _ = w.Write([]byte(`{"value":null}`))
+		}
+	}))
+	defer server.Close()
+
+	client := NewWebDriverClient(server.URL).forSession("session-1")
+	ctx := context.Background()
+
+	for selector, countScript := range map[string]string{
+		"li.item":        "querySelectorAll",
+		"xpath=//li":     "count(",
+		"pierce=li.item": `pierceQueryAll(document, "li.item").length`,
+		"li:visible":     "isVisible(el)",
+	} {
+		paths, scripts = nil, nil
+		count, err := client.FindElements(ctx, selector)
+		if err != nil {
+			t.Fatalf("Unexpected error for %s: %v", selector, err)
+		}
+		if count != 3 {
+			t.Errorf("Expected the count from the script for %s, got %d", selector, count)
+		}
+		if len(paths) != 1 || len(scripts) != 1 {
+			t.Errorf("Expected a single script call for %s, got %v", selector, paths)
+		} else if !strings.Contains(scripts[0], countScript) {
+			t.Errorf("Expected a %s script for %s, got %s", countScript, selector, scripts[0])
+		}
+	}
+
+	if _, err := NewWebDriverClient(server.URL).FindElements(ctx, "li"); !errors.Is(err, ErrNoSession) {
+		t.Errorf("Expected ErrNoSession, got %v", err)
+	}
+}
+
+func TestSentinelErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/element"):
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"value":{"error":"no such element","message":"missing"}}`))
+		case strings.HasSuffix(r.URL.Path, "/title"):
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"value":{"error":"invalid session id","message":"gone"}}`))
+		default:
+			_, _ = w.Write([]byte(`{"value":false}`))
+		}
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+
+	if _, err := NewWebDriverClient(server.URL).GetTitle(ctx); !errors.Is(err, ErrNoSession) {
+		t.Errorf("Expected ErrNoSession without a session, got %v", err)
+	}
+
+	client := NewWebDriverClient(server.URL).forSession("session-1")
+
+	_, err := client.FindElement(ctx, "#missing")
+	if !errors.Is(err, ErrElementNotFound) {
+		t.Errorf("Expected ErrElementNotFound, got %v", err)
+	}
+	if errors.Is(err, ErrTimeout) {
+		t.Error("Expected element not found not to be a timeout")
+	}
+
+	if _, err := client.GetTitle(ctx); !errors.Is(err, ErrNoSession) {
+		t.Errorf("Expected ErrNoSession for an invalid session id, got %v", err)
+	}
+
+	err = client.pollForConditionWithOptions(ctx, "return false;", 10*time.Millisecond, 50*time.Millisecond)
+	if !errors.Is(err, ErrTimeout) {
+		t.Errorf("Expected ErrTimeout, got %v", err)
+	}
+	if err != nil && !strings.HasPrefix(err.Error(), "timeout waiting for condition") {
+		t.Errorf("Expected timeout message to be unchanged, got %q", err.Error())
+	}
+}
+
+func TestGenerateWaitScript(t *testing.T) {
+	tests := []struct {
+		name          string
+		selector      string
+		state         string
+		wantSubstring string
+	}{
+		{"CSS", "div.loading", "hidden", `var element = document.querySelector("div.loading");`},
+		{"XPath", "//li[@class='item']", "attached", `XPathResult.ORDERED_NODE_SNAPSHOT_TYPE`},
+		{"stable compares the box with the previous check", "aside.drawer", "stable", `return previous === box;`},
+		{"custom strategy is called as a function", "text=Done", "visible", `var elements = Array.prototype.slice.call((function() {`},
+		{"CSS with engine pseudos is called as a function", "button:has-text(\"Done\")", "visible", `var elements = Array.prototype.slice.call((function() {`},
+		{"custom strategy is visible if any match is", "data-testid=toast", "visible", `return elements.some(`},
+		{"custom strategy is hidden if every match is", "data-testid=toast", "hidden", `return elements.every(`},
+		{"custom strategy is detached without matches", "data-testid=toast", "detached", `return elements.every(`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := generateWaitScript(tt.selector, tt.state)
+			if !strings.Contains(got, tt.wantSubstring) {
+				t.Errorf("generateWaitScript(%q, %q) = %v, want to contain %v", tt.selector, tt.state, got, tt.wantSubstring)
+			}
+		})
+	}
+}
+
+func TestGenerateWaitScriptMultipleMatches(t *testing.T) {
+	// A page with two toasts, the first of them hidden
+	rt := sobek.New()
+	_, err := rt.RunString(`
+		var toasts = [
+			{ offsetWidth: 0, offsetHeight: 0, style: { display: 'none' } },
+			{ offsetWidth: 200, offsetHeight: 40, style: { display: 'block' } }
+		];
+		var document = {
+			querySelectorAll: function(selector) {
+				return selector === '[data-testid="toast"]' ? toasts : [];
+			}
+		};
+		var window = {
+			getComputedStyle: function(element) {
+				return { display: element.style.display, visibility: 'visible', opacity: '1' };
+			}
+		};
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	check := func(selector, state string) bool {
+		t.Helper()
+		result, err := rt.RunString("(function() {" + generateWaitScript(selector, state) + "})()")
+		if err != nil {
+			t.Fatalf("Wait script for %q to be %s failed: %v", selector, state, err)
+		}
+		return result.ToBoolean()
+	}
+
+	for state, want := range map[string]bool{"attached": true, "visible": true, "hidden": false, "detached": false} {
+		if got := check("data-testid=toast", state); got != want {
+			t.Errorf("Expected state %s to be %v while the second toast is visible, got %v", state, want, got)
+		}
+	}
+
+	if _, err := rt.RunString(`toasts[1].offsetWidth = 0; toasts[1].style.display = 'none';`); err != nil {
+		t.Fatal(err)
+	}
+	if !check("data-testid=toast", "hidden") || check("data-testid=toast", "visible") {
+		t.Error("Expected the toasts to be hidden once both are")
+	}
+
+	if !check("data-testid=missing", "detached") || !check("data-testid=missing", "hidden") || check("data-testid=missing", "attached") {
+		t.Error("Expected no matches to be detached and hidden only")
+	}
+}
+
+func TestVisibilityScriptsAgree(t *testing.T) {
+	// A button with a size, hidden by its opacity only
+	rt := sobek.New()
+	_, err := rt.RunString(`
+		var button = {
+			isConnected: true, offsetWidth: 80, offsetHeight: 24, textContent: 'Save',
+			matches: function() { return false; },
+			getAttribute: function() { return null; },
+			getElementsByTagName: function() { return []; }
+		};
+		var document = { querySelectorAll: function() { return [button]; } };
+		var window = {
+			getComputedStyle: function() { return { display: 'block', visibility: 'visible', opacity: '0' }; }
+		};
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	run := func(script string) sobek.Value {
+		t.Helper()
+		result, err := rt.RunString("(function() {" + script + "}).apply(null, [button])")
+		if err != nil {
+			t.Fatalf("Script failed: %v\n%s", err, script)
+		}
+		return result
+	}
+
+	if run(generateStateCheckScript("arguments[0]", "visible")).ToBoolean() {
+		t.Error("Expected the visible state check to see the button as hidden")
+	}
+	if !run(generateStateCheckScript("arguments[0]", "hidden")).ToBoolean() {
+		t.Error("Expected the hidden state check to see the button as hidden")
+	}
+	if got := run(actionabilityScript).String(); got != "not visible" {
+		t.Errorf("Expected the actionability check to report the button as not visible, got %s", got)
+	}
+	if run(elementAssertionStateScript).ToObject(rt).Get("visible").ToBoolean() {
+		t.Error("Expected the assertion state to see the button as hidden")
+	}
+	for _, selector := range []string{"visible-text=Save", "button:visible"} {
+		parsed := ParseSelector(selector)
+		if n := run(generateAllSelectorScript(parsed.Strategy, parsed.Value)).ToObject(rt).Get("length").ToInteger(); n != 0 {
+			t.Errorf("Expected %s to skip the button, got %d matches", selector, n)
+		}
+	}
+}
+
+func TestWebDriverClientTextContents(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+
+		var payload struct {
+			Args []interface{} `json:"args"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&payload)
+		elements, _ := payload.Args[0].([]interface{})
+		if len(elements) != 3 {
+			t.Errorf("Expected all 3 elements in a single call, got %v", payload.Args)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"value":["$10.00", "", null]}`))
+	}))
+	defer server.Close()
+
+	client := NewWebDriverClient(server.URL).forSession("session-1")
+	ctx := context.Background()
+
+	texts, err := client.textContents(ctx, []string{"cell-1", "cell-2", "cell-3"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(texts) != 3 || texts[0] != "$10.00" || texts[1] != "" || texts[2] != "" {
+		t.Errorf("Unexpected text contents: %q", texts)
+	}
+	if requests != 1 {
+		t.Errorf("Expected 1 request, got %d", requests)
+	}
+
+	// No elements means no round-trip
+	texts, err = client.textContents(ctx, nil)
+	if err != nil || len(texts) != 0 || requests != 1 {
+		t.Errorf("Expected no request for no elements, got %q (%v), %d requests", texts, err, requests)
+	}
 }
@@ -0,0 +1,200 @@
+package browser
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/grafana/sobek"
+)
+
+// sessionWindows tracks the windows of a WebDriver session. Windows opened by a
+// page, like target="_blank" links, belong to its session, and WebDriver
+// commands go to one window at a time
+type sessionWindows struct {
+	// Set once a second window of the session got a page; from then on each
+	// page operation switches the session to the page's window
+	shared atomic.Bool
+
+	mu      sync.Mutex // Held by each page operation of the session
+	current string     // Window the session's commands go to
+}
+
+// popupPollInterval is how often WaitForPage checks for new windows
+const popupPollInterval = 100 * time.Millisecond
+
+// Context returns the browser context of the page
+func (p *Page) Context() *BrowserContext {
+	return p.context
+}
+
+// inWindow runs fn with the session switched to the page's window if the
+// session has several windows with pages. The operations of a session are
+// serialized, so that none runs while another switched the session into a
+// frame or to another window. inWindow must not be nested
+func (p *Page) inWindow(ctx context.Context, fn func() error) error {
+	w := p.windows
+	if w == nil {
+		p.sessionMu.Lock()
+		defer p.sessionMu.Unlock()
+		return fn()
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.shared.Load() && w.current != p.windowHandle {
+		if err := p.client.SwitchToWindow(ctx, p.windowHandle); err != nil {
+			return fmt.Errorf("failed to switch to the page's window: %w", err)
+		}
+		w.current = p.windowHandle
+	}
+	return fn()
+}
+
+// closeWindow closes the page's window, leaving the other windows of its
+// session open. It must be called through inWindow
+func (p *Page) closeWindow(ctx context.Context) error {
+	remaining, err := p.client.CloseWindow(ctx)
+	if err != nil {
+		return err
+	}
+
+	p.windows.current = ""
+	if len(remaining) == 0 {
+		// Closing the last window ends the session
+		p.client.setSession("")
+	}
+	return nil
+}
+
+// Pages returns the open pages of the context, including the windows opened
+// by its pages, like target="_blank" links and window.open popups
+func (bc *BrowserContext) Pages() (*sobek.Promise, error) {
+	return Promise(bc.vu, func() (interface{}, error) {
+		if _, err := bc.discoverPages(context.Background()); err != nil {
+			return nil, err
+		}
+		return bc.currentPages(), nil
+	}), nil
+}
+
+// WaitForPage waits for a page to open a new window and returns its page
+// Options: timeout (ms, default: the default timeout)
+func (bc *BrowserContext) WaitForPage(options map[string]interface{}) (*sobek.Promise, error) {
+	timeout := DefaultTimeout()
+	if ms, ok := toFloat64(options["timeout"]); ok && ms > 0 {
+		timeout = time.Duration(ms) * time.Millisecond
+	}
+
+	return Promise(bc.vu, func() (interface{}, error) {
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+
+		ticker := time.NewTicker(popupPollInterval)
+		defer ticker.Stop()
+
+		for {
+			popups, err := bc.discoverPages(ctx)
+			if err != nil && ctx.Err() == nil {
+				return nil, err
+			}
+			if len(popups) > 0 {
+				return popups[0], nil
+			}
+
+			select {
+			case <-ctx.Done():
+				return nil, fmt.Errorf("%w waiting for a new page after %v", ErrTimeout, timeout)
+			case <-ticker.C:
+			}
+		}
+	}), nil
+}
+
+// discoverPages creates pages for the windows of the context's sessions that
+// don't have one yet, returning them
+func (bc *BrowserContext) discoverPages(ctx context.Context) ([]*Page, error) {
+	bc.discoverMu.Lock()
+	defer bc.discoverMu.Unlock()
+
+	// Window handles are looked up once per session, through one of its pages
+	openers := make(map[*sessionWindows]*Page)
+	known := make(map[string]bool)
+	for _, page := range bc.currentPages() {
+		if page.windows == nil || page.client.session() == "" {
+			continue
+		}
+		if _, ok := openers[page.windows]; !ok {
+			openers[page.windows] = page
+		}
+		known[page.client.session()+"/"+page.windowHandle] = true
+	}
+
+	var popups []*Page
+	for _, opener := range openers {
+		handles, err := opener.client.GetWindowHandles(ctx)
+		if err != nil {
+			return popups, fmt.Errorf("failed to get window handles: %w", err)
+		}
+		bc.forgetClosedWindows(opener.windows, handles)
+
+		for _, handle := range handles {
+			if known[opener.client.session()+"/"+handle] {
+				continue
+			}
+
+			popups = append(popups, opener.newPopup(ctx, handle))
+		}
+	}
+
+	return popups, nil
+}
+
+// forgetClosedWindows removes the pages of windows of the session that were
+// closed by the page, e.g. with window.close()
+func (bc *BrowserContext) forgetClosedWindows(windows *sessionWindows, handles []string) {
+	open := make(map[string]bool, len(handles))
+	for _, handle := range handles {
+		open[handle] = true
+	}
+
+	for _, page := range bc.currentPages() {
+		if page.windows != windows || open[page.windowHandle] {
+			continue
+		}
+		bc.removePage(page)
+		if page.browser != nil {
+			page.browser.untrackPage(page)
+		}
+	}
+}
+
+// newPopup creates the page of a window opened by the page
+func (p *Page) newPopup(ctx context.Context, handle string) *Page {
+	popup := &Page{
+		vu:           p.vu,
+		client:       p.client,
+		session:      p.session,
+		browser:      p.browser,
+		context:      p.context,
+		windows:      p.windows,
+		windowHandle: handle,
+		userAgent:    p.userAgent,
+	}
+	p.windows.shared.Store(true)
+
+	// Log warnings but don't fail, like for pages created with NewPage
+	err := popup.inWindow(ctx, func() error { return popup.injectScript(ctx) })
+	logInjectScriptError(p.vu, "in the new window", err)
+
+	if p.browser != nil {
+		p.browser.trackPage(popup)
+	}
+	if p.context != nil {
+		p.context.addPage(popup)
+	}
+	return popup
+}
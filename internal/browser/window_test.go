@@ -0,0 +1,271 @@
+package browser
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"go.k6.io/k6/js/modulestest"
+)
+
+// windowServer fakes a WebDriver session with several windows
+type windowServer struct {
+	mu       sync.Mutex
+	handles  []string
+	current  string
+	requests []string // Window switches and scripts, as "switch:<handle>" and "script@<handle>"
+}
+
+func (ws *windowServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var payload map[string]interface{}
+	_ = json.NewDecoder(r.Body).Decode(&payload)
+
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	switch {
+	case strings.HasSuffix(r.URL.Path, "/window/handles"):
+		data, _ := json.Marshal(map[string]interface{}{"value": ws.handles})
+		_, _ = w.Write(data)
+
+	case strings.HasSuffix(r.URL.Path, "/window") && r.Method == http.MethodGet:
+		data, _ := json.Marshal(map[string]interface{}{"value": ws.current})
+		_, _ = w.Write(data)
+
+	case strings.HasSuffix(r.URL.Path, "/window") && r.Method == http.MethodPost:
+		ws.current, _ = payload["handle"].(string)
+		ws.requests = append(ws.requests, "switch:"+ws.current)
+		_, _ = w.Write([]byte(`{"value":null}`))
+
+	case strings.HasSuffix(r.URL.Path, "/window") && r.Method == http.MethodDelete:
+		ws.requests = append(ws.requests, "close:"+ws.current)
+		for i, handle := range ws.handles {
+			if handle == ws.current {
+				ws.handles = append(ws.handles[:i], ws.handles[i+1:]...)
+				break
+			}
+		}
+		data, _ := json.Marshal(map[string]interface{}{"value": ws.handles})
+		_, _ = w.Write(data)
+
+	default:
+		ws.requests = append(ws.requests, "script@"+ws.current)
+		_, _ = w.Write([]byte(`{"value":null}`))
+	}
+}
+
+func (ws *windowServer) recorded() string {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+
+	recorded := strings.Join(ws.requests, ",")
+	ws.requests = nil
+	return recorded
+}
+
+func TestWebDriverClientWindows(t *testing.T) {
+	ws := &windowServer{handles: []string{"w1", "w2"}, current: "w1"}
+	server := httptest.NewServer(ws)
+	defer server.Close()
+
+	client := NewWebDriverClient(server.URL).forSession("session-1")
+	ctx := context.Background()
+
+	handle, err := client.GetWindowHandle(ctx)
+	if err != nil || handle != "w1" {
+		t.Errorf("Expected the current window, got %q, %v", handle, err)
+	}
+	handles, err := client.GetWindowHandles(ctx)
+	if err != nil || len(handles) != 2 || handles[1] != "w2" {
+		t.Errorf("Expected both windows, got %v, %v", handles, err)
+	}
+
+	if err := client.SwitchToWindow(ctx, "w2"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	remaining, err := client.CloseWindow(ctx)
+	if err != nil || len(remaining) != 1 || remaining[0] != "w1" {
+		t.Errorf("Expected the remaining window, got %v, %v", remaining, err)
+	}
+	if got := ws.recorded(); got != "switch:w2,close:w2" {
+		t.Errorf("Unexpected requests: %s", got)
+	}
+
+	if _, err := NewWebDriverClient(server.URL).GetWindowHandles(ctx); !errors.Is(err, ErrNoSession) {
+		t.Errorf("Expected ErrNoSession, got %v", err)
+	}
+}
+
+func TestBrowserContextDiscoverPages(t *testing.T) {
+	runtime := modulestest.NewRuntime(t)
+	ws := &windowServer{handles: []string{"w1"}, current: "w1"}
+	server := httptest.NewServer(ws)
+	defer server.Close()
+
+	browserContext := &BrowserContext{vu: runtime.VU}
+	opener := &Page{
+		vu:           runtime.VU,
+		client:       NewWebDriverClient(server.URL).forSession("session-1"),
+		context:      browserContext,
+		windows:      &sessionWindows{current: "w1"},
+		windowHandle: "w1",
+	}
+	browserContext.addPage(opener)
+
+	popups, err := browserContext.discoverPages(context.Background())
+	if err != nil || len(popups) != 0 {
+		t.Fatalf("Expected no new pages, got %v, %v", popups, err)
+	}
+
+	// A target="_blank" link opened a second window
+	ws.handles = append(ws.handles, "w2")
+	popups, err = browserContext.discoverPages(context.Background())
+	if err != nil || len(popups) != 1 {
+		t.Fatalf("Expected a new page, got %v, %v", popups, err)
+	}
+	popup := popups[0]
+	if popup.windowHandle != "w2" || popup.Context() != browserContext {
+		t.Errorf("Unexpected popup page: %+v", popup)
+	}
+	if got := ws.recorded(); got != "switch:w2,script@w2" {
+		t.Errorf("Expected the script to be injected into the new window, got %s", got)
+	}
+	if pages := browserContext.currentPages(); len(pages) != 2 {
+		t.Errorf("Expected both pages in the context, got %d", len(pages))
+	}
+
+	// Each page's operations switch the session to its own window
+	ctx := context.Background()
+	for _, page := range []*Page{opener, opener, popup} {
+		if err := page.inWindow(ctx, func() error {
+			_, err := page.client.ExecuteScript(ctx, "return 1;", nil)
+			return err
+		}); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+	if got := ws.recorded(); got != "switch:w1,script@w1,script@w1,switch:w2,script@w2" {
+		t.Errorf("Unexpected requests: %s", got)
+	}
+
+	// Closing the popup only closes its window
+	if err := popup.inWindow(ctx, func() error { return popup.closeWindow(ctx) }); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got := ws.recorded(); got != "close:w2" {
+		t.Errorf("Unexpected requests: %s", got)
+	}
+	if popup.client.session() == "" {
+		t.Error("Expected the session to stay open while the opener's window is open")
+	}
+
+	// Pages of windows closed by the page itself are forgotten
+	ws.handles = []string{"w1"}
+	browserContext.addPage(popup)
+	if _, err := browserContext.discoverPages(ctx); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if pages := browserContext.currentPages(); len(pages) != 1 || pages[0] != opener {
+		t.Errorf("Expected only the opener to be left, got %v", pages)
+	}
+}
+
+func TestBrowserContextWaitForPageTimeout(t *testing.T) {
+	runtime := modulestest.NewRuntime(t)
+	ws := &windowServer{handles: []string{"w1"}, current: "w1"}
+	server := httptest.NewServer(ws)
+	defer server.Close()
+
+	browserContext := &BrowserContext{vu: runtime.VU}
+	browserContext.addPage(&Page{
+		vu:           runtime.VU,
+		client:       NewWebDriverClient(server.URL).forSession("session-1"),
+		context:      browserContext,
+		windows:      &sessionWindows{current: "w1"},
+		windowHandle: "w1",
+	})
+	if err := runtime.VU.Runtime().Set("context", browserContext); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := runtime.RunOnEventLoop(`
+		var failure;
+		context.waitForPage({ timeout: 250 }).catch(function(e) { failure = String(e); });
+	`)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if got := runtime.VU.Runtime().Get("failure").String(); !strings.Contains(got, "timeout waiting for a new page") {
+		t.Errorf("Expected a timeout, got %s", got)
+	}
+}
+
+func TestPageCloseKeepsOtherWindows(t *testing.T) {
+	runtime := modulestest.NewRuntime(t)
+	// The page opened a second window, which wasn't looked up yet
+	ws := &windowServer{handles: []string{"w1", "w2"}, current: "w1"}
+	server := httptest.NewServer(ws)
+	defer server.Close()
+
+	browserContext := &BrowserContext{vu: runtime.VU}
+	opener := &Page{
+		vu:           runtime.VU,
+		client:       NewWebDriverClient(server.URL).forSession("session-1"),
+		context:      browserContext,
+		windows:      &sessionWindows{current: "w1"},
+		windowHandle: "w1",
+	}
+	browserContext.addPage(opener)
+	if err := runtime.VU.Runtime().Set("context", browserContext); err != nil {
+		t.Fatal(err)
+	}
+	if err := runtime.VU.Runtime().Set("opener", opener); err != nil {
+		t.Fatal(err)
+	}
+
+	close := func(script string) {
+		t.Helper()
+		_, err := runtime.RunOnEventLoop(`
+			var failure = "";
+			` + script + `.catch(function(e) { failure = String(e); });
+		`)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if failure := runtime.VU.Runtime().Get("failure").String(); failure != "" {
+			t.Fatalf("Unexpected failure: %s", failure)
+		}
+	}
+
+	// Only the opener's window is closed, after the popup got a page
+	close("opener.close()")
+	if got := ws.recorded(); got != "switch:w2,script@w2,switch:w1,close:w1" {
+		t.Errorf("Expected only the opener's window to be closed, got %s", got)
+	}
+	pages := browserContext.currentPages()
+	if len(pages) != 1 || pages[0].windowHandle != "w2" {
+		t.Fatalf("Expected the popup's page to be left, got %v", pages)
+	}
+	if opener.client.session() != "session-1" {
+		t.Error("Expected the session to stay open for the popup")
+	}
+
+	// Closing the last window ends the session
+	if err := runtime.VU.Runtime().Set("popup", pages[0]); err != nil {
+		t.Fatal(err)
+	}
+	close("popup.close()")
+	if got := ws.recorded(); got != "switch:w2,close:w2" {
+		t.Errorf("Expected the popup's window to be closed, got %s", got)
+	}
+	if opener.client.session() != "" || len(browserContext.currentPages()) != 0 {
+		t.Error("Expected the session to end with its last window")
+	}
+}
@@ -10,31 +10,46 @@ import (
 type rootModule struct{}
 
 func (*rootModule) NewModuleInstance(vu modules.VU) modules.Instance {
-	return &module{vu}
+	// Metrics can only be registered in the init context
+	return &module{vu: vu, metrics: browser.RegisterK6Metrics(vu.InitEnv().Registry)}
 }
 
 type module struct {
-	vu modules.VU
+	vu      modules.VU
+	metrics *browser.K6Metrics
 }
 
 func (m *module) Exports() modules.Exports {
-	// Start safaridriver when module loads
-	if err := browser.StartSafariDriver(); err != nil {
-		// Log error but don't fail module loading
-		// The error will surface when trying to create a page
+	// Configure a safaridriver for this VU on its own port. It's only started by
+	// the first newPage, as k6 also creates VUs that never run an iteration
+	driverURL := browser.DefaultDriverURL
+	driver, err := browser.NewSafariDriver()
+	if err == nil {
+		driverURL = driver.DriverURL()
 	}
+	// Otherwise don't fail module loading: assume an externally started driver,
+	// and let any error surface when trying to create a page
 
 	// Create and return the browser instance directly
 	b := &browser.Browser{
-		VU:     m.vu,
-		Client: browser.NewWebDriverClient("http://localhost:4444"),
+		VU:      m.vu,
+		Client:  browser.NewWebDriverClientWithOptions(driverURL, browser.ClientOptionsFromEnv()),
+		Driver:  driver,
+		Metrics: m.metrics,
 	}
 
 	return modules.Exports{
 		Named: map[string]any{
-			"browser":            b,
-			"compareScreenshots": browser.CompareImages,
-			"createDiffImage":    browser.CreateDiffImage,
+			"browser":                         b,
+			"compareScreenshots":              browser.CompareImages,
+			"createDiffImage":                 browser.CreateDiffImage,
+			"createDiffImageWithStats":        browser.CreateDiffImageWithStats,
+			"compareScreenshotToFile":         browser.CompareScreenshotToFile,
+			"createDiffImageWithBaselineFile": browser.CreateDiffImageWithBaselineFile,
+			"compareAgainstBaseline":          browser.CompareAgainstBaseline,
+			"expect":                          browser.Expect,
+			"devices":                         browser.Devices,
+			"setLogLevel":                     browser.SetLogLevel,
 		},
 	}
 }
@@ -26,6 +26,7 @@ func Test_module(t *testing.T) { //nolint:tparallel
 		{name: "browser", check: `typeof mod.browser === "object"`},
 		{name: "browser.newPage()", check: `typeof mod.browser.newPage === "function"`},
 		{name: "browser.close()", check: `typeof mod.browser.close === "function"`},
+		{name: "compareAgainstBaseline()", check: `typeof mod.compareAgainstBaseline === "function"`},
 	}
 	for _, tt := range tests { //nolint:paralleltest
 		t.Run(tt.name, func(t *testing.T) {